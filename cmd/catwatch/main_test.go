@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/maniack/catwatch/internal/storage"
+)
+
+func TestServerAutoMigratesEmptyDatabase(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("pick a port: %v", err)
+	}
+	addr := l.Addr().String()
+	_ = l.Close()
+
+	db := filepath.Join(t.TempDir(), "catwatch.db")
+	t.Setenv("AUTO_MIGRATE", "1")
+	t.Setenv("DB_PATH", db)
+	t.Setenv("LISTEN", addr)
+	t.Setenv("JWT_SECRET", "test-secret")
+
+	// The server runs until the test binary exits; a failed boot exits it with an error
+	go func() {
+		_ = newCommand().Run(context.Background(), []string{os.Args[0]})
+	}()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		resp, err := http.Get("http://" + addr + "/healthz/ready")
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				break
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("server did not become ready: %v", err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	store, err := storage.Connect(db)
+	if err != nil {
+		t.Fatalf("open storage: %v", err)
+	}
+	pending, err := store.PendingMigrations()
+	if err != nil {
+		t.Fatalf("pending migrations: %v", err)
+	}
+	if len(pending) != 0 {
+		t.Fatalf("expected all migrations applied, %d pending", len(pending))
+	}
+}
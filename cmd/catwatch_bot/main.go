@@ -45,6 +45,8 @@ func main() {
 			&cli.StringFlag{Name: "healthz-endpoint", Usage: "Healthz endpoint path", Value: "/healthz", Sources: cli.EnvVars("HEALTHZ_ENDPOINT")},
 			&cli.BoolFlag{Name: "debug", Usage: "Enable debug logging", Sources: cli.EnvVars("DEBUG")},
 			&cli.StringFlag{Name: "log-format", Usage: "Log format (text or json)", Value: "text", Sources: cli.EnvVars("LOG_FORMAT")},
+			&cli.DurationFlag{Name: "overdue-renotify-after", Usage: "Remind the volunteer who planned a procedure again when it is this late (0 disables)", Value: 2 * time.Hour, Sources: cli.EnvVars("OVERDUE_RENOTIFY_AFTER")},
+			&cli.DurationFlag{Name: "overdue-escalate-after", Usage: "Notify the coordinators of the organization when a procedure is this late (0 disables)", Value: 6 * time.Hour, Sources: cli.EnvVars("OVERDUE_ESCALATE_AFTER")},
 		},
 		Commands: []*cli.Command{
 			{
@@ -104,6 +106,9 @@ func main() {
 				Logger:           log,
 				Debug:            c.Bool("debug"),
 				HealthListenAddr: c.String("listen"),
+
+				OverdueRenotifyAfter: c.Duration("overdue-renotify-after"),
+				OverdueEscalateAfter: c.Duration("overdue-escalate-after"),
 			}
 
 			b, err := bot.NewBot(cfg)
@@ -0,0 +1,210 @@
+package backend
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maniack/catwatch/internal/storage"
+)
+
+const (
+	auditPageDefault = 100
+	auditPageMax     = 1000
+	// auditExportBatch is the number of rows fetched per query while streaming an export.
+	auditExportBatch = 500
+)
+
+var auditCSVHeader = []string{"id", "ts", "user_id", "method", "route", "target_type", "target_id", "status", "status_code", "request_id", "delta", "seq", "kind", "prev_hash", "hash"}
+
+// handleAdminAudit lists audit log entries for coordinators, limited to changes within their
+// organizations; admins see every entry:
+// GET /api/admin/audit?target_type=cat&target_id=...&user_id=...&route=...&status=error&since=...&until=...
+//
+// JSON responses are paginated with limit/cursor and a Link rel="next" header.
+// format=csv or format=ndjson (or the matching Accept header) streams all matching entries.
+func (s *Server) handleAdminAudit(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f := storage.AuditFilter{
+		TargetType: q.Get("target_type"),
+		TargetID:   q.Get("target_id"),
+		UserID:     q.Get("user_id"),
+		Route:      q.Get("route"),
+	}
+	scope := s.memberScope(r)
+	f.Scope = &scope
+	// status accepts either the outcome (success, error) or an HTTP status code
+	if st := q.Get("status"); st != "" {
+		if code, err := strconv.Atoi(st); err == nil {
+			f.StatusCode = code
+		} else if st == "success" || st == "error" {
+			f.Status = st
+		} else {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "status must be success, error or an HTTP status code"})
+			return
+		}
+	}
+	var err error
+	if f.Since, err = parseTimeRFC3339(q.Get("since")); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid since"})
+		return
+	}
+	if f.Until, err = parseTimeRFC3339(q.Get("until")); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid until"})
+		return
+	}
+	cursor, err := decodeCursor(q.Get("cursor"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if cursor != nil {
+		f.AfterTimestamp, f.AfterID = cursor.Timestamp, cursor.ID
+	}
+
+	switch auditFormat(r) {
+	case "csv":
+		s.exportAudit(w, f, "text/csv; charset=utf-8", "csv", newAuditCSVWriter)
+		return
+	case "ndjson":
+		s.exportAudit(w, f, "application/x-ndjson", "ndjson", newAuditNDJSONWriter)
+		return
+	case "json":
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "format must be json, csv or ndjson"})
+		return
+	}
+
+	limit, err := parseLimit(r, auditPageDefault, auditPageMax)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	// Fetch one extra row to learn whether there is a next page
+	f.Limit = limit + 1
+	logs, err := s.store.QueryAuditLogs(f)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	if len(logs) > limit {
+		logs = logs[:limit]
+		last := logs[len(logs)-1]
+		setNextLink(w, r, encodeCursor(last.Timestamp, last.ID))
+	}
+	writeJSON(w, http.StatusOK, logs)
+}
+
+// auditFormat picks the response format from the format parameter or the Accept header.
+func auditFormat(r *http.Request) string {
+	if f := r.URL.Query().Get("format"); f != "" {
+		return strings.ToLower(f)
+	}
+	accept := r.Header.Get("Accept")
+	switch {
+	case strings.Contains(accept, "text/csv"):
+		return "csv"
+	case strings.Contains(accept, "application/x-ndjson"):
+		return "ndjson"
+	}
+	return "json"
+}
+
+// auditRowWriter writes exported audit entries in a specific format.
+type auditRowWriter interface {
+	Write(e storage.AuditLog) error
+	Flush() error
+}
+
+type auditCSVWriter struct{ w *csv.Writer }
+
+func newAuditCSVWriter(w http.ResponseWriter) (auditRowWriter, error) {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(auditCSVHeader); err != nil {
+		return nil, err
+	}
+	return &auditCSVWriter{w: cw}, nil
+}
+
+func (a *auditCSVWriter) Write(e storage.AuditLog) error {
+	var seq string
+	if e.Seq != nil {
+		seq = strconv.FormatInt(*e.Seq, 10)
+	}
+	return a.w.Write([]string{
+		e.ID,
+		e.Timestamp.UTC().Format(time.RFC3339Nano),
+		e.UserID,
+		e.Method,
+		e.Route,
+		e.TargetType,
+		e.TargetID,
+		e.Status,
+		strconv.Itoa(e.StatusCode),
+		e.RequestID,
+		e.Delta,
+		seq,
+		e.Kind,
+		e.PrevHash,
+		e.Hash,
+	})
+}
+
+func (a *auditCSVWriter) Flush() error {
+	a.w.Flush()
+	return a.w.Error()
+}
+
+type auditNDJSONWriter struct{ enc *json.Encoder }
+
+func newAuditNDJSONWriter(w http.ResponseWriter) (auditRowWriter, error) {
+	return &auditNDJSONWriter{enc: json.NewEncoder(w)}, nil
+}
+
+func (a *auditNDJSONWriter) Write(e storage.AuditLog) error { return a.enc.Encode(e) }
+func (a *auditNDJSONWriter) Flush() error                   { return nil }
+
+// exportAudit streams all entries matching the filter, fetching them in batches.
+func (s *Server) exportAudit(w http.ResponseWriter, f storage.AuditFilter, contentType, ext string, newWriter func(http.ResponseWriter) (auditRowWriter, error)) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="audit-%s.%s"`, time.Now().UTC().Format("20060102-150405"), ext))
+	w.WriteHeader(http.StatusOK)
+
+	out, err := newWriter(w)
+	if err != nil {
+		s.log.WithError(err).Warn("audit: export failed")
+		return
+	}
+	f.Limit = auditExportBatch
+	for {
+		logs, err := s.store.QueryAuditLogs(f)
+		if err != nil {
+			// Headers are already sent; the truncated export is the best we can do
+			s.log.WithError(err).Error("audit: export query failed")
+			break
+		}
+		for _, e := range logs {
+			if err := out.Write(e); err != nil {
+				s.log.WithError(err).Warn("audit: export write failed")
+				return
+			}
+		}
+		if len(logs) < auditExportBatch {
+			break
+		}
+		last := logs[len(logs)-1]
+		f.AfterTimestamp, f.AfterID = last.Timestamp, last.ID
+		if err := out.Flush(); err != nil {
+			return
+		}
+		if fl, ok := w.(http.Flusher); ok {
+			fl.Flush()
+		}
+	}
+	_ = out.Flush()
+}
@@ -4,6 +4,14 @@ import (
 	"time"
 )
 
+// auditKey returns the HMAC key for audit checkpoints.
+func (s *Server) auditKey() []byte {
+	if s.cfg.AuditKey != "" {
+		return []byte(s.cfg.AuditKey)
+	}
+	return []byte(s.cfg.JWTSecret)
+}
+
 // startAuditLogCleanup launches a periodic cleanup of the AuditLog table based on TTL.
 func (s *Server) startAuditLogCleanup(interval time.Duration) {
 	ttl := s.cfg.AuditLogTTL
@@ -18,7 +26,7 @@ func (s *Server) startAuditLogCleanup(interval time.Duration) {
 	go func() {
 		for {
 			before := time.Now().Add(-ttl)
-			deleted, err := s.store.PruneAuditLogs(before)
+			deleted, err := s.store.PruneAuditLogs(before, s.auditKey())
 			if err != nil {
 				s.log.WithError(err).Warn("audit: cleanup failed")
 			} else if deleted > 0 {
@@ -0,0 +1,271 @@
+package backend
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maniack/catwatch/internal/storage"
+)
+
+func TestAdminAuditQuery(t *testing.T) {
+	s := newTestServer(t)
+	r := s.Router
+	coordinator := issueTestTokenWithRole(t, s, "coord-user", storage.RoleCoordinator)
+	volunteer := issueTestToken(t, s, "volunteer-user")
+
+	// Coordinators see changes of cats in their organizations
+	for i := 0; i < 3; i++ {
+		s.store.DB.Create(&storage.Cat{ID: fmt.Sprintf("cat-%d", i), OrganizationID: s.store.DefaultOrganizationID(), Name: "Audited"})
+	}
+
+	// 25 entries; pairs share a timestamp to exercise the keyset tie-breaker
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 25; i++ {
+		e := storage.AuditLog{
+			Timestamp:  base.Add(time.Duration(i/2) * time.Minute),
+			UserID:     "user-a",
+			Method:     http.MethodPut,
+			Route:      "/api/cats/{id}/",
+			TargetType: "cat",
+			TargetID:   fmt.Sprintf("cat-%d", i%3),
+			Status:     "success",
+			StatusCode: http.StatusOK,
+		}
+		if i%5 == 0 {
+			e.UserID = "user-b"
+			e.Status = "error"
+			e.StatusCode = http.StatusNotFound
+		}
+		if err := s.store.WriteAudit(&e); err != nil {
+			t.Fatalf("write audit: %v", err)
+		}
+	}
+
+	get := func(path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := get("/api/admin/audit", volunteer); w.Code != http.StatusForbidden {
+		t.Fatalf("volunteer: expected 403, got %d", w.Code)
+	}
+
+	// Follow the Link header through all pages
+	linkRe := regexp.MustCompile(`<([^>]+)>; rel="next"`)
+	seen := map[string]bool{}
+	var prev time.Time
+	path := "/api/admin/audit?target_type=cat&limit=10"
+	pages := 0
+	for path != "" {
+		w := get(path, coordinator)
+		if w.Code != http.StatusOK {
+			t.Fatalf("page %d: %d %s", pages, w.Code, w.Body.String())
+		}
+		var logs []storage.AuditLog
+		_ = json.Unmarshal(w.Body.Bytes(), &logs)
+		for _, e := range logs {
+			if seen[e.ID] {
+				t.Fatalf("duplicate entry %s across pages", e.ID)
+			}
+			if !prev.IsZero() && e.Timestamp.After(prev) {
+				t.Fatalf("entries are not ordered newest first")
+			}
+			seen[e.ID] = true
+			prev = e.Timestamp
+		}
+		pages++
+		path = ""
+		if m := linkRe.FindStringSubmatch(w.Header().Get("Link")); m != nil {
+			path = m[1]
+		}
+	}
+	if len(seen) != 25 || pages != 3 {
+		t.Fatalf("expected 25 entries on 3 pages, got %d on %d", len(seen), pages)
+	}
+
+	// Filters
+	cases := map[string]int{
+		"user_id=user-b":                     5,
+		"status=error":                       5,
+		"status=404":                         5,
+		"target_id=cat-0":                    9,
+		"route=" + "/api/cats/{id}/":         25,
+		"since=2024-05-01T12:10:00Z":         5,
+		"until=2024-05-01T12:01:00Z":         2,
+		"target_type=record":                 0,
+		"user_id=user-a&target_id=cat-1":     7,
+		"since=2024-05-01T12:02:00Z&limit=3": 3,
+	}
+	for query, want := range cases {
+		w := get("/api/admin/audit?"+query, coordinator)
+		var logs []storage.AuditLog
+		_ = json.Unmarshal(w.Body.Bytes(), &logs)
+		if len(logs) != want {
+			t.Fatalf("%s: expected %d entries, got %d", query, want, len(logs))
+		}
+	}
+
+	for _, query := range []string{"cursor=garbage", "since=yesterday", "status=maybe", "limit=0", "format=xml"} {
+		if w := get("/api/admin/audit?"+query, coordinator); w.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", query, w.Code)
+		}
+	}
+
+	// CSV export contains every matching entry regardless of limit
+	w := get("/api/admin/audit?format=csv&status=error&limit=1", coordinator)
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+		t.Fatalf("csv content type: %s", ct)
+	}
+	rows, err := csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+	if err != nil {
+		t.Fatalf("parse csv: %v", err)
+	}
+	if len(rows) != 6 || rows[0][0] != "id" {
+		t.Fatalf("expected header and 5 rows, got %d rows", len(rows))
+	}
+
+	// NDJSON via Accept header
+	req := httptest.NewRequest(http.MethodGet, "/api/admin/audit?user_id=user-a", nil)
+	req.Header.Set("Authorization", "Bearer "+coordinator)
+	req.Header.Set("Accept", "application/x-ndjson")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	sc := bufio.NewScanner(w.Body)
+	lines := 0
+	for sc.Scan() {
+		var e storage.AuditLog
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil || e.UserID != "user-a" {
+			t.Fatalf("bad ndjson line %q", sc.Text())
+		}
+		lines++
+	}
+	if lines != 20 {
+		t.Fatalf("expected 20 ndjson lines, got %d", lines)
+	}
+}
+
+func TestAdminAuditOrganizationScope(t *testing.T) {
+	s := newTestServer(t)
+	r := s.Router
+
+	org := storage.Organization{ID: storage.NewUUID(), Name: "Shelter B", Slug: "shelter-b"}
+	if err := s.store.DB.Create(&org).Error; err != nil {
+		t.Fatalf("create org: %v", err)
+	}
+	_ = s.store.AddMember(org.ID, "coord-b")
+	cat := storage.Cat{ID: storage.NewUUID(), OrganizationID: org.ID, Name: "Cat B"}
+	rec := storage.Record{ID: storage.NewUUID(), CatID: cat.ID, Type: "feeding"}
+	s.store.DB.Create(&cat)
+	s.store.DB.Create(&rec)
+	for _, target := range [][2]string{{"cat", cat.ID}, {"record", rec.ID}, {"organization", org.ID}, {"user", "coord-b"}} {
+		e := storage.AuditLog{Timestamp: time.Now(), UserID: "coord-b", Method: http.MethodPut, TargetType: target[0], TargetID: target[1], Status: "success"}
+		if err := s.store.WriteAudit(&e); err != nil {
+			t.Fatalf("write audit: %v", err)
+		}
+	}
+
+	count := func(token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/admin/audit", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("audit: %d %s", w.Code, w.Body.String())
+		}
+		var logs []storage.AuditLog
+		_ = json.Unmarshal(w.Body.Bytes(), &logs)
+		return len(logs)
+	}
+
+	// Coordinators of other organizations see nothing, their own coordinators the
+	// organization's changes and admins everything
+	if n := count(issueTestTokenWithRole(t, s, "coord-a", storage.RoleCoordinator)); n != 0 {
+		t.Fatalf("foreign coordinator: expected no entries, got %d", n)
+	}
+	if n := count(issueTestTokenWithRole(t, s, "coord-b", storage.RoleCoordinator)); n != 3 {
+		t.Fatalf("coordinator: expected 3 entries, got %d", n)
+	}
+	if n := count(issueTestTokenWithRole(t, s, "admin", storage.RoleAdmin)); n != 4 {
+		t.Fatalf("admin: expected 4 entries, got %d", n)
+	}
+}
+
+func TestAuditHashChain(t *testing.T) {
+	s := newTestServer(t)
+	key := s.auditKey()
+
+	base := time.Now().Add(-48 * time.Hour)
+	for i := 0; i < 6; i++ {
+		e := storage.AuditLog{Timestamp: base.Add(time.Duration(i) * time.Hour), UserID: "u", Method: http.MethodPost, TargetType: "cat", Status: "success"}
+		if err := s.store.WriteAudit(&e); err != nil {
+			t.Fatalf("write audit: %v", err)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		e := storage.AuditLog{UserID: "u", Method: http.MethodPut, TargetType: "cat", Status: "success"}
+		_ = s.store.WriteAudit(&e)
+	}
+	verify := func(k []byte) *storage.AuditVerifyReport {
+		t.Helper()
+		rep, err := s.store.VerifyAuditChain(k)
+		if err != nil {
+			t.Fatalf("verify: %v", err)
+		}
+		return rep
+	}
+	if rep := verify(key); !rep.OK() || rep.Checked != 9 || rep.LastSeq != 9 {
+		t.Fatalf("fresh chain: %+v", rep)
+	}
+
+	// Pruning writes a signed checkpoint and keeps the rest verifiable
+	deleted, err := s.store.PruneAuditLogs(time.Now().Add(-24*time.Hour), key)
+	if err != nil || deleted != 6 {
+		t.Fatalf("prune: deleted=%d err=%v", deleted, err)
+	}
+	rep := verify(key)
+	if !rep.OK() || rep.FirstSeq != 7 || rep.Checkpoints != 1 {
+		t.Fatalf("after prune: %+v", rep)
+	}
+	if rep := verify([]byte("wrong-key")); rep.OK() || rep.Problems[0].Kind != storage.AuditProblemBadSignature {
+		t.Fatalf("wrong key must invalidate the checkpoint: %+v", rep)
+	}
+
+	// Modified content
+	var victim storage.AuditLog
+	s.store.DB.Where("seq = ?", 8).First(&victim)
+	s.store.DB.Model(&victim).Update("user_id", "someone-else")
+	rep = verify(key)
+	if rep.OK() || rep.Problems[0].Kind != storage.AuditProblemModified || rep.Problems[0].Seq != 8 {
+		t.Fatalf("modified row not detected: %+v", rep)
+	}
+	s.store.DB.Model(&victim).Update("user_id", "u")
+
+	// Deleted row in the middle
+	s.store.DB.Where("seq = ?", 8).Delete(&storage.AuditLog{})
+	rep = verify(key)
+	if rep.OK() || rep.Problems[0].Kind != storage.AuditProblemGap {
+		t.Fatalf("gap not detected: %+v", rep)
+	}
+
+	// Deleting the oldest rows without a checkpoint is detected as well
+	s.store.DB.Where("1 = 1").Delete(&storage.AuditLog{})
+	for i := 0; i < 3; i++ {
+		e := storage.AuditLog{UserID: "u", Method: http.MethodPost, TargetType: "cat", Status: "success"}
+		_ = s.store.WriteAudit(&e)
+	}
+	s.store.DB.Where("seq = ?", 1).Delete(&storage.AuditLog{})
+	if rep, _ := s.store.VerifyAuditChain(key); rep.OK() {
+		t.Fatalf("truncated chain must fail verification")
+	}
+}
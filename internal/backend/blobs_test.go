@@ -0,0 +1,118 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/maniack/catwatch/internal/blobstore"
+	"github.com/maniack/catwatch/internal/storage"
+)
+
+// presigningFS adds fake presigned URLs to the filesystem store.
+type presigningFS struct{ *blobstore.FS }
+
+func (presigningFS) PresignGet(key string, ttl time.Duration) (string, error) {
+	return "https://blobs.example/" + key + "?ttl=" + ttl.String(), nil
+}
+
+func (presigningFS) Origin() string { return "https://blobs.example" }
+
+func TestImagesInBlobStore(t *testing.T) {
+	s := newTestServer(t)
+	r := s.Router
+	token := issueTestTokenWithRole(t, s, "coord-user", storage.RoleCoordinator)
+	root := t.TempDir()
+	fs, err := blobstore.NewFS(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	catID := storage.NewUUID()
+	s.store.DB.Create(&storage.Cat{ID: catID, OrganizationID: s.store.DefaultOrganizationID(), Name: "Blob"})
+	var photo bytes.Buffer
+	_ = png.Encode(&photo, image.NewRGBA(image.Rect(0, 0, 640, 480)))
+
+	// A photo uploaded before the blob store was configured stays in the database
+	legacy := storage.Image{ID: storage.NewUUID(), CatID: catID, MIME: "image/png", Data: photo.Bytes()}
+	s.store.DB.Create(&legacy)
+	s.store.SetBlobStore(fs)
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	fw, _ := mw.CreateFormFile("file", "cat.png")
+	_, _ = fw.Write(photo.Bytes())
+	_ = mw.Close()
+	req := httptest.NewRequest(http.MethodPost, "/api/cats/"+catID+"/images", &buf)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("X-CSRF-Token", "1")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var uploaded storage.Image
+	_ = json.Unmarshal(w.Body.Bytes(), &uploaded)
+
+	var row storage.Image
+	s.store.DB.First(&row, "id = ?", uploaded.ID)
+	if row.Data != nil || row.BlobKey == "" {
+		t.Fatalf("uploaded photo must go to the blob store (data %d bytes, key %q)", len(row.Data), row.BlobKey)
+	}
+	if _, err := os.Stat(filepath.Join(root, row.BlobKey)); err != nil {
+		t.Fatalf("blob file: %v", err)
+	}
+
+	get := func(imgID, size string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/cats/"+catID+"/images/"+imgID+"?size="+size, nil))
+		return w
+	}
+	for _, id := range []string{legacy.ID, uploaded.ID} {
+		if w := get(id, "original"); w.Code != http.StatusOK || !bytes.Equal(w.Body.Bytes(), photo.Bytes()) {
+			t.Fatalf("original of %s: %d, %d bytes", id, w.Code, w.Body.Len())
+		}
+		if w := get(id, "thumb"); w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/webp" {
+			t.Fatalf("thumb of %s: %d %s", id, w.Code, w.Header().Get("Content-Type"))
+		}
+	}
+	if rend, err := s.store.GetImageRendition(uploaded.ID, "thumb"); err != nil || rend.BlobKey == "" || rend.Data != nil {
+		t.Fatalf("rendition must be stored as a blob: %+v %v", rend, err)
+	}
+
+	// Existing rows are moved out of the database
+	moved, err := s.store.MoveImagesToBlobStore(context.Background(), 1)
+	if err != nil || moved != 1 { // the legacy thumb was rendered into the blob store already
+		t.Fatalf("move: %d %v", moved, err)
+	}
+	s.store.DB.First(&row, "id = ?", legacy.ID)
+	if row.Data != nil || row.BlobKey == "" {
+		t.Fatalf("legacy photo not moved")
+	}
+	if w := get(legacy.ID, "original"); !bytes.Equal(w.Body.Bytes(), photo.Bytes()) {
+		t.Fatalf("moved photo not served")
+	}
+
+	// Presigning stores redirect instead of streaming
+	s.store.SetBlobStore(presigningFS{fs})
+	s.cfg.ImagePresignTTL = time.Minute
+	if w := get(uploaded.ID, "original"); w.Code != http.StatusFound || w.Header().Get("Location") != "https://blobs.example/images/"+uploaded.ID+"/original?ttl=1m0s" {
+		t.Fatalf("expected a redirect, got %d %q", w.Code, w.Header().Get("Location"))
+	}
+
+	// Deleting an image removes its blobs
+	req = httptest.NewRequest(http.MethodDelete, "/api/cats/"+catID+"/images/"+uploaded.ID, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("X-CSRF-Token", "1")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	if entries, _ := os.ReadDir(filepath.Join(root, "images", uploaded.ID)); len(entries) != 0 {
+		t.Fatalf("blobs left after delete: %v", entries)
+	}
+}
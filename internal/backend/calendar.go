@@ -0,0 +1,216 @@
+package backend
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/maniack/catwatch/internal/ical"
+	"github.com/maniack/catwatch/internal/storage"
+	"gorm.io/gorm"
+)
+
+const (
+	// calendarEventDuration is the length of procedures in calendar feeds.
+	calendarEventDuration = 30 * time.Minute
+	// calendarAlarm matches the bot reminders, sent 30 minutes ahead.
+	calendarAlarm = 30 * time.Minute
+)
+
+// calendarFeedURLs returns the feed URLs of a calendar token secret.
+func (s *Server) calendarFeedURLs(r *http.Request, secret string) map[string]string {
+	q := url.Values{"token": {secret}}.Encode()
+	return map[string]string{
+		"url":          s.getBaseURL(r) + "/api/calendar/planned.ics?" + q,
+		"cat_url_tmpl": s.getBaseURL(r) + "/api/cats/{id}/records.ics?" + q,
+	}
+}
+
+func (s *Server) handleListCalendarTokens(w http.ResponseWriter, r *http.Request) {
+	uid, _ := UserIDFromCtx(r.Context())
+	tokens, err := s.store.ListCalendarTokens(uid)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, tokens)
+}
+
+// handleCreateCalendarToken issues a calendar token. The secret and the feed URLs built from it
+// are only returned here.
+func (s *Server) handleCreateCalendarToken(w http.ResponseWriter, r *http.Request) {
+	uid, _ := UserIDFromCtx(r.Context())
+	t, secret, err := s.store.CreateCalendarToken(uid)
+	if err != nil {
+		s.LogAuditError(r, "calendar_token", "", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	s.LogAudit(r, "calendar_token", t.ID, nil, t)
+	out := map[string]any{"id": t.ID, "created_at": t.CreatedAt, "token": secret}
+	for k, v := range s.calendarFeedURLs(r, secret) {
+		out[k] = v
+	}
+	writeJSON(w, http.StatusCreated, out)
+}
+
+func (s *Server) handleRevokeCalendarToken(w http.ResponseWriter, r *http.Request) {
+	uid, _ := UserIDFromCtx(r.Context())
+	id := chi.URLParam(r, "tokenId")
+	t, err := s.store.RevokeCalendarToken(uid, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "token not found"})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	s.LogAudit(r, "calendar_token", id, t, nil)
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// calendarScope returns the organizations the owner of the token in the request belongs to.
+// It writes 401 and returns false for missing, unknown and revoked tokens.
+func (s *Server) calendarScope(w http.ResponseWriter, r *http.Request) (storage.OrgScope, bool) {
+	secret := r.URL.Query().Get("token")
+	if secret == "" {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "token required"})
+		return storage.OrgScope{}, false
+	}
+	u, err := s.store.CalendarTokenUser(secret)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid token"})
+		return storage.OrgScope{}, false
+	}
+	return s.store.MemberScope(u.ID, u.Role), true
+}
+
+// handleCalendarFeed serves the planned records of the token owner's organizations as iCalendar.
+func (s *Server) handleCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	scope, ok := s.calendarScope(w, r)
+	if !ok {
+		return
+	}
+	s.writeCalendar(w, r, scope, "", "CatWatch")
+}
+
+// handleCatCalendarFeed serves the planned records of one cat as iCalendar.
+func (s *Server) handleCatCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	catID := chi.URLParam(r, "id")
+	scope, ok := s.calendarScope(w, r)
+	if !ok {
+		return
+	}
+	if !s.requireCatInScope(w, catID, scope) {
+		return
+	}
+	var cat storage.Cat
+	if err := s.store.DB.Select("id", "name").First(&cat, "id = ?", catID).Error; err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	s.writeCalendar(w, r, scope, catID, "CatWatch: "+cat.Name)
+}
+
+func (s *Server) writeCalendar(w http.ResponseWriter, r *http.Request, scope storage.OrgScope, catID, name string) {
+	recs, err := s.plannedRecords(scope, catID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	var ids, catIDs []string
+	for _, rec := range recs {
+		ids = append(ids, rec.ID)
+		catIDs = append(catIDs, rec.CatID)
+	}
+	overrides, err := s.store.RecordOverrides(ids)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	byRecord := map[string][]storage.RecordOverride{}
+	for _, o := range overrides {
+		byRecord[o.RecordID] = append(byRecord[o.RecordID], o)
+	}
+	catNames := map[string]string{}
+	if len(catIDs) > 0 {
+		var cats []storage.Cat
+		if err := s.store.DB.Select("id", "name").Where("id IN ?", catIDs).Find(&cats).Error; err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		for _, c := range cats {
+			catNames[c.ID] = c.Name
+		}
+	}
+
+	cal := ical.Calendar{ProdID: "-//CatWatch//Planned procedures//EN", Name: name}
+	for _, rec := range recs {
+		cal.Events = append(cal.Events, calendarEvents(rec, byRecord[rec.ID], catNames[rec.CatID], s.getBaseURL(r))...)
+	}
+	w.Header().Set("Content-Type", ical.ContentType)
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	w.WriteHeader(http.StatusOK)
+	if err := cal.Encode(w); err != nil {
+		s.log.WithError(err).Warn("calendar: failed to write feed")
+	}
+}
+
+// calendarEvents returns the event of a planned record. Recurring records keep their rule: days
+// excluded, skipped or done become EXDATEs and moved occurrences separate events with the same UID.
+func calendarEvents(rec storage.Record, overrides []storage.RecordOverride, catName, baseURL string) []ical.Event {
+	loc := rec.Location()
+	base := ical.Event{
+		UID:         rec.ID + "@catwatch",
+		Stamp:       rec.CreatedAt,
+		Start:       rec.PlannedAt.In(loc),
+		Duration:    calendarEventDuration,
+		Summary:     rec.Type + ": " + catName,
+		Description: rec.Note,
+		URL:         baseURL + "/#/cat/view/" + rec.CatID,
+		Alarm:       calendarAlarm,
+	}
+	if rec.TimeZone != "" {
+		base.TZID = rec.TimeZone
+	}
+	set, ok := rec.RecurrenceSet()
+	if !ok {
+		return []ical.Event{base}
+	}
+	master := base
+	if rec.RRule != "" || rec.Recurrence != "" {
+		master.RRule = set.Rule.String()
+	}
+	// EXDATE and RDATE values carry the time of the occurrence they match
+	at := func(day time.Time) time.Time {
+		d := day.In(loc)
+		return time.Date(d.Year(), d.Month(), d.Day(), set.Start.Hour(), set.Start.Minute(), set.Start.Second(), 0, loc)
+	}
+	for _, d := range rec.ExDates {
+		master.ExDates = append(master.ExDates, at(d))
+	}
+	for _, d := range rec.RDates {
+		master.RDates = append(master.RDates, d.In(loc))
+	}
+	events := []ical.Event{master}
+	for _, o := range overrides {
+		orig, ok := set.On(o.Day)
+		if !ok {
+			continue
+		}
+		if o.PlannedAt == nil {
+			// Skipped or done
+			events[0].ExDates = append(events[0].ExDates, orig)
+			continue
+		}
+		moved := base
+		moved.Stamp = o.CreatedAt
+		moved.Start = o.PlannedAt.In(loc)
+		moved.RecurrenceID = &orig
+		events = append(events, moved)
+	}
+	return events
+}
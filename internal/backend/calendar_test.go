@@ -0,0 +1,119 @@
+package backend
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maniack/catwatch/internal/recurrence"
+	"github.com/maniack/catwatch/internal/storage"
+)
+
+func TestCalendarFeeds(t *testing.T) {
+	s := newTestServer(t)
+	r := s.Router
+	uid := "calendar-user"
+	s.store.DB.Create(&storage.User{ID: uid, Name: "Calendar", Role: storage.DefaultRole})
+	token := issueTestToken(t, s, uid)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	cat := storage.Cat{ID: storage.NewUUID(), OrganizationID: s.store.DefaultOrganizationID(), Name: "Murka"}
+	other := storage.Cat{ID: storage.NewUUID(), OrganizationID: s.store.DefaultOrganizationID(), Name: "Barsik"}
+	s.store.DB.Create(&cat)
+	s.store.DB.Create(&other)
+	start := time.Date(2026, time.March, 28, 9, 0, 0, 0, berlin)
+	daily := storage.Record{
+		ID: storage.NewUUID(), CatID: cat.ID, Type: "feeding", Note: "Wet food", PlannedAt: &start,
+		TimeZone: "Europe/Berlin", RRule: "FREQ=DAILY", ExDates: []time.Time{start.AddDate(0, 0, 1)},
+	}
+	visit := time.Date(2026, time.April, 1, 10, 0, 0, 0, time.UTC)
+	vet := storage.Record{ID: storage.NewUUID(), CatID: other.ID, Type: "vet_visit", PlannedAt: &visit}
+	done := storage.Record{ID: storage.NewUUID(), CatID: other.ID, Type: "medication", PlannedAt: &start, DoneAt: &start}
+	for _, rec := range []storage.Record{daily, vet, done} {
+		s.store.DB.Create(&rec)
+	}
+	moved := start.AddDate(0, 0, 3).Add(2 * time.Hour)
+	s.store.DB.Create(&storage.RecordOverride{ID: storage.NewUUID(), RecordID: daily.ID, Day: start.AddDate(0, 0, 2).Format(recurrence.DayLayout), Action: storage.OverrideSkipped})
+	s.store.DB.Create(&storage.RecordOverride{ID: storage.NewUUID(), RecordID: daily.ID, Day: start.AddDate(0, 0, 3).Format(recurrence.DayLayout), Action: storage.OverrideRescheduled, PlannedAt: &moved})
+
+	// Issue a token; the secret is only shown once
+	w := doTestRequest(s, http.MethodPost, "/api/user/calendar-tokens", token, nil)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create token: %d %s", w.Code, w.Body.String())
+	}
+	var created struct {
+		ID    string `json:"id"`
+		Token string `json:"token"`
+		URL   string `json:"url"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &created)
+	if created.Token == "" || !strings.Contains(created.URL, "/api/calendar/planned.ics?token="+created.Token) {
+		t.Fatalf("unexpected token response: %s", w.Body.String())
+	}
+	w = doTestRequest(s, http.MethodGet, "/api/user/calendar-tokens", token, nil)
+	if w.Code != http.StatusOK || strings.Contains(w.Body.String(), created.Token) || !strings.Contains(w.Body.String(), created.ID) {
+		t.Fatalf("list tokens: %d %s", w.Code, w.Body.String())
+	}
+
+	w = get("/api/calendar/planned.ics?token=" + created.Token)
+	if w.Code != http.StatusOK {
+		t.Fatalf("feed: %d %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/calendar") {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	feed := w.Body.String()
+	for _, want := range []string{
+		"UID:" + daily.ID + "@catwatch\r\n",
+		"DTSTART;TZID=Europe/Berlin:20260328T090000\r\n",
+		"RRULE:FREQ=DAILY\r\n",
+		"EXDATE;TZID=Europe/Berlin:20260329T090000\r\n",
+		"EXDATE;TZID=Europe/Berlin:20260330T090000\r\n",
+		"RECURRENCE-ID;TZID=Europe/Berlin:20260331T090000\r\n",
+		"DTSTART;TZID=Europe/Berlin:20260331T110000\r\n",
+		"SUMMARY:feeding: Murka\r\n",
+		"TRIGGER:-PT30M\r\n",
+		"UID:" + vet.ID + "@catwatch\r\n",
+		"DTSTART:20260401T100000Z\r\n",
+	} {
+		if !strings.Contains(feed, want) {
+			t.Errorf("feed misses %q:\n%s", want, feed)
+		}
+	}
+	if strings.Contains(feed, done.ID) {
+		t.Errorf("done records must not be in the feed")
+	}
+
+	// Per-cat feed
+	w = get("/api/cats/" + cat.ID + "/records.ics?token=" + created.Token)
+	if w.Code != http.StatusOK {
+		t.Fatalf("cat feed: %d %s", w.Code, w.Body.String())
+	}
+	if body := w.Body.String(); !strings.Contains(body, daily.ID) || strings.Contains(body, vet.ID) {
+		t.Fatalf("cat feed has wrong records:\n%s", body)
+	}
+
+	if w := get("/api/calendar/planned.ics"); w.Code != http.StatusUnauthorized {
+		t.Fatalf("feed without token: expected 401, got %d", w.Code)
+	}
+
+	// Revoked tokens stop working
+	if w := doTestRequest(s, http.MethodDelete, "/api/user/calendar-tokens/"+created.ID, token, nil); w.Code != http.StatusOK {
+		t.Fatalf("revoke: %d %s", w.Code, w.Body.String())
+	}
+	if w := doTestRequest(s, http.MethodDelete, "/api/user/calendar-tokens/"+created.ID, token, nil); w.Code != http.StatusNotFound {
+		t.Fatalf("revoke twice: expected 404, got %d", w.Code)
+	}
+	if w := get("/api/calendar/planned.ics?token=" + created.Token); w.Code != http.StatusUnauthorized {
+		t.Fatalf("revoked token: expected 401, got %d", w.Code)
+	}
+}
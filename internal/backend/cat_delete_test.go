@@ -13,7 +13,7 @@ import (
 func TestCatSoftDelete(t *testing.T) {
 	s := newTestServer(t)
 	r := s.Router
-	token := issueTestToken(t, s, "admin-user")
+	token := issueTestTokenWithRole(t, s, "admin-user", storage.RoleAdmin)
 
 	// 1. Create a cat
 	in := catIn{Name: "SoftDeleteCat"}
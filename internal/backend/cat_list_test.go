@@ -0,0 +1,136 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/maniack/catwatch/internal/storage"
+)
+
+func TestListCatsPagination(t *testing.T) {
+	s := newTestServer(t)
+	r := s.Router
+	token := issueTestToken(t, s, "user-a")
+
+	ginger := storage.Tag{ID: storage.NewUUID(), Name: "ginger"}
+	shy := storage.Tag{ID: storage.NewUUID(), Name: "shy"}
+	base := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 11; i++ {
+		c := storage.Cat{
+			ID:             storage.NewUUID(),
+			OrganizationID: s.store.DefaultOrganizationID(),
+			Name:           fmt.Sprintf("Cat %02d", i%7), // duplicate names exercise the tie-breaker
+			Gender:         []string{"male", "female"}[i%2],
+			IsSterilized:   i%3 == 0,
+			Condition:      i%5 + 1,
+			NeedAttention:  i%5 < 2,
+			CreatedAt:      base.Add(time.Duration(i/2) * time.Hour), // pairs share created_at
+		}
+		if i%4 != 0 {
+			seen := base.Add(time.Duration(20-i) * time.Hour)
+			c.LastSeen = &seen
+		}
+		if i%2 == 0 {
+			c.Tags = append(c.Tags, ginger)
+		}
+		if i%3 == 0 {
+			c.Tags = append(c.Tags, shy)
+		}
+		if err := s.store.DB.Create(&c).Error; err != nil {
+			t.Fatalf("create cat: %v", err)
+		}
+	}
+
+	get := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	ids := func(w *httptest.ResponseRecorder) []string {
+		var cats []PublicCat
+		if err := json.Unmarshal(w.Body.Bytes(), &cats); err != nil {
+			t.Fatalf("decode %s: %v", w.Body.String(), err)
+		}
+		out := make([]string, len(cats))
+		for i, c := range cats {
+			out[i] = c.ID
+		}
+		return out
+	}
+
+	// Without limit everything is returned in one response
+	w := get("/api/cats/")
+	if all := ids(w); len(all) != 11 || w.Header().Get("Link") != "" {
+		t.Fatalf("legacy list: %d cats, Link %q", len(all), w.Header().Get("Link"))
+	}
+
+	// Pages concatenate to the unpaginated order for every sort key
+	linkRe := regexp.MustCompile(`<([^>]+)>; rel="next"`)
+	for _, sort := range []string{"", "created_at", "-updated_at", "last_seen", "-last_seen", "name", "-name", "condition", "-condition"} {
+		want := ids(get("/api/cats/?sort=" + sort))
+		var got []string
+		path := "/api/cats/?limit=3&sort=" + sort
+		for path != "" {
+			w := get(path)
+			if w.Code != http.StatusOK {
+				t.Fatalf("sort %q: %d %s", sort, w.Code, w.Body.String())
+			}
+			got = append(got, ids(w)...)
+			path = ""
+			if m := linkRe.FindStringSubmatch(w.Header().Get("Link")); m != nil {
+				path = m[1]
+			}
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("sort %q: paginated order %v differs from %v", sort, got, want)
+		}
+	}
+
+	// Filters
+	cases := map[string]int{
+		"tag=ginger":                            6,
+		"tag=ginger&tag=shy":                    2,
+		"gender=female":                         5,
+		"sterilized=true":                       4,
+		"need_attention=true":                   5,
+		"condition_min=2&condition_max=3":       4,
+		"last_seen_after=2024-03-01T25:00:00Z":  -1,
+		"last_seen_after=2024-03-02T04:00:00Z":  2,
+		"last_seen_before=2024-03-01T23:00:00Z": 2,
+		"created_after=2024-03-01T13:00:00Z":    5,
+		"created_before=2024-03-01T11:00:00Z":   2,
+		"gender=male&sterilized=false&limit=2":  2,
+	}
+	for query, want := range cases {
+		w := get("/api/cats/?" + query)
+		if want < 0 {
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("%s: expected 400, got %d", query, w.Code)
+			}
+			continue
+		}
+		if got := ids(w); len(got) != want {
+			t.Fatalf("%s: expected %d cats, got %d", query, want, len(got))
+		}
+	}
+
+	// A cursor is bound to its sort order
+	w = get("/api/cats/?limit=3&sort=name")
+	m := linkRe.FindStringSubmatch(w.Header().Get("Link"))
+	if m == nil {
+		t.Fatalf("expected a Link header")
+	}
+	cursor := regexp.MustCompile(`cursor=([^&]+)`).FindStringSubmatch(m[1])[1]
+	for _, query := range []string{"sort=condition&cursor=" + cursor, "cursor=garbage", "sort=color", "gender=cat", "condition_min=9", "sterilized=maybe", "limit=-1"} {
+		if w := get("/api/cats/?" + query); w.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", query, w.Code)
+		}
+	}
+}
@@ -7,23 +7,26 @@ import (
 )
 
 type PublicCat struct {
-	ID            string                `json:"id"`
-	Name          string                `json:"name"`
-	Description   string                `json:"description,omitempty"`
-	Color         string                `json:"color,omitempty"`
-	BirthDate     *time.Time            `json:"birth_date,omitempty"`
-	Gender        string                `json:"gender"`
-	IsSterilized  bool                  `json:"is_sterilized"`
-	Condition     int                   `json:"condition"`
-	NeedAttention bool                  `json:"need_attention"`
-	Tags          []storage.Tag         `json:"tags,omitempty"`
-	LastSeen      *time.Time            `json:"last_seen,omitempty"`
-	Locations     []storage.CatLocation `json:"locations,omitempty"`
-	Images        []storage.Image       `json:"images,omitempty"`
-	Likes         int64                 `json:"likes"`
-	Liked         bool                  `json:"liked"`
-	CreatedAt     time.Time             `json:"created_at"`
-	Records       any                   `json:"records,omitempty"`
+	ID             string                `json:"id"`
+	OrganizationID string                `json:"organization_id,omitempty"`
+	Name           string                `json:"name"`
+	Description    string                `json:"description,omitempty"`
+	Color          string                `json:"color,omitempty"`
+	BirthDate      *time.Time            `json:"birth_date,omitempty"`
+	Gender         string                `json:"gender"`
+	IsSterilized   bool                  `json:"is_sterilized"`
+	Condition      int                   `json:"condition"`
+	NeedAttention  bool                  `json:"need_attention"`
+	Tags           []storage.Tag         `json:"tags,omitempty"`
+	LastSeen       *time.Time            `json:"last_seen,omitempty"`
+	ImageRetention *int                  `json:"image_retention,omitempty"`
+	Locations      []storage.CatLocation `json:"locations,omitempty"`
+	Images         []storage.Image       `json:"images,omitempty"`
+	Likes          int64                 `json:"likes"`
+	Liked          bool                  `json:"liked"`
+	CreatedAt      time.Time             `json:"created_at"`
+	DeletedAt      *time.Time            `json:"deleted_at,omitempty"`
+	Records        any                   `json:"records,omitempty"`
 }
 
 type PublicRecord struct {
@@ -34,24 +37,30 @@ type PublicRecord struct {
 }
 
 func ToPublicCat(c storage.Cat) PublicCat {
-	return PublicCat{
-		ID:            c.ID,
-		Name:          c.Name,
-		Description:   c.Description,
-		Color:         c.Color,
-		BirthDate:     c.BirthDate,
-		Gender:        c.Gender,
-		IsSterilized:  c.IsSterilized,
-		Condition:     c.Condition,
-		NeedAttention: c.NeedAttention,
-		Tags:          c.Tags,
-		LastSeen:      c.LastSeen,
-		Locations:     c.Locations,
-		Images:        c.Images,
-		Likes:         c.Likes,
-		Liked:         c.Liked,
-		CreatedAt:     c.CreatedAt,
+	pc := PublicCat{
+		ID:             c.ID,
+		OrganizationID: c.OrganizationID,
+		Name:           c.Name,
+		Description:    c.Description,
+		Color:          c.Color,
+		BirthDate:      c.BirthDate,
+		Gender:         c.Gender,
+		IsSterilized:   c.IsSterilized,
+		Condition:      c.Condition,
+		NeedAttention:  c.NeedAttention,
+		Tags:           c.Tags,
+		LastSeen:       c.LastSeen,
+		ImageRetention: c.ImageRetention,
+		Locations:      c.Locations,
+		Images:         c.Images,
+		Likes:          c.Likes,
+		Liked:          c.Liked,
+		CreatedAt:      c.CreatedAt,
 	}
+	if c.DeletedAt.Valid {
+		pc.DeletedAt = &c.DeletedAt.Time
+	}
+	return pc
 }
 
 func ToPublicRecord(r storage.Record) PublicRecord {
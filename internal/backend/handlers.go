@@ -14,6 +14,7 @@ import (
 	"github.com/go-chi/chi/v5"
 	"gorm.io/gorm"
 
+	"github.com/maniack/catwatch/internal/blobstore"
 	"github.com/maniack/catwatch/internal/l10n"
 	"github.com/maniack/catwatch/internal/logging"
 	"github.com/maniack/catwatch/internal/monitoring"
@@ -161,26 +162,156 @@ func (s *Server) handleDevLoginGET(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+const (
+	catPageDefault = 50
+	catPageMax     = 200
+)
+
+// listCats lists cats visible to the caller: GET /api/cats/?tag=...&gender=...&sort=-last_seen&limit=20
+//
+// Without limit and cursor all matching cats are returned. Otherwise the response is a page
+// and the next one is advertised in a Link rel="next" header.
 func (s *Server) listCats(w http.ResponseWriter, r *http.Request) {
 	uid, _ := UserIDFromCtx(r.Context())
-	var cats []storage.Cat
-	if err := s.store.DB.Preload("Locations").Preload("Images").Preload("Tags").Order("created_at DESC").Find(&cats).Error; err != nil {
+	f, err := parseCatFilter(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	q := r.URL.Query()
+	paged := q.Get("limit") != "" || q.Get("cursor") != ""
+	limit := 0
+	if paged {
+		if limit, err = parseLimit(r, catPageDefault, catPageMax); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		// Fetch one extra cat to learn whether there is a next page
+		f.Limit = limit + 1
+	}
+
+	cats, err := s.store.QueryCats(s.readScope(r), f)
+	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		return
 	}
+	if paged && len(cats) > limit {
+		cats = cats[:limit]
+		last := &cats[len(cats)-1]
+		setNextLink(w, r, encodeSortCursor(f.Sort, formatCatSortValue(storage.CatSortValue(last, f.Sort)), last.ID))
+	}
 
 	out := make([]PublicCat, len(cats))
 	for i, c := range cats {
-		pc := ToPublicCat(c)
-		pc.Likes, _ = s.store.LikesCount(c.ID)
-		if uid != "" {
-			pc.Liked, _ = s.store.IsLikedByUser(c.ID, uid)
-		}
-		out[i] = pc
+		out[i] = ToPublicCat(c)
 	}
+	s.fillLikes(out, uid)
 	writeJSON(w, http.StatusOK, out)
 }
 
+// fillLikes sets like counts and the caller's like flags on the cats with a single store call.
+func (s *Server) fillLikes(cats []PublicCat, uid string) {
+	ids := make([]string, len(cats))
+	for i, c := range cats {
+		ids[i] = c.ID
+	}
+	stats, err := s.store.LikeStats(ids, uid)
+	if err != nil {
+		s.log.WithError(err).Warn("likes: failed to load like stats")
+		return
+	}
+	for i := range cats {
+		st := stats[cats[i].ID]
+		cats[i].Likes, cats[i].Liked = st.Count, st.Liked
+	}
+}
+
+// parseCatFilter reads cat list filters, the sort order ("name", "-last_seen", ...) and the cursor.
+func parseCatFilter(r *http.Request) (storage.CatFilter, error) {
+	q := r.URL.Query()
+	f := storage.CatFilter{
+		Tags:   q["tag"],
+		Gender: q.Get("gender"),
+		Sort:   storage.CatSortCreated,
+		Desc:   true,
+	}
+	if sort := q.Get("sort"); sort != "" {
+		f.Desc = strings.HasPrefix(sort, "-")
+		f.Sort = strings.TrimPrefix(sort, "-")
+		if !storage.ValidCatSort(f.Sort) {
+			return f, fmt.Errorf("sort must be one of created_at, updated_at, last_seen, name, condition (prefix with - for descending)")
+		}
+	}
+	if f.Gender != "" && f.Gender != "male" && f.Gender != "female" && f.Gender != "unknown" {
+		return f, fmt.Errorf("gender must be male, female or unknown")
+	}
+
+	var err error
+	for name, dst := range map[string]**bool{"sterilized": &f.Sterilized, "need_attention": &f.NeedAttention} {
+		if v := q.Get(name); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return f, fmt.Errorf("invalid %s", name)
+			}
+			*dst = &b
+		}
+	}
+	for name, dst := range map[string]*int{"condition_min": &f.ConditionMin, "condition_max": &f.ConditionMax} {
+		if v := q.Get(name); v != "" {
+			if *dst, err = strconv.Atoi(v); err != nil || *dst < 1 || *dst > 5 {
+				return f, fmt.Errorf("%s must be between 1 and 5", name)
+			}
+		}
+	}
+	for name, dst := range map[string]**time.Time{
+		"last_seen_after":  &f.LastSeenAfter,
+		"last_seen_before": &f.LastSeenBefore,
+		"created_after":    &f.CreatedAfter,
+		"created_before":   &f.CreatedBefore,
+	} {
+		if *dst, err = parseTimeRFC3339(q.Get(name)); err != nil {
+			return f, fmt.Errorf("invalid %s", name)
+		}
+	}
+
+	cursor, err := decodeSortCursor(q.Get("cursor"))
+	if err != nil {
+		return f, err
+	}
+	if cursor != nil {
+		if cursor.Sort != f.Sort {
+			return f, errInvalidCursor
+		}
+		if f.AfterValue, err = parseCatSortValue(f.Sort, cursor.Value); err != nil {
+			return f, errInvalidCursor
+		}
+		f.AfterID = cursor.ID
+	}
+	return f, nil
+}
+
+func formatCatSortValue(v any) string {
+	switch v := v.(type) {
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case int:
+		return strconv.Itoa(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func parseCatSortValue(sort, v string) (any, error) {
+	switch sort {
+	case storage.CatSortName:
+		return v, nil
+	case storage.CatSortCondition:
+		return strconv.Atoi(v)
+	default:
+		return time.Parse(time.RFC3339Nano, v)
+	}
+}
+
 func (s *Server) createCat(w http.ResponseWriter, r *http.Request) {
 	var in storage.Cat
 	if err := jsonNewDecoder(r).Decode(&in); err != nil {
@@ -190,6 +321,18 @@ func (s *Server) createCat(w http.ResponseWriter, r *http.Request) {
 	if in.ID == "" {
 		in.ID = storage.NewUUID()
 	}
+	// New cats go to the caller's first organization unless one is given explicitly
+	scope := s.memberScope(r)
+	if in.OrganizationID == "" {
+		uid, _ := UserIDFromCtx(r.Context())
+		if orgs := s.store.UserOrganizationIDs(uid); len(orgs) > 0 {
+			in.OrganizationID = orgs[0]
+		}
+	}
+	if !scope.Allows(in.OrganizationID) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "not a member of this organization"})
+		return
+	}
 	// normalize condition 1..5 (default 3)
 	if in.Condition < 1 || in.Condition > 5 {
 		in.Condition = 3
@@ -212,12 +355,12 @@ func (s *Server) createCat(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := s.store.DB.Create(&in).Error; err != nil {
-		s.LogAudit(r, "cat", in.ID, "error", err.Error())
+		s.LogAuditError(r, "cat", in.ID, err)
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		return
 	}
 
-	s.LogAudit(r, "cat", in.ID, "success", "create")
+	s.LogAudit(r, "cat", in.ID, nil, in)
 	writeJSON(w, http.StatusCreated, ToPublicCat(in))
 }
 
@@ -240,8 +383,12 @@ func (s *Server) getCat(w http.ResponseWriter, r *http.Request) {
 	uid, _ := UserIDFromCtx(r.Context())
 	id := chi.URLParam(r, "id")
 	var cat storage.Cat
-	if err := s.store.DB.Preload("Locations").Preload("Images").Preload("Tags").Preload("Records").First(&cat, "id = ?", id).Error; err != nil {
+	db := s.readScope(r).Cats(s.store.DB)
+	if err := db.Preload("Locations").Preload("Images", storage.OrderedImages).Preload("Tags").Preload("Records").First(&cat, "id = ?", id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
+			if s.redirectMergedCat(w, r, id) {
+				return
+			}
 			writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
 			return
 		}
@@ -249,13 +396,13 @@ func (s *Server) getCat(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	pc := ToPublicCat(cat)
-	pc.Likes, _ = s.store.LikesCount(cat.ID)
-	if uid != "" {
-		pc.Liked, _ = s.store.IsLikedByUser(cat.ID, uid)
+	pcs := []PublicCat{ToPublicCat(cat)}
+	s.fillLikes(pcs, uid)
+	pc := pcs[0]
+	if uid != "" && s.memberScope(r).Allows(cat.OrganizationID) {
 		pc.Records = cat.Records
 	} else {
-		// Anonymous users can only see done records
+		// Anonymous users and non-members can only see done records
 		var publicRecs []PublicRecord
 		for _, rec := range cat.Records {
 			if rec.DoneAt != nil {
@@ -276,6 +423,19 @@ func (s *Server) updateCat(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	in.ID = id
+	scope := s.memberScope(r)
+	orgID, err := s.catOrganization(id)
+	if err != nil || !scope.Allows(orgID) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "cat not found"})
+		return
+	}
+	// Moving a cat requires membership in the target organization as well
+	if in.OrganizationID == "" {
+		in.OrganizationID = orgID
+	} else if !scope.Allows(in.OrganizationID) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "not a member of this organization"})
+		return
+	}
 	// normalize condition 1..5 (default 3)
 	if in.Condition < 1 || in.Condition > 5 {
 		in.Condition = 3
@@ -296,34 +456,41 @@ func (s *Server) updateCat(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	var before storage.Cat
+	_ = s.store.DB.Preload("Locations").Preload("Images").Preload("Tags").First(&before, "id = ?", id).Error
+	// Photo retention is set by coordinators through its own endpoint
+	in.ImageRetention = before.ImageRetention
+
 	// Full save to handle many-to-many tags correctly
 	if err := s.store.DB.Save(&in).Error; err != nil {
-		s.LogAudit(r, "cat", id, "error", err.Error())
+		s.LogAuditError(r, "cat", id, err)
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		return
 	}
 
-	s.LogAudit(r, "cat", id, "success", "update")
 	var out storage.Cat
 	_ = s.store.DB.Preload("Locations").Preload("Images").Preload("Tags").First(&out, "id = ?", id).Error
+	s.LogAudit(r, "cat", id, before, out)
 
 	uid, _ := UserIDFromCtx(r.Context())
-	pc := ToPublicCat(out)
-	pc.Likes, _ = s.store.LikesCount(out.ID)
-	if uid != "" {
-		pc.Liked, _ = s.store.IsLikedByUser(out.ID, uid)
-	}
-	writeJSON(w, http.StatusOK, pc)
+	pcs := []PublicCat{ToPublicCat(out)}
+	s.fillLikes(pcs, uid)
+	writeJSON(w, http.StatusOK, pcs[0])
 }
 
 func (s *Server) deleteCat(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
+	if !s.requireCatInScope(w, id, s.memberScope(r)) {
+		return
+	}
+	var before storage.Cat
+	_ = s.store.DB.Preload("Tags").First(&before, "id = ?", id).Error
 	if err := s.store.DB.Delete(&storage.Cat{}, "id = ?", id).Error; err != nil {
-		s.LogAudit(r, "cat", id, "error", err.Error())
+		s.LogAuditError(r, "cat", id, err)
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		return
 	}
-	s.LogAudit(r, "cat", id, "success", "delete")
+	s.LogAudit(r, "cat", id, before, nil)
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -332,7 +499,7 @@ func (s *Server) addCatImage(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	// Ensure cat exists
 	var cat storage.Cat
-	if err := s.store.DB.First(&cat, "id = ?", id).Error; err != nil {
+	if err := s.memberScope(r).Cats(s.store.DB).First(&cat, "id = ?", id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			writeJSON(w, http.StatusNotFound, map[string]string{"error": "cat not found"})
 			return
@@ -342,6 +509,10 @@ func (s *Server) addCatImage(w http.ResponseWriter, r *http.Request) {
 	}
 	ct := r.Header.Get("Content-Type")
 	img := storage.Image{ID: storage.NewUUID(), CatID: id}
+	var (
+		stripped   []string
+		suggestion *photoLocation
+	)
 	if strings.HasPrefix(ct, "multipart/form-data") {
 		// Limit memory usage for multipart parsing
 		if err := r.ParseMultipartForm(12 << 20); err != nil { // 12MB
@@ -357,10 +528,27 @@ func (s *Server) addCatImage(w http.ResponseWriter, r *http.Request) {
 		data := make([]byte, hdr.Size)
 		n, _ := file.Read(data)
 		data = data[:n]
-		img.Data = data
 		if m := http.DetectContentType(data); m != "application/octet-stream" {
 			img.MIME = m
 		}
+		// Photos must not reveal where volunteers feed the colony
+		clean, err := stripImageMetadata(data, img.MIME)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid image: " + err.Error()})
+			return
+		}
+		data, stripped = clean.Data, clean.Removed
+		if clean.EXIF != nil && s.wantsPhotoLocation(r) {
+			suggestion, _ = exifPhotoLocation(clean.EXIF)
+		}
+		if len(stripped) > 0 {
+			s.log.WithField("image_id", img.ID).WithField("removed", stripped).Info("images: stripped metadata from upload")
+		}
+		if err := s.store.SetImageData(r.Context(), &img, data); err != nil {
+			s.log.WithError(err).Error("images: failed to store image data")
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to store image"})
+			return
+		}
 		img.URL = ""
 	} else {
 		// Try JSON with URL
@@ -375,18 +563,54 @@ func (s *Server) addCatImage(w http.ResponseWriter, r *http.Request) {
 			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "url required"})
 			return
 		}
+		if err := storage.ValidateImageURL(in.URL); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		// Downloaded in the background, see image_fetcher.go
 		img.URL = in.URL
+		img.FetchStatus = storage.ImageFetchPending
 	}
-	if err := s.store.DB.Create(&img).Error; err != nil {
-		s.LogAudit(r, "image", img.ID, "error", err.Error())
+	err := s.store.PlaceNewImage(&img)
+	if err == nil {
+		err = s.store.DB.Create(&img).Error
+	}
+	if err != nil {
+		if img.BlobKey != "" {
+			_ = s.store.BlobStore().Delete(r.Context(), img.BlobKey)
+		}
+		s.LogAuditError(r, "image", img.ID, err)
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		return
 	}
 	// Reload with timestamps
 	_ = s.store.DB.First(&img, "id = ?", img.ID).Error
+	if img.FetchStatus == storage.ImageFetchPending {
+		s.wakeImageFetcher()
+	}
+
+	s.LogAudit(r, "image", img.ID, nil, struct {
+		storage.Image
+		StrippedMetadata []string `json:"stripped_metadata,omitempty"`
+	}{img, stripped})
+	// The position only goes back to the uploader; it is kept nowhere unless they accept it
+	writeJSON(w, http.StatusCreated, struct {
+		storage.Image
+		LocationSuggestion *photoLocation `json:"location_suggestion,omitempty"`
+	}{img, suggestion})
+}
 
-	s.LogAudit(r, "image", img.ID, "success", "create")
-	writeJSON(w, http.StatusCreated, img)
+// wantsPhotoLocation reports whether the caller opted in to location suggestions from photos.
+func (s *Server) wantsPhotoLocation(r *http.Request) bool {
+	uid, _ := UserIDFromCtx(r.Context())
+	if uid == "" {
+		return false
+	}
+	var u storage.User
+	if err := s.store.DB.Select("photo_location_suggestions").First(&u, "id = ?", uid).Error; err != nil {
+		return false
+	}
+	return u.PhotoLocationSuggestions
 }
 
 // deleteCatImage removes an image by id for a cat.
@@ -395,7 +619,7 @@ func (s *Server) deleteCatImage(w http.ResponseWriter, r *http.Request) {
 	imgID := chi.URLParam(r, "imgId")
 
 	// Ensure image exists and optionally belongs to cat
-	db := s.store.DB.Model(&storage.Image{}).Where("id = ?", imgID)
+	db := s.memberScope(r).ByCat(s.store.DB.Model(&storage.Image{})).Where("id = ?", imgID)
 	if id != "" {
 		db = db.Where("cat_id = ?", id)
 	}
@@ -410,18 +634,21 @@ func (s *Server) deleteCatImage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.store.DB.Delete(&storage.Image{}, "id = ?", imgID).Error; err != nil {
-		s.LogAudit(r, "image", imgID, "error", err.Error())
+	if err := s.store.DeleteImage(imgID); err != nil {
+		s.LogAuditError(r, "image", imgID, err)
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		return
 	}
-	s.LogAudit(r, "image", imgID, "success", "delete")
+	s.LogAudit(r, "image", imgID, img, nil)
 	writeJSON(w, http.StatusOK, img)
 }
 
 func (s *Server) addCatLocation(w http.ResponseWriter, r *http.Request) {
 	uid, _ := UserIDFromCtx(r.Context())
 	catID := chi.URLParam(r, "id")
+	if !s.requireCatInScope(w, catID, s.memberScope(r)) {
+		return
+	}
 	var loc storage.CatLocation
 	if err := jsonNewDecoder(r).Decode(&loc); err != nil {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json"})
@@ -436,12 +663,12 @@ func (s *Server) addCatLocation(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := s.store.DB.Create(&loc).Error; err != nil {
-		s.LogAudit(r, "location", loc.ID, "error", err.Error())
+		s.LogAuditError(r, "location", loc.ID, err)
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		return
 	}
 
-	s.LogAudit(r, "location", loc.ID, "success", "create")
+	s.LogAudit(r, "location", loc.ID, nil, loc)
 
 	// Create an observation record as well
 	obsRec := storage.Record{
@@ -456,7 +683,7 @@ func (s *Server) addCatLocation(w http.ResponseWriter, r *http.Request) {
 	if err := s.store.DB.Create(&obsRec).Error; err != nil {
 		s.log.WithError(err).Warn("failed to create automatic observation record for location")
 	} else {
-		s.LogAudit(r, "record", obsRec.ID, "success", "create-auto-loc")
+		s.LogAudit(r, "record", obsRec.ID, nil, obsRec)
 		monitoring.IncRecord(obsRec.Type, catID)
 	}
 
@@ -471,6 +698,10 @@ func (s *Server) addCatLocation(w http.ResponseWriter, r *http.Request) {
 func (s *Server) updateRecord(w http.ResponseWriter, r *http.Request) {
 	catID := chi.URLParam(r, "id")
 	rid := chi.URLParam(r, "rid")
+	scope := s.memberScope(r)
+	if !s.requireCatInScope(w, catID, scope) {
+		return
+	}
 	var in storage.Record
 	if err := jsonNewDecoder(r).Decode(&in); err != nil {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json"})
@@ -478,17 +709,27 @@ func (s *Server) updateRecord(w http.ResponseWriter, r *http.Request) {
 	}
 	in.ID = rid
 	in.CatID = catID
-	if err := s.store.DB.Model(&storage.Record{ID: rid}).Updates(in).Error; err != nil {
-		s.LogAudit(r, "record", rid, "error", err.Error())
+	if err := in.NormalizeRecurrence(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	// Only records of the cat in the URL can be changed, so records never move between cats
+	var before storage.Record
+	if err := scope.ByCat(s.store.DB).Where("id = ? AND cat_id = ?", rid, catID).First(&before).Error; err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "record not found"})
+		return
+	}
+	if err := scope.ByCat(s.store.DB.Model(&storage.Record{ID: rid})).Where("cat_id = ?", catID).Updates(in).Error; err != nil {
+		s.LogAuditError(r, "record", rid, err)
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		return
 	}
-	s.LogAudit(r, "record", rid, "success", "update")
 	var out storage.Record
 	if err := s.store.DB.First(&out, "id = ? AND cat_id = ?", rid, catID).Error; err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		return
 	}
+	s.LogAudit(r, "record", rid, before, out)
 	// Update LastSeen
 	s.updateCatLastSeenFromRecord(out)
 
@@ -499,61 +740,64 @@ func (s *Server) markRecordDone(w http.ResponseWriter, r *http.Request) {
 	catID := chi.URLParam(r, "id")
 	rid := chi.URLParam(r, "rid")
 	now := time.Now()
+	scope := s.memberScope(r)
 
-	// Handle virtual records from recurring events
-	if strings.HasPrefix(rid, "virtual-") {
-		// virtual-originalID-date
-		idAndDate := strings.TrimPrefix(rid, "virtual-")
-		lastDash := strings.LastIndex(idAndDate, "-")
-		if lastDash != -1 {
-			originalID := idAndDate[:lastDash]
-			dateStr := idAndDate[lastDash+1:]
-
-			var orig storage.Record
-			if err := s.store.DB.First(&orig, "id = ?", originalID).Error; err == nil {
-				// Create a real record for this occurrence
-				occurrenceDate, _ := time.Parse("20060102", dateStr)
-				// keep original time
-				plannedAt := time.Date(occurrenceDate.Year(), occurrenceDate.Month(), occurrenceDate.Day(),
-					orig.PlannedAt.Hour(), orig.PlannedAt.Minute(), orig.PlannedAt.Second(), orig.PlannedAt.Nanosecond(), orig.PlannedAt.Location())
-
-				newRec := orig
-				newRec.ID = storage.NewUUID()
-				newRec.PlannedAt = &plannedAt
-				newRec.DoneAt = &now
-				newRec.Recurrence = "" // this instance is done, no recurrence
-				newRec.CreatedAt = now
-
-				if err := s.store.DB.Create(&newRec).Error; err != nil {
-					writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to create record instance: " + err.Error()})
-					return
-				}
-				s.LogAudit(r, "record", newRec.ID, "success", "done-virtual")
-				monitoring.IncRecord(newRec.Type, newRec.CatID)
-				s.updateCatLastSeenFromRecord(newRec)
-				writeJSON(w, http.StatusOK, newRec)
-				return
-			}
+	// Occurrences of recurring records are stored as new records when done
+	if _, _, ok := storage.ParseVirtualRecordID(rid); ok {
+		uid, _ := UserIDFromCtx(r.Context())
+		newRec, err := s.store.CompleteOccurrence(s.occurrenceLookup(scope, catID), rid, uid, now)
+		if err != nil {
+			s.writeOccurrenceError(w, err)
+			return
 		}
+		s.LogAudit(r, "record", newRec.ID, nil, newRec)
+		monitoring.IncRecord(newRec.Type, newRec.CatID)
+		s.updateCatLastSeenFromRecord(*newRec)
+		writeJSON(w, http.StatusOK, newRec)
+		return
 	}
 
-	db := s.store.DB.Model(&storage.Record{}).Where("id = ?", rid)
+	var existing storage.Record
+	lookup := scope.ByCat(s.store.DB).Where("id = ?", rid)
 	if catID != "" {
-		db = db.Where("cat_id = ?", catID)
+		lookup = lookup.Where("cat_id = ?", catID)
+	}
+	if err := lookup.First(&existing).Error; err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "record not found"})
+		return
 	}
 
+	// Plans anchored to completion are stored as done and move on to their next occurrence
+	if existing.DoneAt == nil && existing.CompletionAnchored() {
+		done, plan, err := s.store.CompleteAnchoredRecord(s.occurrenceLookup(scope, catID), rid, now)
+		if err != nil {
+			s.LogAuditError(r, "record", rid, err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		if done.ID != plan.ID {
+			s.LogAudit(r, "record", done.ID, nil, done)
+		}
+		s.LogAudit(r, "record", rid, existing, plan)
+		monitoring.IncRecord(done.Type, done.CatID)
+		s.updateCatLastSeenFromRecord(*done)
+		writeJSON(w, http.StatusOK, done)
+		return
+	}
+
+	db := s.store.DB.Model(&storage.Record{}).Where("id = ?", rid)
 	if err := db.Update("done_at", &now).Error; err != nil {
-		s.LogAudit(r, "record", rid, "error", err.Error())
+		s.LogAuditError(r, "record", rid, err)
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		return
 	}
 
-	s.LogAudit(r, "record", rid, "success", "done")
 	var out storage.Record
 	if err := s.store.DB.First(&out, "id = ?", rid).Error; err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		return
 	}
+	s.LogAudit(r, "record", rid, existing, out)
 	// Update LastSeen
 	s.updateCatLastSeenFromRecord(out)
 
@@ -567,6 +811,19 @@ func (s *Server) listRecords(w http.ResponseWriter, r *http.Request) {
 	startStr := r.URL.Query().Get("start")
 	endStr := r.URL.Query().Get("end")
 
+	if s.redirectMergedCat(w, r, catID) {
+		return
+	}
+	if !s.requireCatInScope(w, catID, s.readScope(r)) {
+		return
+	}
+	// Non-members of the cat's organization get the same public view as anonymous users
+	if uid != "" {
+		if orgID, _ := s.catOrganization(catID); !s.memberScope(r).Allows(orgID) {
+			uid = ""
+		}
+	}
+
 	var recs []storage.Record
 
 	db := s.store.DB.Model(&storage.Record{}).Where("cat_id = ?", catID).Preload("User")
@@ -592,14 +849,27 @@ func (s *Server) listRecords(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	loc, err := s.viewerLocation(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
 	// If start/end provided, expand recurring records
 	if uid != "" && startStr != "" && endStr != "" {
 		start, startErr := time.Parse(time.RFC3339, startStr)
 		end, endErr := time.Parse(time.RFC3339, endStr)
 		if startErr == nil && endErr == nil {
-			recs = s.expandRecurringRecords(recs, start, end)
+			if recs, err = s.expandRecurringRecords(recs, start, end); err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+				return
+			}
 		}
 	}
+	storage.MarkOverdue(recs, time.Now())
+	if loc != nil {
+		recs = storage.RecordsIn(recs, loc)
+	}
 
 	if uid == "" {
 		publicRecs := make([]PublicRecord, len(recs))
@@ -613,88 +883,48 @@ func (s *Server) listRecords(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, recs)
 }
 
-func (s *Server) expandRecurringRecords(recs []storage.Record, start, end time.Time) []storage.Record {
-	var expanded []storage.Record
-	for _, r := range recs {
-		// Add the original record if it's within range
-		if isWithinRange(r, start, end) {
-			expanded = append(expanded, r)
-		}
-
-		if r.Recurrence == "" || r.PlannedAt == nil || r.DoneAt != nil {
-			continue
-		}
-
-		// Generate virtual occurrences starting from PlannedAt
-		curr := *r.PlannedAt
-		interval := r.Interval
-		if interval <= 0 {
-			interval = 1
-		}
-
-		count := 0
-		for {
-			// Save current position
-			prev := curr
-
-			switch r.Recurrence {
-			case "daily":
-				curr = curr.AddDate(0, 0, interval)
-			case "weekly":
-				curr = curr.AddDate(0, 0, 7*interval)
-			case "monthly":
-				curr = curr.AddDate(0, interval, 0)
-			case "yearly":
-				curr = curr.AddDate(interval, 0, 0)
-			default:
-				goto nextRecord
-			}
-
-			// Infinite loop protection if AddDate doesn't advance (shouldn't happen with positive interval)
-			if !curr.After(prev) {
-				break
-			}
-
-			// If current occurrence is past the end of the search range or past the record's EndDate, stop
-			if curr.After(end) || (r.EndDate != nil && curr.After(*r.EndDate)) {
-				break
-			}
-
-			// If current occurrence is within the search range, add it
-			if curr.After(start) || curr.Equal(start) {
-				// Create a virtual instance
-				instance := r
-				instance.ID = "virtual-" + r.ID + "-" + curr.Format("20060102")
-				newPlanned := curr
-				instance.PlannedAt = &newPlanned
-				expanded = append(expanded, instance)
-				count++
-			}
+// expandRecurringRecords replaces recurring planned records with their occurrences within
+// [start, end], with their overrides applied; see storage.ExpandRecords.
+func (s *Server) expandRecurringRecords(recs []storage.Record, start, end time.Time) ([]storage.Record, error) {
+	return s.store.ExpandPlannedRecords(recs, start, end)
+}
 
-			// Safety break to prevent infinite loops (or too many occurrences)
-			if count > 1000 || curr.Year() > 2100 {
-				break
-			}
-		}
-	nextRecord:
+// plannedRecords returns the planned records (planned_at set, done_at null) of the scope, of the
+// cat if catID is set, unexpanded.
+func (s *Server) plannedRecords(scope storage.OrgScope, catID string) ([]storage.Record, error) {
+	db := scope.ByCat(s.store.DB.Model(&storage.Record{})).Where("planned_at IS NOT NULL AND done_at IS NULL").Preload("User")
+	if catID != "" {
+		db = db.Where("cat_id = ?", catID).Order("planned_at ASC")
 	}
-	return expanded
+	var recs []storage.Record
+	err := db.Find(&recs).Error
+	return recs, err
 }
 
-func isWithinRange(r storage.Record, start, end time.Time) bool {
-	t := r.Timestamp
-	if r.PlannedAt != nil {
-		t = *r.PlannedAt
-	}
-	if r.DoneAt != nil {
-		t = *r.DoneAt
+// viewerLocation returns the zone record times are rendered in: the tz query parameter, else
+// the time zone of the signed-in user. Nil keeps the stored times as they are.
+func (s *Server) viewerLocation(r *http.Request) (*time.Location, error) {
+	name := r.URL.Query().Get("tz")
+	if name == "" {
+		uid, _ := UserIDFromCtx(r.Context())
+		if uid == "" {
+			return nil, nil
+		}
+		var u storage.User
+		if err := s.store.DB.Select("time_zone").First(&u, "id = ?", uid).Error; err != nil || u.TimeZone == "" {
+			return nil, nil
+		}
+		name = u.TimeZone
 	}
-	return (t.After(start) || t.Equal(start)) && (t.Before(end) || t.Equal(end))
+	return storage.LoadTimeZone(name)
 }
 
 func (s *Server) createRecord(w http.ResponseWriter, r *http.Request) {
 	uid, _ := UserIDFromCtx(r.Context())
 	catID := chi.URLParam(r, "id")
+	if !s.requireCatInScope(w, catID, s.memberScope(r)) {
+		return
+	}
 	var in storage.Record
 	if err := jsonNewDecoder(r).Decode(&in); err != nil {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json"})
@@ -705,16 +935,24 @@ func (s *Server) createRecord(w http.ResponseWriter, r *http.Request) {
 	}
 	in.CatID = catID
 	in.UserID = uid
+	// Plans repeat in the zone of the cat's organization unless the client picked one
+	if in.PlannedAt != nil && in.TimeZone == "" {
+		in.TimeZone = s.store.CatTimeZone(catID)
+	}
+	if err := in.NormalizeRecurrence(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
 	// If neither planned nor done provided, treat as immediate event and set timestamp
 	if in.PlannedAt == nil && in.DoneAt == nil && in.Timestamp.IsZero() {
 		in.Timestamp = time.Now()
 	}
 	if err := s.store.DB.Create(&in).Error; err != nil {
-		s.LogAudit(r, "record", in.ID, "error", err.Error())
+		s.LogAuditError(r, "record", in.ID, err)
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		return
 	}
-	s.LogAudit(r, "record", in.ID, "success", "create")
+	s.LogAudit(r, "record", in.ID, nil, in)
 	monitoring.IncRecord(in.Type, catID)
 	// Update LastSeen if record is done or has timestamp
 	s.updateCatLastSeenFromRecord(in)
@@ -726,11 +964,20 @@ func (s *Server) listAllPlannedRecords(w http.ResponseWriter, r *http.Request) {
 	startStr := r.URL.Query().Get("start")
 	endStr := r.URL.Query().Get("end")
 
-	var recs []storage.Record
-	// Only planned records (planned_at set, done_at null)
-	db := s.store.DB.Model(&storage.Record{}).Where("planned_at IS NOT NULL AND done_at IS NULL").Preload("User")
+	// Members see their organizations' plans, anonymous visitors only those of public organizations
+	scope := s.memberScope(r)
+	if uid, _ := UserIDFromCtx(r.Context()); uid == "" && !s.isBotRequest(r) {
+		scope = s.store.PublicScope()
+	}
 
-	if err := db.Find(&recs).Error; err != nil {
+	loc, err := s.viewerLocation(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	recs, err := s.plannedRecords(scope, "")
+	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		return
 	}
@@ -739,9 +986,16 @@ func (s *Server) listAllPlannedRecords(w http.ResponseWriter, r *http.Request) {
 		start, startErr := time.Parse(time.RFC3339, startStr)
 		end, endErr := time.Parse(time.RFC3339, endStr)
 		if startErr == nil && endErr == nil {
-			recs = s.expandRecurringRecords(recs, start, end)
+			if recs, err = s.expandRecurringRecords(recs, start, end); err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+				return
+			}
 		}
 	}
+	storage.MarkOverdue(recs, time.Now())
+	if loc != nil {
+		recs = storage.RecordsIn(recs, loc)
+	}
 
 	writeJSON(w, http.StatusOK, recs)
 }
@@ -839,23 +1093,69 @@ func (s *Server) listBotUsers(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Let the bot route reminders to members of the cat's organization only
+	for i := range users {
+		users[i].OrganizationIDs = s.store.UserOrganizationIDs(users[i].ID)
+	}
+
 	writeJSON(w, http.StatusOK, users)
 }
 
 func (s *Server) getCatImageBinary(w http.ResponseWriter, r *http.Request) {
 	imgID := chi.URLParam(r, "imgId")
+	size := r.URL.Query().Get("size")
+	if size == "" {
+		size = storage.ImageSizeCard
+	}
+	if !validImageSize(size) {
+		http.Error(w, "invalid size", http.StatusBadRequest)
+		return
+	}
 	var img storage.Image
-	if err := s.store.DB.First(&img, "id = ?", imgID).Error; err != nil {
+	if err := s.readScope(r).ByCat(s.store.DB).First(&img, "id = ?", imgID).Error; err != nil {
 		http.Error(w, "image not found", http.StatusNotFound)
 		return
 	}
-	if len(img.Data) == 0 {
+	if !img.HasData() {
 		http.Error(w, "no data", http.StatusNoContent)
 		return
 	}
-	w.Header().Set("Content-Type", img.MIME)
-	w.Header().Set("Cache-Control", "public, max-age=31536000")
-	_, _ = w.Write(img.Data)
+	// Photos of cats outside public organizations must not end up in shared caches
+	cacheability := "private"
+	if orgID, err := s.catOrganization(img.CatID); err == nil && s.store.PublicScope().Allows(orgID) {
+		cacheability = "public"
+	}
+	src, err := s.imageRendition(r.Context(), img, size)
+	if err != nil {
+		s.log.WithError(err).WithField("image_id", img.ID).Warn("images: failed to render, serving the original")
+		src = imageSource{key: img.BlobKey, data: img.Data, mime: img.MIME}
+	}
+	if src.key == "" {
+		w.Header().Set("Content-Type", src.mime)
+		w.Header().Set("Cache-Control", cacheability+", max-age=31536000")
+		_, _ = w.Write(src.data)
+		return
+	}
+	if p, ok := s.store.BlobStore().(blobstore.Presigner); ok && s.cfg.ImagePresignTTL > 0 {
+		u, err := p.PresignGet(src.key, s.cfg.ImagePresignTTL)
+		if err == nil {
+			// Cache the redirect for less than the URL lives
+			w.Header().Set("Cache-Control", fmt.Sprintf("%s, max-age=%d", cacheability, int(s.cfg.ImagePresignTTL.Seconds()/2)))
+			http.Redirect(w, r, u, http.StatusFound)
+			return
+		}
+		s.log.WithError(err).WithField("image_id", img.ID).Warn("images: failed to presign, streaming instead")
+	}
+	rc, err := s.store.OpenBlob(r.Context(), src.key)
+	if err != nil {
+		s.log.WithError(err).WithField("image_id", img.ID).Error("images: failed to read blob")
+		http.Error(w, "image unavailable", http.StatusBadGateway)
+		return
+	}
+	defer rc.Close()
+	w.Header().Set("Content-Type", src.mime)
+	w.Header().Set("Cache-Control", cacheability+", max-age=31536000")
+	_, _ = io.Copy(w, rc)
 }
 
 func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
@@ -908,6 +1208,9 @@ func (s *Server) handleToggleLike(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	id := chi.URLParam(r, "id")
+	if !s.requireCatInScope(w, id, s.readScope(r)) {
+		return
+	}
 	cur, err := s.store.IsLikedByUser(id, uid)
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "db error"})
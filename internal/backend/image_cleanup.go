@@ -1,32 +1,93 @@
 package backend
 
 import (
+	"errors"
+	"net/http"
 	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/maniack/catwatch/internal/monitoring"
+	"github.com/maniack/catwatch/internal/storage"
+	"gorm.io/gorm"
 )
 
-// startImageCleanup launches a periodic cleanup that keeps only last N images per cat.
-func (s *Server) startImageCleanup(keepN int, interval time.Duration) {
-	if keepN <= 0 {
-		keepN = 5
-	}
+// startImageCleanup launches a periodic cleanup that moves photos beyond the retention of each
+// cat to the trash.
+func (s *Server) startImageCleanup(interval time.Duration) {
 	if interval <= 0 {
 		interval = 10 * time.Minute
 	}
-	s.log.WithField("keep", keepN).WithField("interval", interval.String()).Info("images: starting cleanup worker")
+	s.log.WithField("keep", s.cfg.ImageRetention).WithField("interval", interval.String()).Info("images: starting cleanup worker")
 	go func() {
 		for {
-			deleted, err := s.store.PruneAllCatsImages(keepN)
+			trashed, err := s.store.PruneAllCatsImages(s.cfg.ImageRetention)
 			if err != nil {
 				s.log.WithError(err).Warn("images: cleanup failed")
-			} else if deleted > 0 {
-				s.log.WithField("deleted", deleted).Info("images: cleanup pruned old images")
-				for i := int64(0); i < deleted; i++ {
-					monitoring.ImagesPrunedTotal.Inc()
-				}
+			} else if trashed > 0 {
+				s.log.WithField("trashed", trashed).Info("images: cleanup moved old images to the trash")
+				monitoring.ImagesPrunedTotal.Add(float64(trashed))
 			}
 			time.Sleep(interval)
 		}
 	}()
 }
+
+// imagePruneReport is what the next image cleanup would move to the trash.
+type imagePruneReport struct {
+	DefaultKeep int                      `json:"default_keep"`
+	Total       int                      `json:"total"`
+	Cats        []storage.ImagePrunePlan `json:"cats"`
+}
+
+// handleImagePruneReport is a dry run of the image cleanup for the caller's organizations:
+// GET /api/images/prune-report
+func (s *Server) handleImagePruneReport(w http.ResponseWriter, r *http.Request) {
+	plans, err := s.store.PlanImagePrune(s.memberScope(r), s.cfg.ImageRetention)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	out := imagePruneReport{DefaultKeep: s.cfg.ImageRetention, Cats: plans}
+	if out.Cats == nil {
+		out.Cats = []storage.ImagePrunePlan{}
+	}
+	for _, p := range plans {
+		out.Total += len(p.Images)
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// setCatImageRetention sets how many photos of a cat are kept: PUT /api/cats/{id}/image-retention
+// with {"keep": n}; null returns to the global setting and 0 keeps all photos.
+func (s *Server) setCatImageRetention(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	var before storage.Cat
+	if err := s.memberScope(r).Cats(s.store.DB).First(&before, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "cat not found"})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	var in struct {
+		Keep *int `json:"keep"`
+	}
+	if err := jsonNewDecoder(r).Decode(&in); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json"})
+		return
+	}
+	if in.Keep != nil && *in.Keep < 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "keep must not be negative"})
+		return
+	}
+	if err := s.store.DB.Model(&storage.Cat{}).Where("id = ?", id).Update("image_retention", in.Keep).Error; err != nil {
+		s.LogAuditError(r, "cat", id, err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	after := before
+	after.ImageRetention = in.Keep
+	s.LogAudit(r, "cat", id, ToPublicCat(before), ToPublicCat(after))
+	writeJSON(w, http.StatusOK, map[string]any{"image_retention": in.Keep, "keep": storage.CatImageRetention(after, s.cfg.ImageRetention)})
+}
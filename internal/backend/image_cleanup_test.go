@@ -0,0 +1,121 @@
+package backend
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/maniack/catwatch/internal/storage"
+)
+
+func TestImageRetention(t *testing.T) {
+	s := newTestServer(t)
+	s.cfg.ImageRetention = 2
+	coordinator := issueTestTokenWithRole(t, s, "coord-user", storage.RoleCoordinator)
+	volunteer := issueTestToken(t, s, "volunteer-user")
+
+	org := s.store.DefaultOrganizationID()
+	addCat := func(name string) (storage.Cat, []string) {
+		cat := storage.Cat{ID: storage.NewUUID(), OrganizationID: org, Name: name}
+		s.store.DB.Create(&cat)
+		var ids []string
+		for i := range 5 {
+			img := storage.Image{ID: storage.NewUUID(), CatID: cat.ID, MIME: "image/png", Data: []byte{1}, CreatedAt: time.Now().Add(time.Duration(i) * time.Minute)}
+			_ = s.store.PlaceNewImage(&img)
+			s.store.DB.Create(&img)
+			ids = append(ids, img.ID)
+		}
+		return cat, ids
+	}
+	murka, murkaImgs := addCat("Murka")
+	patient, patientImgs := addCat("Patient")
+	// The oldest photo of Murka is primary; the next one is medical evidence
+	s.store.DB.Model(&storage.Image{}).Where("id = ?", murkaImgs[1]).Update("pinned", true)
+
+	// Per-cat retention
+	path := "/api/cats/" + patient.ID + "/image-retention"
+	if w := doTestRequest(s, http.MethodPut, path, volunteer, map[string]any{"keep": 0}); w.Code != http.StatusForbidden {
+		t.Fatalf("volunteer retention: expected 403, got %d", w.Code)
+	}
+	if w := doTestRequest(s, http.MethodPut, path, coordinator, map[string]any{"keep": -1}); w.Code != http.StatusBadRequest {
+		t.Fatalf("negative retention: expected 400, got %d", w.Code)
+	}
+	if w := doTestRequest(s, http.MethodPut, path, coordinator, map[string]any{"keep": 0}); w.Code != http.StatusOK {
+		t.Fatalf("set retention: %d %s", w.Code, w.Body.String())
+	}
+	// Editing the cat does not reset it
+	if w := doTestRequest(s, http.MethodPut, "/api/cats/"+patient.ID, volunteer, map[string]any{"name": "Patient"}); w.Code != http.StatusOK {
+		t.Fatalf("update cat: %d %s", w.Code, w.Body.String())
+	}
+	var stored storage.Cat
+	s.store.DB.First(&stored, "id = ?", patient.ID)
+	if stored.ImageRetention == nil || *stored.ImageRetention != 0 {
+		t.Fatalf("retention lost: %v", stored.ImageRetention)
+	}
+
+	// Dry run
+	if w := doTestRequest(s, http.MethodGet, "/api/images/prune-report", volunteer, nil); w.Code != http.StatusForbidden {
+		t.Fatalf("volunteer report: expected 403, got %d", w.Code)
+	}
+	w := doTestRequest(s, http.MethodGet, "/api/images/prune-report", coordinator, nil)
+	var report imagePruneReport
+	_ = json.Unmarshal(w.Body.Bytes(), &report)
+	if w.Code != http.StatusOK || report.Total != 1 || len(report.Cats) != 1 || report.Cats[0].CatID != murka.ID || report.Cats[0].Images[0].ID != murkaImgs[2] {
+		t.Fatalf("report: %d %s", w.Code, w.Body.String())
+	}
+	var count int64
+	s.store.DB.Model(&storage.Image{}).Count(&count)
+	if count != 10 {
+		t.Fatalf("dry run deleted photos: %d left", count)
+	}
+
+	// Cleanup moves the photo to the trash
+	if n, err := s.store.PruneAllCatsImages(s.cfg.ImageRetention); err != nil || n != 1 {
+		t.Fatalf("prune: %d %v", n, err)
+	}
+	var visible PublicCat
+	_ = json.Unmarshal(doTestRequest(s, http.MethodGet, "/api/cats/"+murka.ID, volunteer, nil).Body.Bytes(), &visible)
+	if len(visible.Images) != 4 {
+		t.Fatalf("expected 4 photos of Murka, got %d", len(visible.Images))
+	}
+	_ = json.Unmarshal(doTestRequest(s, http.MethodGet, "/api/cats/"+patient.ID, volunteer, nil).Body.Bytes(), &visible)
+	if len(visible.Images) != len(patientImgs) {
+		t.Fatalf("cat keeping all photos lost some: %d", len(visible.Images))
+	}
+	w = doTestRequest(s, http.MethodGet, "/api/trash/images", coordinator, nil)
+	var trash []trashedImage
+	_ = json.Unmarshal(w.Body.Bytes(), &trash)
+	if w.Code != http.StatusOK || len(trash) != 1 || trash[0].ID != murkaImgs[2] || !trash[0].PurgeAt.After(time.Now()) {
+		t.Fatalf("trash: %d %s", w.Code, w.Body.String())
+	}
+
+	// Restored photos are pinned, so the next cleanup keeps them
+	if w := doTestRequest(s, http.MethodPost, "/api/images/"+murkaImgs[2]+"/restore", volunteer, nil); w.Code != http.StatusForbidden {
+		t.Fatalf("volunteer restore: expected 403, got %d", w.Code)
+	}
+	if w := doTestRequest(s, http.MethodPost, "/api/images/"+murkaImgs[2]+"/restore", coordinator, nil); w.Code != http.StatusOK {
+		t.Fatalf("restore: %d %s", w.Code, w.Body.String())
+	}
+	if w := doTestRequest(s, http.MethodPost, "/api/images/"+murkaImgs[2]+"/restore", coordinator, nil); w.Code != http.StatusNotFound {
+		t.Fatalf("second restore: expected 404, got %d", w.Code)
+	}
+	if n, _ := s.store.PruneAllCatsImages(s.cfg.ImageRetention); n != 0 {
+		t.Fatalf("restored photo pruned again: %d", n)
+	}
+
+	// Photos are purged after the trash retention
+	s.store.DB.Model(&storage.Image{}).Where("id = ?", murkaImgs[2]).Update("pinned", false)
+	if n, _ := s.store.PruneAllCatsImages(s.cfg.ImageRetention); n != 1 {
+		t.Fatalf("expected one photo trashed, got %d", n)
+	}
+	s.purgeTrashedImages(time.Now().Add(-time.Hour))
+	if _, err := s.store.GetTrashedImage(murkaImgs[2]); err != nil {
+		t.Fatalf("photo purged too early: %v", err)
+	}
+	s.purgeTrashedImages(time.Now().Add(time.Minute))
+	s.store.DB.Unscoped().Model(&storage.Image{}).Where("id = ?", murkaImgs[2]).Count(&count)
+	if count != 0 {
+		t.Fatal("trashed photo was not purged")
+	}
+}
@@ -0,0 +1,221 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+
+	"github.com/maniack/catwatch/internal/monitoring"
+	"github.com/maniack/catwatch/internal/storage"
+)
+
+// Images added by URL are downloaded in the background and stored like uploads, so they keep
+// working when the remote host disappears and go through metadata stripping and the optimizer.
+const (
+	imgFetchTimeout      = 30 * time.Second
+	imgFetchMaxBytes     = 12 << 20 // as for uploads
+	imgFetchMaxAttempts  = 5
+	imgFetchRetryAfter   = 5 * time.Minute
+	imgFetchMaxRedirects = 3
+	imgFetchBatch        = 5
+)
+
+// imgFetchMIMEs are the sniffed content types accepted from remote hosts.
+var imgFetchMIMEs = map[string]bool{"image/jpeg": true, "image/png": true, "image/gif": true, "image/webp": true}
+
+var errBlockedAddress = errors.New("address is not publicly routable")
+
+// blockedPrefixes are special-purpose ranges not covered by the net.IP helpers.
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"), // benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"), // NAT64 may reach IPv4 internals
+}
+
+// publicAddress reports whether ip may be contacted on behalf of users.
+func publicAddress(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsValid() || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsMulticast() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return false
+	}
+	for _, p := range blockedPrefixes {
+		if p.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// fetchError is a failed download; final ones are not retried.
+type fetchError struct {
+	msg   string
+	final bool
+}
+
+func (e *fetchError) Error() string { return e.msg }
+
+func finalFetchError(format string, args ...any) error {
+	return &fetchError{msg: fmt.Sprintf(format, args...), final: true}
+}
+
+// newImageFetchClient returns the HTTP client of the URL fetcher. Unless allowPrivate is set,
+// it refuses to connect to loopback, private and other internal addresses. The check runs on
+// the resolved address of every connection, redirects included, so DNS tricks do not bypass it.
+func newImageFetchClient(allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			ap, err := netip.ParseAddrPort(address)
+			if err != nil || !publicAddress(ap.Addr()) {
+				return errBlockedAddress
+			}
+			return nil
+		}
+	}
+	return &http.Client{
+		Timeout: imgFetchTimeout,
+		Transport: &http.Transport{
+			Proxy:                 nil, // a proxy would be the only address checked
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ResponseHeaderTimeout: 15 * time.Second,
+			MaxIdleConns:          4,
+			IdleConnTimeout:       30 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > imgFetchMaxRedirects {
+				return finalFetchError("too many redirects")
+			}
+			if err := storage.ValidateImageURL(req.URL.String()); err != nil {
+				return finalFetchError("redirect: %v", err)
+			}
+			return nil
+		},
+	}
+}
+
+// fetchImageURL downloads an image and returns its bytes with metadata removed and the sniffed type.
+func (s *Server) fetchImageURL(ctx context.Context, rawURL string) ([]byte, string, error) {
+	if err := storage.ValidateImageURL(rawURL); err != nil {
+		return nil, "", finalFetchError("%v", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, "", finalFetchError("%v", err)
+	}
+	req.Header.Set("Accept", "image/*")
+	req.Header.Set("User-Agent", "catwatch/"+s.cfg.Version)
+	resp, err := s.imageFetch.Do(req)
+	if err != nil {
+		var fe *fetchError
+		if errors.As(err, &fe) {
+			return nil, "", fe
+		}
+		if errors.Is(err, errBlockedAddress) {
+			return nil, "", finalFetchError("%v", errBlockedAddress)
+		}
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusOK:
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return nil, "", fmt.Errorf("remote returned %s", resp.Status)
+	default:
+		return nil, "", finalFetchError("remote returned %s", resp.Status)
+	}
+	if resp.ContentLength > imgFetchMaxBytes {
+		return nil, "", finalFetchError("image is larger than %d bytes", imgFetchMaxBytes)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, imgFetchMaxBytes+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(data) > imgFetchMaxBytes {
+		return nil, "", finalFetchError("image is larger than %d bytes", imgFetchMaxBytes)
+	}
+	// The declared Content-Type is not trusted
+	mime := http.DetectContentType(data)
+	if !imgFetchMIMEs[mime] {
+		return nil, "", finalFetchError("not an image (%s)", mime)
+	}
+	clean, err := stripImageMetadata(data, mime)
+	if err != nil {
+		return nil, "", finalFetchError("invalid image: %v", err)
+	}
+	return clean.Data, mime, nil
+}
+
+// startImageFetcher launches the background worker that downloads images added by URL.
+func (s *Server) startImageFetcher() {
+	s.log.Info("images: starting URL fetcher")
+	go func() {
+		for {
+			n, err := s.fetchImagesBatch(imgFetchBatch)
+			if err == nil && n == imgFetchBatch {
+				continue // likely more waiting
+			}
+			select {
+			case <-s.fetchWake:
+			case <-time.After(time.Minute):
+			}
+		}
+	}()
+}
+
+// wakeImageFetcher makes the fetcher look for new work right away.
+func (s *Server) wakeImageFetcher() {
+	select {
+	case s.fetchWake <- struct{}{}:
+	default:
+	}
+}
+
+// fetchImagesBatch downloads up to limit pending images and returns how many were processed.
+func (s *Server) fetchImagesBatch(limit int) (int, error) {
+	imgs, err := s.store.ListImagesToFetch(limit, imgFetchRetryAfter)
+	if err != nil {
+		s.log.WithError(err).Warn("images: failed to query images to fetch")
+		return 0, err
+	}
+	for i := range imgs {
+		img := &imgs[i]
+		log := s.log.WithField("image_id", img.ID)
+		ctx, cancel := context.WithTimeout(context.Background(), imgFetchTimeout)
+		data, mime, err := s.fetchImageURL(ctx, img.URL)
+		if err == nil {
+			err = s.store.SaveFetchedImage(ctx, img, data, mime)
+		}
+		cancel()
+		if err == nil {
+			monitoring.ImageFetchTotal.WithLabelValues("stored").Inc()
+			log.WithField("bytes", len(data)).Info("images: stored image fetched from URL")
+			continue
+		}
+		var fe *fetchError
+		final := errors.As(err, &fe) && fe.final
+		gaveUp, merr := s.store.MarkImageFetchFailed(img.ID, err.Error(), final, imgFetchMaxAttempts)
+		if merr != nil {
+			log.WithError(merr).Warn("images: failed to record fetch failure")
+			continue
+		}
+		if gaveUp {
+			monitoring.ImageFetchTotal.WithLabelValues("failed").Inc()
+			log.WithError(err).Warn("images: giving up fetching image URL")
+		} else {
+			monitoring.ImageFetchTotal.WithLabelValues("retry").Inc()
+			log.WithError(err).Info("images: fetching image URL failed, will retry")
+		}
+	}
+	return len(imgs), nil
+}
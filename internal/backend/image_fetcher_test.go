@@ -0,0 +1,166 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/maniack/catwatch/internal/storage"
+)
+
+func TestPublicAddress(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"93.184.216.34":        true,
+		"2606:2800:220:1::":    true,
+		"127.0.0.1":            false,
+		"10.1.2.3":             false,
+		"172.16.0.1":           false,
+		"192.168.1.1":          false,
+		"169.254.169.254":      false, // cloud metadata
+		"100.64.0.1":           false,
+		"0.0.0.0":              false,
+		"::1":                  false,
+		"fd00::1":              false,
+		"fe80::1":              false,
+		"::ffff:127.0.0.1":     false,
+		"64:ff9b::a00:1":       false,
+		"ff02::1":              false,
+		"224.0.0.1":            false,
+		"255.255.255.255":      false,
+		"2001:db8::1":          true, // documentation range, but routable as far as we care
+		"8.8.8.8":              true,
+		"::ffff:93.184.216.34": true,
+	} {
+		if got := publicAddress(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("publicAddress(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestFetchImageURLs(t *testing.T) {
+	s := newTestServer(t)
+	r := s.Router
+	token := issueTestTokenWithRole(t, s, "coord-user", storage.RoleCoordinator)
+
+	// A PNG carrying a GPS position and a text comment
+	var plain bytes.Buffer
+	_ = png.Encode(&plain, image.NewGray(image.Rect(0, 0, 32, 32)))
+	var photo bytes.Buffer
+	photo.Write(plain.Bytes()[:33])
+	writePNGChunk(&photo, "tEXt", []byte("Comment\x00near the garages"))
+	writePNGChunk(&photo, "eXIf", gpsEXIF())
+	photo.Write(plain.Bytes()[33:])
+
+	flaky := 0
+	remote := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cat":
+			w.Header().Set("Content-Type", "application/octet-stream") // sniffed, not trusted
+			_, _ = w.Write(photo.Bytes())
+		case "/page":
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write([]byte("<html><body>not a cat</body></html>"))
+		case "/huge":
+			w.Header().Set("Content-Length", "99999999")
+			w.WriteHeader(http.StatusOK)
+		case "/flaky":
+			flaky++
+			http.Error(w, "try later", http.StatusServiceUnavailable)
+		case "/moved":
+			http.Redirect(w, r, "/cat", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer remote.Close()
+
+	org := s.store.DefaultOrganizationID()
+	cat := storage.Cat{ID: storage.NewUUID(), OrganizationID: org, Name: "Murka"}
+	s.store.DB.Create(&cat)
+	add := func(u string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]string{"url": u})
+		req := httptest.NewRequest(http.MethodPost, "/api/cats/"+cat.ID+"/images", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("X-CSRF-Token", "1")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	for _, u := range []string{"file:///etc/passwd", "ftp://example.com/cat.jpg", "https://user:pw@example.com/cat.jpg", "/cat.jpg"} {
+		if w := add(u); w.Code != http.StatusBadRequest {
+			t.Fatalf("add %s: expected 400, got %d", u, w.Code)
+		}
+	}
+	ids := map[string]string{}
+	for _, path := range []string{"/cat", "/moved", "/page", "/huge", "/flaky", "/gone"} {
+		w := add(remote.URL + path)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("add %s: %d %s", path, w.Code, w.Body.String())
+		}
+		var img storage.Image
+		_ = json.Unmarshal(w.Body.Bytes(), &img)
+		if img.FetchStatus != storage.ImageFetchPending || img.URL == "" {
+			t.Fatalf("add %s: expected a pending download, got %s", path, w.Body.String())
+		}
+		ids[path] = img.ID
+	}
+	load := func(path string) storage.Image {
+		var img storage.Image
+		s.store.DB.First(&img, "id = ?", ids[path])
+		return img
+	}
+
+	// The test server listens on loopback, which the default client refuses to reach
+	if n, err := s.fetchImagesBatch(1); err != nil || n != 1 {
+		t.Fatalf("fetch batch: %d %v", n, err)
+	}
+	if img := load("/cat"); img.FetchStatus != storage.ImageFetchFailed || !strings.Contains(img.FetchError, "not publicly routable") {
+		t.Fatalf("loopback fetch: expected a final failure, got %q %q", img.FetchStatus, img.FetchError)
+	}
+	s.store.DB.Model(&storage.Image{}).Where("id = ?", ids["/cat"]).Updates(map[string]any{"fetch_status": storage.ImageFetchPending, "fetch_attempts": 0})
+
+	s.imageFetch = newImageFetchClient(true)
+	if _, err := s.fetchImagesBatch(10); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"/cat", "/moved"} {
+		img := load(path)
+		if img.FetchStatus != "" || img.URL != "" || img.SourceURL != remote.URL+path || !img.HasData() || img.MIME != "image/png" || img.Optimized {
+			t.Fatalf("%s: not stored like an upload: %+v", path, img)
+		}
+		data, _ := s.store.ImageData(t.Context(), &img)
+		if bytes.Contains(data, []byte("garages")) || bytes.Contains(data, []byte("eXIf")) {
+			t.Fatalf("%s: metadata was not stripped", path)
+		}
+	}
+	for path, reason := range map[string]string{"/page": "not an image", "/huge": "larger than", "/gone": "404"} {
+		if img := load(path); img.FetchStatus != storage.ImageFetchFailed || !strings.Contains(img.FetchError, reason) {
+			t.Fatalf("%s: expected failure %q, got %q %q", path, reason, img.FetchStatus, img.FetchError)
+		}
+	}
+	// Server errors are retried later
+	if img := load("/flaky"); img.FetchStatus != storage.ImageFetchPending || img.FetchAttempts != 1 {
+		t.Fatalf("/flaky: expected a retry, got %q after %d attempts", img.FetchStatus, img.FetchAttempts)
+	}
+	if n, _ := s.fetchImagesBatch(10); n != 0 || flaky != 1 {
+		t.Fatalf("retried too early: %d images, %d requests", n, flaky)
+	}
+
+	// Stored images are served by the backend
+	req := httptest.NewRequest(http.MethodGet, "/api/cats/"+cat.ID+"/images/"+ids["/cat"]+"?size=original", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/png" {
+		t.Fatalf("serve fetched image: %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	if csp := w.Header().Get("Content-Security-Policy"); !strings.Contains(csp, "img-src 'self' data:;") {
+		t.Fatalf("CSP still allows external images: %s", csp)
+	}
+}
@@ -0,0 +1,140 @@
+package backend
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/maniack/catwatch/internal/storage"
+	"gorm.io/gorm"
+)
+
+const maxImageCaption = 500 // runes
+
+// imageOrderAudit is the photo order of a cat as recorded in the audit log.
+type imageOrderAudit struct {
+	Order   []string `json:"order"`
+	Primary string   `json:"primary,omitempty"`
+}
+
+func (s *Server) catImageOrder(catID string) imageOrderAudit {
+	var imgs []storage.Image
+	_ = storage.OrderedImages(s.store.DB.Select("id", "position", "is_primary", "created_at")).
+		Where("cat_id = ?", catID).Find(&imgs).Error
+	var a imageOrderAudit
+	for _, im := range imgs {
+		a.Order = append(a.Order, im.ID)
+		if im.IsPrimary {
+			a.Primary = im.ID
+		}
+	}
+	return a
+}
+
+// findCatImage loads the image of the request within the member scope of the caller.
+// The cat is optional so that the shorter /api/images routes work as well.
+func (s *Server) findCatImage(w http.ResponseWriter, r *http.Request) (storage.Image, bool) {
+	id := chi.URLParam(r, "id")
+	imgID := chi.URLParam(r, "imgId")
+	db := s.memberScope(r).ByCat(s.store.DB.Model(&storage.Image{})).Omit("data").Where("id = ?", imgID)
+	if id != "" {
+		db = db.Where("cat_id = ?", id)
+	}
+	var img storage.Image
+	if err := db.First(&img).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "image not found"})
+			return img, false
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return img, false
+	}
+	return img, true
+}
+
+// reorderCatImages sets the display order of the photos of a cat.
+func (s *Server) reorderCatImages(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if !s.requireCatInScope(w, id, s.memberScope(r)) {
+		return
+	}
+	var in struct {
+		IDs []string `json:"ids"`
+	}
+	if err := jsonNewDecoder(r).Decode(&in); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json"})
+		return
+	}
+	before := s.catImageOrder(id)
+	if err := s.store.ReorderImages(id, in.IDs); err != nil {
+		if errors.Is(err, storage.ErrImageOrder) {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		s.LogAuditError(r, "cat", id, err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	after := s.catImageOrder(id)
+	s.LogAudit(r, "cat", id, before, after)
+	writeJSON(w, http.StatusOK, after)
+}
+
+// setPrimaryCatImage makes an image the cover photo of its cat.
+func (s *Server) setPrimaryCatImage(w http.ResponseWriter, r *http.Request) {
+	img, ok := s.findCatImage(w, r)
+	if !ok {
+		return
+	}
+	before := s.catImageOrder(img.CatID)
+	if err := s.store.SetPrimaryImage(img.CatID, img.ID); err != nil {
+		s.LogAuditError(r, "cat", img.CatID, err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	s.LogAudit(r, "cat", img.CatID, before, s.catImageOrder(img.CatID))
+	img.IsPrimary = true
+	writeJSON(w, http.StatusOK, img)
+}
+
+// updateCatImage edits the caption of an image and whether it is pinned.
+func (s *Server) updateCatImage(w http.ResponseWriter, r *http.Request) {
+	img, ok := s.findCatImage(w, r)
+	if !ok {
+		return
+	}
+	var in struct {
+		Title  *string `json:"title"`
+		Pinned *bool   `json:"pinned"`
+	}
+	if err := jsonNewDecoder(r).Decode(&in); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json"})
+		return
+	}
+	updates := map[string]any{}
+	after := img
+	if in.Title != nil {
+		title := strings.TrimSpace(*in.Title)
+		if utf8.RuneCountInString(title) > maxImageCaption {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "title is too long"})
+			return
+		}
+		updates["title"], after.Title = title, title
+	}
+	if in.Pinned != nil {
+		updates["pinned"], after.Pinned = *in.Pinned, *in.Pinned
+	}
+	if len(updates) == 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "title or pinned required"})
+		return
+	}
+	if err := s.store.DB.Model(&storage.Image{}).Where("id = ?", img.ID).Updates(updates).Error; err != nil {
+		s.LogAuditError(r, "image", img.ID, err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	s.LogAudit(r, "image", img.ID, img, after)
+	writeJSON(w, http.StatusOK, after)
+}
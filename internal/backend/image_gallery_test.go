@@ -0,0 +1,116 @@
+package backend
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/maniack/catwatch/internal/storage"
+)
+
+func TestCatImageOrder(t *testing.T) {
+	s := newTestServer(t)
+	volunteer := issueTestToken(t, s, "volunteer-user")
+	viewer := issueTestTokenWithRole(t, s, "viewer-user", storage.RoleViewer)
+
+	cat := storage.Cat{ID: storage.NewUUID(), OrganizationID: s.store.DefaultOrganizationID(), Name: "Murka"}
+	s.store.DB.Create(&cat)
+	var ids []string
+	for i := range 4 {
+		img := storage.Image{ID: storage.NewUUID(), CatID: cat.ID, MIME: "image/png", Data: []byte{1}, CreatedAt: time.Now().Add(time.Duration(i) * time.Minute)}
+		if err := s.store.PlaceNewImage(&img); err != nil {
+			t.Fatal(err)
+		}
+		s.store.DB.Create(&img)
+		ids = append(ids, img.ID)
+	}
+	images := func() []storage.Image {
+		w := doTestRequest(s, http.MethodGet, "/api/cats/"+cat.ID, volunteer, nil)
+		var out PublicCat
+		_ = json.Unmarshal(w.Body.Bytes(), &out)
+		return out.Images
+	}
+	order := func() []string {
+		var out []string
+		for _, im := range images() {
+			out = append(out, im.ID)
+		}
+		return out
+	}
+	primary := func() string {
+		im, _ := storage.PrimaryImage(images())
+		return im.ID
+	}
+	if got := order(); len(got) != 4 || got[0] != ids[0] || got[3] != ids[3] || primary() != ids[0] {
+		t.Fatalf("new photos are not appended in order: %v", got)
+	}
+
+	// Reorder
+	orderPath := "/api/cats/" + cat.ID + "/images/order"
+	reversed := []string{ids[3], ids[2], ids[1], ids[0]}
+	if w := doTestRequest(s, http.MethodPut, orderPath, viewer, map[string]any{"ids": reversed}); w.Code != http.StatusForbidden {
+		t.Fatalf("viewer reorder: expected 403, got %d", w.Code)
+	}
+	for _, bad := range [][]string{ids[:3], {ids[0], ids[0], ids[1], ids[2]}, {ids[0], ids[1], ids[2], storage.NewUUID()}} {
+		if w := doTestRequest(s, http.MethodPut, orderPath, volunteer, map[string]any{"ids": bad}); w.Code != http.StatusBadRequest {
+			t.Fatalf("reorder %v: expected 400, got %d", bad, w.Code)
+		}
+	}
+	if w := doTestRequest(s, http.MethodPut, orderPath, volunteer, map[string]any{"ids": reversed}); w.Code != http.StatusOK {
+		t.Fatalf("reorder: %d %s", w.Code, w.Body.String())
+	}
+	if got := order(); got[0] != ids[3] || got[3] != ids[0] {
+		t.Fatalf("order not applied: %v", got)
+	}
+
+	// Primary, through the cat route and the short one used by the bot
+	if w := doTestRequest(s, http.MethodPost, "/api/cats/"+cat.ID+"/images/"+ids[2]+"/primary", volunteer, nil); w.Code != http.StatusOK {
+		t.Fatalf("set primary: %d %s", w.Code, w.Body.String())
+	}
+	if primary() != ids[2] {
+		t.Fatalf("expected %s to be primary, got %s", ids[2], primary())
+	}
+	if w := doTestRequest(s, http.MethodPost, "/api/images/"+ids[1]+"/primary", volunteer, nil); w.Code != http.StatusOK {
+		t.Fatalf("set primary by image: %d %s", w.Code, w.Body.String())
+	}
+	var primaries int64
+	s.store.DB.Model(&storage.Image{}).Where("cat_id = ? AND is_primary = ?", cat.ID, true).Count(&primaries)
+	if primary() != ids[1] || primaries != 1 {
+		t.Fatalf("expected only %s to be primary, got %s (%d)", ids[1], primary(), primaries)
+	}
+	if w := doTestRequest(s, http.MethodPost, "/api/images/"+storage.NewUUID()+"/primary", volunteer, nil); w.Code != http.StatusNotFound {
+		t.Fatalf("unknown image: expected 404, got %d", w.Code)
+	}
+
+	// Caption and pin
+	imgPath := "/api/cats/" + cat.ID + "/images/" + ids[0]
+	if w := doTestRequest(s, http.MethodPatch, imgPath, volunteer, map[string]any{}); w.Code != http.StatusBadRequest {
+		t.Fatalf("empty update: expected 400, got %d", w.Code)
+	}
+	if w := doTestRequest(s, http.MethodPatch, imgPath, volunteer, map[string]any{"title": "  asleep on the bench ", "pinned": true}); w.Code != http.StatusOK {
+		t.Fatalf("update image: %d %s", w.Code, w.Body.String())
+	}
+	var img storage.Image
+	s.store.DB.First(&img, "id = ?", ids[0])
+	if img.Title != "asleep on the bench" || !img.Pinned {
+		t.Fatalf("caption or pin not saved: %q %v", img.Title, img.Pinned)
+	}
+
+	// Pruning keeps the primary and pinned photos even though they are the oldest
+	if n, err := s.store.PruneOldCatImages(cat.ID, 1); err != nil || n != 1 {
+		t.Fatalf("prune: %d %v", n, err)
+	}
+	if got := order(); len(got) != 3 || got[0] != ids[3] || got[1] != ids[1] || got[2] != ids[0] {
+		t.Fatalf("prune removed a protected photo: %v", got)
+	}
+
+	// Deleting the primary photo promotes the next one
+	coordinator := issueTestTokenWithRole(t, s, "coord-user", storage.RoleCoordinator)
+	if w := doTestRequest(s, http.MethodDelete, "/api/images/"+ids[1], coordinator, nil); w.Code != http.StatusOK {
+		t.Fatalf("delete: %d %s", w.Code, w.Body.String())
+	}
+	if primary() != ids[3] || !images()[0].IsPrimary {
+		t.Fatalf("remaining photo was not made primary")
+	}
+}
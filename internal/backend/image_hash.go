@@ -0,0 +1,160 @@
+package backend
+
+import (
+	"image"
+	"image/color"
+	"io"
+	"math/bits"
+	"net/http"
+	"sort"
+
+	"github.com/maniack/catwatch/internal/storage"
+)
+
+// Photo matching compares difference hashes (dHash): 64 bits telling whether brightness rises
+// between neighbouring cells of a 9x8 grid. Photos of the same cat from a similar angle differ
+// in a few bits; unrelated photos in about half of them.
+const (
+	matchMaxDistance = 12 // largest Hamming distance still reported as a match
+	matchDefault     = 5
+	matchMax         = 20
+)
+
+// imageHash decodes an image and returns its dHash.
+func imageHash(b []byte, mime string) (uint64, error) {
+	img, err := decodeImage(b, mime)
+	if err != nil {
+		return 0, err
+	}
+	return dHash(img), nil
+}
+
+// dHash computes the difference hash of an image. Cells are averaged from a sample grid so
+// large photos are cheap to hash.
+func dHash(img image.Image) uint64 {
+	const cols, rows, samples = 9, 8, 8
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	var luma [rows][cols]float64
+	for cy := 0; cy < rows; cy++ {
+		for cx := 0; cx < cols; cx++ {
+			var sum float64
+			for sy := 0; sy < samples; sy++ {
+				y := bounds.Min.Y + (cy*samples+sy)*h/(rows*samples)
+				for sx := 0; sx < samples; sx++ {
+					x := bounds.Min.X + (cx*samples+sx)*w/(cols*samples)
+					sum += float64(color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y)
+				}
+			}
+			luma[cy][cx] = sum
+		}
+	}
+	var hash uint64
+	for y := 0; y < rows; y++ {
+		for x := 0; x < cols-1; x++ {
+			hash <<= 1
+			if luma[y][x] < luma[y][x+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// catMatch is a registered cat resembling a photo.
+type catMatch struct {
+	Cat      PublicCat `json:"cat"`
+	ImageID  string    `json:"image_id"` // the most similar photo of the cat
+	Distance int       `json:"distance"` // differing hash bits, 0 = identical
+	Score    float64   `json:"score"`    // similarity from 0 to 1
+}
+
+// rankImageMatches keeps the closest photo of each cat within matchMaxDistance, most similar first.
+func rankImageMatches(hash uint64, hashes []storage.ImageHash, limit int) []catMatch {
+	best := map[string]catMatch{}
+	for _, h := range hashes {
+		d := bits.OnesCount64(hash ^ h.PHash)
+		if d > matchMaxDistance {
+			continue
+		}
+		if m, ok := best[h.CatID]; !ok || d < m.Distance {
+			best[h.CatID] = catMatch{Cat: PublicCat{ID: h.CatID}, ImageID: h.ImageID, Distance: d, Score: 1 - float64(d)/64}
+		}
+	}
+	out := make([]catMatch, 0, len(best))
+	for _, m := range best {
+		out = append(out, m)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Distance != out[j].Distance {
+			return out[i].Distance < out[j].Distance
+		}
+		return out[i].Cat.ID < out[j].Cat.ID
+	})
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
+
+// matchCats finds registered cats that look like the uploaded photo: POST /api/cats/match (multipart "file").
+func (s *Server) matchCats(w http.ResponseWriter, r *http.Request) {
+	limit, err := parseLimit(r, matchDefault, matchMax)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if err := r.ParseMultipartForm(12 << 20); err != nil { // 12MB, as for uploads
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid multipart: " + err.Error()})
+		return
+	}
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "file field required"})
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "cannot read file"})
+		return
+	}
+	hash, err := imageHash(data, http.DetectContentType(data))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid image: " + err.Error()})
+		return
+	}
+
+	scope := s.readScope(r)
+	hashes, err := s.store.ImageHashes(scope)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	matches := rankImageMatches(hash, hashes, limit)
+	if len(matches) == 0 {
+		writeJSON(w, http.StatusOK, matches)
+		return
+	}
+	ids := make([]string, len(matches))
+	for i, m := range matches {
+		ids[i] = m.Cat.ID
+	}
+	var cats []storage.Cat
+	if err := scope.Cats(s.store.DB).Preload("Tags").Find(&cats, "id IN ?", ids).Error; err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	byID := make(map[string]storage.Cat, len(cats))
+	for _, c := range cats {
+		byID[c.ID] = c
+	}
+	out := matches[:0]
+	for _, m := range matches {
+		if c, ok := byID[m.Cat.ID]; ok {
+			m.Cat = ToPublicCat(c)
+			out = append(out, m)
+		}
+	}
+	writeJSON(w, http.StatusOK, out)
+}
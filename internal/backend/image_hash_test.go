@@ -0,0 +1,108 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math/rand"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/maniack/catwatch/internal/storage"
+)
+
+// blockyImage draws a random pattern of gray blocks; different seeds give unrelated images.
+func blockyImage(seed int64, w, h int) image.Image {
+	rnd := rand.New(rand.NewSource(seed))
+	var cells [12][12]uint8
+	for y := range cells {
+		for x := range cells[y] {
+			cells[y][x] = uint8(rnd.Intn(256))
+		}
+	}
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetGray(x, y, color.Gray{Y: cells[y*12/h][x*12/w]})
+		}
+	}
+	return img
+}
+
+func TestMatchCats(t *testing.T) {
+	s := newTestServer(t)
+	r := s.Router
+	token := issueTestTokenWithRole(t, s, "coord-user", storage.RoleCoordinator)
+
+	post := func(path string, data []byte) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		mw := multipart.NewWriter(&buf)
+		fw, _ := mw.CreateFormFile("file", "cat.png")
+		_, _ = fw.Write(data)
+		_ = mw.Close()
+		req := httptest.NewRequest(http.MethodPost, path, &buf)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("X-CSRF-Token", "1")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	encode := func(img image.Image) []byte {
+		var b bytes.Buffer
+		_ = png.Encode(&b, img)
+		return b.Bytes()
+	}
+
+	org := s.store.DefaultOrganizationID()
+	murka, barsik, gone := storage.NewUUID(), storage.NewUUID(), storage.NewUUID()
+	for i, c := range []storage.Cat{{ID: murka, Name: "Murka"}, {ID: barsik, Name: "Barsik"}, {ID: gone, Name: "Gone"}} {
+		c.OrganizationID = org
+		s.store.DB.Create(&c)
+		if w := post("/api/cats/"+c.ID+"/images", encode(blockyImage(int64(i+1), 640, 480))); w.Code != http.StatusCreated {
+			t.Fatalf("upload: %d %s", w.Code, w.Body.String())
+		}
+	}
+	// Deleted cats are not suggested, even for the same photo
+	if w := post("/api/cats/"+gone+"/images", encode(blockyImage(1, 640, 480))); w.Code != http.StatusCreated {
+		t.Fatalf("upload: %d", w.Code)
+	}
+	s.store.DB.Delete(&storage.Cat{ID: gone})
+	if _, err := s.optimizeImagesBatch(10); err != nil {
+		t.Fatal(err)
+	}
+	var hashed int64
+	s.store.DB.Model(&storage.Image{}).Where("phash IS NOT NULL").Count(&hashed)
+	if hashed != 4 {
+		t.Fatalf("hashed %d images, want 4", hashed)
+	}
+
+	// A smaller JPEG of Murka's photo matches Murka only
+	var probe bytes.Buffer
+	_ = jpeg.Encode(&probe, blockyImage(1, 320, 240), &jpeg.Options{Quality: 60})
+	w := post("/api/cats/match", probe.Bytes())
+	if w.Code != http.StatusOK {
+		t.Fatalf("match: %d %s", w.Code, w.Body.String())
+	}
+	var matches []catMatch
+	_ = json.Unmarshal(w.Body.Bytes(), &matches)
+	if len(matches) != 1 || matches[0].Cat.ID != murka || matches[0].Cat.Name != "Murka" || matches[0].Score < 0.9 {
+		t.Fatalf("unexpected matches: %+v", matches)
+	}
+
+	// An unrelated photo matches nothing
+	w = post("/api/cats/match", encode(blockyImage(42, 640, 480)))
+	_ = json.Unmarshal(w.Body.Bytes(), &matches)
+	if w.Code != http.StatusOK || len(matches) != 0 {
+		t.Fatalf("unrelated photo matched: %d %+v", w.Code, matches)
+	}
+
+	if w := post("/api/cats/match", []byte("not an image")); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for garbage, got %d", w.Code)
+	}
+}
@@ -0,0 +1,517 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"strings"
+	"time"
+)
+
+// Uploaded photos are sanitized before they are stored: EXIF (GPS position, camera make and
+// model, timestamps), XMP, IPTC, comments and trailing data are removed. Only the EXIF
+// orientation survives, rewritten as a minimal EXIF block, so photos keep displaying upright.
+
+var errMalformedImage = errors.New("malformed image")
+
+// strippedImage is an image without metadata.
+type strippedImage struct {
+	Data    []byte
+	Removed []string // names of the removed blocks
+	EXIF    []byte   // the original EXIF (TIFF) block, to read what it revealed before it is gone
+}
+
+// stripImageMetadata removes metadata from JPEG, PNG and WebP images. Other formats are
+// returned unchanged.
+func stripImageMetadata(b []byte, mime string) (strippedImage, error) {
+	switch strings.ToLower(mime) {
+	case "image/jpeg":
+		return stripJPEGMetadata(b)
+	case "image/png":
+		return stripPNGMetadata(b)
+	case "image/webp":
+		return stripWebPMetadata(b)
+	default:
+		return strippedImage{Data: b}, nil
+	}
+}
+
+// EXIF IFD0 tags reported when they are removed
+const (
+	exifTagMake     = 0x010F
+	exifTagModel    = 0x0110
+	exifTagDateTime = 0x0132
+	exifTagGPS      = 0x8825
+)
+
+// describeEXIF names the removed EXIF block and the sensitive parts it contained. A block
+// holding only the orientation is what the sanitizer writes itself and is not reported.
+func describeEXIF(tiff []byte) []string {
+	tags := tiffIFD0Tags(tiff)
+	if len(tags) == 1 && tags[0x0112] {
+		return nil
+	}
+	out := []string{"exif"}
+	if tags[exifTagGPS] {
+		out = append(out, "exif:gps")
+	}
+	if tags[exifTagMake] || tags[exifTagModel] {
+		out = append(out, "exif:device")
+	}
+	if tags[exifTagDateTime] {
+		out = append(out, "exif:datetime")
+	}
+	return out
+}
+
+// tiffIFD0Tags lists the tags present in the first IFD of a TIFF (EXIF) structure.
+func tiffIFD0Tags(tiff []byte) map[uint16]bool {
+	tags := map[uint16]bool{}
+	if t, ok := newTIFFReader(tiff); ok {
+		for tag := range t.ifd0() {
+			tags[tag] = true
+		}
+	}
+	return tags
+}
+
+// tiffReader reads IFD entries of a TIFF (EXIF) structure.
+type tiffReader struct {
+	b     []byte
+	order binary.ByteOrder
+}
+
+// tiffEntry is an IFD entry; pos is the offset of its 4-byte value field.
+type tiffEntry struct {
+	typ   uint16
+	count uint32
+	pos   int
+}
+
+func newTIFFReader(b []byte) (*tiffReader, bool) {
+	if len(b) < 8 {
+		return nil, false
+	}
+	switch string(b[:2]) {
+	case "II":
+		return &tiffReader{b, binary.LittleEndian}, true
+	case "MM":
+		return &tiffReader{b, binary.BigEndian}, true
+	}
+	return nil, false
+}
+
+func (t *tiffReader) u32(p int) (int, bool) {
+	if p < 0 || p+4 > len(t.b) {
+		return 0, false
+	}
+	return int(t.order.Uint32(t.b[p:])), true
+}
+
+func (t *tiffReader) ifd0() map[uint16]tiffEntry {
+	off, _ := t.u32(4)
+	return t.ifd(off)
+}
+
+// ifd returns the entries of the IFD at off, or nil when it is out of bounds.
+func (t *tiffReader) ifd(off int) map[uint16]tiffEntry {
+	if off <= 0 || off+2 > len(t.b) {
+		return nil
+	}
+	count := int(t.order.Uint16(t.b[off:]))
+	entries := make(map[uint16]tiffEntry, count)
+	for i, pos := 0, off+2; i < count && pos+12 <= len(t.b); i, pos = i+1, pos+12 {
+		entries[t.order.Uint16(t.b[pos:])] = tiffEntry{
+			typ:   t.order.Uint16(t.b[pos+2:]),
+			count: t.order.Uint32(t.b[pos+4:]),
+			pos:   pos + 8,
+		}
+	}
+	return entries
+}
+
+// ascii reads an ASCII value without its terminating NUL.
+func (t *tiffReader) ascii(e tiffEntry) (string, bool) {
+	if e.typ != 2 || e.count == 0 || e.count > 64 {
+		return "", false
+	}
+	p := e.pos
+	if e.count > 4 {
+		var ok bool
+		if p, ok = t.u32(e.pos); !ok {
+			return "", false
+		}
+	}
+	if p+int(e.count) > len(t.b) {
+		return "", false
+	}
+	return strings.TrimRight(string(t.b[p:p+int(e.count)]), "\x00 "), true
+}
+
+// rationals reads n unsigned RATIONAL values.
+func (t *tiffReader) rationals(e tiffEntry, n int) ([]float64, bool) {
+	if e.typ != 5 || int(e.count) < n {
+		return nil, false
+	}
+	p, ok := t.u32(e.pos)
+	if !ok || p+8*n > len(t.b) {
+		return nil, false
+	}
+	out := make([]float64, n)
+	for i := range out {
+		num, den := t.order.Uint32(t.b[p+8*i:]), t.order.Uint32(t.b[p+8*i+4:])
+		if den == 0 {
+			return nil, false
+		}
+		out[i] = float64(num) / float64(den)
+	}
+	return out, true
+}
+
+// orientationTIFF builds a TIFF structure holding nothing but the orientation tag.
+func orientationTIFF(orientation int) []byte {
+	var b bytes.Buffer
+	b.WriteString("MM\x00\x2a")
+	_ = binary.Write(&b, binary.BigEndian, uint32(8)) // IFD0 offset
+	_ = binary.Write(&b, binary.BigEndian, uint16(1)) // one entry
+	_ = binary.Write(&b, binary.BigEndian, []uint16{0x0112, 3})
+	_ = binary.Write(&b, binary.BigEndian, uint32(1))
+	_ = binary.Write(&b, binary.BigEndian, []uint16{uint16(orientation), 0})
+	_ = binary.Write(&b, binary.BigEndian, uint32(0)) // no next IFD
+	return b.Bytes()
+}
+
+func stripJPEGMetadata(b []byte) (strippedImage, error) {
+	if !looksLikeJPEG(b) {
+		return strippedImage{}, errMalformedImage
+	}
+	var (
+		head     [][]byte // APP0 segments, kept first
+		segments [][]byte
+		removed  []string
+		exif     []byte
+	)
+	orientation := 1
+	i := 2
+	for {
+		if i >= len(b) || b[i] != 0xFF {
+			return strippedImage{}, fmt.Errorf("%w: JPEG marker expected at %d", errMalformedImage, i)
+		}
+		for i < len(b) && b[i] == 0xFF { // fill bytes
+			i++
+		}
+		if i >= len(b) {
+			return strippedImage{}, errMalformedImage
+		}
+		marker := b[i]
+		start := i - 1
+		i++
+		if marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7) { // standalone markers
+			segments = append(segments, b[start:i])
+			continue
+		}
+		if marker == 0xD9 { // EOI without image data
+			return strippedImage{}, errMalformedImage
+		}
+		if i+2 > len(b) {
+			return strippedImage{}, errMalformedImage
+		}
+		n := int(binary.BigEndian.Uint16(b[i:]))
+		if n < 2 || i+n > len(b) {
+			return strippedImage{}, errMalformedImage
+		}
+		seg, payload := b[start:i+n], b[i+2:i+n]
+		i += n
+		if marker == 0xDA { // start of scan: the rest is image data up to EOI
+			end := bytes.Index(b[i:], []byte{0xFF, 0xD9})
+			if end < 0 {
+				return strippedImage{}, errMalformedImage
+			}
+			end += i + 2
+			segments = append(segments, b[start:end])
+			if end < len(b) {
+				removed = append(removed, "trailer")
+			}
+			break
+		}
+		switch {
+		case marker == 0xE0:
+			head = append(head, seg)
+		case marker == 0xE1 && bytes.HasPrefix(payload, []byte("Exif\x00\x00")):
+			if exif == nil {
+				exif = payload[6:]
+			}
+			if o, ok := parseTIFFOrientation(payload[6:]); ok {
+				orientation = o
+			}
+			removed = append(removed, describeEXIF(payload[6:])...)
+		case marker == 0xE1 && bytes.HasPrefix(payload, []byte("http://ns.adobe.com/")):
+			removed = append(removed, "xmp")
+		case marker == 0xE2 && bytes.HasPrefix(payload, []byte("ICC_PROFILE\x00")), marker == 0xEE:
+			segments = append(segments, seg) // color profile and Adobe color transform
+		case marker == 0xED:
+			removed = append(removed, "iptc")
+		case marker == 0xFE:
+			removed = append(removed, "comment")
+		case marker >= 0xE1 && marker <= 0xEF:
+			removed = append(removed, fmt.Sprintf("app%d", marker-0xE0))
+		default:
+			segments = append(segments, seg)
+		}
+	}
+	if len(removed) == 0 {
+		return strippedImage{Data: b, EXIF: exif}, nil
+	}
+	out := bytes.NewBuffer(make([]byte, 0, len(b)))
+	out.Write([]byte{0xFF, 0xD8})
+	for _, seg := range head {
+		out.Write(seg)
+	}
+	if orientation > 1 {
+		exif := append([]byte("Exif\x00\x00"), orientationTIFF(orientation)...)
+		out.Write([]byte{0xFF, 0xE1})
+		_ = binary.Write(out, binary.BigEndian, uint16(len(exif)+2))
+		out.Write(exif)
+	}
+	for _, seg := range segments {
+		out.Write(seg)
+	}
+	return strippedImage{Data: out.Bytes(), Removed: removed, EXIF: exif}, nil
+}
+
+const pngSignature = "\x89PNG\r\n\x1a\n"
+
+func stripPNGMetadata(b []byte) (strippedImage, error) {
+	if !bytes.HasPrefix(b, []byte(pngSignature)) {
+		return strippedImage{}, errMalformedImage
+	}
+	out := bytes.NewBuffer(make([]byte, 0, len(b)))
+	out.WriteString(pngSignature)
+	var removed []string
+	var exif []byte
+	orientation := 1
+	wroteEXIF := false
+	for i := len(pngSignature); ; {
+		if i+12 > len(b) {
+			return strippedImage{}, fmt.Errorf("%w: truncated PNG chunk", errMalformedImage)
+		}
+		n := int(binary.BigEndian.Uint32(b[i:]))
+		if n < 0 || i+12+n > len(b) {
+			return strippedImage{}, fmt.Errorf("%w: truncated PNG chunk", errMalformedImage)
+		}
+		typ, data, chunk := string(b[i+4:i+8]), b[i+8:i+8+n], b[i:i+12+n]
+		i += 12 + n
+		switch typ {
+		case "eXIf":
+			if exif == nil {
+				exif = data
+			}
+			if o, ok := parseTIFFOrientation(data); ok {
+				orientation = o
+			}
+			removed = append(removed, describeEXIF(data)...)
+			continue
+		case "tEXt", "zTXt", "iTXt":
+			removed = append(removed, "text")
+			continue
+		case "tIME":
+			removed = append(removed, "time")
+			continue
+		case "IDAT":
+			// eXIf must precede the image data
+			if orientation > 1 && !wroteEXIF {
+				writePNGChunk(out, "eXIf", orientationTIFF(orientation))
+				wroteEXIF = true
+			}
+		}
+		out.Write(chunk)
+		if typ == "IEND" {
+			if i < len(b) {
+				removed = append(removed, "trailer")
+			}
+			break
+		}
+	}
+	if len(removed) == 0 {
+		return strippedImage{Data: b, EXIF: exif}, nil
+	}
+	return strippedImage{Data: out.Bytes(), Removed: removed, EXIF: exif}, nil
+}
+
+func writePNGChunk(out *bytes.Buffer, typ string, data []byte) {
+	_ = binary.Write(out, binary.BigEndian, uint32(len(data)))
+	out.WriteString(typ)
+	out.Write(data)
+	crc := crc32.NewIEEE()
+	crc.Write([]byte(typ))
+	crc.Write(data)
+	_ = binary.Write(out, binary.BigEndian, crc.Sum32())
+}
+
+// VP8X feature flags
+const (
+	webpFlagEXIF = 0x08
+	webpFlagXMP  = 0x04
+)
+
+func stripWebPMetadata(b []byte) (strippedImage, error) {
+	if len(b) < 12 || string(b[:4]) != "RIFF" || string(b[8:12]) != "WEBP" {
+		return strippedImage{}, errMalformedImage
+	}
+	type chunk struct {
+		fourcc string
+		data   []byte
+	}
+	var (
+		chunks  []chunk
+		removed []string
+		exif    []byte
+	)
+	orientation := 1
+	end := 8 + int(binary.LittleEndian.Uint32(b[4:8]))
+	if end > len(b) || end < 12 {
+		return strippedImage{}, errMalformedImage
+	}
+	if end < len(b) {
+		removed = append(removed, "trailer")
+	}
+	for i := 12; i < end; {
+		if i+8 > end {
+			return strippedImage{}, fmt.Errorf("%w: truncated WebP chunk", errMalformedImage)
+		}
+		fourcc, n := string(b[i:i+4]), int(binary.LittleEndian.Uint32(b[i+4:]))
+		if n < 0 || i+8+n > end {
+			return strippedImage{}, fmt.Errorf("%w: truncated WebP chunk", errMalformedImage)
+		}
+		data := b[i+8 : i+8+n]
+		i += 8 + n + n%2
+		switch fourcc {
+		case "EXIF":
+			tiff := bytes.TrimPrefix(data, []byte("Exif\x00\x00"))
+			if exif == nil {
+				exif = tiff
+			}
+			if o, ok := parseTIFFOrientation(tiff); ok {
+				orientation = o
+			}
+			removed = append(removed, describeEXIF(tiff)...)
+		case "XMP ":
+			removed = append(removed, "xmp")
+		default:
+			chunks = append(chunks, chunk{fourcc, data})
+		}
+	}
+	if len(removed) == 0 {
+		return strippedImage{Data: b, EXIF: exif}, nil
+	}
+	// EXIF is only allowed in the extended format; simple files cannot carry orientation
+	if len(chunks) > 0 && chunks[0].fourcc == "VP8X" && len(chunks[0].data) >= 1 {
+		flags := append([]byte(nil), chunks[0].data...)
+		flags[0] &^= webpFlagEXIF | webpFlagXMP
+		if orientation > 1 {
+			flags[0] |= webpFlagEXIF
+			chunks = append(chunks, chunk{"EXIF", orientationTIFF(orientation)})
+		}
+		chunks[0].data = flags
+	}
+	var body bytes.Buffer
+	body.WriteString("WEBP")
+	for _, c := range chunks {
+		body.WriteString(c.fourcc)
+		_ = binary.Write(&body, binary.LittleEndian, uint32(len(c.data)))
+		body.Write(c.data)
+		if len(c.data)%2 == 1 {
+			body.WriteByte(0)
+		}
+	}
+	out := bytes.NewBuffer(make([]byte, 0, body.Len()+8))
+	out.WriteString("RIFF")
+	_ = binary.Write(out, binary.LittleEndian, uint32(body.Len()))
+	out.Write(body.Bytes())
+	return strippedImage{Data: out.Bytes(), Removed: removed, EXIF: exif}, nil
+}
+
+// photoLocation is where and when a photo was taken according to its EXIF GPS data.
+type photoLocation struct {
+	Latitude  float64    `json:"lat"`
+	Longitude float64    `json:"lon"`
+	TakenAt   *time.Time `json:"taken_at,omitempty"`
+}
+
+// EXIF tags used to locate a photo
+const (
+	exifTagExifIFD          = 0x8769
+	exifTagDateTimeOriginal = 0x9003
+	exifTagOffsetOriginal   = 0x9011
+	gpsTagLatitudeRef       = 0x0001
+	gpsTagLatitude          = 0x0002
+	gpsTagLongitudeRef      = 0x0003
+	gpsTagLongitude         = 0x0004
+	gpsTagTimeStamp         = 0x0007
+	gpsTagDateStamp         = 0x001D
+)
+
+// exifPhotoLocation reads the GPS position and capture time from an EXIF block. The time is
+// taken from the GPS clock (UTC) or else from DateTimeOriginal.
+func exifPhotoLocation(tiff []byte) (*photoLocation, bool) {
+	t, ok := newTIFFReader(tiff)
+	if !ok {
+		return nil, false
+	}
+	ifd0 := t.ifd0()
+	gpsEntry, ok := ifd0[exifTagGPS]
+	if !ok {
+		return nil, false
+	}
+	gpsOff, _ := t.u32(gpsEntry.pos)
+	gps := t.ifd(gpsOff)
+	coord := func(valueTag, refTag uint16, negative string, limit float64) (float64, bool) {
+		dms, ok := t.rationals(gps[valueTag], 3)
+		if !ok {
+			return 0, false
+		}
+		v := dms[0] + dms[1]/60 + dms[2]/3600
+		if ref, _ := t.ascii(gps[refTag]); strings.EqualFold(ref, negative) {
+			v = -v
+		}
+		return v, v >= -limit && v <= limit
+	}
+	lat, okLat := coord(gpsTagLatitude, gpsTagLatitudeRef, "S", 90)
+	lon, okLon := coord(gpsTagLongitude, gpsTagLongitudeRef, "W", 180)
+	if !okLat || !okLon || (lat == 0 && lon == 0) {
+		return nil, false
+	}
+	loc := &photoLocation{Latitude: lat, Longitude: lon}
+
+	var taken time.Time
+	if date, ok := t.ascii(gps[gpsTagDateStamp]); ok {
+		if hms, ok := t.rationals(gps[gpsTagTimeStamp], 3); ok {
+			if d, err := time.Parse("2006:01:02", date); err == nil {
+				taken = d.Add(time.Duration(hms[0]*float64(time.Hour) + hms[1]*float64(time.Minute) + hms[2]*float64(time.Second)))
+			}
+		}
+	}
+	if e, ok := ifd0[exifTagExifIFD]; ok && taken.IsZero() {
+		off, _ := t.u32(e.pos)
+		exif := t.ifd(off)
+		if s, ok := t.ascii(exif[exifTagDateTimeOriginal]); ok {
+			zone := time.Local
+			if off, ok := t.ascii(exif[exifTagOffsetOriginal]); ok {
+				if z, err := time.Parse("-07:00", off); err == nil {
+					zone = z.Location()
+				}
+			}
+			if d, err := time.ParseInLocation("2006:01:02 15:04:05", s, zone); err == nil {
+				taken = d
+			}
+		}
+	}
+	// Cameras with unset clocks report dates far off; only plausible times are suggested
+	if !taken.IsZero() && taken.Before(time.Now().Add(time.Hour)) && taken.Year() >= 2000 {
+		taken = taken.UTC()
+		loc.TakenAt = &taken
+	}
+	return loc, true
+}
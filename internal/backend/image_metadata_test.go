@@ -0,0 +1,313 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/chai2010/webp"
+	"github.com/maniack/catwatch/internal/storage"
+	xwebp "golang.org/x/image/webp"
+)
+
+// testEXIF builds a little-endian EXIF block with orientation, camera make and a GPS IFD
+// holding a latitude reference.
+func testEXIF() []byte {
+	var b bytes.Buffer
+	le := binary.LittleEndian
+	b.WriteString("II\x2a\x00")
+	_ = binary.Write(&b, le, uint32(8))
+	_ = binary.Write(&b, le, uint16(3))
+	_ = binary.Write(&b, le, []uint16{0x010F, 2}) // Make, ASCII
+	_ = binary.Write(&b, le, []uint32{4, 0x00585858})
+	_ = binary.Write(&b, le, []uint16{0x0112, 3, 1, 0, 6, 0}) // Orientation 6
+	_ = binary.Write(&b, le, []uint16{0x8825, 4})             // GPS IFD pointer
+	_ = binary.Write(&b, le, []uint32{1, 50})
+	_ = binary.Write(&b, le, uint32(0))
+	_ = binary.Write(&b, le, uint16(1)) // GPS IFD at offset 50
+	_ = binary.Write(&b, le, []uint16{0x0001, 2})
+	_ = binary.Write(&b, le, []uint32{2, 0x4E})
+	_ = binary.Write(&b, le, uint32(0))
+	return b.Bytes()
+}
+
+func TestStripImageMetadata(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 64, 32))
+
+	var plain bytes.Buffer
+	_ = jpeg.Encode(&plain, src, nil)
+	exif := append([]byte("Exif\x00\x00"), testEXIF()...)
+	var jpg bytes.Buffer
+	jpg.Write(plain.Bytes()[:2])
+	jpg.Write([]byte{0xFF, 0xE1})
+	_ = binary.Write(&jpg, binary.BigEndian, uint16(len(exif)+2))
+	jpg.Write(exif)
+	jpg.Write([]byte{0xFF, 0xFE, 0x00, 0x07, 'h', 'e', 'l', 'l', 'o'})
+	jpg.Write(plain.Bytes()[2:])
+	jpg.WriteString("trailing")
+
+	var pngBuf bytes.Buffer
+	_ = png.Encode(&pngBuf, src)
+	raw := pngBuf.Bytes()
+	var pngOut bytes.Buffer
+	pngOut.Write(raw[:33]) // signature and IHDR
+	writePNGChunk(&pngOut, "tEXt", []byte("Comment\x00near the garages"))
+	writePNGChunk(&pngOut, "eXIf", testEXIF())
+	pngOut.Write(raw[33:])
+
+	// Wrap a simple WebP into the extended format with EXIF and XMP chunks
+	var simple bytes.Buffer
+	_ = webp.Encode(&simple, src, &webp.Options{Lossless: true})
+	var body bytes.Buffer
+	body.WriteString("WEBP")
+	body.WriteString("VP8X")
+	_ = binary.Write(&body, binary.LittleEndian, uint32(10))
+	body.Write([]byte{webpFlagEXIF | webpFlagXMP, 0, 0, 0, 63, 0, 0, 31, 0, 0})
+	body.Write(simple.Bytes()[12:])
+	for _, c := range []struct{ fourcc, data string }{{"EXIF", string(testEXIF())}, {"XMP ", "<x:xmpmeta/>"}} {
+		body.WriteString(c.fourcc)
+		_ = binary.Write(&body, binary.LittleEndian, uint32(len(c.data)))
+		body.WriteString(c.data)
+		if len(c.data)%2 == 1 {
+			body.WriteByte(0)
+		}
+	}
+	var wp bytes.Buffer
+	wp.WriteString("RIFF")
+	_ = binary.Write(&wp, binary.LittleEndian, uint32(body.Len()))
+	wp.Write(body.Bytes())
+
+	tests := []struct {
+		name    string
+		mime    string
+		data    []byte
+		removed string
+		decode  func([]byte) error
+	}{
+		{"jpeg", "image/jpeg", jpg.Bytes(), "exif,exif:gps,exif:device,comment,trailer", func(b []byte) error {
+			_, err := jpeg.Decode(bytes.NewReader(b))
+			return err
+		}},
+		{"png", "image/png", pngOut.Bytes(), "text,exif,exif:gps,exif:device", func(b []byte) error {
+			_, err := png.Decode(bytes.NewReader(b))
+			return err
+		}},
+		{"webp", "image/webp", wp.Bytes(), "exif,exif:gps,exif:device,xmp", func(b []byte) error {
+			_, err := xwebp.Decode(bytes.NewReader(b))
+			return err
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := stripImageMetadata(tc.data, tc.mime)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out := res.Data
+			if got := strings.Join(res.Removed, ","); got != tc.removed {
+				t.Fatalf("removed %q, want %q", got, tc.removed)
+			}
+			if bytes.Contains(out, testEXIF()) || bytes.Contains(out, []byte("garages")) || bytes.Contains(out, []byte("xmpmeta")) || bytes.HasSuffix(out, []byte("trailing")) {
+				t.Fatalf("metadata left in the output")
+			}
+			if err := tc.decode(out); err != nil {
+				t.Fatalf("stripped image does not decode: %v", err)
+			}
+			if o := readOrientation(out); o != 6 {
+				t.Fatalf("orientation %d, want 6", o)
+			}
+			if !bytes.Equal(res.EXIF, testEXIF()) {
+				t.Fatalf("original EXIF not returned")
+			}
+			if again, _ := stripImageMetadata(out, tc.mime); len(again.Removed) != 0 || !bytes.Equal(again.Data, out) {
+				t.Fatalf("second pass changed the image: %v", again.Removed)
+			}
+		})
+	}
+
+	if _, err := stripImageMetadata([]byte("\xFF\xD8garbage"), "image/jpeg"); err == nil {
+		t.Fatalf("malformed JPEG accepted")
+	}
+	gif := []byte("GIF89a...")
+	if res, err := stripImageMetadata(gif, "image/gif"); err != nil || res.Removed != nil || !bytes.Equal(res.Data, gif) {
+		t.Fatalf("other formats must pass through")
+	}
+}
+
+// readOrientation finds the orientation the sanitizer kept, whatever the container.
+func readOrientation(b []byte) int {
+	i := bytes.Index(b, []byte("MM\x00\x2a"))
+	if i < 0 {
+		return 1
+	}
+	o, _ := parseTIFFOrientation(b[i:])
+	return o
+}
+
+func TestUploadStripsMetadata(t *testing.T) {
+	s := newTestServer(t)
+	token := issueTestTokenWithRole(t, s, "coord-user", storage.RoleCoordinator)
+	catID := storage.NewUUID()
+	s.store.DB.Create(&storage.Cat{ID: catID, OrganizationID: s.store.DefaultOrganizationID(), Name: "Private"})
+
+	var plain bytes.Buffer
+	_ = jpeg.Encode(&plain, image.NewRGBA(image.Rect(0, 0, 64, 32)), nil)
+	exif := append([]byte("Exif\x00\x00"), testEXIF()...)
+	var photo bytes.Buffer
+	photo.Write(plain.Bytes()[:2])
+	photo.Write([]byte{0xFF, 0xE1})
+	_ = binary.Write(&photo, binary.BigEndian, uint16(len(exif)+2))
+	photo.Write(exif)
+	photo.Write(plain.Bytes()[2:])
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	fw, _ := mw.CreateFormFile("file", "cat.jpg")
+	_, _ = fw.Write(photo.Bytes())
+	_ = mw.Close()
+	req := httptest.NewRequest(http.MethodPost, "/api/cats/"+catID+"/images", &buf)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("X-CSRF-Token", "1")
+	w := httptest.NewRecorder()
+	s.Router.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("upload: %d %s", w.Code, w.Body.String())
+	}
+	var img storage.Image
+	_ = json.Unmarshal(w.Body.Bytes(), &img)
+
+	w = httptest.NewRecorder()
+	s.Router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/cats/"+catID+"/images/"+img.ID+"?size=original", nil))
+	if w.Code != http.StatusOK || bytes.Contains(w.Body.Bytes(), testEXIF()) {
+		t.Fatalf("original still carries EXIF: %d", w.Code)
+	}
+	if o := readOrientation(w.Body.Bytes()); o != 6 {
+		t.Fatalf("orientation lost: %d", o)
+	}
+
+	var entry storage.AuditLog
+	if err := s.store.DB.Where("target_type = ? AND target_id = ?", "image", img.ID).First(&entry).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(entry.Delta, "stripped_metadata") || !strings.Contains(entry.Delta, "exif:gps") {
+		t.Fatalf("audit delta does not list removed metadata: %s", entry.Delta)
+	}
+}
+
+// gpsEXIF builds a big-endian EXIF block with a GPS position (55°45'21"N 37°37'4.8"E) and
+// GPS time 2024-05-01 12:30 UTC.
+func gpsEXIF() []byte {
+	var b bytes.Buffer
+	be := binary.BigEndian
+	b.WriteString("MM\x00\x2a")
+	_ = binary.Write(&b, be, uint32(8))
+	_ = binary.Write(&b, be, uint16(1))
+	_ = binary.Write(&b, be, []uint16{exifTagGPS, 4})
+	_ = binary.Write(&b, be, []uint32{1, 26, 0})
+	_ = binary.Write(&b, be, uint16(6)) // GPS IFD, data follows at 104
+	_ = binary.Write(&b, be, []uint16{gpsTagLatitudeRef, 2})
+	_ = binary.Write(&b, be, []uint32{2, 'N' << 24})
+	_ = binary.Write(&b, be, []uint16{gpsTagLatitude, 5})
+	_ = binary.Write(&b, be, []uint32{3, 104})
+	_ = binary.Write(&b, be, []uint16{gpsTagLongitudeRef, 2})
+	_ = binary.Write(&b, be, []uint32{2, 'E' << 24})
+	_ = binary.Write(&b, be, []uint16{gpsTagLongitude, 5})
+	_ = binary.Write(&b, be, []uint32{3, 128})
+	_ = binary.Write(&b, be, []uint16{gpsTagTimeStamp, 5})
+	_ = binary.Write(&b, be, []uint32{3, 152})
+	_ = binary.Write(&b, be, []uint16{gpsTagDateStamp, 2})
+	_ = binary.Write(&b, be, []uint32{11, 176, 0})
+	_ = binary.Write(&b, be, []uint32{55, 1, 45, 1, 210, 10})
+	_ = binary.Write(&b, be, []uint32{37, 1, 37, 1, 48, 10})
+	_ = binary.Write(&b, be, []uint32{12, 1, 30, 1, 0, 1})
+	b.WriteString("2024:05:01\x00")
+	return b.Bytes()
+}
+
+func TestPhotoLocationSuggestion(t *testing.T) {
+	s := newTestServer(t)
+	uid := "coord-user"
+	token := issueTestTokenWithRole(t, s, uid, storage.RoleCoordinator)
+	s.store.DB.Create(&storage.User{ID: uid, ProviderID: "dev:" + uid, Role: storage.RoleCoordinator})
+	catID := storage.NewUUID()
+	s.store.DB.Create(&storage.Cat{ID: catID, OrganizationID: s.store.DefaultOrganizationID(), Name: "Located"})
+
+	var plain bytes.Buffer
+	_ = jpeg.Encode(&plain, image.NewRGBA(image.Rect(0, 0, 64, 32)), nil)
+	exif := append([]byte("Exif\x00\x00"), gpsEXIF()...)
+	var photo bytes.Buffer
+	photo.Write(plain.Bytes()[:2])
+	photo.Write([]byte{0xFF, 0xE1})
+	_ = binary.Write(&photo, binary.BigEndian, uint16(len(exif)+2))
+	photo.Write(exif)
+	photo.Write(plain.Bytes()[2:])
+
+	type uploaded struct {
+		storage.Image
+		LocationSuggestion *photoLocation `json:"location_suggestion"`
+	}
+	upload := func() uploaded {
+		var buf bytes.Buffer
+		mw := multipart.NewWriter(&buf)
+		fw, _ := mw.CreateFormFile("file", "cat.jpg")
+		_, _ = fw.Write(photo.Bytes())
+		_ = mw.Close()
+		req := httptest.NewRequest(http.MethodPost, "/api/cats/"+catID+"/images", &buf)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		s.Router.ServeHTTP(w, req)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("upload: %d %s", w.Code, w.Body.String())
+		}
+		var out uploaded
+		_ = json.Unmarshal(w.Body.Bytes(), &out)
+		return out
+	}
+
+	// Suggestions are opt-in
+	if got := upload(); got.LocationSuggestion != nil {
+		t.Fatalf("suggestion without opt-in: %+v", got.LocationSuggestion)
+	}
+	if w := doTestRequest(s, http.MethodPatch, "/api/user/", token, map[string]bool{"photo_location_suggestions": true}); w.Code != http.StatusOK {
+		t.Fatalf("opt in: %d %s", w.Code, w.Body.String())
+	}
+	got := upload()
+	sug := got.LocationSuggestion
+	if sug == nil {
+		t.Fatalf("no suggestion for a photo with GPS")
+	}
+	if d := sug.Latitude - 55.755833; d > 1e-5 || d < -1e-5 {
+		t.Fatalf("latitude %f", sug.Latitude)
+	}
+	if d := sug.Longitude - 37.618; d > 1e-5 || d < -1e-5 {
+		t.Fatalf("longitude %f", sug.Longitude)
+	}
+	if sug.TakenAt == nil || sug.TakenAt.Format("2006-01-02T15:04:05Z07:00") != "2024-05-01T12:30:00Z" {
+		t.Fatalf("taken at %v", sug.TakenAt)
+	}
+	var stored storage.Image
+	s.store.DB.First(&stored, "id = ?", got.ID)
+	if bytes.Contains(stored.Data, gpsEXIF()) {
+		t.Fatalf("GPS stored with the photo")
+	}
+
+	// Accepting the suggestion records the sighting at the time the photo was taken
+	body := map[string]any{"lat": sug.Latitude, "lon": sug.Longitude, "created_at": sug.TakenAt}
+	if w := doTestRequest(s, http.MethodPost, "/api/cats/"+catID+"/locations", token, body); w.Code != http.StatusCreated {
+		t.Fatalf("accept: %d %s", w.Code, w.Body.String())
+	}
+	var obs storage.Record
+	if err := s.store.DB.First(&obs, "cat_id = ? AND type = ?", catID, "observation").Error; err != nil || !obs.Timestamp.Equal(*sug.TakenAt) {
+		t.Fatalf("observation: %+v %v", obs, err)
+	}
+}
@@ -2,8 +2,10 @@ package backend
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
 	"errors"
+	"fmt"
 	"image"
 	"image/color"
 	_ "image/gif"
@@ -18,18 +20,98 @@ import (
 	"github.com/maniack/catwatch/internal/monitoring"
 	"github.com/maniack/catwatch/internal/storage"
 	xwebp "golang.org/x/image/webp"
+	"gorm.io/gorm"
 )
 
 // Image optimizer defaults
 const (
-	imgOptMaxW         = 300
-	imgOptMaxH         = 300
 	imgOptWebPQuality  = 85.0
-	imgOptMinGainRatio = 0.03 // require at least 3% size reduction to rewrite
+	imgOptMinGainRatio = 0.03 // require at least 3% size reduction to store a rendition
 )
 
+// imageRenditions lists the generated sizes and their bounding boxes.
+var imageRenditions = []struct {
+	Size       string
+	MaxW, MaxH int
+}{
+	{storage.ImageSizeThumb, 300, 300},
+	{storage.ImageSizeCard, 800, 800},
+	{storage.ImageSizeFull, 1600, 1600},
+}
+
+// validImageSize reports whether size can be requested from the image endpoint.
+func validImageSize(size string) bool {
+	if size == storage.ImageSizeOriginal {
+		return true
+	}
+	for _, r := range imageRenditions {
+		if r.Size == size {
+			return true
+		}
+	}
+	return false
+}
+
+// renderImage produces one rendition of an image. A rendition without data means the
+// original fits and is served instead.
+func renderImage(im storage.Image, size string) (storage.ImageRendition, error) {
+	for _, r := range imageRenditions {
+		if r.Size != size {
+			continue
+		}
+		data, same, mime, err := optimizeBytes(im.Data, im.MIME, r.MaxW, r.MaxH)
+		if err != nil {
+			return storage.ImageRendition{}, err
+		}
+		if same {
+			return storage.ImageRendition{Size: size}, nil
+		}
+		return storage.ImageRendition{Size: size, MIME: mime, Data: data}, nil
+	}
+	return storage.ImageRendition{}, fmt.Errorf("unknown image size %q", size)
+}
+
+// imageSource locates the bytes to serve for an image size: a blob store key or inline data.
+type imageSource struct {
+	key  string
+	data []byte
+	mime string
+}
+
+// imageRendition resolves an image size, generating and storing a missing rendition on demand.
+func (s *Server) imageRendition(ctx context.Context, im storage.Image, size string) (imageSource, error) {
+	original := imageSource{key: im.BlobKey, data: im.Data, mime: im.MIME}
+	if size == storage.ImageSizeOriginal {
+		return original, nil
+	}
+	rend, err := s.store.GetImageRendition(im.ID, size)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if im.Data, err = s.store.ImageData(ctx, &im); err != nil {
+			return imageSource{}, err
+		}
+		r, err := renderImage(im, size)
+		if err != nil {
+			return imageSource{}, err
+		}
+		src := imageSource{data: r.Data, mime: r.MIME}
+		if err := s.store.SaveImageRenditions(ctx, im.ID, []storage.ImageRendition{r}, false); err != nil {
+			s.log.WithError(err).WithField("image_id", im.ID).Warn("optimizer: failed to store rendition")
+		}
+		if len(src.data) == 0 {
+			return original, nil
+		}
+		return src, nil
+	} else if err != nil {
+		return imageSource{}, err
+	}
+	if !rend.HasData() {
+		return original, nil
+	}
+	return imageSource{key: rend.BlobKey, data: rend.Data, mime: rend.MIME}, nil
+}
+
 // startImageOptimizer launches a background goroutine that periodically
-// optimizes unprocessed images in the database.
+// generates the renditions of new images in the database.
 func (s *Server) startImageOptimizer() {
 	s.log.Info("optimizer: starting background worker")
 	go s.imageOptimizerLoop()
@@ -111,7 +193,8 @@ type optStats struct {
 	dbErrors     int
 }
 
-// optimizeImagesBatch finds a small batch of unoptimized images and compresses them.
+// optimizeImagesBatch finds a small batch of images without renditions and generates them.
+// The original bytes are left untouched.
 func (s *Server) optimizeImagesBatch(limit int) (optStats, error) {
 	stats := optStats{}
 	imgs, err := s.store.ListImagesToOptimize(limit)
@@ -125,11 +208,10 @@ func (s *Server) optimizeImagesBatch(limit int) (optStats, error) {
 	}
 
 	type result struct {
-		im   storage.Image
-		data []byte
-		same bool
-		mime string
-		err  error
+		im    storage.Image
+		rends []storage.ImageRendition
+		hash  uint64
+		err   error
 	}
 
 	jobs := make(chan storage.Image)
@@ -146,16 +228,30 @@ func (s *Server) optimizeImagesBatch(limit int) (optStats, error) {
 	for w := 0; w < workers; w++ {
 		go func() {
 			for im := range jobs {
-				if len(im.Data) == 0 {
-					results <- result{im: im, same: true, mime: im.MIME}
+				if !im.HasData() {
+					results <- result{im: im}
 					continue
 				}
-				data, same, outMIME, err := optimizeBytes(im.Data, im.MIME, imgOptMaxW, imgOptMaxH)
+				res := result{im: im}
+				data, err := s.store.ImageData(context.Background(), &im)
 				if err != nil {
-					results <- result{im: im, err: err}
+					res.err = err
+					results <- res
 					continue
 				}
-				results <- result{im: im, data: data, same: same, mime: outMIME}
+				im.Data = data
+				for _, r := range imageRenditions {
+					rend, err := renderImage(im, r.Size)
+					if err != nil {
+						res.err = err
+						break
+					}
+					res.rends = append(res.rends, rend)
+				}
+				if res.err == nil {
+					res.hash, res.err = imageHash(im.Data, im.MIME)
+				}
+				results <- res
 			}
 		}()
 	}
@@ -169,7 +265,7 @@ func (s *Server) optimizeImagesBatch(limit int) (optStats, error) {
 
 	for i := 0; i < len(imgs); i++ {
 		r := <-results
-		if len(r.im.Data) == 0 {
+		if !r.im.HasData() {
 			if err := s.store.MarkImageOptimizedEmpty(r.im.ID); err != nil {
 				stats.dbErrors++
 				s.log.WithError(err).WithField("image_id", r.im.ID).Warn("optimizer: failed to mark empty image optimized")
@@ -184,58 +280,37 @@ func (s *Server) optimizeImagesBatch(limit int) (optStats, error) {
 			s.log.WithError(r.err).WithField("image_id", r.im.ID).Warn("optimizer: skip image (decode/encode error)")
 			continue
 		}
-		if r.same {
-			if err := s.store.MarkImageOptimizedNoChange(r.im.ID); err != nil {
-				stats.dbErrors++
-				s.log.WithError(err).WithField("image_id", r.im.ID).Warn("optimizer: failed to mark optimized")
-			} else {
-				stats.marked++
-			}
+		if err := s.store.SetImagePHash(r.im.ID, r.hash); err != nil {
+			stats.dbErrors++
+			s.log.WithError(err).WithField("image_id", r.im.ID).Warn("optimizer: failed to store image hash")
 			continue
 		}
-		if err := s.store.UpdateImageOptimizedData(r.im.ID, r.data, r.mime); err != nil {
+		if err := s.store.SaveImageRenditions(context.Background(), r.im.ID, r.rends, true); err != nil {
 			stats.dbErrors++
-			s.log.WithError(err).WithField("image_id", r.im.ID).Warn("optimizer: failed to update image")
-		} else {
+			s.log.WithError(err).WithField("image_id", r.im.ID).Warn("optimizer: failed to store renditions")
+			continue
+		}
+		resized := false
+		for _, rend := range r.rends {
+			resized = resized || len(rend.Data) > 0
+		}
+		if resized {
 			stats.resized++
+		} else {
+			stats.marked++
 		}
 	}
 	return stats, nil
 }
 
-// optimizeBytes resizes/encodes the image if beneficial.
+// optimizeBytes downscales the image to fit maxW x maxH, or re-encodes it if beneficial.
 // Returns (data, same, outMIME, err). If same=true, caller should not rewrite bytes.
 func optimizeBytes(b []byte, mime string, maxW, maxH int) ([]byte, bool, string, error) {
-	// Decode image; support WebP input explicitly
-	var img image.Image
-	lower := strings.ToLower(mime)
-	if strings.Contains(lower, "webp") {
-		var derr error
-		img, derr = xwebp.Decode(bytes.NewReader(b))
-		if derr != nil {
-			// fallback to generic decode
-			var err2 error
-			img, _, err2 = image.Decode(bytes.NewReader(b))
-			if err2 != nil {
-				return nil, false, "", err2
-			}
-		}
-	} else {
-		var err error
-		img, _, err = image.Decode(bytes.NewReader(b))
-		if err != nil {
-			// If generic decode fails, try WebP as a fallback (e.g., wrong MIME stored)
-			if img2, err2 := xwebp.Decode(bytes.NewReader(b)); err2 == nil {
-				img = img2
-			} else {
-				return nil, false, "", err
-			}
-		}
+	img, err := decodeImage(b, mime)
+	if err != nil {
+		return nil, false, "", err
 	}
 
-	// Apply EXIF orientation for JPEGs (best-effort)
-	img = applyEXIFOrientation(b, img)
-
 	bounds := img.Bounds()
 	w := bounds.Dx()
 	h := bounds.Dy()
@@ -270,7 +345,7 @@ func optimizeBytes(b []byte, mime string, maxW, maxH int) ([]byte, bool, string,
 		return newData, false, "image/webp", nil
 	}
 
-	// Need to downscale
+	// Need to downscale; the result must fit the bounds even if it is not smaller
 	newW := int(math.Max(1, math.Round(float64(w)*scale)))
 	newH := int(math.Max(1, math.Round(float64(h)*scale)))
 	resized := resizeHighQuality(img, newW, newH)
@@ -278,13 +353,39 @@ func optimizeBytes(b []byte, mime string, maxW, maxH int) ([]byte, bool, string,
 	if err != nil {
 		return nil, false, "", err
 	}
-	if len(b) > 0 {
-		gain := float64(len(b)-len(newData)) / float64(len(b))
-		if gain < imgOptMinGainRatio {
-			return b, true, mime, nil
+	return newData, false, "image/webp", nil
+}
+
+// decodeImage decodes an image upright, supporting WebP input explicitly.
+func decodeImage(b []byte, mime string) (image.Image, error) {
+	var img image.Image
+	lower := strings.ToLower(mime)
+	if strings.Contains(lower, "webp") {
+		var derr error
+		img, derr = xwebp.Decode(bytes.NewReader(b))
+		if derr != nil {
+			// fallback to generic decode
+			var err2 error
+			img, _, err2 = image.Decode(bytes.NewReader(b))
+			if err2 != nil {
+				return nil, err2
+			}
+		}
+	} else {
+		var err error
+		img, _, err = image.Decode(bytes.NewReader(b))
+		if err != nil {
+			// If generic decode fails, try WebP as a fallback (e.g., wrong MIME stored)
+			if img2, err2 := xwebp.Decode(bytes.NewReader(b)); err2 == nil {
+				img = img2
+			} else {
+				return nil, err
+			}
 		}
 	}
-	return newData, false, "image/webp", nil
+
+	// Apply EXIF orientation for JPEGs (best-effort)
+	return applyEXIFOrientation(b, img), nil
 }
 
 // resizeHighQuality performs a simple bilinear downscale to dstW x dstH.
@@ -0,0 +1,120 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/maniack/catwatch/internal/storage"
+	xwebp "golang.org/x/image/webp"
+)
+
+func TestImageRenditions(t *testing.T) {
+	s := newTestServer(t)
+	r := s.Router
+	token := issueTestTokenWithRole(t, s, "coord-user", storage.RoleCoordinator)
+
+	catID := storage.NewUUID()
+	s.store.DB.Create(&storage.Cat{ID: catID, OrganizationID: s.store.DefaultOrganizationID(), Name: "Photo"})
+
+	src := image.NewRGBA(image.Rect(0, 0, 2000, 1000))
+	for y := 0; y < 1000; y++ {
+		for x := 0; x < 2000; x++ {
+			src.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: uint8(x ^ y), A: 255})
+		}
+	}
+	var original bytes.Buffer
+	_ = png.Encode(&original, src)
+
+	upload := func() string {
+		var buf bytes.Buffer
+		mw := multipart.NewWriter(&buf)
+		fw, _ := mw.CreateFormFile("file", "cat.png")
+		_, _ = fw.Write(original.Bytes())
+		_ = mw.Close()
+		req := httptest.NewRequest(http.MethodPost, "/api/cats/"+catID+"/images", &buf)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("X-CSRF-Token", "1")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("upload: %d %s", w.Code, w.Body.String())
+		}
+		var img storage.Image
+		_ = json.Unmarshal(w.Body.Bytes(), &img)
+		return img.ID
+	}
+	get := func(imgID, size string) *httptest.ResponseRecorder {
+		path := "/api/cats/" + catID + "/images/" + imgID
+		if size != "" {
+			path += "?size=" + size
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+	bounds := func(w *httptest.ResponseRecorder) image.Rectangle {
+		t.Helper()
+		if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/webp" {
+			t.Fatalf("expected a webp rendition, got %d %s", w.Code, w.Header().Get("Content-Type"))
+		}
+		img, err := xwebp.Decode(bytes.NewReader(w.Body.Bytes()))
+		if err != nil {
+			t.Fatalf("decode rendition: %v", err)
+		}
+		return img.Bounds()
+	}
+
+	// Background generation keeps the original
+	first := upload()
+	if _, err := s.optimizeImagesBatch(10); err != nil {
+		t.Fatalf("optimize: %v", err)
+	}
+	var stored storage.Image
+	s.store.DB.First(&stored, "id = ?", first)
+	if !stored.Optimized || !bytes.Equal(stored.Data, original.Bytes()) {
+		t.Fatalf("original must be preserved (optimized=%v, %d bytes)", stored.Optimized, len(stored.Data))
+	}
+	var n int64
+	s.store.DB.Model(&storage.ImageRendition{}).Where("image_id = ?", first).Count(&n)
+	if n != 3 {
+		t.Fatalf("expected 3 renditions, got %d", n)
+	}
+	for size, width := range map[string]int{"thumb": 300, "card": 800, "full": 1600, "": 800} {
+		if b := bounds(get(first, size)); b.Dx() != width || b.Dy() != width/2 {
+			t.Fatalf("size %q: got %v", size, b)
+		}
+	}
+	if w := get(first, "original"); !bytes.Equal(w.Body.Bytes(), original.Bytes()) || w.Header().Get("Content-Type") != "image/png" {
+		t.Fatalf("original not served as uploaded")
+	}
+	if w := get(first, "huge"); w.Code != http.StatusBadRequest {
+		t.Fatalf("unknown size: expected 400, got %d", w.Code)
+	}
+
+	// Missing renditions are generated on demand
+	second := upload()
+	if b := bounds(get(second, "thumb")); b.Dx() != 300 {
+		t.Fatalf("on-demand thumb: %v", b)
+	}
+	if _, err := s.store.GetImageRendition(second, "thumb"); err != nil {
+		t.Fatalf("on-demand rendition not stored: %v", err)
+	}
+
+	// Deleting an image removes its renditions
+	req := httptest.NewRequest(http.MethodDelete, "/api/cats/"+catID+"/images/"+first, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("X-CSRF-Token", "1")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	s.store.DB.Model(&storage.ImageRendition{}).Where("image_id = ?", first).Count(&n)
+	if n != 0 {
+		t.Fatalf("renditions left after delete: %d", n)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -12,10 +13,12 @@ import (
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/maniack/catwatch/internal/storage"
+	"gorm.io/gorm"
 )
 
 type jwtClaims struct {
 	UserID string `json:"uid"`
+	Role   string `json:"role,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -52,7 +55,13 @@ func (s *Server) JWTAuth(next http.Handler) http.Handler {
 			return
 		}
 
+		role := claims.Role
+		if !storage.ValidRole(role) {
+			// Tokens issued before roles were introduced
+			role = storage.DefaultRole
+		}
 		ctx := WithUserID(r.Context(), claims.UserID)
+		ctx = WithUserRole(ctx, role)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -82,7 +91,19 @@ func (s *Server) issueTokens(w http.ResponseWriter, r *http.Request, userID stri
 		refreshTTL = 30 * 24 * time.Hour
 	}
 
-	accessToken, err := s.generateAccessToken(userID, accessTTL)
+	var u storage.User
+	if err := s.store.DB.First(&u, "id = ?", userID).Error; err == nil && len(s.cfg.AdminEmails) > 0 {
+		if err := s.store.PromoteAdminByEmail(&u, s.cfg.AdminEmails); err != nil {
+			s.log.WithError(err).Warn("failed to promote bootstrap admin")
+		}
+	}
+
+	// Tokens carry the role, so none is issued when it cannot be read
+	role, err := s.store.GetUserRole(userID)
+	if err != nil {
+		return err
+	}
+	accessToken, err := s.generateAccessToken(userID, role, accessTTL)
 	if err != nil {
 		return err
 	}
@@ -128,9 +149,10 @@ func (s *Server) issueTokens(w http.ResponseWriter, r *http.Request, userID stri
 	return nil
 }
 
-func (s *Server) generateAccessToken(userID string, ttl time.Duration) (string, error) {
+func (s *Server) generateAccessToken(userID, role string, ttl time.Duration) (string, error) {
 	claims := jwtClaims{
 		UserID: userID,
+		Role:   role,
 		RegisteredClaims: jwt.RegisteredClaims{
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
@@ -169,6 +191,10 @@ func (s *Server) handleRefresh(w http.ResponseWriter, r *http.Request) {
 	// Rotate tokens
 	_ = s.sessions.Del("sess:" + refreshToken)
 	if err := s.issueTokens(w, r, uid); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid refresh token"})
+			return
+		}
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "token error"})
 		return
 	}
@@ -204,8 +230,10 @@ func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleUpdateUser(w http.ResponseWriter, r *http.Request) {
 	uid, _ := UserIDFromCtx(r.Context())
 	var in struct {
-		Name  string `json:"name"`
-		Email string `json:"email"`
+		Name                     string  `json:"name"`
+		Email                    string  `json:"email"`
+		PhotoLocationSuggestions *bool   `json:"photo_location_suggestions"`
+		TimeZone                 *string `json:"time_zone"`
 	}
 	if err := jsonNewDecoder(r).Decode(&in); err != nil {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json"})
@@ -218,19 +246,31 @@ func (s *Server) handleUpdateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Only the name is kept in the audit diff (GDPR: no contact data in the log)
+	before := map[string]any{"name": u.Name, "photo_location_suggestions": u.PhotoLocationSuggestions, "time_zone": u.TimeZone}
+	if in.TimeZone != nil {
+		if _, err := storage.LoadTimeZone(*in.TimeZone); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		u.TimeZone = *in.TimeZone
+	}
 	if in.Name != "" {
 		u.Name = in.Name
 	}
 	if in.Email != "" {
 		u.Email = in.Email
 	}
+	if in.PhotoLocationSuggestions != nil {
+		u.PhotoLocationSuggestions = *in.PhotoLocationSuggestions
+	}
 
 	if err := s.store.DB.Save(&u).Error; err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		return
 	}
 
-	s.LogAudit(r, "user", u.ID, "success", "updated profile")
+	s.LogAudit(r, "user", u.ID, before, map[string]any{"name": u.Name, "photo_location_suggestions": u.PhotoLocationSuggestions, "time_zone": u.TimeZone})
 	writeJSON(w, http.StatusOK, u)
 }
 
@@ -286,11 +326,9 @@ func (s *Server) handleGetUserLikes(w http.ResponseWriter, r *http.Request) {
 
 	out := make([]PublicCat, len(cats))
 	for i, c := range cats {
-		pc := ToPublicCat(c)
-		pc.Liked = true
-		pc.Likes, _ = s.store.LikesCount(c.ID)
-		out[i] = pc
+		out[i] = ToPublicCat(c)
 	}
+	s.fillLikes(out, uid)
 	writeJSON(w, http.StatusOK, out)
 }
 
@@ -334,7 +372,16 @@ func (s *Server) handleBotToken(w http.ResponseWriter, r *http.Request) {
 	// For simplicity, if they are linked, we issue a token.
 	// But to follow the "refresh" requirement, we should ideally check a server-side session.
 
-	accessToken, err := s.generateAccessToken(link.UserID, s.cfg.AccessTTL)
+	role, err := s.store.GetUserRole(link.UserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "user not found"})
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "token error"})
+		return
+	}
+	accessToken, err := s.generateAccessToken(link.UserID, role, s.cfg.AccessTTL)
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "token error"})
 		return
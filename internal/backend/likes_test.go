@@ -0,0 +1,85 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/maniack/catwatch/internal/storage"
+	"gorm.io/gorm"
+)
+
+func TestListCatsLikeQueries(t *testing.T) {
+	for _, ttl := range []time.Duration{0, time.Minute} {
+		t.Run(fmt.Sprintf("cache=%s", ttl), func(t *testing.T) {
+			s := newTestServer(t)
+			s.store.EnableLikeCache(ttl)
+			r := s.Router
+			token := issueTestToken(t, s, "user-a")
+
+			var queries atomic.Int64
+			_ = s.store.DB.Callback().Query().After("gorm:query").Register("test:count", func(*gorm.DB) { queries.Add(1) })
+			_ = s.store.DB.Callback().Raw().After("gorm:raw").Register("test:count_raw", func(*gorm.DB) { queries.Add(1) })
+
+			addCats := func(n int) {
+				for i := 0; i < n; i++ {
+					id := storage.NewUUID()
+					s.store.DB.Create(&storage.Cat{ID: id, OrganizationID: s.store.DefaultOrganizationID(), Name: "Cat"})
+					_ = s.store.SetLike(id, "user-b", true)
+					if i%2 == 0 {
+						_ = s.store.SetLike(id, "user-a", true)
+					}
+				}
+			}
+			list := func() ([]PublicCat, int64) {
+				req := httptest.NewRequest(http.MethodGet, "/api/cats/", nil)
+				req.Header.Set("Authorization", "Bearer "+token)
+				w := httptest.NewRecorder()
+				before := queries.Load()
+				r.ServeHTTP(w, req)
+				n := queries.Load() - before
+				var cats []PublicCat
+				_ = json.Unmarshal(w.Body.Bytes(), &cats)
+				return cats, n
+			}
+
+			addCats(2)
+			_, few := list()
+			addCats(8)
+			cats, many := list()
+			if many != few {
+				t.Fatalf("queries grow with the number of cats: %d for 2 cats, %d for 10", few, many)
+			}
+			liked := 0
+			for _, c := range cats {
+				want := int64(1)
+				if c.Liked {
+					want = 2
+					liked++
+				}
+				if c.Likes != want {
+					t.Fatalf("cat %s: expected %d likes, got %d", c.ID, want, c.Likes)
+				}
+			}
+			if liked != 5 {
+				t.Fatalf("expected 5 cats liked by the caller, got %d", liked)
+			}
+
+			// Toggling a like is visible right away
+			req := httptest.NewRequest(http.MethodPost, "/api/cats/"+cats[0].ID+"/like", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			req.Header.Set("X-CSRF-Token", "1")
+			r.ServeHTTP(httptest.NewRecorder(), req)
+			after, _ := list()
+			for _, c := range after {
+				if c.ID == cats[0].ID && (c.Liked == cats[0].Liked || c.Likes == cats[0].Likes) {
+					t.Fatalf("like toggle not reflected: before %+v, after %+v", cats[0], c)
+				}
+			}
+		})
+	}
+}
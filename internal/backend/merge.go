@@ -0,0 +1,89 @@
+package backend
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/maniack/catwatch/internal/storage"
+	"gorm.io/gorm"
+)
+
+// catMergeAudit is a cat snapshot in the audit entries of a merge.
+type catMergeAudit struct {
+	PublicCat
+	MergedFrom string `json:"merged_from,omitempty"`
+	MergedInto string `json:"merged_into,omitempty"`
+}
+
+// mergeCat merges a duplicate cat into another one: POST /api/cats/{id}/merge {"into": "<cat id>"}.
+// The duplicate is moved to the trash and its ID redirects to the surviving cat.
+func (s *Server) mergeCat(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	var in struct {
+		Into string `json:"into"`
+	}
+	if err := jsonNewDecoder(r).Decode(&in); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json: " + err.Error()})
+		return
+	}
+	if in.Into == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "into is required"})
+		return
+	}
+	if in.Into == id {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": storage.ErrMergeSelf.Error()})
+		return
+	}
+	scope := s.memberScope(r)
+	if !s.requireCatInScope(w, id, scope) || !s.requireCatInScope(w, in.Into, scope) {
+		return
+	}
+	var source, target storage.Cat
+	if err := s.store.DB.Preload("Tags").First(&source, "id = ?", id).Error; err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	if err := s.store.DB.Preload("Tags").First(&target, "id = ?", in.Into).Error; err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	if source.OrganizationID != target.OrganizationID {
+		writeJSON(w, http.StatusConflict, map[string]string{"error": "cats belong to different organizations"})
+		return
+	}
+
+	cat, err := s.store.MergeCats(id, in.Into)
+	if err != nil {
+		s.LogAuditError(r, "cat", in.Into, err)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "cat not found"})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	s.LogAudit(r, "cat", id, catMergeAudit{PublicCat: ToPublicCat(source)}, catMergeAudit{PublicCat: ToPublicCat(source), MergedInto: in.Into})
+	s.LogAudit(r, "cat", in.Into, catMergeAudit{PublicCat: ToPublicCat(target)}, catMergeAudit{PublicCat: ToPublicCat(*cat), MergedFrom: id})
+	s.log.WithField("source", id).WithField("target", in.Into).Info("cats: merged duplicate")
+
+	pcs := []PublicCat{ToPublicCat(*cat)}
+	uid, _ := UserIDFromCtx(r.Context())
+	s.fillLikes(pcs, uid)
+	writeJSON(w, http.StatusOK, pcs[0])
+}
+
+// redirectMergedCat answers a request for a cat that was merged away with a permanent redirect
+// to the same URL of the surviving cat. It reports whether a redirect was sent.
+func (s *Server) redirectMergedCat(w http.ResponseWriter, r *http.Request, id string) bool {
+	to, ok := s.store.CatRedirect(id)
+	if !ok {
+		return false
+	}
+	u := *r.URL
+	u.Path = strings.Replace(u.Path, "/cats/"+id, "/cats/"+to, 1)
+	u.RawPath = ""
+	http.Redirect(w, r, u.RequestURI(), http.StatusPermanentRedirect)
+	return true
+}
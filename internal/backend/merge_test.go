@@ -0,0 +1,104 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/maniack/catwatch/internal/storage"
+)
+
+func TestMergeCats(t *testing.T) {
+	s := newTestServer(t)
+	coordinator := issueTestTokenWithRole(t, s, "coord-user", storage.RoleCoordinator)
+	volunteer := issueTestToken(t, s, "volunteer-user")
+
+	org := s.store.DefaultOrganizationID()
+	earlier, later := time.Now().Add(-48*time.Hour), time.Now().Add(-time.Hour)
+	ginger, tabby := storage.Tag{ID: storage.NewUUID(), Name: "ginger"}, storage.Tag{ID: storage.NewUUID(), Name: "tabby"}
+	source := storage.Cat{ID: storage.NewUUID(), OrganizationID: org, Name: "Ryzhik", Condition: 2, NeedAttention: true, LastSeen: &later, Tags: []storage.Tag{ginger, tabby}}
+	target := storage.Cat{ID: storage.NewUUID(), OrganizationID: org, Name: "Ginger", Condition: 4, LastSeen: &earlier, Tags: []storage.Tag{ginger}}
+	s.store.DB.Create(&source)
+	s.store.DB.Create(&target)
+	s.store.DB.Create(&storage.Record{ID: storage.NewUUID(), CatID: source.ID, Type: "feeding"})
+	s.store.DB.Create(&storage.Image{ID: storage.NewUUID(), CatID: source.ID, MIME: "image/png", Data: []byte{1}})
+	s.store.DB.Create(&storage.CatLocation{ID: storage.NewUUID(), CatID: source.ID, Latitude: 1, Longitude: 1})
+	// Both cats are liked by one user, the source also by another one
+	s.store.DB.Create(&storage.Like{ID: storage.NewUUID(), CatID: source.ID, UserID: "fan-1"})
+	s.store.DB.Create(&storage.Like{ID: storage.NewUUID(), CatID: target.ID, UserID: "fan-1"})
+	s.store.DB.Create(&storage.Like{ID: storage.NewUUID(), CatID: source.ID, UserID: "fan-2"})
+
+	merge := "/api/cats/" + source.ID + "/merge"
+	if w := doTestRequest(s, http.MethodPost, merge, volunteer, map[string]string{"into": target.ID}); w.Code != http.StatusForbidden {
+		t.Fatalf("volunteer merge: expected 403, got %d", w.Code)
+	}
+	if w := doTestRequest(s, http.MethodPost, merge, coordinator, map[string]string{"into": source.ID}); w.Code != http.StatusBadRequest {
+		t.Fatalf("self merge: expected 400, got %d", w.Code)
+	}
+	if w := doTestRequest(s, http.MethodPost, merge, coordinator, map[string]string{"into": storage.NewUUID()}); w.Code != http.StatusNotFound {
+		t.Fatalf("unknown target: expected 404, got %d", w.Code)
+	}
+	w := doTestRequest(s, http.MethodPost, merge, coordinator, map[string]string{"into": target.ID})
+	if w.Code != http.StatusOK {
+		t.Fatalf("merge: %d %s", w.Code, w.Body.String())
+	}
+	var merged PublicCat
+	_ = json.Unmarshal(w.Body.Bytes(), &merged)
+	if merged.ID != target.ID || merged.Name != "Ginger" || len(merged.Tags) != 2 || merged.Likes != 2 {
+		t.Fatalf("unexpected merged cat: %s", w.Body.String())
+	}
+	// The source was seen last: its condition wins
+	if merged.Condition != 2 || !merged.NeedAttention || merged.LastSeen == nil || !merged.LastSeen.Equal(later) {
+		t.Fatalf("condition not reconciled: %s", w.Body.String())
+	}
+	for _, model := range []any{&storage.Record{}, &storage.Image{}, &storage.CatLocation{}, &storage.Like{}} {
+		var n int64
+		s.store.DB.Model(model).Where("cat_id = ?", source.ID).Count(&n)
+		if n != 0 {
+			t.Fatalf("%T rows left on the source: %d", model, n)
+		}
+	}
+
+	// Old links redirect to the target
+	w = doTestRequest(s, http.MethodGet, "/api/cats/"+source.ID+"/?lang=ru", "", nil)
+	if w.Code != http.StatusPermanentRedirect || w.Header().Get("Location") != "/api/cats/"+target.ID+"/?lang=ru" {
+		t.Fatalf("redirect: %d %q", w.Code, w.Header().Get("Location"))
+	}
+	w = doTestRequest(s, http.MethodGet, "/api/cats/"+source.ID+"/records", coordinator, nil)
+	if w.Code != http.StatusPermanentRedirect || w.Header().Get("Location") != "/api/cats/"+target.ID+"/records" {
+		t.Fatalf("records redirect: %d %q", w.Code, w.Header().Get("Location"))
+	}
+
+	// The merged cat is gone from the trash and cannot be restored
+	w = doTestRequest(s, http.MethodGet, "/api/trash/cats", coordinator, nil)
+	var trash []PublicCat
+	_ = json.Unmarshal(w.Body.Bytes(), &trash)
+	if len(trash) != 0 {
+		t.Fatalf("trash: expected no cats, got %s", w.Body.String())
+	}
+	if w := doTestRequest(s, http.MethodPost, "/api/cats/"+source.ID+"/restore", coordinator, nil); w.Code != http.StatusConflict {
+		t.Fatalf("restore merged: expected 409, got %d", w.Code)
+	}
+
+	// Merging the target further moves the redirect along
+	third := storage.Cat{ID: storage.NewUUID(), OrganizationID: org, Name: "Third"}
+	s.store.DB.Create(&third)
+	if w := doTestRequest(s, http.MethodPost, "/api/cats/"+target.ID+"/merge", coordinator, map[string]string{"into": third.ID}); w.Code != http.StatusOK {
+		t.Fatalf("second merge: %d %s", w.Code, w.Body.String())
+	}
+	w = doTestRequest(s, http.MethodGet, "/api/cats/"+source.ID+"/", "", nil)
+	if w.Header().Get("Location") != "/api/cats/"+third.ID+"/" {
+		t.Fatalf("chained redirect: %d %q", w.Code, w.Header().Get("Location"))
+	}
+
+	var audits []storage.AuditLog
+	s.store.DB.Where("route = ? AND status = ?", "/api/cats/{id}/merge", "success").Order("seq").Find(&audits)
+	if len(audits) != 4 || audits[0].TargetID != source.ID || audits[1].TargetID != target.ID {
+		t.Fatalf("unexpected merge audit entries: %+v", audits)
+	}
+	if !bytes.Contains([]byte(audits[1].Delta), []byte(`"merged_from"`)) {
+		t.Fatalf("target audit entry misses merged_from: %s", audits[1].Delta)
+	}
+}
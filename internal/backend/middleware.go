@@ -2,12 +2,15 @@ package backend
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	chmw "github.com/go-chi/chi/v5/middleware"
+	"github.com/maniack/catwatch/internal/blobstore"
 	"github.com/maniack/catwatch/internal/logging"
 	"github.com/maniack/catwatch/internal/storage"
 	"github.com/sirupsen/logrus"
@@ -36,7 +39,9 @@ func (lrw *loggingResponseWriter) Write(b []byte) (int, error) {
 type ctxKey string
 
 const (
-	ctxUserID ctxKey = "userID"
+	ctxUserID     ctxKey = "userID"
+	ctxUserRole   ctxKey = "userRole"
+	ctxAuditTrail ctxKey = "auditTrail"
 )
 
 func WithUserID(ctx context.Context, uid string) context.Context {
@@ -54,6 +59,24 @@ func UserIDFromCtx(ctx context.Context) (string, bool) {
 	return id, ok
 }
 
+func WithUserRole(ctx context.Context, role string) context.Context {
+	ctx = context.WithValue(ctx, ctxUserRole, role)
+	ctx = context.WithValue(ctx, logging.ContextUserRole, role)
+	return ctx
+}
+
+// UserRoleFromCtx returns the role carried by the access token; authenticated requests
+// without an explicit role claim get storage.DefaultRole.
+func UserRoleFromCtx(ctx context.Context) string {
+	if v, ok := ctx.Value(ctxUserRole).(string); ok && v != "" {
+		return v
+	}
+	if uid, _ := UserIDFromCtx(ctx); uid != "" {
+		return storage.DefaultRole
+	}
+	return ""
+}
+
 func RequestIDFromCtx(ctx context.Context) (string, bool) {
 	if rid := chmw.GetReqID(ctx); rid != "" {
 		return rid, true
@@ -117,21 +140,23 @@ func RequestLogger(l *logrus.Logger, debugPaths ...string) func(http.Handler) ht
 	}
 }
 
-// SecurityHeaders adds common security-related headers to all responses.
-func SecurityHeaders() func(http.Handler) http.Handler {
+// SecurityHeaders adds common security-related headers to all responses. Images are served
+// by the backend itself; imgOrigins are additional origins photos are redirected to and
+// avatars are loaded from.
+func SecurityHeaders(imgOrigins ...string) func(http.Handler) http.Handler {
+	csp := strings.Join([]string{
+		"default-src 'self'",
+		"base-uri 'self'",
+		"form-action 'self'",
+		"script-src 'self' 'unsafe-inline'",
+		"style-src 'self' 'unsafe-inline'",
+		strings.Join(append([]string{"img-src 'self' data:"}, imgOrigins...), " "),
+		"font-src 'self' data:",
+		"connect-src 'self' ws: wss: https:",
+		"frame-ancestors 'none'",
+	}, "; ")
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			csp := strings.Join([]string{
-				"default-src 'self'",
-				"base-uri 'self'",
-				"form-action 'self'",
-				"script-src 'self' 'unsafe-inline'",
-				"style-src 'self' 'unsafe-inline'",
-				"img-src 'self' data: https:",
-				"font-src 'self' data:",
-				"connect-src 'self' ws: wss: https:",
-				"frame-ancestors 'none'",
-			}, "; ")
 			w.Header().Set("Content-Security-Policy", csp)
 			w.Header().Set("X-Content-Type-Options", "nosniff")
 			w.Header().Set("X-Frame-Options", "DENY")
@@ -143,7 +168,51 @@ func SecurityHeaders() func(http.Handler) http.Handler {
 	}
 }
 
+// imageOrigins returns the origins photo downloads are redirected to, for the CSP.
+func (s *Server) imageOrigins() []string {
+	if p, ok := s.store.BlobStore().(blobstore.Presigner); ok && s.cfg.ImagePresignTTL > 0 {
+		return []string{p.Origin()}
+	}
+	return nil
+}
+
+// googleAvatarOrigin serves the profile pictures of Google accounts.
+const googleAvatarOrigin = "https://*.googleusercontent.com"
+
+// avatarOrigins returns the origins user avatars are loaded from, for the CSP: those of the
+// configured identity providers and AvatarOrigins.
+func (s *Server) avatarOrigins() []string {
+	var origins []string
+	if s.cfg.OAuth.GoogleClientID != "" {
+		origins = append(origins, googleAvatarOrigin)
+	}
+	if u, err := url.Parse(s.cfg.OAuth.OIDCIssuer); err == nil && u.Scheme != "" && u.Host != "" {
+		origins = append(origins, u.Scheme+"://"+u.Host)
+	}
+	return append(origins, s.cfg.AvatarOrigins...)
+}
+
+// auditChange is a change to a single entity recorded by a handler during a request.
+type auditChange struct {
+	targetType string
+	targetID   string
+	delta      string
+	failed     bool
+}
+
+// auditTrail collects the changes of one mutating request; AuditMiddleware writes them
+// once the response status is known.
+type auditTrail struct {
+	changes []auditChange
+}
+
+// auditSkipPrefixes are mutations that are not audited by the fallback entry:
+// session handling and MCP messages (tools audit themselves).
+var auditSkipPrefixes = []string{"/api/auth/", "/api/mcp"}
+
 // AuditMiddleware logs mutations to the AuditLog table.
+// Handlers describe what they changed via LogAudit; the middleware adds the response status.
+// Authenticated mutations that recorded no change still get a generic "request" entry.
 func (s *Server) AuditMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Detect language from Accept-Language header
@@ -164,24 +233,67 @@ func (s *Server) AuditMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		// We'll capture status after next.ServeHTTP
-		// But for now, let's just log the attempt and status will be handled in handlers for more detail,
-		// or we wrap ResponseWriter.
-		next.ServeHTTP(w, r)
-
-		// Simple audit from middleware (can be enriched in handlers)
-		uid, _ := UserIDFromCtx(r.Context())
-		if uid == "" && !strings.Contains(r.URL.Path, "/auth/") {
-			// Skip logging if not authenticated and not auth path?
-			// Actually we should log attempts too.
+		trail := &auditTrail{}
+		r = r.WithContext(context.WithValue(r.Context(), ctxAuditTrail, trail))
+		lrw := &loggingResponseWriter{ResponseWriter: w}
+		next.ServeHTTP(lrw, r)
+		if lrw.status == 0 {
+			lrw.status = http.StatusOK
 		}
 
-		// Detailed audit is better done in handlers where we know target_type and target_id.
-		// Middleware can serve as a fallback or for general logging.
+		changes := trail.changes
+		if len(changes) == 0 {
+			uid, _ := UserIDFromCtx(r.Context())
+			if uid == "" {
+				return
+			}
+			for _, p := range auditSkipPrefixes {
+				if strings.HasPrefix(r.URL.Path, p) {
+					return
+				}
+			}
+			changes = []auditChange{{targetType: "request"}}
+		}
+		for _, c := range changes {
+			status := "success"
+			if c.failed || lrw.status >= 400 {
+				status = "error"
+			}
+			s.writeAudit(r, c, status, lrw.status)
+		}
 	})
 }
 
-func (s *Server) LogAudit(r *http.Request, targetType, targetID, status, delta string) {
+// LogAudit records a change of the target entity. before and after are snapshots of the
+// entity (nil for creations and deletions respectively) and are stored as a JSON diff.
+func (s *Server) LogAudit(r *http.Request, targetType, targetID string, before, after any) {
+	delta, err := storage.AuditDelta(before, after)
+	if err != nil {
+		s.log.WithError(err).Warn("failed to compute audit delta")
+	}
+	s.recordAudit(r, auditChange{targetType: targetType, targetID: targetID, delta: delta})
+}
+
+// LogAuditError records a failed change of the target entity.
+func (s *Server) LogAuditError(r *http.Request, targetType, targetID string, cause error) {
+	delta, _ := json.Marshal(map[string]string{"error": cause.Error()})
+	s.recordAudit(r, auditChange{targetType: targetType, targetID: targetID, delta: string(delta), failed: true})
+}
+
+func (s *Server) recordAudit(r *http.Request, c auditChange) {
+	if trail, ok := r.Context().Value(ctxAuditTrail).(*auditTrail); ok {
+		trail.changes = append(trail.changes, c)
+		return
+	}
+	// Called outside of AuditMiddleware: write right away
+	status := "success"
+	if c.failed {
+		status = "error"
+	}
+	s.writeAudit(r, c, status, 0)
+}
+
+func (s *Server) writeAudit(r *http.Request, c auditChange, status string, code int) {
 	uid, _ := UserIDFromCtx(r.Context())
 	rid, _ := RequestIDFromCtx(r.Context())
 
@@ -194,19 +306,17 @@ func (s *Server) LogAudit(r *http.Request, targetType, targetID, status, delta s
 	}
 
 	entry := storage.AuditLog{
-		ID:         storage.NewUUID(),
-		Timestamp:  time.Now().UTC(),
 		UserID:     uid,
 		Method:     r.Method,
 		Route:      route,
-		TargetType: targetType,
-		TargetID:   targetID,
+		TargetType: c.targetType,
+		TargetID:   c.targetID,
 		Status:     status,
+		StatusCode: code,
 		RequestID:  rid,
-		Delta:      delta,
+		Delta:      c.delta,
 	}
-
-	if err := s.store.DB.Create(&entry).Error; err != nil {
+	if err := s.store.WriteAudit(&entry); err != nil {
 		s.log.WithError(err).Error("failed to write audit log")
 	}
 }
@@ -4,8 +4,13 @@ import (
 	"bytes"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/maniack/catwatch/internal/logging"
+	"github.com/maniack/catwatch/internal/oauth"
+	"github.com/maniack/catwatch/internal/sessions"
+	"github.com/maniack/catwatch/internal/storage"
 	"github.com/sirupsen/logrus"
 )
 
@@ -50,3 +55,37 @@ func TestRequestLoggerLevel(t *testing.T) {
 		t.Errorf("Expected info level for error even on debug path, got: %s", buf.String())
 	}
 }
+
+func TestSecurityHeadersAllowAvatars(t *testing.T) {
+	logging.Init(false, false)
+	store, err := storage.Open("file:TestSecurityHeadersAllowAvatars?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("open storage: %v", err)
+	}
+	s, err := NewServer(Config{
+		Store:        store,
+		Logger:       logging.L(),
+		JWTSecret:    "test-secret",
+		SessionStore: sessions.NewMemorySessionStore(),
+		SkipWorkers:  true,
+		OAuth: oauth.Config{
+			GoogleClientID: "google-client",
+			OIDCIssuer:     "https://id.example.org/realms/catwatch",
+		},
+		AvatarOrigins: []string{"https://avatars.example.net"},
+	})
+	if err != nil {
+		t.Fatalf("new server: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	s.Router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/cats/", nil))
+	csp := w.Header().Get("Content-Security-Policy")
+	want := "img-src 'self' data: https://*.googleusercontent.com https://id.example.org https://avatars.example.net;"
+	if !strings.Contains(csp, want) {
+		t.Fatalf("expected %q in CSP, got %s", want, csp)
+	}
+	if strings.Contains(csp, "img-src 'self' data: https:;") {
+		t.Fatalf("CSP allows images from any origin: %s", csp)
+	}
+}
@@ -0,0 +1,128 @@
+package backend
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/maniack/catwatch/internal/logging"
+	"github.com/maniack/catwatch/internal/storage"
+)
+
+func TestMigrations(t *testing.T) {
+	logging.Init(false, false)
+	store, err := storage.Connect("file:TestMigrations?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	all := storage.Migrations()
+	if pending, err := store.PendingMigrations(); err != nil || len(pending) != len(all) {
+		t.Fatalf("fresh database: %d pending, err %v", len(pending), err)
+	}
+
+	// Up to the search index, then roll it back and re-apply
+	applied, err := store.MigrateUp(3)
+	if err != nil || len(applied) != 3 {
+		t.Fatalf("migrate up to 3: %d applied, err %v", len(applied), err)
+	}
+	// Only the columns the schema has at this version
+	if err := store.DB.Select("ID", "CreatedAt", "UpdatedAt", "Name").Create(&storage.Cat{ID: storage.NewUUID(), Name: "Pushok"}).Error; err != nil {
+		t.Fatalf("create cat: %v", err)
+	}
+	if hits, err := store.Search(storage.OrgScope{All: true}, "pushok", 10); err != nil || len(hits) != 1 {
+		t.Fatalf("search after up: %v %v", hits, err)
+	}
+	reverted, err := store.MigrateDown(1)
+	if err != nil || len(reverted) != 1 || reverted[0].Version != 3 {
+		t.Fatalf("migrate down: %v %v", reverted, err)
+	}
+	if store.DB.Migrator().HasTable("cat_search") {
+		t.Fatalf("cat_search must be dropped by the rollback")
+	}
+	pending, _ := store.PendingMigrations()
+	if len(pending) != len(all)-2 || pending[0].Version != 3 {
+		t.Fatalf("pending after rollback: %v", pending)
+	}
+
+	if _, err := store.MigrateUp(0); err != nil {
+		t.Fatalf("migrate up: %v", err)
+	}
+	if hits, err := store.Search(storage.OrgScope{All: true}, "pushok", 10); err != nil || len(hits) != 1 {
+		t.Fatalf("search index must be rebuilt on re-apply: %v %v", hits, err)
+	}
+	if applied, err := store.MigrateUp(0); err != nil || len(applied) != 0 {
+		t.Fatalf("second run must be a no-op: %v %v", applied, err)
+	}
+	states, err := store.MigrationStatus()
+	if err != nil || len(states) != len(all) {
+		t.Fatalf("status: %v %v", states, err)
+	}
+	for _, st := range states {
+		if st.AppliedAt == nil {
+			t.Fatalf("migration %d not applied", st.Version)
+		}
+	}
+
+	// Irreversible migrations stop a rollback
+	if _, err := store.MigrateDown(len(all)); !errors.Is(err, storage.ErrIrreversibleMigration) {
+		t.Fatalf("expected irreversible error, got %v", err)
+	}
+}
+
+func TestMigrationSchemaPerVersion(t *testing.T) {
+	logging.Init(false, false)
+	store, err := storage.Connect("file:TestMigrationSchemaPerVersion?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	m := store.DB.Migrator()
+	// Columns added by later steps are absent until their step runs
+	later := []struct {
+		version int64
+		table   string
+		column  string
+	}{
+		{7, "users", "photo_location_suggestions"},
+		{8, "images", "phash"},
+		{10, "images", "fetch_status"},
+		{11, "images", "position"},
+		{12, "cats", "image_retention"},
+		{12, "images", "deleted_at"},
+		{13, "records", "rrule"},
+		{15, "users", "time_zone"},
+		{15, "records", "time_zone"},
+		{16, "records", "recurrence_anchor"},
+	}
+	check := func(version int64) {
+		t.Helper()
+		for _, c := range later {
+			if has := m.HasColumn(c.table, c.column); has != (c.version <= version) {
+				t.Fatalf("version %d: %s.%s present: %v", version, c.table, c.column, has)
+			}
+		}
+	}
+	if _, err := store.MigrateUp(6); err != nil {
+		t.Fatalf("migrate up to 6: %v", err)
+	}
+	check(6)
+	if _, err := store.MigrateUp(12); err != nil {
+		t.Fatalf("migrate up to 12: %v", err)
+	}
+	check(12)
+	if !m.HasIndex("images", "idx_images_fetch_status") || !m.HasIndex("images", "idx_images_deleted_at") {
+		t.Fatalf("indexes of added columns are missing")
+	}
+	if _, err := store.MigrateUp(0); err != nil {
+		t.Fatalf("migrate up: %v", err)
+	}
+	check(int64(len(storage.Migrations())))
+
+	// Rolling back restores the schema of the version rolled back to
+	if _, err := store.MigrateDown(len(storage.Migrations()) - 7); err != nil {
+		t.Fatalf("migrate down to 7: %v", err)
+	}
+	check(7)
+	if _, err := store.MigrateUp(0); err != nil {
+		t.Fatalf("migrate up again: %v", err)
+	}
+	check(int64(len(storage.Migrations())))
+}
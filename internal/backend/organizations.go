@@ -0,0 +1,224 @@
+package backend
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/maniack/catwatch/internal/storage"
+	"gorm.io/gorm"
+)
+
+var slugRe = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+// isBotRequest reports whether the request carries the configured bot API key.
+func (s *Server) isBotRequest(r *http.Request) bool {
+	return s.cfg.BotAPIKey != "" && r.Header.Get("X-Bot-Key") == s.cfg.BotAPIKey
+}
+
+// readScope returns the organizations whose cats the caller may see:
+// members see their organizations plus public ones, anonymous visitors only public ones.
+// Anonymous requests from the bot (reminders, photos) are trusted with all organizations.
+func (s *Server) readScope(r *http.Request) storage.OrgScope {
+	uid, _ := UserIDFromCtx(r.Context())
+	if uid == "" {
+		if s.isBotRequest(r) {
+			return storage.OrgScope{All: true}
+		}
+		return s.store.PublicScope()
+	}
+	return s.store.ReadScope(uid, UserRoleFromCtx(r.Context()))
+}
+
+// memberScope returns the organizations the caller belongs to (all for admins).
+func (s *Server) memberScope(r *http.Request) storage.OrgScope {
+	uid, _ := UserIDFromCtx(r.Context())
+	if uid == "" {
+		if s.isBotRequest(r) {
+			return storage.OrgScope{All: true}
+		}
+		return storage.OrgScope{}
+	}
+	return s.store.MemberScope(uid, UserRoleFromCtx(r.Context()))
+}
+
+// catOrganization returns the organization of the cat or gorm.ErrRecordNotFound.
+func (s *Server) catOrganization(catID string) (string, error) {
+	var cat storage.Cat
+	if err := s.store.DB.Select("id", "organization_id").First(&cat, "id = ?", catID).Error; err != nil {
+		return "", err
+	}
+	return cat.OrganizationID, nil
+}
+
+// requireCatInScope writes 404 and returns false if the cat does not exist or is outside the scope.
+// Cats of other organizations are reported as missing to avoid leaking their existence.
+func (s *Server) requireCatInScope(w http.ResponseWriter, catID string, scope storage.OrgScope) bool {
+	orgID, err := s.catOrganization(catID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "cat not found"})
+			return false
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return false
+	}
+	if !scope.Allows(orgID) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "cat not found"})
+		return false
+	}
+	return true
+}
+
+// canManageOrganization reports whether the caller may manage members of the organization:
+// admins, or coordinators that are members of it.
+func (s *Server) canManageOrganization(r *http.Request, orgID string) bool {
+	role := UserRoleFromCtx(r.Context())
+	if role == storage.RoleAdmin {
+		return true
+	}
+	return storage.HasRole(role, storage.RoleCoordinator) && s.memberScope(r).Allows(orgID)
+}
+
+func (s *Server) handleListOrganizations(w http.ResponseWriter, r *http.Request) {
+	orgs, err := s.store.ListOrganizations(s.readScope(r))
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, orgs)
+}
+
+type organizationIn struct {
+	Name     string  `json:"name"`
+	Slug     string  `json:"slug"`
+	Public   *bool   `json:"public"`
+	TimeZone *string `json:"time_zone"`
+}
+
+func (s *Server) handleCreateOrganization(w http.ResponseWriter, r *http.Request) {
+	var in organizationIn
+	if err := jsonNewDecoder(r).Decode(&in); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json"})
+		return
+	}
+	in.Slug = strings.ToLower(strings.TrimSpace(in.Slug))
+	if strings.TrimSpace(in.Name) == "" || !slugRe.MatchString(in.Slug) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "name and slug ([a-z0-9-]) required"})
+		return
+	}
+	if in.TimeZone != nil {
+		if _, err := storage.LoadTimeZone(*in.TimeZone); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+	}
+	org := storage.Organization{ID: storage.NewUUID(), Name: strings.TrimSpace(in.Name), Slug: in.Slug}
+	if in.Public != nil {
+		org.Public = *in.Public
+	}
+	if in.TimeZone != nil {
+		org.TimeZone = *in.TimeZone
+	}
+	if err := s.store.DB.Create(&org).Error; err != nil {
+		s.LogAuditError(r, "organization", org.ID, err)
+		writeJSON(w, http.StatusConflict, map[string]string{"error": "organization already exists"})
+		return
+	}
+	s.LogAudit(r, "organization", org.ID, nil, org)
+	writeJSON(w, http.StatusCreated, org)
+}
+
+func (s *Server) handleUpdateOrganization(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "orgId")
+	var in organizationIn
+	if err := jsonNewDecoder(r).Decode(&in); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json"})
+		return
+	}
+	var org storage.Organization
+	if err := s.store.DB.First(&org, "id = ?", id).Error; err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "organization not found"})
+		return
+	}
+	before := org
+	if in.TimeZone != nil {
+		if _, err := storage.LoadTimeZone(*in.TimeZone); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		org.TimeZone = *in.TimeZone
+	}
+	if strings.TrimSpace(in.Name) != "" {
+		org.Name = strings.TrimSpace(in.Name)
+	}
+	if in.Public != nil {
+		org.Public = *in.Public
+	}
+	if err := s.store.DB.Save(&org).Error; err != nil {
+		s.LogAuditError(r, "organization", org.ID, err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	s.LogAudit(r, "organization", org.ID, before, org)
+	writeJSON(w, http.StatusOK, org)
+}
+
+func (s *Server) handleListMembers(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "orgId")
+	if !s.canManageOrganization(r, id) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
+		return
+	}
+	users, err := s.store.ListMembers(id)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, users)
+}
+
+func (s *Server) handleAddMember(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "orgId")
+	uid := chi.URLParam(r, "uid")
+	if !s.canManageOrganization(r, id) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
+		return
+	}
+	var n int64
+	s.store.DB.Model(&storage.Organization{}).Where("id = ?", id).Count(&n)
+	if n == 0 {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "organization not found"})
+		return
+	}
+	s.store.DB.Model(&storage.User{}).Where("id = ?", uid).Count(&n)
+	if n == 0 {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "user not found"})
+		return
+	}
+	if err := s.store.AddMember(id, uid); err != nil {
+		s.LogAuditError(r, "organization", id, err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	s.LogAudit(r, "organization", id, nil, storage.Membership{OrganizationID: id, UserID: uid})
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (s *Server) handleRemoveMember(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "orgId")
+	uid := chi.URLParam(r, "uid")
+	if !s.canManageOrganization(r, id) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
+		return
+	}
+	if err := s.store.RemoveMember(id, uid); err != nil {
+		s.LogAuditError(r, "organization", id, err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	s.LogAudit(r, "organization", id, storage.Membership{OrganizationID: id, UserID: uid}, nil)
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
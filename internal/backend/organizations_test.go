@@ -0,0 +1,205 @@
+package backend
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/maniack/catwatch/internal/storage"
+)
+
+func TestOrganizationIsolation(t *testing.T) {
+	s := newTestServer(t)
+
+	orgA := storage.Organization{ID: storage.NewUUID(), Name: "Shelter A", Slug: "shelter-a"}
+	orgB := storage.Organization{ID: storage.NewUUID(), Name: "Shelter B", Slug: "shelter-b"}
+	for _, o := range []*storage.Organization{&orgA, &orgB} {
+		if err := s.store.DB.Create(o).Error; err != nil {
+			t.Fatalf("create org: %v", err)
+		}
+	}
+	_ = s.store.AddMember(orgA.ID, "user-a")
+	_ = s.store.AddMember(orgB.ID, "user-b")
+	tokenA := issueTestToken(t, s, "user-a")
+	tokenB := issueTestToken(t, s, "user-b")
+
+	// New cats land in the creator's organization
+	w := doTestRequest(s, http.MethodPost, "/api/cats/", tokenA, catIn{Name: "Private Cat"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create cat: %d %s", w.Code, w.Body.String())
+	}
+	var cat storage.Cat
+	_ = json.Unmarshal(w.Body.Bytes(), &cat)
+	if cat.OrganizationID != orgA.ID {
+		t.Fatalf("expected cat in org A, got %q", cat.OrganizationID)
+	}
+
+	// Members of another organization see nothing
+	if w := doTestRequest(s, http.MethodGet, "/api/cats/"+cat.ID+"/", tokenB, nil); w.Code != http.StatusNotFound {
+		t.Fatalf("foreign get: expected 404, got %d", w.Code)
+	}
+	if w := doTestRequest(s, http.MethodPut, "/api/cats/"+cat.ID+"/", tokenB, catIn{Name: "Stolen"}); w.Code != http.StatusNotFound {
+		t.Fatalf("foreign update: expected 404, got %d", w.Code)
+	}
+	if w := doTestRequest(s, http.MethodPost, "/api/cats/"+cat.ID+"/records", tokenB, map[string]any{"type": "feeding"}); w.Code != http.StatusNotFound {
+		t.Fatalf("foreign record: expected 404, got %d", w.Code)
+	}
+	w = doTestRequest(s, http.MethodGet, "/api/cats/", tokenB, nil)
+	var list []storage.Cat
+	_ = json.Unmarshal(w.Body.Bytes(), &list)
+	if len(list) != 0 {
+		t.Fatalf("foreign list: expected no cats, got %d", len(list))
+	}
+
+	// Cats cannot be created in someone else's organization
+	if w := doTestRequest(s, http.MethodPost, "/api/cats/", tokenB, map[string]string{"name": "Sneaky", "organization_id": orgA.ID}); w.Code != http.StatusForbidden {
+		t.Fatalf("create in foreign org: expected 403, got %d", w.Code)
+	}
+
+	// Anonymous visitors only see public organizations
+	if w := doTestRequest(s, http.MethodGet, "/api/cats/"+cat.ID+"/", "", nil); w.Code != http.StatusNotFound {
+		t.Fatalf("anonymous get private cat: expected 404, got %d", w.Code)
+	}
+	s.store.DB.Model(&orgA).Update("public", true)
+	if w := doTestRequest(s, http.MethodGet, "/api/cats/"+cat.ID+"/", "", nil); w.Code != http.StatusOK {
+		t.Fatalf("anonymous get public cat: expected 200, got %d", w.Code)
+	}
+	// ... but public visibility does not grant write access
+	if w := doTestRequest(s, http.MethodPut, "/api/cats/"+cat.ID+"/", tokenB, catIn{Name: "Stolen"}); w.Code != http.StatusNotFound {
+		t.Fatalf("foreign update of public cat: expected 404, got %d", w.Code)
+	}
+
+	// Planned records are scoped as well
+	planned := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	if w := doTestRequest(s, http.MethodPost, "/api/cats/"+cat.ID+"/records", tokenA, map[string]any{"type": "feeding", "planned_at": planned}); w.Code != http.StatusCreated {
+		t.Fatalf("create planned record: %d %s", w.Code, w.Body.String())
+	}
+	for token, want := range map[string]int{tokenA: 1, tokenB: 0} {
+		w := doTestRequest(s, http.MethodGet, "/api/records/planned", token, nil)
+		var recs []storage.Record
+		_ = json.Unmarshal(w.Body.Bytes(), &recs)
+		if len(recs) != want {
+			t.Fatalf("planned records: expected %d, got %d", want, len(recs))
+		}
+	}
+
+	// Records of another organization cannot be rewritten through one's own cat
+	w = doTestRequest(s, http.MethodPost, "/api/cats/", tokenB, catIn{Name: "Other Cat"})
+	var catB storage.Cat
+	_ = json.Unmarshal(w.Body.Bytes(), &catB)
+	w = doTestRequest(s, http.MethodPost, "/api/cats/"+catB.ID+"/records", tokenB, map[string]any{"type": "feeding"})
+	var recB storage.Record
+	_ = json.Unmarshal(w.Body.Bytes(), &recB)
+	if w := doTestRequest(s, http.MethodPut, "/api/cats/"+cat.ID+"/records/"+recB.ID+"/", tokenA, map[string]any{"type": "hijacked"}); w.Code != http.StatusNotFound {
+		t.Fatalf("update foreign record via own cat: expected 404, got %d", w.Code)
+	}
+	var got storage.Record
+	if err := s.store.DB.First(&got, "id = ?", recB.ID).Error; err != nil {
+		t.Fatalf("load record: %v", err)
+	}
+	if got.CatID != catB.ID || got.Type != "feeding" {
+		t.Fatalf("foreign record changed: cat %q type %q", got.CatID, got.Type)
+	}
+}
+
+func TestOrganizationManagement(t *testing.T) {
+	s := newTestServer(t)
+	admin := issueTestTokenWithRole(t, s, "admin-user", storage.RoleAdmin)
+	volunteer := issueTestToken(t, s, "volunteer-user")
+	coordinator := issueTestTokenWithRole(t, s, "coord-user", storage.RoleCoordinator)
+	member, _ := s.store.FindOrCreateUser("dev", "m@example.com", "m@example.com", "Member", "")
+
+	if w := doTestRequest(s, http.MethodPost, "/api/orgs/", volunteer, organizationIn{Name: "Nope", Slug: "nope"}); w.Code != http.StatusForbidden {
+		t.Fatalf("volunteer create org: expected 403, got %d", w.Code)
+	}
+	if w := doTestRequest(s, http.MethodPost, "/api/orgs/", admin, organizationIn{Name: "Bad", Slug: "Bad Slug!"}); w.Code != http.StatusBadRequest {
+		t.Fatalf("invalid slug: expected 400, got %d", w.Code)
+	}
+	w := doTestRequest(s, http.MethodPost, "/api/orgs/", admin, organizationIn{Name: "North", Slug: "north"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create org: %d %s", w.Code, w.Body.String())
+	}
+	var org storage.Organization
+	_ = json.Unmarshal(w.Body.Bytes(), &org)
+	if org.Public {
+		t.Fatalf("new organizations must be private by default")
+	}
+	if w := doTestRequest(s, http.MethodPost, "/api/orgs/", admin, organizationIn{Name: "North 2", Slug: "north"}); w.Code != http.StatusConflict {
+		t.Fatalf("duplicate slug: expected 409, got %d", w.Code)
+	}
+
+	// Coordinators may only manage organizations they belong to
+	if w := doTestRequest(s, http.MethodPut, "/api/orgs/"+org.ID+"/members/"+member.ID, coordinator, nil); w.Code != http.StatusForbidden {
+		t.Fatalf("foreign coordinator add member: expected 403, got %d", w.Code)
+	}
+	if w := doTestRequest(s, http.MethodPut, "/api/orgs/"+org.ID+"/members/coord-user", admin, nil); w.Code != http.StatusNotFound {
+		t.Fatalf("add unknown user: expected 404, got %d", w.Code)
+	}
+	_ = s.store.AddMember(org.ID, "coord-user")
+	if w := doTestRequest(s, http.MethodPut, "/api/orgs/"+org.ID+"/members/"+member.ID, coordinator, nil); w.Code != http.StatusOK {
+		t.Fatalf("coordinator add member: %d %s", w.Code, w.Body.String())
+	}
+	w = doTestRequest(s, http.MethodGet, "/api/orgs/"+org.ID+"/members", coordinator, nil)
+	var members []storage.User
+	_ = json.Unmarshal(w.Body.Bytes(), &members)
+	if len(members) != 1 || members[0].ID != member.ID {
+		t.Fatalf("expected the added member, got %+v", members)
+	}
+
+	// Members see their organization, the volunteer only the public default one
+	w = doTestRequest(s, http.MethodGet, "/api/orgs/", volunteer, nil)
+	var orgs []storage.Organization
+	_ = json.Unmarshal(w.Body.Bytes(), &orgs)
+	if len(orgs) != 1 || orgs[0].Slug != storage.DefaultOrganizationSlug {
+		t.Fatalf("volunteer orgs: %+v", orgs)
+	}
+
+	if w := doTestRequest(s, http.MethodDelete, "/api/orgs/"+org.ID+"/members/"+member.ID, coordinator, nil); w.Code != http.StatusOK {
+		t.Fatalf("remove member: %d", w.Code)
+	}
+	if ids := s.store.UserOrganizationIDs(member.ID); len(ids) != 1 || ids[0] != s.store.DefaultOrganizationID() {
+		t.Fatalf("user without memberships should fall back to the default org, got %v", ids)
+	}
+}
+
+func TestImageCacheVisibility(t *testing.T) {
+	s := newTestServer(t)
+	r := s.Router
+
+	org := storage.Organization{ID: storage.NewUUID(), Name: "Closed Shelter", Slug: "closed-shelter"}
+	if err := s.store.DB.Create(&org).Error; err != nil {
+		t.Fatalf("create org: %v", err)
+	}
+	_ = s.store.AddMember(org.ID, "member")
+	token := issueTestToken(t, s, "member")
+
+	get := func(catID, token string) *httptest.ResponseRecorder {
+		img := storage.Image{ID: storage.NewUUID(), CatID: catID, Data: []byte("png"), MIME: "image/png"}
+		s.store.DB.Create(&img)
+		req := httptest.NewRequest(http.MethodGet, "/api/cats/"+catID+"/images/"+img.ID+"?size=original", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("get image: %d %s", w.Code, w.Body.String())
+		}
+		return w
+	}
+
+	public := storage.Cat{ID: storage.NewUUID(), OrganizationID: s.store.DefaultOrganizationID(), Name: "Public"}
+	private := storage.Cat{ID: storage.NewUUID(), OrganizationID: org.ID, Name: "Private"}
+	s.store.DB.Create(&public)
+	s.store.DB.Create(&private)
+
+	if cc := get(public.ID, "").Header().Get("Cache-Control"); cc != "public, max-age=31536000" {
+		t.Fatalf("public cat photo: unexpected Cache-Control %q", cc)
+	}
+	// Shared caches must not keep photos only members may see
+	if cc := get(private.ID, token).Header().Get("Cache-Control"); cc != "private, max-age=31536000" {
+		t.Fatalf("private cat photo: unexpected Cache-Control %q", cc)
+	}
+}
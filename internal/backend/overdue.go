@@ -0,0 +1,85 @@
+package backend
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/maniack/catwatch/internal/monitoring"
+	"github.com/maniack/catwatch/internal/storage"
+)
+
+// maxOverdueWindow bounds the window query parameter: every occurrence within it is expanded.
+const maxOverdueWindow = 30 * 24 * time.Hour
+
+// overdueWindow is how far back occurrences of recurring records count as overdue.
+func (s *Server) overdueWindow() time.Duration {
+	if s.cfg.OverdueWindow <= 0 {
+		return storage.DefaultOverdueWindow
+	}
+	return s.cfg.OverdueWindow
+}
+
+// listOverdueRecords lists the planned records and occurrences of the caller's organizations
+// whose time has passed without being done, oldest first. cat_id narrows it to one cat and
+// window (e.g. 48h, at most 30 days) overrides how far back occurrences of recurring records
+// are looked for.
+func (s *Server) listOverdueRecords(w http.ResponseWriter, r *http.Request) {
+	window := s.overdueWindow()
+	if v := r.URL.Query().Get("window"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid window"})
+			return
+		}
+		if d > maxOverdueWindow {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "window exceeds " + maxOverdueWindow.String()})
+			return
+		}
+		window = d
+	}
+	loc, err := s.viewerLocation(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	scope := s.memberScope(r)
+	catID := r.URL.Query().Get("cat_id")
+	if catID != "" && !s.requireCatInScope(w, catID, scope) {
+		return
+	}
+	recs, err := s.store.OverdueRecords(scope, catID, time.Now(), window)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	if recs == nil {
+		recs = []storage.Record{}
+	}
+	if loc != nil {
+		recs = storage.RecordsIn(recs, loc)
+	}
+	writeJSON(w, http.StatusOK, recs)
+}
+
+// startOverdueMetricsCollector periodically exports the number of overdue procedures by type
+func (s *Server) startOverdueMetricsCollector(interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	s.log.WithField("interval", interval.String()).Info("metrics: starting overdue records collector")
+	go func() {
+		for {
+			recs, err := s.store.OverdueRecords(storage.OrgScope{All: true}, "", time.Now(), s.overdueWindow())
+			if err == nil {
+				counts := map[string]int{}
+				for _, rec := range recs {
+					counts[rec.Type]++
+				}
+				monitoring.SetOverdueRecords(counts)
+			} else {
+				s.log.WithError(err).Warn("metrics: failed to load overdue records")
+			}
+			time.Sleep(interval)
+		}
+	}()
+}
@@ -0,0 +1,94 @@
+package backend
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/maniack/catwatch/internal/storage"
+)
+
+func TestOverdueRecords(t *testing.T) {
+	s := newTestServer(t)
+	r := s.Router
+	token := issueTestToken(t, s, "volunteer-user")
+
+	get := func(path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	cat := storage.Cat{ID: storage.NewUUID(), OrganizationID: s.store.DefaultOrganizationID(), Name: "Murka"}
+	other := storage.Cat{ID: storage.NewUUID(), OrganizationID: s.store.DefaultOrganizationID(), Name: "Barsik"}
+	s.store.DB.Create(&cat)
+	s.store.DB.Create(&other)
+	now := time.Now().UTC().Truncate(time.Minute)
+	at := func(d time.Duration) *time.Time { t := now.Add(d); return &t }
+	forgotten := storage.Record{ID: storage.NewUUID(), CatID: cat.ID, Type: "vet_visit", PlannedAt: at(-10 * 24 * time.Hour)}
+	daily := storage.Record{ID: storage.NewUUID(), CatID: cat.ID, Type: "feeding", PlannedAt: at(-10*24*time.Hour - time.Hour), RRule: "FREQ=DAILY"}
+	for _, rec := range []storage.Record{
+		forgotten,
+		daily,
+		{ID: storage.NewUUID(), CatID: cat.ID, Type: "medication", PlannedAt: at(time.Hour)},
+		{ID: storage.NewUUID(), CatID: cat.ID, Type: "medication", PlannedAt: at(-time.Hour), DoneAt: at(-time.Hour)},
+		{ID: storage.NewUUID(), CatID: other.ID, Type: "feeding", PlannedAt: at(-2 * time.Hour)},
+	} {
+		s.store.DB.Create(&rec)
+	}
+
+	list := func(query string) []storage.Record {
+		w := get("/api/records/overdue"+query, token)
+		if w.Code != http.StatusOK {
+			t.Fatalf("overdue%s: %d %s", query, w.Code, w.Body.String())
+		}
+		var out []storage.Record
+		_ = json.Unmarshal(w.Body.Bytes(), &out)
+		return out
+	}
+
+	// One-off plans stay overdue; recurring ones only within the window
+	got := list("?cat_id=" + cat.ID + "&window=48h")
+	want := []string{forgotten.ID, "virtual-" + daily.ID + "-" + now.Add(-25*time.Hour).Format("20060102"), "virtual-" + daily.ID + "-" + now.Add(-time.Hour).Format("20060102")}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d overdue records, got %d: %s", len(want), len(got), get("/api/records/overdue?cat_id="+cat.ID+"&window=48h", token).Body.String())
+	}
+	for i := range want {
+		if got[i].ID != want[i] || !got[i].Overdue {
+			t.Fatalf("overdue %d: expected %s, got %s (overdue %v)", i, want[i], got[i].ID, got[i].Overdue)
+		}
+	}
+	if got := list(""); len(got) != 9 {
+		t.Fatalf("default window: expected 9 overdue records, got %d", len(got))
+	}
+
+	if w := get("/api/records/overdue?window=soon", token); w.Code != http.StatusBadRequest {
+		t.Fatalf("invalid window: expected 400, got %d", w.Code)
+	}
+	if w := get("/api/records/overdue?window=100000h", token); w.Code != http.StatusBadRequest {
+		t.Fatalf("window over the limit: expected 400, got %d", w.Code)
+	}
+	if w := get("/api/records/overdue?window=720h", token); w.Code != http.StatusOK {
+		t.Fatalf("window at the limit: expected 200, got %d", w.Code)
+	}
+	var anon []storage.Record
+	_ = json.Unmarshal(get("/api/records/overdue", "").Body.Bytes(), &anon)
+	if len(anon) != 0 {
+		t.Fatalf("anonymous users see %d overdue records", len(anon))
+	}
+
+	// Listings flag past plans as overdue
+	var planned []storage.Record
+	_ = json.Unmarshal(get("/api/cats/"+cat.ID+"/records?status=planned", token).Body.Bytes(), &planned)
+	for _, rec := range planned {
+		if rec.Overdue != rec.PlannedAt.Before(now) {
+			t.Fatalf("record %s planned at %v: overdue %v", rec.ID, rec.PlannedAt, rec.Overdue)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package backend
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// pageCursor points at the last item of a page in a (timestamp, id) ordered listing.
+type pageCursor struct {
+	Timestamp time.Time
+	ID        string
+}
+
+// encodeCursor returns an opaque cursor string for the item.
+func encodeCursor(ts time.Time, id string) string {
+	raw := ts.UTC().Format(time.RFC3339Nano) + "|" + id
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeCursor parses a cursor produced by encodeCursor. An empty string yields nil.
+func decodeCursor(s string) (*pageCursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	tsStr, id, ok := strings.Cut(string(raw), "|")
+	if !ok || id == "" {
+		return nil, errInvalidCursor
+	}
+	ts, err := time.Parse(time.RFC3339Nano, tsStr)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	return &pageCursor{Timestamp: ts, ID: id}, nil
+}
+
+// sortCursor points at the last item of a page in a listing ordered by (sort key, id).
+// The sort key is part of the cursor so that it cannot be reused with another order.
+type sortCursor struct {
+	Sort  string
+	Value string
+	ID    string
+}
+
+// encodeSortCursor returns an opaque cursor string for the item.
+func encodeSortCursor(sort, value, id string) string {
+	raw := sort + "|" + value + "|" + id
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeSortCursor parses a cursor produced by encodeSortCursor. An empty string yields nil.
+func decodeSortCursor(s string) (*sortCursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	// The value may contain separators (names); the sort key and ID never do
+	str := string(raw)
+	i, j := strings.Index(str, "|"), strings.LastIndex(str, "|")
+	if i < 1 || j == i || j == len(str)-1 {
+		return nil, errInvalidCursor
+	}
+	return &sortCursor{Sort: str[:i], Value: str[i+1 : j], ID: str[j+1:]}, nil
+}
+
+// parseLimit reads the "limit" query parameter, falling back to def and capping at max.
+func parseLimit(r *http.Request, def, max int) (int, error) {
+	v := r.URL.Query().Get("limit")
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid limit")
+	}
+	if n > max {
+		n = max
+	}
+	return n, nil
+}
+
+// setNextLink adds a Link header (RFC 8288) pointing at the next page of the current request.
+func setNextLink(w http.ResponseWriter, r *http.Request, cursor string) {
+	q := r.URL.Query()
+	q.Set("cursor", cursor)
+	u := *r.URL
+	u.RawQuery = q.Encode()
+	w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, u.RequestURI()))
+}
@@ -0,0 +1,74 @@
+package backend
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/maniack/catwatch/internal/storage"
+	"gorm.io/gorm"
+)
+
+// RequireRole middleware ensures the user is authenticated and has at least the given role.
+func (s *Server) RequireRole(role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return s.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !storage.HasRole(UserRoleFromCtx(r.Context()), role) {
+				writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
+				return
+			}
+			next.ServeHTTP(w, r)
+		}))
+	}
+}
+
+func (s *Server) handleAdminListUsers(w http.ResponseWriter, r *http.Request) {
+	users, err := s.store.ListUsers()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, users)
+}
+
+// handleAdminSetRole grants a role to the user: PUT /api/admin/users/{id}/role {"role": "coordinator"}
+func (s *Server) handleAdminSetRole(w http.ResponseWriter, r *http.Request) {
+	var in struct {
+		Role string `json:"role"`
+	}
+	if err := jsonNewDecoder(r).Decode(&in); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json"})
+		return
+	}
+	if !storage.ValidRole(in.Role) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unknown role"})
+		return
+	}
+	s.setUserRole(w, r, chi.URLParam(r, "id"), in.Role)
+}
+
+// handleAdminRevokeRole drops the user back to read-only access: DELETE /api/admin/users/{id}/role
+func (s *Server) handleAdminRevokeRole(w http.ResponseWriter, r *http.Request) {
+	s.setUserRole(w, r, chi.URLParam(r, "id"), storage.RoleViewer)
+}
+
+func (s *Server) setUserRole(w http.ResponseWriter, r *http.Request, userID, role string) {
+	prev, err := s.store.GetUserRole(userID)
+	var u *storage.User
+	if err == nil {
+		u, err = s.store.SetUserRole(userID, role)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "user not found"})
+		case errors.Is(err, storage.ErrLastAdmin):
+			writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+		default:
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		}
+		return
+	}
+	s.LogAudit(r, "user", u.ID, map[string]string{"role": prev}, map[string]string{"role": role})
+	writeJSON(w, http.StatusOK, u)
+}
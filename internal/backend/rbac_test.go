@@ -0,0 +1,187 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/maniack/catwatch/internal/storage"
+	"gorm.io/gorm"
+)
+
+func TestRolePermissions(t *testing.T) {
+	s := newTestServer(t)
+	viewer := issueTestTokenWithRole(t, s, "viewer-user", storage.RoleViewer)
+	volunteer := issueTestToken(t, s, "volunteer-user")
+	coordinator := issueTestTokenWithRole(t, s, "coord-user", storage.RoleCoordinator)
+
+	// Viewers are read-only
+	if w := doTestRequest(s, http.MethodPost, "/api/cats/", viewer, catIn{Name: "Nope"}); w.Code != http.StatusForbidden {
+		t.Fatalf("viewer create cat: expected 403, got %d", w.Code)
+	}
+
+	// Volunteers can create cats
+	w := doTestRequest(s, http.MethodPost, "/api/cats/", volunteer, catIn{Name: "Role Cat"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("volunteer create cat: expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var cat storage.Cat
+	_ = json.Unmarshal(w.Body.Bytes(), &cat)
+
+	// ... but viewers may still like them
+	if w := doTestRequest(s, http.MethodPost, "/api/cats/"+cat.ID+"/like", viewer, nil); w.Code != http.StatusOK {
+		t.Fatalf("viewer like: expected 200, got %d", w.Code)
+	}
+
+	// Deleting requires coordinator
+	if w := doTestRequest(s, http.MethodDelete, "/api/cats/"+cat.ID+"/", volunteer, nil); w.Code != http.StatusForbidden {
+		t.Fatalf("volunteer delete cat: expected 403, got %d", w.Code)
+	}
+	if w := doTestRequest(s, http.MethodDelete, "/api/images/some-image", volunteer, nil); w.Code != http.StatusForbidden {
+		t.Fatalf("volunteer delete image: expected 403, got %d", w.Code)
+	}
+	if w := doTestRequest(s, http.MethodDelete, "/api/cats/"+cat.ID+"/", coordinator, nil); w.Code != http.StatusNoContent {
+		t.Fatalf("coordinator delete cat: expected 204, got %d", w.Code)
+	}
+
+	// Admin API is admin-only
+	if w := doTestRequest(s, http.MethodGet, "/api/admin/users/", coordinator, nil); w.Code != http.StatusForbidden {
+		t.Fatalf("coordinator admin users: expected 403, got %d", w.Code)
+	}
+}
+
+func TestAdminRoleManagement(t *testing.T) {
+	s := newTestServer(t)
+	r := s.Router
+
+	admin, err := s.store.FindOrCreateUser("dev", "admin@example.com", "admin@example.com", "Admin", "")
+	if err != nil {
+		t.Fatalf("create admin: %v", err)
+	}
+	if admin.Role != storage.DefaultRole {
+		t.Fatalf("expected new user to get default role, got %s", admin.Role)
+	}
+	if _, err := s.store.SetUserRole(admin.ID, storage.RoleAdmin); err != nil {
+		t.Fatalf("promote admin: %v", err)
+	}
+	member, _ := s.store.FindOrCreateUser("dev", "member@example.com", "member@example.com", "Member", "")
+	token := issueTestTokenWithRole(t, s, admin.ID, storage.RoleAdmin)
+
+	setRole := func(method, uid string, body any) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			_ = json.NewEncoder(&buf).Encode(body)
+		}
+		req := httptest.NewRequest(method, "/api/admin/users/"+uid+"/role", &buf)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// List users
+	req := httptest.NewRequest(http.MethodGet, "/api/admin/users/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("list users: %d", w.Code)
+	}
+	var users []storage.User
+	_ = json.Unmarshal(w.Body.Bytes(), &users)
+	if len(users) != 2 {
+		t.Fatalf("expected 2 users, got %d", len(users))
+	}
+
+	// Grant
+	if w := setRole(http.MethodPut, member.ID, map[string]string{"role": storage.RoleCoordinator}); w.Code != http.StatusOK {
+		t.Fatalf("grant role: %d %s", w.Code, w.Body.String())
+	}
+	if got, _ := s.store.GetUserRole(member.ID); got != storage.RoleCoordinator {
+		t.Fatalf("expected coordinator, got %s", got)
+	}
+	// Unknown role
+	if w := setRole(http.MethodPut, member.ID, map[string]string{"role": "overlord"}); w.Code != http.StatusBadRequest {
+		t.Fatalf("unknown role: expected 400, got %d", w.Code)
+	}
+	// Unknown user
+	if w := setRole(http.MethodPut, "missing", map[string]string{"role": storage.RoleViewer}); w.Code != http.StatusNotFound {
+		t.Fatalf("unknown user: expected 404, got %d", w.Code)
+	}
+	// Revoke
+	if w := setRole(http.MethodDelete, member.ID, nil); w.Code != http.StatusOK {
+		t.Fatalf("revoke role: %d", w.Code)
+	}
+	if got, _ := s.store.GetUserRole(member.ID); got != storage.RoleViewer {
+		t.Fatalf("expected viewer after revoke, got %s", got)
+	}
+	// The last admin cannot be demoted
+	if w := setRole(http.MethodDelete, admin.ID, nil); w.Code != http.StatusConflict {
+		t.Fatalf("demote last admin: expected 409, got %d", w.Code)
+	}
+
+	// Role changes are audited
+	var count int64
+	s.store.DB.Model(&storage.AuditLog{}).Where("target_type = ? AND target_id = ?", "user", member.ID).Count(&count)
+	if count != 2 {
+		t.Fatalf("expected 2 audit entries for role changes, got %d", count)
+	}
+}
+
+func TestIssuedTokenCarriesRole(t *testing.T) {
+	s := newTestServer(t)
+	s.cfg.AdminEmails = []string{"Boss@Example.com"}
+
+	u, _ := s.store.FindOrCreateUser("dev", "boss@example.com", "boss@example.com", "Boss", "")
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/dev-login", nil)
+	if err := s.issueTokens(w, req, u.ID); err != nil {
+		t.Fatalf("issue tokens: %v", err)
+	}
+	var access string
+	for _, c := range w.Result().Cookies() {
+		if c.Name == "access_token" {
+			access, _ = url.QueryUnescape(c.Value)
+		}
+	}
+	if access == "" {
+		t.Fatalf("missing access token cookie")
+	}
+
+	// Bootstrap admin email was promoted and the role is honored by the admin API
+	if got, _ := s.store.GetUserRole(u.ID); got != storage.RoleAdmin {
+		t.Fatalf("expected bootstrap admin, got %s", got)
+	}
+	req = httptest.NewRequest(http.MethodGet, "/api/admin/users/", nil)
+	req.Header.Set("Authorization", "Bearer "+access)
+	w = httptest.NewRecorder()
+	s.Router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("admin API with issued token: expected 200, got %d", w.Code)
+	}
+}
+
+func TestUserRoleFailsClosed(t *testing.T) {
+	s := newTestServer(t)
+
+	// Unknown users get no token instead of the default role
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/dev-login", nil)
+	if err := s.issueTokens(httptest.NewRecorder(), req, "missing-user"); err == nil {
+		t.Fatalf("expected an error issuing tokens for an unknown user")
+	}
+	if _, err := s.store.GetUserRole("missing-user"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("unknown user: expected ErrRecordNotFound, got %v", err)
+	}
+
+	// Invalid stored roles grant read-only access
+	u, _ := s.store.FindOrCreateUser("dev", "odd@example.com", "odd@example.com", "Odd", "")
+	s.store.DB.Model(u).Update("role", "superuser")
+	if got, err := s.store.GetUserRole(u.ID); err != nil || got != storage.RoleViewer {
+		t.Fatalf("invalid role: expected %s, got %q (%v)", storage.RoleViewer, got, err)
+	}
+}
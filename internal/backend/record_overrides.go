@@ -0,0 +1,70 @@
+package backend
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/maniack/catwatch/internal/storage"
+	"gorm.io/gorm"
+)
+
+// occurrenceLookup returns the records an occurrence may belong to: those of the caller's
+// organizations and, on the cat routes, of that cat.
+func (s *Server) occurrenceLookup(scope storage.OrgScope, catID string) *gorm.DB {
+	db := scope.ByCat(s.store.DB.Model(&storage.Record{}))
+	if catID != "" {
+		db = db.Where("cat_id = ?", catID)
+	}
+	return db
+}
+
+func (s *Server) writeOccurrenceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "occurrence not found"})
+	case errors.Is(err, storage.ErrInvalidOverride):
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+	case errors.Is(err, storage.ErrOccurrenceDone):
+		writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+	default:
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+}
+
+// overrideOccurrence skips, reschedules or snoozes one occurrence of a recurring record. The
+// occurrence is addressed by its virtual ID, or by the record ID for the first one.
+func (s *Server) overrideOccurrence(w http.ResponseWriter, r *http.Request) {
+	catID := chi.URLParam(r, "id")
+	rid := chi.URLParam(r, "rid")
+	uid, _ := UserIDFromCtx(r.Context())
+	var in storage.OccurrenceChange
+	if err := jsonNewDecoder(r).Decode(&in); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid json"})
+		return
+	}
+	o, prev, err := s.store.OverrideOccurrence(s.occurrenceLookup(s.memberScope(r), catID), rid, uid, in, time.Now())
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			s.LogAuditError(r, "record", rid, err)
+		}
+		s.writeOccurrenceError(w, err)
+		return
+	}
+	s.LogAudit(r, "record", rid, prev, o)
+	writeJSON(w, http.StatusOK, o)
+}
+
+// clearOccurrenceOverride puts a skipped, rescheduled or snoozed occurrence back on its schedule.
+func (s *Server) clearOccurrenceOverride(w http.ResponseWriter, r *http.Request) {
+	catID := chi.URLParam(r, "id")
+	rid := chi.URLParam(r, "rid")
+	prev, err := s.store.ClearOccurrenceOverride(s.occurrenceLookup(s.memberScope(r), catID), rid)
+	if err != nil {
+		s.writeOccurrenceError(w, err)
+		return
+	}
+	s.LogAudit(r, "record", rid, prev, nil)
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
@@ -0,0 +1,316 @@
+package backend
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/maniack/catwatch/internal/storage"
+)
+
+func TestRecordRRule(t *testing.T) {
+	s := newTestServer(t)
+	token := issueTestToken(t, s, "volunteer-user")
+
+	cat := storage.Cat{ID: storage.NewUUID(), OrganizationID: s.store.DefaultOrganizationID(), Name: "Murka"}
+	s.store.DB.Create(&cat)
+	recordsPath := "/api/cats/" + cat.ID + "/records"
+
+	if w := doTestRequest(s, http.MethodPost, recordsPath, token, map[string]any{"type": "feeding", "planned_at": time.Now(), "rrule": "FREQ=HOURLY"}); w.Code != http.StatusBadRequest {
+		t.Fatalf("unsupported rule: expected 400, got %d", w.Code)
+	}
+
+	// Monday 5 January 2026, every Mon/Wed/Fri, skipping Wednesday 7th, plus Sunday 11th
+	planned := time.Date(2026, time.January, 5, 9, 0, 0, 0, time.UTC)
+	w := doTestRequest(s, http.MethodPost, recordsPath, token, map[string]any{
+		"type":       "feeding",
+		"planned_at": planned,
+		"rrule":      "rrule:freq=weekly;byday=mo,we,fr",
+		"exdates":    []time.Time{time.Date(2026, time.January, 7, 0, 0, 0, 0, time.UTC)},
+		"rdates":     []time.Time{time.Date(2026, time.January, 11, 12, 0, 0, 0, time.UTC)},
+	})
+	var rec storage.Record
+	_ = json.Unmarshal(w.Body.Bytes(), &rec)
+	if w.Code != http.StatusCreated || rec.RRule != "FREQ=WEEKLY;BYDAY=MO,WE,FR" || rec.Recurrence != "weekly" {
+		t.Fatalf("create: %d %s", w.Code, w.Body.String())
+	}
+
+	list := func(start, end time.Time) []storage.Record {
+		q := url.Values{"status": {"planned"}, "start": {start.Format(time.RFC3339)}, "end": {end.Format(time.RFC3339)}}
+		w := doTestRequest(s, http.MethodGet, recordsPath+"?"+q.Encode(), token, nil)
+		var out []storage.Record
+		_ = json.Unmarshal(w.Body.Bytes(), &out)
+		return out
+	}
+	got := list(planned, planned.AddDate(0, 0, 7))
+	want := []string{rec.ID, "virtual-" + rec.ID + "-20260109", "virtual-" + rec.ID + "-20260111", "virtual-" + rec.ID + "-20260112"}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d occurrences, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if got[i].ID != want[i] {
+			t.Fatalf("occurrence %d: expected %s, got %s", i, want[i], got[i].ID)
+		}
+	}
+	if got[2].PlannedAt.Hour() != 12 || got[3].PlannedAt.Hour() != 9 {
+		t.Fatalf("wrong occurrence times: %v %v", got[2].PlannedAt, got[3].PlannedAt)
+	}
+
+	// Excluding the first day hides the record itself
+	if w := doTestRequest(s, http.MethodPut, recordsPath+"/"+rec.ID, token, map[string]any{"exdates": []time.Time{planned}}); w.Code != http.StatusOK {
+		t.Fatalf("update: %d %s", w.Code, w.Body.String())
+	}
+	if got := list(planned, planned.Add(time.Hour)); len(got) != 0 {
+		t.Fatalf("excluded day still listed: %v", got[0].ID)
+	}
+
+	// Completing an occurrence stores it at its planned time; days without one are rejected
+	if w := doTestRequest(s, http.MethodPost, recordsPath+"/virtual-"+rec.ID+"-20260110/done", token, nil); w.Code != http.StatusNotFound {
+		t.Fatalf("day without occurrence: expected 404, got %d", w.Code)
+	}
+	w = doTestRequest(s, http.MethodPost, recordsPath+"/virtual-"+rec.ID+"-20260109/done", token, nil)
+	var done storage.Record
+	_ = json.Unmarshal(w.Body.Bytes(), &done)
+	if w.Code != http.StatusOK || done.DoneAt == nil || !done.PlannedAt.Equal(planned.AddDate(0, 0, 4)) || done.RRule != "" || len(done.ExDates) != 0 {
+		t.Fatalf("done: %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestOccurrenceOverrides(t *testing.T) {
+	s := newTestServer(t)
+	token := issueTestToken(t, s, "volunteer-user")
+	viewer := issueTestTokenWithRole(t, s, "viewer-user", storage.RoleViewer)
+
+	cat := storage.Cat{ID: storage.NewUUID(), OrganizationID: s.store.DefaultOrganizationID(), Name: "Murka"}
+	s.store.DB.Create(&cat)
+	planned := time.Now().UTC().Add(time.Hour).Truncate(time.Minute)
+	w := doTestRequest(s, http.MethodPost, "/api/cats/"+cat.ID+"/records", token, map[string]any{"type": "feeding", "planned_at": planned, "rrule": "FREQ=DAILY"})
+	var rec storage.Record
+	_ = json.Unmarshal(w.Body.Bytes(), &rec)
+	day := func(n int) string { return planned.AddDate(0, 0, n).Format("20060102") }
+	occ := func(n int) string {
+		if n == 0 {
+			return rec.ID
+		}
+		return "virtual-" + rec.ID + "-" + day(n)
+	}
+	list := func() map[string]storage.Record {
+		q := url.Values{"start": {planned.Format(time.RFC3339)}, "end": {planned.AddDate(0, 0, 4).Format(time.RFC3339)}}
+		w := doTestRequest(s, http.MethodGet, "/api/records/planned?"+q.Encode(), token, nil)
+		var recs []storage.Record
+		_ = json.Unmarshal(w.Body.Bytes(), &recs)
+		out := map[string]storage.Record{}
+		for _, r := range recs {
+			out[r.ID] = r
+		}
+		return out
+	}
+	override := func(id string, body any) *httptest.ResponseRecorder {
+		return doTestRequest(s, http.MethodPost, "/api/records/"+id+"/override", token, body)
+	}
+
+	if w := doTestRequest(s, http.MethodPost, "/api/records/"+occ(1)+"/override", viewer, map[string]any{"action": "skipped"}); w.Code != http.StatusForbidden {
+		t.Fatalf("viewer: expected 403, got %d", w.Code)
+	}
+	for _, bad := range []map[string]any{{"action": "cancelled"}, {"action": "rescheduled"}, {"action": "snoozed", "minutes": -5}} {
+		if w := override(occ(1), bad); w.Code != http.StatusBadRequest {
+			t.Fatalf("%v: expected 400, got %d", bad, w.Code)
+		}
+	}
+	if w := override("virtual-"+rec.ID+"-19990101", map[string]any{"action": "skipped"}); w.Code != http.StatusNotFound {
+		t.Fatalf("day outside the rule: expected 404, got %d", w.Code)
+	}
+
+	// Skip the first occurrence, move the second by two days, snooze the third
+	if w := override(occ(0), map[string]any{"action": "skipped"}); w.Code != http.StatusOK {
+		t.Fatalf("skip: %d %s", w.Code, w.Body.String())
+	}
+	moved := planned.AddDate(0, 0, 3).Add(2 * time.Hour)
+	if w := doTestRequest(s, http.MethodPost, "/api/cats/"+cat.ID+"/records/"+occ(1)+"/override", token, map[string]any{"action": "rescheduled", "planned_at": moved}); w.Code != http.StatusOK {
+		t.Fatalf("reschedule: %d %s", w.Code, w.Body.String())
+	}
+	w = override(occ(2), map[string]any{"action": "snoozed", "minutes": 30})
+	var snoozed storage.RecordOverride
+	_ = json.Unmarshal(w.Body.Bytes(), &snoozed)
+	if w.Code != http.StatusOK || !snoozed.PlannedAt.Equal(planned.AddDate(0, 0, 2).Add(30*time.Minute)) {
+		t.Fatalf("snooze: %d %s", w.Code, w.Body.String())
+	}
+	got := list()
+	if _, ok := got[occ(0)]; ok {
+		t.Fatal("skipped occurrence is listed")
+	}
+	if o, ok := got[occ(1)]; !ok || !o.PlannedAt.Equal(moved) || o.Override == nil || o.Override.Action != storage.OverrideRescheduled {
+		t.Fatalf("rescheduled occurrence: %+v", o)
+	}
+	if o := got[occ(2)]; o.Override == nil || o.Override.ID != snoozed.ID {
+		t.Fatalf("snoozed occurrence: %+v", o)
+	}
+
+	// Snoozing again gives a new override, so the bot reminds once more
+	w = override(occ(2), map[string]any{"action": "snoozed", "minutes": 30})
+	var again storage.RecordOverride
+	_ = json.Unmarshal(w.Body.Bytes(), &again)
+	if again.ID == snoozed.ID || !again.PlannedAt.Equal(snoozed.PlannedAt.Add(30*time.Minute)) {
+		t.Fatalf("second snooze: %s", w.Body.String())
+	}
+
+	// Undo
+	if w := doTestRequest(s, http.MethodDelete, "/api/records/"+occ(0)+"/override", token, nil); w.Code != http.StatusOK {
+		t.Fatalf("clear: %d %s", w.Code, w.Body.String())
+	}
+	if w := doTestRequest(s, http.MethodDelete, "/api/records/"+occ(0)+"/override", token, nil); w.Code != http.StatusNotFound {
+		t.Fatalf("second clear: expected 404, got %d", w.Code)
+	}
+	if _, ok := list()[occ(0)]; !ok {
+		t.Fatal("restored occurrence is not listed")
+	}
+
+	// Done occurrences leave the plan, at their rescheduled time, and cannot be overridden
+	w = doTestRequest(s, http.MethodPost, "/api/records/"+occ(1)+"/done", token, nil)
+	var done storage.Record
+	_ = json.Unmarshal(w.Body.Bytes(), &done)
+	if w.Code != http.StatusOK || !done.PlannedAt.Equal(moved) {
+		t.Fatalf("done: %d %s", w.Code, w.Body.String())
+	}
+	if _, ok := list()[occ(1)]; ok {
+		t.Fatal("done occurrence is still planned")
+	}
+	if w := override(occ(1), map[string]any{"action": "skipped"}); w.Code != http.StatusConflict {
+		t.Fatalf("override done occurrence: expected 409, got %d", w.Code)
+	}
+	if w := doTestRequest(s, http.MethodPost, "/api/records/"+occ(1)+"/done", token, nil); w.Code != http.StatusConflict {
+		t.Fatalf("done twice: expected 409, got %d", w.Code)
+	}
+}
+
+func TestRecordTimeZones(t *testing.T) {
+	s := newTestServer(t)
+	s.store.DB.Create(&storage.User{ID: "volunteer-user", ProviderID: "dev:volunteer-user", Role: storage.DefaultRole})
+	token := issueTestToken(t, s, "volunteer-user")
+
+	s.store.DB.Model(&storage.Organization{}).Where("id = ?", s.store.DefaultOrganizationID()).Update("time_zone", "Europe/Berlin")
+	cat := storage.Cat{ID: storage.NewUUID(), OrganizationID: s.store.DefaultOrganizationID(), Name: "Murka"}
+	s.store.DB.Create(&cat)
+	recordsPath := "/api/cats/" + cat.ID + "/records"
+
+	if w := doTestRequest(s, http.MethodPost, recordsPath, token, map[string]any{"type": "feeding", "planned_at": time.Now(), "rrule": "FREQ=DAILY", "time_zone": "Mars/Olympus"}); w.Code != http.StatusBadRequest {
+		t.Fatalf("unknown zone: expected 400, got %d", w.Code)
+	}
+
+	// 09:00 in Berlin on the day before the switch to summer time
+	planned := time.Date(2026, time.March, 28, 8, 0, 0, 0, time.UTC)
+	w := doTestRequest(s, http.MethodPost, recordsPath, token, map[string]any{"type": "feeding", "planned_at": planned, "rrule": "FREQ=DAILY"})
+	var rec storage.Record
+	_ = json.Unmarshal(w.Body.Bytes(), &rec)
+	if w.Code != http.StatusCreated || rec.TimeZone != "Europe/Berlin" {
+		t.Fatalf("create: %d %s", w.Code, w.Body.String())
+	}
+
+	list := func(tz string) *httptest.ResponseRecorder {
+		q := url.Values{"status": {"planned"}, "start": {planned.Format(time.RFC3339)}, "end": {planned.AddDate(0, 0, 2).Format(time.RFC3339)}}
+		if tz != "" {
+			q.Set("tz", tz)
+		}
+		return doTestRequest(s, http.MethodGet, recordsPath+"?"+q.Encode(), token, nil)
+	}
+	var got []storage.Record
+	_ = json.Unmarshal(list("").Body.Bytes(), &got)
+	if len(got) != 3 {
+		t.Fatalf("expected 3 occurrences, got %d", len(got))
+	}
+	// The feeding stays at 09:00 local time, an hour earlier in UTC after the switch
+	if want := time.Date(2026, time.March, 29, 7, 0, 0, 0, time.UTC); !got[1].PlannedAt.Equal(want) || got[1].ID != "virtual-"+rec.ID+"-20260329" {
+		t.Fatalf("occurrence after DST: %s %v", got[1].ID, got[1].PlannedAt)
+	}
+
+	// Times are rendered in the viewer's zone: the tz parameter, else the user's
+	if w := list("Mars/Olympus"); w.Code != http.StatusBadRequest {
+		t.Fatalf("unknown tz: expected 400, got %d", w.Code)
+	}
+	if w := doTestRequest(s, http.MethodPatch, "/api/user/", token, map[string]any{"time_zone": "Nowhere"}); w.Code != http.StatusBadRequest {
+		t.Fatalf("unknown user zone: expected 400, got %d", w.Code)
+	}
+	if w := doTestRequest(s, http.MethodPatch, "/api/user/", token, map[string]any{"time_zone": "Asia/Tokyo"}); w.Code != http.StatusOK {
+		t.Fatalf("set user zone: %d %s", w.Code, w.Body.String())
+	}
+	offset := func(w *httptest.ResponseRecorder) int {
+		var recs []storage.Record
+		_ = json.Unmarshal(w.Body.Bytes(), &recs)
+		if len(recs) == 0 {
+			t.Fatalf("no records: %s", w.Body.String())
+		}
+		_, off := recs[0].PlannedAt.Zone()
+		return off
+	}
+	if off := offset(list("")); off != 9*3600 {
+		t.Fatalf("user zone: offset %d", off)
+	}
+	if off := offset(list("America/New_York")); off != -4*3600 {
+		t.Fatalf("tz parameter: offset %d", off)
+	}
+}
+
+func TestCompletionAnchoredRecord(t *testing.T) {
+	s := newTestServer(t)
+	token := issueTestToken(t, s, "volunteer-user")
+
+	cat := storage.Cat{ID: storage.NewUUID(), OrganizationID: s.store.DefaultOrganizationID(), Name: "Murka"}
+	s.store.DB.Create(&cat)
+	recordsPath := "/api/cats/" + cat.ID + "/records"
+
+	planned := time.Now().UTC().AddDate(0, 0, -3).Truncate(time.Hour)
+	for _, bad := range []map[string]any{
+		{"type": "medication", "planned_at": planned, "recurrence_anchor": "done"},
+		{"type": "medication", "planned_at": planned, "rrule": "FREQ=WEEKLY", "recurrence_anchor": "later"},
+		{"type": "medication", "planned_at": planned, "rrule": "FREQ=WEEKLY", "recurrence_anchor": "done", "rdates": []time.Time{planned}},
+	} {
+		if w := doTestRequest(s, http.MethodPost, recordsPath, token, bad); w.Code != http.StatusBadRequest {
+			t.Fatalf("%v: expected 400, got %d", bad, w.Code)
+		}
+	}
+
+	// Every two weeks after the last treatment, twice
+	w := doTestRequest(s, http.MethodPost, recordsPath, token, map[string]any{"type": "medication", "planned_at": planned, "rrule": "FREQ=WEEKLY;INTERVAL=2;COUNT=2", "recurrence_anchor": "done"})
+	var rec storage.Record
+	_ = json.Unmarshal(w.Body.Bytes(), &rec)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: %d %s", w.Code, w.Body.String())
+	}
+
+	planList := func() []storage.Record {
+		q := url.Values{"status": {"planned"}, "start": {planned.Format(time.RFC3339)}, "end": {planned.AddDate(0, 2, 0).Format(time.RFC3339)}}
+		var out []storage.Record
+		_ = json.Unmarshal(doTestRequest(s, http.MethodGet, recordsPath+"?"+q.Encode(), token, nil).Body.Bytes(), &out)
+		return out
+	}
+	if got := planList(); len(got) != 1 || got[0].ID != rec.ID {
+		t.Fatalf("completion-anchored plan is expanded: %d records", len(got))
+	}
+
+	// Done late: the next one is two weeks after today, at the planned time of day
+	w = doTestRequest(s, http.MethodPost, "/api/records/"+rec.ID+"/done", token, nil)
+	var done storage.Record
+	_ = json.Unmarshal(w.Body.Bytes(), &done)
+	if w.Code != http.StatusOK || done.ID == rec.ID || done.DoneAt == nil || !done.PlannedAt.Equal(planned) || done.RRule != "" {
+		t.Fatalf("done: %d %s", w.Code, w.Body.String())
+	}
+	now := time.Now().UTC()
+	next := time.Date(now.Year(), now.Month(), now.Day(), planned.Hour(), 0, 0, 0, time.UTC).AddDate(0, 0, 14)
+	got := planList()
+	if len(got) != 1 || got[0].ID != rec.ID || !got[0].PlannedAt.Equal(next) || got[0].RRule != "FREQ=WEEKLY;INTERVAL=2;COUNT=1" {
+		t.Fatalf("next occurrence: %+v, want %v", got, next)
+	}
+
+	// The last one completes the plan itself
+	w = doTestRequest(s, http.MethodPost, recordsPath+"/"+rec.ID+"/done", token, nil)
+	_ = json.Unmarshal(w.Body.Bytes(), &done)
+	if w.Code != http.StatusOK || done.ID != rec.ID || done.DoneAt == nil {
+		t.Fatalf("last done: %d %s", w.Code, w.Body.String())
+	}
+	if got := planList(); len(got) != 0 {
+		t.Fatalf("finished plan is still listed: %+v", got)
+	}
+}
@@ -0,0 +1,75 @@
+package backend
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/maniack/catwatch/internal/storage"
+)
+
+const (
+	searchPageDefault = 20
+	searchPageMax     = 100
+)
+
+// SearchResult is a cat matching a search query with its rank and a highlighted excerpt.
+type SearchResult struct {
+	Cat       PublicCat `json:"cat"`
+	Rank      float64   `json:"rank"`
+	Highlight string    `json:"highlight"`
+}
+
+// handleSearch runs a full-text search over cats visible to the caller: GET /api/search?q=ginger&limit=20
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "q is required"})
+		return
+	}
+	limit, err := parseLimit(r, searchPageDefault, searchPageMax)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	scope := s.readScope(r)
+	hits, err := s.store.Search(scope, q, limit)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+
+	ids := make([]string, len(hits))
+	for i, h := range hits {
+		ids[i] = h.CatID
+	}
+	var cats []storage.Cat
+	if len(ids) > 0 {
+		err = scope.Cats(s.store.DB).
+			Preload("Images", storage.OrderedImages).
+			Preload("Tags").
+			Where("cats.id IN ?", ids).
+			Find(&cats).Error
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+	}
+	byID := make(map[string]PublicCat, len(cats))
+	pcs := make([]PublicCat, len(cats))
+	for i, c := range cats {
+		pcs[i] = ToPublicCat(c)
+	}
+	uid, _ := UserIDFromCtx(r.Context())
+	s.fillLikes(pcs, uid)
+	for _, pc := range pcs {
+		byID[pc.ID] = pc
+	}
+
+	out := make([]SearchResult, 0, len(hits))
+	for _, h := range hits {
+		if pc, ok := byID[h.CatID]; ok {
+			out = append(out, SearchResult{Cat: pc, Rank: h.Rank, Highlight: h.Highlight})
+		}
+	}
+	writeJSON(w, http.StatusOK, out)
+}
@@ -0,0 +1,104 @@
+package backend
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maniack/catwatch/internal/storage"
+)
+
+func TestSearch(t *testing.T) {
+	s := newTestServer(t)
+	coordinator := issueTestTokenWithRole(t, s, "coord-user", storage.RoleCoordinator)
+
+	create := func(body map[string]any) string {
+		w := doTestRequest(s, http.MethodPost, "/api/cats/", coordinator, body)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("create cat: %d %s", w.Code, w.Body.String())
+		}
+		var c storage.Cat
+		_ = json.Unmarshal(w.Body.Bytes(), &c)
+		return c.ID
+	}
+	search := func(q, token string) []SearchResult {
+		t.Helper()
+		w := doTestRequest(s, http.MethodGet, "/api/search?q="+q, token, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("search %q: %d %s", q, w.Code, w.Body.String())
+		}
+		var res []SearchResult
+		_ = json.Unmarshal(w.Body.Bytes(), &res)
+		return res
+	}
+
+	barsik := create(map[string]any{"name": "Барсик", "color": "ginger", "description": "Friendly <b>tomcat</b> near the bakery"})
+	murka := create(map[string]any{"name": "Murka", "description": "Shy, eats from Барсика's bowl", "tags": []map[string]string{{"name": "tabby"}}})
+
+	// Prefix, case-insensitive Cyrillic matching and ranking: a name match beats a description match
+	res := search("барс", "")
+	if len(res) != 2 || res[0].Cat.ID != barsik || res[1].Cat.ID != murka || res[0].Rank <= res[1].Rank {
+		t.Fatalf("ranking: %+v", res)
+	}
+	if res := search("tomcat", ""); len(res) != 1 || !strings.Contains(res[0].Highlight, "<mark>tomcat</mark>") || !strings.Contains(res[0].Highlight, "&lt;b&gt;") {
+		t.Fatalf("highlight must mark matches and escape HTML: %+v", res)
+	}
+	if res := search("tabby", ""); len(res) != 1 || res[0].Cat.ID != murka {
+		t.Fatalf("tag search: %+v", res)
+	}
+	if res := search("ginger+tom", ""); len(res) != 1 {
+		t.Fatalf("all words must match: %+v", res)
+	}
+
+	// Locations and done record notes are indexed as they change; planned notes are not
+	doTestRequest(s, http.MethodPost, "/api/cats/"+murka+"/locations", coordinator, map[string]any{"name": "Pushkin square", "lat": 1, "lon": 1})
+	if res := search("pushkin", ""); len(res) != 1 || res[0].Cat.ID != murka {
+		t.Fatalf("location search: %+v", res)
+	}
+	planned := time.Now().Add(24 * time.Hour)
+	w := doTestRequest(s, http.MethodPost, "/api/cats/"+barsik+"/records", coordinator, map[string]any{"type": "medical", "note": "deworming pills", "planned_at": planned})
+	var rec storage.Record
+	_ = json.Unmarshal(w.Body.Bytes(), &rec)
+	if res := search("deworming", coordinator); len(res) != 0 {
+		t.Fatalf("planned notes must not be indexed: %+v", res)
+	}
+	doTestRequest(s, http.MethodPost, "/api/cats/"+barsik+"/records/"+rec.ID+"/done", coordinator, nil)
+	if res := search("deworming", ""); len(res) != 1 || res[0].Cat.ID != barsik {
+		t.Fatalf("done note search: %+v", res)
+	}
+
+	// Deleted cats and other organizations' private cats are not found
+	doTestRequest(s, http.MethodDelete, "/api/cats/"+murka+"/", coordinator, nil)
+	if res := search("murka", ""); len(res) != 0 {
+		t.Fatalf("deleted cat found: %+v", res)
+	}
+	doTestRequest(s, http.MethodPost, "/api/cats/"+murka+"/restore", coordinator, nil)
+	if res := search("murka", ""); len(res) != 1 {
+		t.Fatalf("restored cat not found: %+v", res)
+	}
+	private := storage.Organization{ID: storage.NewUUID(), Name: "Private", Slug: "private"}
+	s.store.DB.Create(&private)
+	s.store.DB.Model(&storage.Cat{}).Where("id = ?", murka).Update("organization_id", private.ID)
+	if res := search("murka", ""); len(res) != 0 {
+		t.Fatalf("private cat visible to anonymous users: %+v", res)
+	}
+
+	// The index survives a rebuild
+	if err := s.store.RebuildSearchIndex(); err != nil {
+		t.Fatalf("rebuild: %v", err)
+	}
+	if res := search("bakery", ""); len(res) != 1 {
+		t.Fatalf("after rebuild: %+v", res)
+	}
+
+	for _, q := range []string{"", "%20"} {
+		if w := doTestRequest(s, http.MethodGet, "/api/search?q="+q, "", nil); w.Code != http.StatusBadRequest {
+			t.Fatalf("q=%q: expected 400, got %d", q, w.Code)
+		}
+	}
+	if res := search("%22*%20OR%20NEAR(", ""); len(res) != 0 {
+		t.Fatalf("operators must be ignored: %+v", res)
+	}
+}
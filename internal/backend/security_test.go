@@ -263,6 +263,9 @@ func TestAuthRefresh(t *testing.T) {
 
 	// 1. Create session in store
 	uid := "refresh-user"
+	if err := s.store.DB.Create(&storage.User{ID: uid, Provider: "dev", ProviderID: uid}).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
 	refreshToken := s.generateOpaqueToken()
 	payload, _ := json.Marshal(map[string]any{"uid": uid, "iat": time.Now().Unix()})
 	s.sessions.Set("sess:"+refreshToken, payload, 1*time.Hour)
@@ -297,4 +300,58 @@ func TestAuthRefresh(t *testing.T) {
 	if !newRefresh {
 		t.Error("expected new refresh_token cookie")
 	}
+
+	// Sessions of deleted users are not refreshed
+	gone := s.generateOpaqueToken()
+	payload, _ = json.Marshal(map[string]any{"uid": "deleted-user", "iat": time.Now().Unix()})
+	s.sessions.Set("sess:"+gone, payload, 1*time.Hour)
+	req = httptest.NewRequest(http.MethodPost, "/api/auth/refresh", nil)
+	req.AddCookie(&http.Cookie{Name: "refresh_token", Value: gone})
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("refresh of deleted user: expected 401, got %d", w.Code)
+	}
+}
+
+func TestAuditDelta(t *testing.T) {
+	s := newTestServer(t)
+	token := issueTestToken(t, s, "audit-user")
+
+	w := doTestRequest(s, http.MethodPost, "/api/cats/", token, map[string]any{"name": "Diff Cat", "condition": 3})
+	var cat storage.Cat
+	_ = json.Unmarshal(w.Body.Bytes(), &cat)
+
+	if w := doTestRequest(s, http.MethodPut, "/api/cats/"+cat.ID+"/", token, map[string]any{"name": "Diff Cat", "condition": 1}); w.Code != http.StatusOK {
+		t.Fatalf("update cat: %d %s", w.Code, w.Body.String())
+	}
+
+	var entry storage.AuditLog
+	s.store.DB.Where("target_id = ? AND method = ?", cat.ID, http.MethodPut).First(&entry)
+	if entry.StatusCode != http.StatusOK || entry.Status != "success" {
+		t.Fatalf("expected 200/success, got %d/%s", entry.StatusCode, entry.Status)
+	}
+	var delta map[string]map[string]any
+	if err := json.Unmarshal([]byte(entry.Delta), &delta); err != nil {
+		t.Fatalf("delta is not JSON: %q", entry.Delta)
+	}
+	if c := delta["condition"]; c["before"] != float64(3) || c["after"] != float64(1) {
+		t.Fatalf("expected condition 3 -> 1 in delta, got %v", delta)
+	}
+	if _, ok := delta["need_attention"]; !ok {
+		t.Fatalf("expected need_attention in delta, got %v", delta)
+	}
+	if _, ok := delta["name"]; ok {
+		t.Fatalf("unchanged fields must not be in delta: %v", delta)
+	}
+
+	// Failed mutations are recorded with their response status
+	if w := doTestRequest(s, http.MethodPut, "/api/cats/missing/", token, map[string]any{"name": "Ghost"}); w.Code != http.StatusNotFound {
+		t.Fatalf("update missing cat: expected 404, got %d", w.Code)
+	}
+	var failed storage.AuditLog
+	s.store.DB.Where("status_code = ?", http.StatusNotFound).First(&failed)
+	if failed.Status != "error" || failed.UserID != "audit-user" {
+		t.Fatalf("expected failed request to be audited, got %+v", failed)
+	}
 }
@@ -37,11 +37,38 @@ type Config struct {
 	RefreshTTL time.Duration
 
 	AuditLogTTL time.Duration
+	// AuditKey signs audit checkpoints written when old entries are pruned (defaults to JWTSecret).
+	AuditKey string
+
+	// TrashRetention is how long deleted cats stay restorable before they are purged.
+	TrashRetention time.Duration
+
+	// OverdueWindow is how far back occurrences of recurring records count as overdue.
+	OverdueWindow time.Duration
+
+	// ImageRetention is how many photos of each cat the cleanup keeps unless the cat has its
+	// own setting (0 keeps all). Older photos go to the trash for ImageTrashRetention.
+	ImageRetention      int
+	ImageTrashRetention time.Duration
+
+	// ImagePresignTTL redirects image downloads to presigned blob store URLs valid this long
+	// (0 streams images through the backend).
+	ImagePresignTTL time.Duration
+
+	// ImageFetchAllowPrivate lets the URL fetcher download from loopback and private networks.
+	ImageFetchAllowPrivate bool
 
 	OAuth        oauth.Config
 	BotAPIKey    string
 	SessionStore sessions.SessionStore
 
+	// AvatarOrigins are origins user avatars are loaded from, besides those of the configured
+	// identity providers.
+	AvatarOrigins []string
+
+	// AdminEmails are promoted to the admin role on login (bootstrap for the first admin).
+	AdminEmails []string
+
 	DevLoginEnabled bool
 	SkipWorkers     bool
 }
@@ -54,6 +81,9 @@ type Server struct {
 	sessions sessions.SessionStore
 	assets   http.FileSystem
 	mcp      *mcp.Server
+
+	imageFetch *http.Client
+	fetchWake  chan struct{}
 }
 
 func NewServer(cfg Config) (*Server, error) {
@@ -76,6 +106,8 @@ func NewServer(cfg Config) (*Server, error) {
 	}
 
 	s := &Server{store: cfg.Store, log: cfg.Logger, cfg: cfg, sessions: cfg.SessionStore, mcp: mcpSrv}
+	s.imageFetch = newImageFetchClient(cfg.ImageFetchAllowPrivate)
+	s.fetchWake = make(chan struct{}, 1)
 	assets, err := frontend.FS(cfg.DevLoginEnabled)
 	if err != nil {
 		return nil, err
@@ -91,13 +123,13 @@ func NewServer(cfg Config) (*Server, error) {
 	r.Use(chmw.Recoverer)
 	r.Use(chmw.Logger)
 	r.Use(RequestLogger(cfg.Logger, cfg.Monitoring.HealthzEndpoint+"/alive", cfg.Monitoring.HealthzEndpoint+"/ready"))
-	r.Use(SecurityHeaders())
+	r.Use(SecurityHeaders(append(s.imageOrigins(), s.avatarOrigins()...)...))
 	r.Use(s.JWTAuth)
 	r.Use(s.AuditMiddleware)
 	r.Use(s.CSRFProtection)
 
 	co := cors.Options{
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
 		ExposedHeaders:   []string{"Link"},
 		AllowCredentials: true,
@@ -157,6 +189,10 @@ func NewServer(cfg Config) (*Server, error) {
 	// API
 	r.Route("/api", func(r chi.Router) {
 		r.Get("/records/planned", s.listAllPlannedRecords)
+		r.Get("/records/overdue", s.listOverdueRecords)
+		// iCalendar feeds, authenticated by the calendar token in the query
+		r.Get("/calendar/planned.ics", s.handleCalendarFeed)
+		r.Get("/search", s.handleSearch)
 
 		r.Route("/auth", func(r chi.Router) {
 			r.Post("/dev-login", s.handleDevLogin)
@@ -172,6 +208,9 @@ func NewServer(cfg Config) (*Server, error) {
 			r.Get("/export", s.handleExportUser)
 			r.Get("/likes", s.handleGetUserLikes)
 			r.Get("/audit", s.handleGetUserAudit)
+			r.Get("/calendar-tokens", s.handleListCalendarTokens)
+			r.Post("/calendar-tokens", s.handleCreateCalendarToken)
+			r.Delete("/calendar-tokens/{tokenId}", s.handleRevokeCalendarToken)
 		})
 
 		r.Route("/cats", func(r chi.Router) {
@@ -179,45 +218,97 @@ func NewServer(cfg Config) (*Server, error) {
 			r.Route("/{id}", func(r chi.Router) {
 				r.Get("/", s.getCat)
 				r.Get("/records", s.listRecords)
+				r.Get("/records.ics", s.handleCatCalendarFeed)
 				r.Get("/images/{imgId}", s.getCatImageBinary)
 
 				// Protected mutation routes
 				r.Group(func(r chi.Router) {
-					r.Use(s.RequireAuth)
+					r.Use(s.RequireRole(storage.RoleVolunteer))
 					r.Put("/", s.updateCat)
-					r.Delete("/", s.deleteCat)
 					// Records
 					r.Post("/records", s.createRecord)
 					r.Route("/records/{rid}", func(r chi.Router) {
 						r.Put("/", s.updateRecord)
 						r.Post("/done", s.markRecordDone)
+						r.Post("/override", s.overrideOccurrence)
+						r.Delete("/override", s.clearOccurrenceOverride)
 					})
 					// Images
 					r.Route("/images", func(r chi.Router) {
 						r.Post("/", s.addCatImage)
-						r.Delete("/{imgId}", s.deleteCatImage)
+						r.Put("/order", s.reorderCatImages)
+						r.Patch("/{imgId}", s.updateCatImage)
+						r.Post("/{imgId}/primary", s.setPrimaryCatImage)
+						r.With(s.RequireRole(storage.RoleCoordinator)).Delete("/{imgId}", s.deleteCatImage)
 					})
 					// Locations
 					r.Post("/locations", s.addCatLocation)
-					// Likes
+				})
+				// Destructive routes
+				r.Group(func(r chi.Router) {
+					r.Use(s.RequireRole(storage.RoleCoordinator))
+					r.Delete("/", s.deleteCat)
+					r.Post("/restore", s.handleRestoreCat)
+					r.Post("/merge", s.mergeCat)
+					r.Put("/image-retention", s.setCatImageRetention)
+				})
+				// Likes (any authenticated user, including viewers)
+				r.Group(func(r chi.Router) {
+					r.Use(s.RequireAuth)
 					r.Post("/like", s.handleToggleLike)
 				})
 			})
 			// Global cat creation (needs to be outside /{id} but inside /cats)
 			r.Group(func(r chi.Router) {
-				r.Use(s.RequireAuth)
+				r.Use(s.RequireRole(storage.RoleVolunteer))
 				r.Post("/", s.createCat)
 			})
+			// Look-alike search for photos (any authenticated user)
+			r.With(s.RequireAuth).Post("/match", s.matchCats)
 		})
 
 		// Global record/image routes (shorter URLs for bot callback data)
 		r.Route("/records", func(r chi.Router) {
-			r.Use(s.RequireAuth)
+			r.Use(s.RequireRole(storage.RoleVolunteer))
 			r.Post("/{rid}/done", s.markRecordDone)
+			r.Post("/{rid}/override", s.overrideOccurrence)
+			r.Delete("/{rid}/override", s.clearOccurrenceOverride)
 		})
 		r.Route("/images", func(r chi.Router) {
+			r.Use(s.RequireRole(storage.RoleVolunteer))
+			r.Post("/{imgId}/primary", s.setPrimaryCatImage)
+			r.Group(func(r chi.Router) {
+				r.Use(s.RequireRole(storage.RoleCoordinator))
+				r.Delete("/{imgId}", s.deleteCatImage)
+				r.Post("/{imgId}/restore", s.handleRestoreImage)
+				r.Get("/prune-report", s.handleImagePruneReport)
+			})
+		})
+
+		r.Route("/trash", func(r chi.Router) {
+			r.Use(s.RequireRole(storage.RoleCoordinator))
+			r.Get("/cats", s.handleListTrashCats)
+			r.Get("/images", s.handleListTrashImages)
+		})
+
+		r.Route("/orgs", func(r chi.Router) {
 			r.Use(s.RequireAuth)
-			r.Delete("/{imgId}", s.deleteCatImage)
+			r.Get("/", s.handleListOrganizations)
+			r.With(s.RequireRole(storage.RoleAdmin)).Post("/", s.handleCreateOrganization)
+			r.With(s.RequireRole(storage.RoleAdmin)).Put("/{orgId}", s.handleUpdateOrganization)
+			r.Get("/{orgId}/members", s.handleListMembers)
+			r.Put("/{orgId}/members/{uid}", s.handleAddMember)
+			r.Delete("/{orgId}/members/{uid}", s.handleRemoveMember)
+		})
+
+		r.Route("/admin", func(r chi.Router) {
+			r.Route("/users", func(r chi.Router) {
+				r.Use(s.RequireRole(storage.RoleAdmin))
+				r.Get("/", s.handleAdminListUsers)
+				r.Put("/{id}/role", s.handleAdminSetRole)
+				r.Delete("/{id}/role", s.handleAdminRevokeRole)
+			})
+			r.With(s.RequireRole(storage.RoleCoordinator)).Get("/audit", s.handleAdminAudit)
 		})
 
 		r.Route("/bot", func(r chi.Router) {
@@ -248,9 +339,12 @@ func NewServer(cfg Config) (*Server, error) {
 	// Start background workers
 	if !cfg.SkipWorkers {
 		s.startImageOptimizer()
-		s.startImageCleanup(5, 10*time.Minute)
+		s.startImageFetcher()
+		s.startImageCleanup(10 * time.Minute)
 		s.startCatMetricsCollector(30 * time.Second)
+		s.startOverdueMetricsCollector(1 * time.Minute)
 		s.startAuditLogCleanup(1 * time.Hour)
+		s.startTrashPurge(1 * time.Hour)
 	}
 
 	return s, nil
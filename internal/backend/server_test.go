@@ -46,13 +46,33 @@ func newTestServer(t *testing.T) *Server {
 }
 
 func issueTestToken(t *testing.T, s *Server, uid string) string {
-	tok, err := s.generateAccessToken(uid, 1*time.Hour)
+	return issueTestTokenWithRole(t, s, uid, storage.DefaultRole)
+}
+
+func issueTestTokenWithRole(t *testing.T, s *Server, uid, role string) string {
+	tok, err := s.generateAccessToken(uid, role, 1*time.Hour)
 	if err != nil {
 		t.Fatalf("issue token: %v", err)
 	}
 	return tok
 }
 
+// doTestRequest sends body as JSON to the server, authenticated with token unless it is empty.
+func doTestRequest(s *Server, method, path, token string, body any) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		_ = json.NewEncoder(&buf).Encode(body)
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	s.Router.ServeHTTP(w, req)
+	return w
+}
+
 func TestHealthz(t *testing.T) {
 	s := newTestServer(t)
 	r := s.Router
@@ -591,9 +611,10 @@ func TestCatImageUploadDelete(t *testing.T) {
 		t.Fatalf("bad image response: %+v", img)
 	}
 
-	// delete image
+	// delete image (coordinator only)
+	coordToken := issueTestTokenWithRole(t, s, "coord-user", storage.RoleCoordinator)
 	dReq := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/api/cats/%s/images/%s", catID, img.ID), nil)
-	dReq.Header.Set("Authorization", "Bearer "+token)
+	dReq.Header.Set("Authorization", "Bearer "+coordToken)
 	dW := httptest.NewRecorder()
 	r.ServeHTTP(dW, dReq)
 	if dW.Code != http.StatusOK {
@@ -0,0 +1,175 @@
+package backend
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/maniack/catwatch/internal/storage"
+	"gorm.io/gorm"
+)
+
+// handleListTrashCats lists deleted cats of the caller's organizations: GET /api/trash/cats
+func (s *Server) handleListTrashCats(w http.ResponseWriter, r *http.Request) {
+	cats, err := s.store.ListDeletedCats(s.memberScope(r))
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	out := make([]PublicCat, len(cats))
+	for i, c := range cats {
+		out[i] = ToPublicCat(c)
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// handleRestoreCat takes a cat out of the trash: POST /api/cats/{id}/restore
+func (s *Server) handleRestoreCat(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	before, err := s.store.GetDeletedCat(id)
+	if err != nil || !s.memberScope(r).Allows(before.OrganizationID) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "cat not found in trash"})
+		return
+	}
+	cat, err := s.store.RestoreCat(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "cat not found in trash"})
+			return
+		}
+		if errors.Is(err, storage.ErrCatMerged) {
+			writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+			return
+		}
+		s.LogAuditError(r, "cat", id, err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	s.LogAudit(r, "cat", id, ToPublicCat(*before), ToPublicCat(*cat))
+	writeJSON(w, http.StatusOK, ToPublicCat(*cat))
+}
+
+// trashedImage is a photo in the trash with the time it is purged.
+type trashedImage struct {
+	storage.Image
+	DeletedAt time.Time `json:"deleted_at"`
+	PurgeAt   time.Time `json:"purge_at"`
+}
+
+// handleListTrashImages lists photos moved to the trash by the cleanup in the caller's
+// organizations: GET /api/trash/images
+func (s *Server) handleListTrashImages(w http.ResponseWriter, r *http.Request) {
+	imgs, err := s.store.ListTrashedImages(s.memberScope(r))
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	out := make([]trashedImage, len(imgs))
+	for i, im := range imgs {
+		out[i] = trashedImage{Image: im, DeletedAt: im.DeletedAt.Time, PurgeAt: im.DeletedAt.Time.Add(s.imageTrashRetention())}
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// handleRestoreImage takes a photo out of the trash and pins it: POST /api/images/{imgId}/restore
+func (s *Server) handleRestoreImage(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "imgId")
+	before, err := s.store.GetTrashedImage(id)
+	if err == nil {
+		var orgID string
+		if orgID, err = s.catOrganization(before.CatID); err == nil && !s.memberScope(r).Allows(orgID) {
+			err = gorm.ErrRecordNotFound
+		}
+	}
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "image not found in trash"})
+		return
+	}
+	img, err := s.store.RestoreImage(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "image not found in trash"})
+			return
+		}
+		s.LogAuditError(r, "image", id, err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	s.LogAudit(r, "image", id, trashedImage{Image: *before, DeletedAt: before.DeletedAt.Time}, img)
+	writeJSON(w, http.StatusOK, img)
+}
+
+func (s *Server) imageTrashRetention() time.Duration {
+	if s.cfg.ImageTrashRetention <= 0 {
+		return 30 * 24 * time.Hour
+	}
+	return s.cfg.ImageTrashRetention
+}
+
+// startTrashPurge periodically hard-deletes cats and photos that have been in the trash longer
+// than their retention period.
+func (s *Server) startTrashPurge(interval time.Duration) {
+	retention := s.cfg.TrashRetention
+	if retention <= 0 {
+		retention = 30 * 24 * time.Hour // Default 30 days
+	}
+	if interval <= 0 {
+		interval = 1 * time.Hour
+	}
+
+	s.log.WithField("retention", retention.String()).WithField("interval", interval.String()).Info("trash: starting purge worker")
+	go func() {
+		for {
+			s.purgeTrash(time.Now().Add(-retention))
+			s.purgeTrashedImages(time.Now().Add(-s.imageTrashRetention()))
+			time.Sleep(interval)
+		}
+	}()
+}
+
+func (s *Server) purgeTrash(before time.Time) {
+	purged, err := s.store.PurgeDeletedCats(before)
+	if err != nil {
+		s.log.WithError(err).Warn("trash: purge failed")
+	}
+	for _, c := range purged {
+		entry := storage.AuditLog{
+			Method:     "PURGE",
+			Route:      "trash",
+			TargetType: "cat",
+			TargetID:   c.ID,
+			Status:     "success",
+		}
+		entry.Delta, _ = storage.AuditDelta(ToPublicCat(c), nil)
+		if err := s.store.WriteAudit(&entry); err != nil {
+			s.log.WithError(err).Error("failed to write audit log")
+		}
+	}
+	if len(purged) > 0 {
+		s.log.WithField("purged", len(purged)).Info("trash: purged deleted cats")
+	}
+}
+
+func (s *Server) purgeTrashedImages(before time.Time) {
+	purged, err := s.store.PurgeTrashedImages(before)
+	if err != nil {
+		s.log.WithError(err).Warn("trash: image purge failed")
+	}
+	for _, im := range purged {
+		entry := storage.AuditLog{
+			Method:     "PURGE",
+			Route:      "trash",
+			TargetType: "image",
+			TargetID:   im.ID,
+			Status:     "success",
+		}
+		entry.Delta, _ = storage.AuditDelta(im, nil)
+		if err := s.store.WriteAudit(&entry); err != nil {
+			s.log.WithError(err).Error("failed to write audit log")
+		}
+	}
+	if len(purged) > 0 {
+		s.log.WithField("purged", len(purged)).Info("trash: purged old images")
+	}
+}
@@ -0,0 +1,118 @@
+package backend
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/maniack/catwatch/internal/storage"
+)
+
+func TestTrashRestoreAndPurge(t *testing.T) {
+	s := newTestServer(t)
+
+	orgB := storage.Organization{ID: storage.NewUUID(), Name: "Shelter B", Slug: "shelter-b"}
+	if err := s.store.DB.Create(&orgB).Error; err != nil {
+		t.Fatalf("create org: %v", err)
+	}
+	_ = s.store.AddMember(orgB.ID, "coord-b")
+	coordinator := issueTestTokenWithRole(t, s, "coord-user", storage.RoleCoordinator)
+	foreign := issueTestTokenWithRole(t, s, "coord-b", storage.RoleCoordinator)
+	volunteer := issueTestToken(t, s, "volunteer-user")
+
+	w := doTestRequest(s, http.MethodPost, "/api/cats/", coordinator, map[string]any{"name": "Tom", "tags": []map[string]string{{"name": "ginger"}}})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create cat: %d %s", w.Code, w.Body.String())
+	}
+	var cat storage.Cat
+	_ = json.Unmarshal(w.Body.Bytes(), &cat)
+
+	// Dependent rows that must go away together with the cat
+	rec := storage.Record{ID: storage.NewUUID(), CatID: cat.ID, Type: "feeding"}
+	s.store.DB.Create(&rec)
+	s.store.DB.Create(&storage.BotNotification{ChatID: 1, RecordID: rec.ID, SentAt: time.Now()})
+	s.store.DB.Create(&storage.Image{ID: storage.NewUUID(), CatID: cat.ID, MIME: "image/png", Data: []byte{1}})
+	s.store.DB.Create(&storage.CatLocation{ID: storage.NewUUID(), CatID: cat.ID, Latitude: 1, Longitude: 1})
+	s.store.DB.Create(&storage.Like{ID: storage.NewUUID(), CatID: cat.ID, UserID: "volunteer-user"})
+
+	if w := doTestRequest(s, http.MethodDelete, "/api/cats/"+cat.ID+"/", coordinator, nil); w.Code != http.StatusNoContent && w.Code != http.StatusOK {
+		t.Fatalf("delete cat: %d %s", w.Code, w.Body.String())
+	}
+
+	// The trash lists the deleted cat
+	if w := doTestRequest(s, http.MethodGet, "/api/trash/cats", volunteer, nil); w.Code != http.StatusForbidden {
+		t.Fatalf("volunteer trash: expected 403, got %d", w.Code)
+	}
+	w = doTestRequest(s, http.MethodGet, "/api/trash/cats", coordinator, nil)
+	var trash []PublicCat
+	_ = json.Unmarshal(w.Body.Bytes(), &trash)
+	if len(trash) != 1 || trash[0].ID != cat.ID || trash[0].DeletedAt == nil {
+		t.Fatalf("trash: unexpected %s", w.Body.String())
+	}
+	w = doTestRequest(s, http.MethodGet, "/api/trash/cats", foreign, nil)
+	_ = json.Unmarshal(w.Body.Bytes(), &trash)
+	if len(trash) != 0 {
+		t.Fatalf("foreign trash: expected no cats, got %d", len(trash))
+	}
+
+	// Restore
+	if w := doTestRequest(s, http.MethodPost, "/api/cats/"+cat.ID+"/restore", foreign, nil); w.Code != http.StatusNotFound {
+		t.Fatalf("foreign restore: expected 404, got %d", w.Code)
+	}
+	if w := doTestRequest(s, http.MethodPost, "/api/cats/"+cat.ID+"/restore", coordinator, nil); w.Code != http.StatusOK {
+		t.Fatalf("restore: %d %s", w.Code, w.Body.String())
+	}
+	if w := doTestRequest(s, http.MethodGet, "/api/cats/"+cat.ID+"/", coordinator, nil); w.Code != http.StatusOK {
+		t.Fatalf("restored cat: expected 200, got %d", w.Code)
+	}
+	if w := doTestRequest(s, http.MethodPost, "/api/cats/"+cat.ID+"/restore", coordinator, nil); w.Code != http.StatusNotFound {
+		t.Fatalf("restore of a live cat: expected 404, got %d", w.Code)
+	}
+	if err := s.store.DB.Where("target_id = ? AND route LIKE ?", cat.ID, "%restore").First(&storage.AuditLog{}).Error; err != nil {
+		t.Fatalf("restore audit entry: %v", err)
+	}
+
+	// Purge respects the retention cutoff and cascades
+	s.store.EnableLikeCache(time.Hour)
+	if stats, _ := s.store.LikeStats([]string{cat.ID}, "volunteer-user"); stats[cat.ID].Count != 1 || !stats[cat.ID].Liked {
+		t.Fatalf("expected the cached like before the purge, got %+v", stats[cat.ID])
+	}
+	doTestRequest(s, http.MethodDelete, "/api/cats/"+cat.ID+"/", coordinator, nil)
+	s.purgeTrash(time.Now().Add(-time.Hour))
+	if _, err := s.store.GetDeletedCat(cat.ID); err != nil {
+		t.Fatalf("cat purged before retention expired: %v", err)
+	}
+	s.purgeTrash(time.Now().Add(time.Hour))
+	if _, err := s.store.GetDeletedCat(cat.ID); err == nil {
+		t.Fatalf("cat still in trash after purge")
+	}
+	for name, model := range map[string]any{
+		"records":   &storage.Record{},
+		"images":    &storage.Image{},
+		"locations": &storage.CatLocation{},
+		"likes":     &storage.Like{},
+	} {
+		var n int64
+		s.store.DB.Unscoped().Model(model).Where("cat_id = ?", cat.ID).Count(&n)
+		if n != 0 {
+			t.Fatalf("%s survived the purge: %d", name, n)
+		}
+	}
+	// Cached like stats go with the likes
+	if stats, _ := s.store.LikeStats([]string{cat.ID}, "volunteer-user"); stats[cat.ID].Count != 0 || stats[cat.ID].Liked {
+		t.Fatalf("like cache survived the purge: %+v", stats[cat.ID])
+	}
+	var n int64
+	s.store.DB.Table("cat_tags").Where("cat_id = ?", cat.ID).Count(&n)
+	if n != 0 {
+		t.Fatalf("tag links survived the purge: %d", n)
+	}
+	s.store.DB.Model(&storage.BotNotification{}).Where("record_id = ?", rec.ID).Count(&n)
+	if n != 0 {
+		t.Fatalf("bot notifications survived the purge: %d", n)
+	}
+	if err := s.store.DB.Where("target_id = ? AND method = ?", cat.ID, "PURGE").First(&storage.AuditLog{}).Error; err != nil {
+		t.Fatalf("purge audit entry: %v", err)
+	}
+}
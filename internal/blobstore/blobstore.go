@@ -0,0 +1,71 @@
+// Package blobstore keeps large binary objects (photos) outside the main database.
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned when a key does not exist.
+var ErrNotFound = errors.New("blob not found")
+
+// Store is a flat key/value store for blobs. Keys are slash-separated paths.
+type Store interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Presigner is implemented by stores that can hand out temporary download URLs.
+type Presigner interface {
+	PresignGet(key string, ttl time.Duration) (string, error)
+	// Origin returns the scheme and host of presigned URLs, e.g. https://bucket.s3.amazonaws.com.
+	Origin() string
+}
+
+// Open creates a store from a URL:
+//
+//	file:///var/lib/catwatch/blobs
+//	s3://bucket/prefix?endpoint=http://minio:9000&region=us-east-1&path_style=true
+//
+// The S3 credentials are passed separately so they stay out of the URL.
+func Open(spec, accessKey, secretKey string) (Store, error) {
+	u, err := url.Parse(spec)
+	if err != nil {
+		return nil, fmt.Errorf("parse blob store url: %w", err)
+	}
+	switch u.Scheme {
+	case "file":
+		root := u.Path
+		if u.Host != "" {
+			// file://relative/path
+			root = u.Host + u.Path
+		}
+		return NewFS(root)
+	case "s3":
+		q := u.Query()
+		pathStyle := false
+		if v := q.Get("path_style"); v != "" {
+			if pathStyle, err = strconv.ParseBool(v); err != nil {
+				return nil, fmt.Errorf("path_style: %w", err)
+			}
+		}
+		return NewS3(S3Config{
+			Endpoint:  q.Get("endpoint"),
+			Region:    q.Get("region"),
+			Bucket:    u.Host,
+			Prefix:    strings.Trim(u.Path, "/"),
+			AccessKey: accessKey,
+			SecretKey: secretKey,
+			PathStyle: pathStyle,
+		})
+	default:
+		return nil, fmt.Errorf("unsupported blob store %q (use file:// or s3://)", u.Scheme)
+	}
+}
@@ -2,11 +2,14 @@ package bot
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"html"
 	"io"
 	"net/http"
 	"net/url"
 	"path"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -15,6 +18,7 @@ import (
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/maniack/catwatch/internal/l10n"
+	"github.com/maniack/catwatch/internal/recurrence"
 	"github.com/maniack/catwatch/internal/storage"
 	"github.com/sirupsen/logrus"
 )
@@ -25,6 +29,12 @@ type Config struct {
 	Logger           *logrus.Logger
 	Debug            bool
 	HealthListenAddr string // e.g. ":8080"
+
+	// Escalation of overdue procedures: the volunteer who planned one is reminded again when it is
+	// OverdueRenotifyAfter late, the coordinators of its organization when it is
+	// OverdueEscalateAfter late. Zero disables the step.
+	OverdueRenotifyAfter time.Duration
+	OverdueEscalateAfter time.Duration
 }
 
 type Bot struct {
@@ -33,16 +43,22 @@ type Bot struct {
 	log              *logrus.Logger
 	states           map[int64]*ConversationState
 	tokens           map[int64]string
+	catCursors       map[int64]string // next page of the cat list per chat
 	healthListenAddr string
+
+	overdueRenotifyAfter time.Duration
+	overdueEscalateAfter time.Duration
 }
 
 type ConversationState struct {
 	Step                 string
 	Cat                  storage.Cat
 	Record               storage.Record
-	CatID                string // for editing
-	PhotoCount           int    // added to track media group/multiple photos
-	ObservationCondition int    // added to store condition during observation flow
+	CatID                string              // for editing
+	PhotoCount           int                 // added to track media group/multiple photos
+	ObservationCondition int                 // added to store condition during observation flow
+	PhotoLocation        *LocationSuggestion // position read from the photo of the observation
+	Photo                *incomingPhoto      // photo of a new cat, uploaded once the cat is created
 }
 
 func NewBot(cfg Config) (*Bot, error) {
@@ -58,7 +74,11 @@ func NewBot(cfg Config) (*Bot, error) {
 		log:              cfg.Logger,
 		states:           make(map[int64]*ConversationState),
 		tokens:           make(map[int64]string),
+		catCursors:       make(map[int64]string),
 		healthListenAddr: cfg.HealthListenAddr,
+
+		overdueRenotifyAfter: cfg.OverdueRenotifyAfter,
+		overdueEscalateAfter: cfg.OverdueEscalateAfter,
 	}, nil
 }
 
@@ -138,6 +158,13 @@ func (b *Bot) handleMessage(msg *tgbotapi.Message, lang string) {
 			b.sendWelcome(msg.Chat.ID, lang)
 		case "help":
 			b.sendHelp(msg.Chat.ID, lang)
+		case "find":
+			if q := strings.TrimSpace(msg.CommandArguments()); q != "" {
+				b.sendSearchResults(msg.Chat.ID, lang, q)
+			} else {
+				b.states[msg.Chat.ID] = &ConversationState{Step: "find_query"}
+				b.replyWithKeyboard(msg.Chat.ID, l10n.T(lang, "msg_find_prompt"), b.cancelKeyboard(lang))
+			}
 		case "stop":
 			if err := b.client.UnlinkBot(msg.Chat.ID); err != nil {
 				b.log.Errorf("failed to unlink bot: %v", err)
@@ -156,8 +183,12 @@ func (b *Bot) handleMessage(msg *tgbotapi.Message, lang string) {
 				delete(b.tokens, msg.Chat.ID)
 				b.reply(msg.Chat.ID, l10n.T(lang, "msg_user_deleted"))
 			}
+		case "photo_location":
+			b.togglePhotoLocation(msg.Chat.ID, lang)
+		case "timezone":
+			b.setTimeZone(msg.Chat.ID, strings.TrimSpace(msg.CommandArguments()), lang)
 		case "cats":
-			b.sendCatsList(msg.Chat.ID, lang)
+			b.sendCatsList(msg.Chat.ID, lang, "")
 		case "add_cat":
 			b.states[msg.Chat.ID] = &ConversationState{Step: "add_name"}
 			b.replyWithKeyboard(msg.Chat.ID, l10n.T(lang, "msg_add_cat_title"), b.cancelKeyboard(lang))
@@ -184,7 +215,7 @@ func (b *Bot) handleMessage(msg *tgbotapi.Message, lang string) {
 	txt := strings.TrimSpace(msg.Text)
 	switch {
 	case txt == l10n.T("en", "menu_cats") || txt == l10n.T("ru", "menu_cats") || txt == "Cats":
-		b.sendCatsList(msg.Chat.ID, lang)
+		b.sendCatsList(msg.Chat.ID, lang, "")
 		return
 	case txt == l10n.T("en", "menu_add_cat") || txt == l10n.T("ru", "menu_add_cat") || txt == "Add cat":
 		b.states[msg.Chat.ID] = &ConversationState{Step: "add_name"}
@@ -213,6 +244,10 @@ func (b *Bot) handleConversation(msg *tgbotapi.Message, state *ConversationState
 	}
 
 	switch state.Step {
+	case "find_query":
+		delete(b.states, msg.Chat.ID)
+		b.sendSearchResults(msg.Chat.ID, lang, msg.Text)
+		b.sendMainMenu(msg.Chat.ID, lang, l10n.T(lang, "msg_done_next"))
 	case "add_name":
 		state.Cat.Name = msg.Text
 		state.Step = "add_desc"
@@ -221,21 +256,35 @@ func (b *Bot) handleConversation(msg *tgbotapi.Message, state *ConversationState
 		if strings.ToLower(msg.Text) != l10n.T(lang, "menu_skip") && strings.ToLower(msg.Text) != "skip" {
 			state.Cat.Description = msg.Text
 		}
-		// Finish creating
-		token, ok := b.ensureAuth(msg.Chat.ID, lang)
-		if !ok {
+		state.Step = "add_cat_photo"
+		b.replyWithKeyboard(msg.Chat.ID, l10n.T(lang, "msg_add_cat_photo"), b.skipCancelKeyboard(lang))
+	case "add_cat_photo":
+		if strings.ToLower(msg.Text) == l10n.T(lang, "menu_skip") || strings.ToLower(msg.Text) == "skip" {
+			b.createCatFromState(msg.Chat.ID, state, lang)
+			return
+		}
+		if !hasImage(msg) {
+			b.reply(msg.Chat.ID, l10n.T(lang, "msg_add_photo_prompt"))
 			return
 		}
-		newCat, err := b.client.CreateCat(state.Cat, token)
+		photo, err := b.downloadIncomingPhoto(msg)
 		if err != nil {
-			b.log.Errorf("failed to create cat: %v", err)
-			b.reply(msg.Chat.ID, l10n.T(lang, "err_create_cat"))
-		} else {
-			b.reply(msg.Chat.ID, l10n.T(lang, "msg_cat_added", map[string]string{"Name": newCat.Name}))
-			b.sendCatDetails(msg.Chat.ID, newCat.ID, lang)
-			b.sendMainMenu(msg.Chat.ID, lang, l10n.T(lang, "msg_done_next"))
+			b.log.Errorf("photo download failed: %v", err)
+			b.reply(msg.Chat.ID, l10n.T(lang, "err_upload_failed"))
+			return
 		}
-		delete(b.states, msg.Chat.ID)
+		state.Photo = photo
+		if !b.warnLookalikes(msg.Chat.ID, photo, lang) {
+			b.createCatFromState(msg.Chat.ID, state, lang)
+			return
+		}
+		state.Step = "add_cat_confirm"
+	case "add_cat_confirm":
+		if strings.TrimSpace(msg.Text) != l10n.T(lang, "btn_create_anyway") {
+			b.reply(msg.Chat.ID, l10n.T(lang, "msg_cat_lookalike_choose"))
+			return
+		}
+		b.createCatFromState(msg.Chat.ID, state, lang)
 
 	case "edit_name":
 		state.Cat.Name = msg.Text
@@ -315,7 +364,7 @@ func (b *Bot) handleConversation(msg *tgbotapi.Message, state *ConversationState
 			return
 		}
 
-		if len(msg.Photo) == 0 {
+		if !hasImage(msg) {
 			b.reply(msg.Chat.ID, l10n.T(lang, "msg_add_photo_prompt"))
 			return
 		}
@@ -325,9 +374,9 @@ func (b *Bot) handleConversation(msg *tgbotapi.Message, state *ConversationState
 			return
 		}
 
-		if err := b.processIncomingPhoto(msg.Chat.ID, state, msg, lang); err != nil {
+		if _, err := b.processIncomingPhoto(msg.Chat.ID, state, msg, lang); err != nil {
 			b.log.Errorf("photo upload failed: %v", err)
-			b.reply(msg.Chat.ID, l10n.T(lang, "err_upload_failed"))
+			b.replyAPIError(msg.Chat.ID, lang, err, "err_upload_failed")
 			return
 		}
 		state.PhotoCount++
@@ -356,10 +405,10 @@ func (b *Bot) handleConversation(msg *tgbotapi.Message, state *ConversationState
 		if !ok {
 			return
 		}
-		err := b.client.AddCatLocation(state.CatID, msg.Location.Latitude, msg.Location.Longitude, "", token)
+		err := b.client.AddCatLocation(state.CatID, msg.Location.Latitude, msg.Location.Longitude, "", time.Time{}, token)
 		if err != nil {
 			b.log.Errorf("add location: %v", err)
-			b.reply(msg.Chat.ID, l10n.T(lang, "err_save_loc"))
+			b.replyAPIError(msg.Chat.ID, lang, err, "err_save_loc")
 		} else {
 			b.reply(msg.Chat.ID, l10n.T(lang, "msg_loc_saved"))
 			b.sendCatDetails(msg.Chat.ID, state.CatID, lang)
@@ -379,7 +428,7 @@ func (b *Bot) handleConversation(msg *tgbotapi.Message, state *ConversationState
 		var err error
 		// Try YYYY-MM-DD HH:MM first
 		if len(text) == 16 && text[4] == '-' && text[7] == '-' && text[10] == ' ' && text[13] == ':' {
-			t, err = time.ParseInLocation("2006-01-02 15:04", text, time.Local)
+			t, err = time.ParseInLocation("2006-01-02 15:04", text, b.userLocation(msg.Chat.ID))
 		} else {
 			t, err = time.Parse(time.RFC3339, text)
 		}
@@ -409,8 +458,20 @@ func (b *Bot) handleConversation(msg *tgbotapi.Message, state *ConversationState
 			state.Record.Recurrence = "weekly"
 		} else if r == l10n.T(lang, "recur_monthly") || r == "monthly" {
 			state.Record.Recurrence = "monthly"
+		} else if r == l10n.T(lang, "recur_months_after_done") || r == "months after done" {
+			// e.g. deworming: the interval counts from when it was actually done
+			state.Record.Recurrence = "monthly"
+			state.Record.RecurrenceAnchor = storage.RecurrenceAnchorDone
 		} else {
-			state.Record.Recurrence = r
+			// A full RFC 5545 rule carries its own interval and end
+			rule, err := recurrence.Parse(msg.Text)
+			if err != nil {
+				b.reply(msg.Chat.ID, l10n.T(lang, "err_invalid_recur"))
+				return
+			}
+			state.Record.RRule = rule.String()
+			b.savePlannedRecord(msg.Chat.ID, state, lang)
+			return
 		}
 		state.Step = "plan_interval"
 		b.replyWithKeyboard(msg.Chat.ID, l10n.T(lang, "msg_plan_interval"), b.cancelKeyboard(lang))
@@ -477,12 +538,14 @@ func (b *Bot) handleConversation(msg *tgbotapi.Message, state *ConversationState
 
 	case "obs_photo":
 		if strings.ToLower(msg.Text) != l10n.T(lang, "menu_skip") && strings.ToLower(msg.Text) != "skip" && strings.ToLower(msg.Text) != l10n.T("ru", "menu_skip") {
-			if len(msg.Photo) > 0 {
-				if err := b.processIncomingPhoto(msg.Chat.ID, state, msg, lang); err != nil {
+			if hasImage(msg) {
+				img, err := b.processIncomingPhoto(msg.Chat.ID, state, msg, lang)
+				if err != nil {
 					b.log.Errorf("photo upload failed: %v", err)
-					b.reply(msg.Chat.ID, l10n.T(lang, "err_upload_failed"))
+					b.replyAPIError(msg.Chat.ID, lang, err, "err_upload_failed")
 					return
 				}
+				state.PhotoLocation = img.LocationSuggestion
 			} else {
 				b.reply(msg.Chat.ID, l10n.T(lang, "msg_add_photo_prompt"))
 				return
@@ -490,19 +553,35 @@ func (b *Bot) handleConversation(msg *tgbotapi.Message, state *ConversationState
 		}
 		state.Step = "obs_loc"
 		btn := tgbotapi.KeyboardButton{Text: l10n.T(lang, "btn_send_loc"), RequestLocation: true}
-		kb := tgbotapi.NewReplyKeyboard(
-			tgbotapi.NewKeyboardButtonRow(btn),
-			tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton(l10n.T(lang, "menu_skip")), tgbotapi.NewKeyboardButton(l10n.T(lang, "menu_cancel"))),
-		)
+		rows := [][]tgbotapi.KeyboardButton{tgbotapi.NewKeyboardButtonRow(btn)}
+		prompt := l10n.T(lang, "msg_obs_loc_prompt")
+		if state.PhotoLocation != nil {
+			rows = append([][]tgbotapi.KeyboardButton{tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton(l10n.T(lang, "btn_use_photo_loc")))}, rows...)
+			prompt = l10n.T(lang, "msg_obs_photo_loc_prompt")
+		}
+		rows = append(rows, tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton(l10n.T(lang, "menu_skip")), tgbotapi.NewKeyboardButton(l10n.T(lang, "menu_cancel"))))
+		kb := tgbotapi.NewReplyKeyboard(rows...)
 		kb.ResizeKeyboard = true
-		b.replyWithKeyboard(msg.Chat.ID, l10n.T(lang, "msg_obs_loc_prompt"), kb)
+		b.replyWithKeyboard(msg.Chat.ID, prompt, kb)
 
 	case "obs_loc":
 		if strings.ToLower(msg.Text) != l10n.T(lang, "menu_skip") && strings.ToLower(msg.Text) != "skip" && strings.ToLower(msg.Text) != l10n.T("ru", "menu_skip") {
-			if msg.Location != nil {
+			if pl := state.PhotoLocation; pl != nil && strings.TrimSpace(msg.Text) == l10n.T(lang, "btn_use_photo_loc") {
+				token, ok := b.ensureAuth(msg.Chat.ID, lang)
+				if ok {
+					var seenAt time.Time
+					if pl.TakenAt != nil {
+						seenAt = *pl.TakenAt
+					}
+					if err := b.client.AddCatLocation(state.CatID, pl.Latitude, pl.Longitude, "", seenAt, token); err != nil {
+						b.log.Errorf("add photo location: %v", err)
+						b.replyAPIError(msg.Chat.ID, lang, err, "err_save_loc")
+					}
+				}
+			} else if msg.Location != nil {
 				token, ok := b.ensureAuth(msg.Chat.ID, lang)
 				if ok {
-					_ = b.client.AddCatLocation(state.CatID, msg.Location.Latitude, msg.Location.Longitude, "", token)
+					_ = b.client.AddCatLocation(state.CatID, msg.Location.Latitude, msg.Location.Longitude, "", time.Time{}, token)
 				}
 			} else {
 				b.reply(msg.Chat.ID, l10n.T(lang, "msg_add_loc_prompt"))
@@ -525,7 +604,7 @@ func (b *Bot) savePlannedRecord(chatID int64, state *ConversationState, lang str
 
 	if err := b.client.CreateRecord(state.CatID, state.Record, token); err != nil {
 		b.log.Errorf("failed to plan record for cat %s: %v", state.CatID, err)
-		b.reply(chatID, l10n.T(lang, "err_plan_event"))
+		b.replyAPIError(chatID, lang, err, "err_plan_event")
 	} else {
 		b.reply(chatID, l10n.T(lang, "msg_event_planned"))
 		b.sendSchedule(chatID, state.CatID, lang)
@@ -542,7 +621,7 @@ func (b *Bot) saveCatEdit(chatID int64, state *ConversationState, lang string) {
 	_, err := b.client.UpdateCat(state.CatID, state.Cat, token)
 	if err != nil {
 		b.log.Errorf("failed to update cat %s: %v", state.CatID, err)
-		b.reply(chatID, l10n.T(lang, "err_update_cat"))
+		b.replyAPIError(chatID, lang, err, "err_update_cat")
 	} else {
 		b.reply(chatID, l10n.T(lang, "msg_data_updated"))
 		b.sendCatDetails(chatID, state.CatID, lang)
@@ -567,6 +646,8 @@ func (b *Bot) handleCallback(cb *tgbotapi.CallbackQuery, lang string) {
 	switch action {
 	case "home":
 		b.sendMainMenu(cb.Message.Chat.ID, lang, l10n.T(lang, "msg_welcome_home"))
+	case "cl": // cats list, next page
+		b.sendCatsList(cb.Message.Chat.ID, lang, b.catCursors[cb.Message.Chat.ID])
 	case "v": // view
 		b.log.WithFields(logrus.Fields{"cat_id": id, "chat_id": cb.Message.Chat.ID}).Debug("bot: view cat")
 		b.sendCatDetails(cb.Message.Chat.ID, id, lang)
@@ -582,6 +663,12 @@ func (b *Bot) handleCallback(cb *tgbotapi.CallbackQuery, lang string) {
 		b.startRecordPlan(cb.Message.Chat.ID, id, lang)
 	case "rd": // rec_done
 		b.markRecordDone(cb.Message.Chat.ID, "", id, lang) // id here is actually recordID
+	case "os": // occurrence_skip
+		b.overrideOccurrence(cb.Message.Chat.ID, id, storage.OccurrenceChange{Action: storage.OverrideSkipped}, lang)
+	case "oz": // occurrence_snooze
+		b.overrideOccurrence(cb.Message.Chat.ID, id, storage.OccurrenceChange{Action: storage.OverrideSnoozed, Minutes: 60}, lang)
+	case "op": // occurrence_postpone
+		b.overrideOccurrence(cb.Message.Chat.ID, id, storage.OccurrenceChange{Action: storage.OverrideRescheduled, Minutes: 24 * 60}, lang)
 	case "em": // edit_menu
 		b.sendEditMenu(cb.Message.Chat.ID, id, lang)
 	case "cm": // cond_menu
@@ -608,6 +695,10 @@ func (b *Bot) handleCallback(cb *tgbotapi.CallbackQuery, lang string) {
 		b.sendPhotosDeleteList(cb.Message.Chat.ID, id, lang)
 	case "ap": // add_photo
 		b.promptAddPhoto(cb.Message.Chat.ID, id, lang)
+	case "pp": // photos_primary
+		b.sendPhotosPrimaryList(cb.Message.Chat.ID, id, lang)
+	case "mp": // make_primary
+		b.makePrimaryPhoto(cb.Message.Chat.ID, id, lang) // id here is actually imgID
 	case "di": // delimg
 		b.deletePhoto(cb.Message.Chat.ID, "", id, lang) // id here is actually imgID
 	case "dc": // delete_confirm
@@ -749,6 +840,7 @@ func (b *Bot) recurrenceKeyboard(lang string) tgbotapi.ReplyKeyboardMarkup {
 	kb := tgbotapi.NewReplyKeyboard(
 		tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton(l10n.T(lang, "recur_none")), tgbotapi.NewKeyboardButton(l10n.T(lang, "recur_daily"))),
 		tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton(l10n.T(lang, "recur_weekly")), tgbotapi.NewKeyboardButton(l10n.T(lang, "recur_monthly"))),
+		tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton(l10n.T(lang, "recur_months_after_done"))),
 		tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton(l10n.T(lang, "menu_cancel"))),
 	)
 	kb.ResizeKeyboard = true
@@ -811,8 +903,14 @@ func (b *Bot) replyRemoveKeyboard(chatID int64, text string) {
 	b.api.Send(msg)
 }
 
-func (b *Bot) sendCatsList(chatID int64, lang string) {
-	cats, err := b.client.ListCats()
+// catsPageSize keeps the cat list keyboard well below Telegram's button limits.
+const catsPageSize = 20
+
+// sendCatsList shows a page of cats starting at cursor ("" for the first page)
+// with a button for the next page.
+func (b *Bot) sendCatsList(chatID int64, lang string, cursor string) {
+	token, _ := b.getToken(chatID) // Anonymous users see public organizations only
+	cats, next, err := b.client.ListCats(token, cursor, catsPageSize)
 	if err != nil {
 		b.log.Errorf("list cats: %v", err)
 		b.reply(chatID, l10n.T(lang, "err_api"))
@@ -836,6 +934,13 @@ func (b *Bot) sendCatsList(chatID int64, lang string) {
 		btn := tgbotapi.NewInlineKeyboardButtonData(label, "v:"+cat.ID)
 		rows = append(rows, tgbotapi.NewInlineKeyboardRow(btn))
 	}
+	// Cursors do not fit into callback data (64 bytes), so the next one is kept per chat
+	if next != "" {
+		b.catCursors[chatID] = next
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(l10n.T(lang, "btn_more_cats"), "cl")))
+	} else {
+		delete(b.catCursors, chatID)
+	}
 
 	msg := tgbotapi.NewMessage(chatID, l10n.T(lang, "msg_cats_list_title"))
 	msg.ParseMode = tgbotapi.ModeMarkdown
@@ -843,6 +948,42 @@ func (b *Bot) sendCatsList(chatID int64, lang string) {
 	b.api.Send(msg)
 }
 
+// searchPageSize is the number of search results shown by /find.
+const searchPageSize = 10
+
+// sendSearchResults runs a full-text search and lists the matching cats with highlighted excerpts.
+func (b *Bot) sendSearchResults(chatID int64, lang string, query string) {
+	token, _ := b.getToken(chatID) // Anonymous users search public organizations only
+	results, err := b.client.Search(query, token, searchPageSize)
+	if err != nil {
+		b.log.Errorf("search cats: %v", err)
+		b.reply(chatID, l10n.T(lang, "err_api"))
+		return
+	}
+	if len(results) == 0 {
+		b.reply(chatID, l10n.T(lang, "msg_find_none", map[string]string{"Query": query}))
+		return
+	}
+
+	// Excerpts are HTML-escaped by the API; Telegram has no <mark>, so matches are shown in bold
+	toTelegram := strings.NewReplacer("<mark>", "<b>", "</mark>", "</b>")
+	var text strings.Builder
+	text.WriteString(l10n.T(lang, "msg_find_results", map[string]string{"Query": html.EscapeString(query)}))
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, r := range results {
+		text.WriteString("\n\n🐱 <b>" + html.EscapeString(r.Cat.Name) + "</b>")
+		if r.Highlight != "" {
+			text.WriteString("\n" + toTelegram.Replace(r.Highlight))
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("🐱 "+r.Cat.Name, "v:"+r.Cat.ID)))
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text.String())
+	msg.ParseMode = tgbotapi.ModeHTML
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	b.api.Send(msg)
+}
+
 func (b *Bot) getToken(chatID int64) (string, error) {
 	t, err := b.client.GetBotToken(chatID)
 	if err != nil {
@@ -870,23 +1011,35 @@ func (b *Bot) isPublicURL(u string) bool {
 	return u != "" && !strings.Contains(u, "localhost") && !strings.Contains(u, "127.0.0.1") && !strings.Contains(u, "backend")
 }
 
-func (b *Bot) getPhotoFileData(catID string, img storage.Image) tgbotapi.RequestFileData {
+// getPhotoFileData returns an image for sending to Telegram. size is one of the storage.ImageSize*
+// renditions: cards use the card size, the photo gallery the full one.
+func (b *Bot) getPhotoFileData(catID string, img storage.Image, size string) tgbotapi.RequestFileData {
 	if img.URL != "" && b.isPublicURL(img.URL) {
 		return tgbotapi.FileURL(img.URL)
 	}
 	// Try to fetch bytes from backend via internal BaseURL
-	data, _, err := b.client.GetCatImageBinary(catID, img.ID)
+	data, mime, err := b.client.GetCatImageBinary(catID, img.ID, size)
 	if err != nil {
 		b.log.Errorf("failed to fetch image %s from backend: %v", img.ID, err)
 		// fallback to public URL even if it might fail (e.g. localhost)
-		url := fmt.Sprintf("%s/api/cats/%s/images/%s", b.client.PublicBaseURL, catID, img.ID)
+		url := fmt.Sprintf("%s/api/cats/%s/images/%s?size=%s", b.client.PublicBaseURL, catID, img.ID, size)
 		return tgbotapi.FileURL(url)
 	}
 	// Use image ID as filename
-	return tgbotapi.FileBytes{Name: img.ID + ".webp", Bytes: data}
+	ext := ".webp"
+	switch {
+	case strings.Contains(mime, "jpeg"):
+		ext = ".jpg"
+	case strings.Contains(mime, "png"):
+		ext = ".png"
+	case strings.Contains(mime, "gif"):
+		ext = ".gif"
+	}
+	return tgbotapi.FileBytes{Name: img.ID + ext, Bytes: data}
 }
 
 func (b *Bot) sendCatDetails(chatID int64, id string, lang string) {
+	loc := b.userLocation(chatID)
 	token, _ := b.getToken(chatID) // Optional for public view
 	cat, err := b.client.GetCat(id, token)
 	if err != nil {
@@ -923,10 +1076,10 @@ func (b *Bot) sendCatDetails(chatID int64, id string, lang string) {
 		if latest.Name != "" {
 			locStr = latest.Name
 		}
-		text += l10n.T(lang, "label_last_loc", map[string]string{"Location": locStr, "Time": latest.CreatedAt.Local().Format("02.01.2006 15:04")}) + "\n"
+		text += l10n.T(lang, "label_last_loc", map[string]string{"Location": locStr, "Time": latest.CreatedAt.In(loc).Format("02.01.2006 15:04")}) + "\n"
 	}
 	if cat.LastSeen != nil {
-		text += l10n.T(lang, "label_last_seen", map[string]string{"Time": cat.LastSeen.Local().Format("02.01.2006 15:04")}) + "\n"
+		text += l10n.T(lang, "label_last_seen", map[string]string{"Time": cat.LastSeen.In(loc).Format("02.01.2006 15:04")}) + "\n"
 	}
 
 	// Show next upcoming event if authorized
@@ -940,7 +1093,7 @@ func (b *Bot) sendCatDetails(chatID int64, id string, lang string) {
 			next := recs[0]
 			timeStr := l10n.T(lang, "label_planned")
 			if next.PlannedAt != nil {
-				timeStr = next.PlannedAt.Local().Format("02.01.2006 15:04")
+				timeStr = next.PlannedAt.In(loc).Format("02.01.2006 15:04")
 			}
 			text += l10n.T(lang, "msg_next_event", map[string]string{"Type": next.Type, "Time": timeStr})
 		}
@@ -976,17 +1129,9 @@ func (b *Bot) sendCatDetails(chatID int64, id string, lang string) {
 		),
 	)
 
-	// If we have at least one image, attach the freshest one as a photo with caption
-	if len(cat.Images) > 0 {
-		// pick latest by CreatedAt
-		latest := cat.Images[0]
-		for _, im := range cat.Images[1:] {
-			if im.CreatedAt.After(latest.CreatedAt) {
-				latest = im
-			}
-		}
-
-		photo := tgbotapi.NewPhoto(chatID, b.getPhotoFileData(cat.ID, latest))
+	// If we have at least one image, attach the primary one as a photo with caption
+	if primary, ok := storage.PrimaryImage(cat.Images); ok {
+		photo := tgbotapi.NewPhoto(chatID, b.getPhotoFileData(cat.ID, primary, storage.ImageSizeCard))
 		photo.Caption = text
 		photo.ParseMode = tgbotapi.ModeMarkdown
 		photo.ReplyMarkup = kb
@@ -1064,11 +1209,11 @@ func (b *Bot) sendPhotosMenu(chatID int64, id string, lang string) {
 	if len(cat.Images) == 0 {
 		b.reply(chatID, l10n.T(lang, "msg_no_photos"))
 	} else {
-		// Sort images by CreatedAt desc (freshest first)
-		sort.Slice(cat.Images, func(i, j int) bool { return cat.Images[i].CreatedAt.After(cat.Images[j].CreatedAt) })
+		// Images come in the order chosen by volunteers
 		var media []interface{}
 		for _, im := range cat.Images {
-			ph := tgbotapi.NewInputMediaPhoto(b.getPhotoFileData(cat.ID, im))
+			ph := tgbotapi.NewInputMediaPhoto(b.getPhotoFileData(cat.ID, im, storage.ImageSizeFull))
+			ph.Caption = im.Title
 			media = append(media, ph)
 		}
 		// Telegram allows up to 10 media per group
@@ -1100,6 +1245,9 @@ func (b *Bot) sendPhotosMenu(chatID int64, id string, lang string) {
 			tgbotapi.NewInlineKeyboardButtonData(l10n.T(lang, "btn_add_photo"), "ap:"+id),
 			tgbotapi.NewInlineKeyboardButtonData(l10n.T(lang, "btn_del_photos"), "pd:"+id),
 		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(l10n.T(lang, "btn_primary_photo"), "pp:"+id),
+		),
 		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData(l10n.T(lang, "menu_back_to_cat"), "v:"+id),
 		),
@@ -1132,7 +1280,7 @@ func (b *Bot) deletePhoto(chatID int64, catID, imgID string, lang string) {
 	}
 	img, err := b.client.DeleteCatImage(catID, imgID, token)
 	if err != nil {
-		b.reply(chatID, l10n.T(lang, "err_del_photo"))
+		b.replyAPIError(chatID, lang, err, "err_del_photo")
 		return
 	}
 
@@ -1172,32 +1320,238 @@ func (b *Bot) sendPhotosDeleteList(chatID int64, catID string, lang string) {
 	b.api.Send(msg)
 }
 
-func (b *Bot) processIncomingPhoto(chatID int64, state *ConversationState, msg *tgbotapi.Message, lang string) error {
-	// Pick the largest size
-	ps := msg.Photo[len(msg.Photo)-1]
-	file, err := b.api.GetFile(tgbotapi.FileConfig{FileID: ps.FileID})
+func (b *Bot) makePrimaryPhoto(chatID int64, imgID string, lang string) {
+	token, ok := b.ensureAuth(chatID, lang)
+	if !ok {
+		return
+	}
+	img, err := b.client.SetPrimaryImage(imgID, token)
+	if err != nil {
+		b.replyAPIError(chatID, lang, err, "err_primary_photo")
+		return
+	}
+	b.reply(chatID, l10n.T(lang, "msg_photo_primary"))
+	b.sendCatDetails(chatID, img.CatID, lang)
+}
+
+func (b *Bot) sendPhotosPrimaryList(chatID int64, catID string, lang string) {
+	token, ok := b.ensureAuth(chatID, lang)
+	if !ok {
+		return
+	}
+	cat, err := b.client.GetCat(catID, token)
+	if err != nil {
+		b.reply(chatID, l10n.T(lang, "err_load_photos"))
+		return
+	}
+	if len(cat.Images) == 0 {
+		b.reply(chatID, l10n.T(lang, "msg_no_photos"))
+		return
+	}
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for i, img := range cat.Images {
+		if img.IsPrimary {
+			continue
+		}
+		label := l10n.T(lang, "label_primary_img", map[string]string{"N": strconv.Itoa(i + 1), "ID": img.ID[len(img.ID)-4:]})
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(label, "mp:"+img.ID),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(l10n.T(lang, "menu_back"), "pm:"+catID)))
+	kb := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	msg := tgbotapi.NewMessage(chatID, l10n.T(lang, "msg_primary_img_prompt"))
+	msg.ReplyMarkup = kb
+	b.api.Send(msg)
+}
+
+// hasImage reports whether the message carries a photo, either compressed or sent as a file.
+// Telegram removes the metadata of compressed photos, so only files can propose a location.
+func hasImage(msg *tgbotapi.Message) bool {
+	return len(msg.Photo) > 0 || (msg.Document != nil && strings.HasPrefix(msg.Document.MimeType, "image/"))
+}
+
+// incomingPhoto is a photo downloaded from Telegram.
+type incomingPhoto struct {
+	data     []byte
+	filename string
+	mime     string
+}
+
+func (b *Bot) downloadIncomingPhoto(msg *tgbotapi.Message) (*incomingPhoto, error) {
+	var fileID string
+	if msg.Document != nil && len(msg.Photo) == 0 {
+		fileID = msg.Document.FileID
+	} else {
+		// Pick the largest size
+		fileID = msg.Photo[len(msg.Photo)-1].FileID
+	}
+	file, err := b.api.GetFile(tgbotapi.FileConfig{FileID: fileID})
 	if err != nil {
-		return err
+		return nil, err
 	}
 	url := file.Link(b.api.Token)
 	resp, err := http.Get(url)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
 	data, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	return &incomingPhoto{data: data, filename: path.Base(file.FilePath), mime: resp.Header.Get("Content-Type")}, nil
+}
+
+func (b *Bot) processIncomingPhoto(chatID int64, state *ConversationState, msg *tgbotapi.Message, lang string) (*UploadedImage, error) {
+	photo, err := b.downloadIncomingPhoto(msg)
+	if err != nil {
+		return nil, err
+	}
+	token, ok := b.ensureAuth(chatID, lang)
+	if !ok {
+		return nil, fmt.Errorf("auth failed")
+	}
+	return b.client.UploadCatImage(state.CatID, photo.filename, photo.mime, photo.data, token)
+}
+
+// warnLookalikes tells the user which registered cats look like the photo and reports whether
+// there were any. Matching is a hint only: failures are logged and treated as no match.
+func (b *Bot) warnLookalikes(chatID int64, photo *incomingPhoto, lang string) bool {
+	token, ok := b.ensureAuth(chatID, lang)
+	if !ok {
+		return false
+	}
+	matches, err := b.client.MatchCats(photo.filename, photo.data, token)
+	if err != nil {
+		b.log.Errorf("match cats: %v", err)
+		return false
+	}
+	if len(matches) == 0 {
+		return false
+	}
+	var text strings.Builder
+	text.WriteString(l10n.T(lang, "msg_cat_lookalike", map[string]string{"Name": html.EscapeString(matches[0].Cat.Name)}))
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, m := range matches {
+		text.WriteString(fmt.Sprintf("\n🐱 <b>%s</b> — %.0f%%", html.EscapeString(m.Cat.Name), m.Score*100))
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("🐱 "+m.Cat.Name, "v:"+m.Cat.ID)))
+	}
+	msg := tgbotapi.NewMessage(chatID, text.String())
+	msg.ParseMode = tgbotapi.ModeHTML
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	b.api.Send(msg)
+
+	kb := tgbotapi.NewReplyKeyboard(
+		tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton(l10n.T(lang, "btn_create_anyway"))),
+		tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton(l10n.T(lang, "menu_cancel"))),
+	)
+	kb.ResizeKeyboard = true
+	b.replyWithKeyboard(chatID, l10n.T(lang, "msg_cat_lookalike_choose"), kb)
+	return true
+}
+
+// createCatFromState creates the cat collected by the add_cat flow and uploads its photo.
+func (b *Bot) createCatFromState(chatID int64, state *ConversationState, lang string) {
+	defer delete(b.states, chatID)
+	token, ok := b.ensureAuth(chatID, lang)
+	if !ok {
+		return
+	}
+	newCat, err := b.client.CreateCat(state.Cat, token)
+	if err != nil {
+		b.log.Errorf("failed to create cat: %v", err)
+		b.replyAPIError(chatID, lang, err, "err_create_cat")
+		return
+	}
+	b.reply(chatID, l10n.T(lang, "msg_cat_added", map[string]string{"Name": newCat.Name}))
+	if p := state.Photo; p != nil {
+		if _, err := b.client.UploadCatImage(newCat.ID, p.filename, p.mime, p.data, token); err != nil {
+			b.log.Errorf("photo upload failed: %v", err)
+			b.replyAPIError(chatID, lang, err, "err_upload_failed")
+		}
 	}
-	mime := resp.Header.Get("Content-Type")
-	filename := path.Base(file.FilePath)
+	b.sendCatDetails(chatID, newCat.ID, lang)
+	b.sendMainMenu(chatID, lang, l10n.T(lang, "msg_done_next"))
+}
 
+// togglePhotoLocation turns location suggestions from photo GPS data on or off for the user.
+func (b *Bot) togglePhotoLocation(chatID int64, lang string) {
 	token, ok := b.ensureAuth(chatID, lang)
 	if !ok {
-		return fmt.Errorf("auth failed")
+		return
+	}
+	u, err := b.client.GetUser(token)
+	if err == nil {
+		err = b.client.SetPhotoLocationSuggestions(!u.PhotoLocationSuggestions, token)
+	}
+	if err != nil {
+		b.log.Errorf("toggle photo location: %v", err)
+		b.replyAPIError(chatID, lang, err, "err_api")
+		return
+	}
+	if u.PhotoLocationSuggestions {
+		b.reply(chatID, l10n.T(lang, "msg_photo_loc_off"))
+	} else {
+		b.reply(chatID, l10n.T(lang, "msg_photo_loc_on"))
+	}
+}
+
+// setTimeZone shows the user's time zone, or changes it to the IANA zone given.
+func (b *Bot) setTimeZone(chatID int64, zone string, lang string) {
+	token, ok := b.ensureAuth(chatID, lang)
+	if !ok {
+		return
+	}
+	if zone == "" {
+		u, err := b.client.GetUser(token)
+		if err != nil {
+			b.log.Errorf("get user: %v", err)
+			b.replyAPIError(chatID, lang, err, "err_api")
+			return
+		}
+		current := u.TimeZone
+		if current == "" {
+			current = time.Local.String()
+		}
+		b.reply(chatID, l10n.T(lang, "msg_timezone_current", map[string]string{"Zone": current}))
+		return
+	}
+	if _, err := storage.LoadTimeZone(zone); err != nil {
+		b.reply(chatID, l10n.T(lang, "err_invalid_timezone"))
+		return
+	}
+	if err := b.client.SetTimeZone(zone, token); err != nil {
+		b.log.Errorf("set time zone: %v", err)
+		b.replyAPIError(chatID, lang, err, "err_api")
+		return
+	}
+	b.reply(chatID, l10n.T(lang, "msg_timezone_set", map[string]string{"Zone": zone}))
+}
+
+// userLocation returns the time zone of the chat's user, or the bot host's if none is set.
+func (b *Bot) userLocation(chatID int64) *time.Location {
+	token, err := b.getToken(chatID)
+	if err != nil {
+		return time.Local
+	}
+	u, err := b.client.GetUser(token)
+	if err != nil {
+		return time.Local
 	}
-	_, err = b.client.UploadCatImage(state.CatID, filename, mime, data, token)
-	return err
+	return zoneLocation(u.TimeZone, time.Local)
+}
+
+// zoneLocation returns the named IANA zone, or fallback if the name is empty or unknown.
+func zoneLocation(name string, fallback *time.Location) *time.Location {
+	if name == "" {
+		return fallback
+	}
+	loc, err := storage.LoadTimeZone(name)
+	if err != nil {
+		return fallback
+	}
+	return loc
 }
 
 func (b *Bot) feedCat(chatID int64, id string, lang string) {
@@ -1215,7 +1569,7 @@ func (b *Bot) feedCat(chatID int64, id string, lang string) {
 
 	if err := b.client.CreateRecord(id, rec, token); err != nil {
 		b.log.Errorf("create feeding record for cat %s: %v", id, err)
-		b.reply(chatID, l10n.T(lang, "err_api"))
+		b.replyAPIError(chatID, lang, err, "err_api")
 		return
 	}
 
@@ -1223,6 +1577,7 @@ func (b *Bot) feedCat(chatID int64, id string, lang string) {
 }
 
 func (b *Bot) sendSchedule(chatID int64, catID string, lang string) {
+	loc := b.userLocation(chatID)
 	token, ok := b.ensureAuth(chatID, lang)
 	if !ok {
 		return
@@ -1237,8 +1592,11 @@ func (b *Bot) sendSchedule(chatID int64, catID string, lang string) {
 		doneRecs = doneRecs[:2]
 	}
 
-	// Fetch 3 closest planned records
-	plannedRecs, err := b.client.ListRecords(catID, "planned", nil, nil, token)
+	// Fetch 3 closest planned occurrences; recurring records are expanded by the API
+	now := time.Now()
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	end := start.AddDate(0, 0, 30)
+	plannedRecs, err := b.client.ListRecords(catID, "planned", &start, &end, token)
 	if err != nil {
 		b.log.Errorf("list planned records for cat %s: %v", catID, err)
 	}
@@ -1255,9 +1613,9 @@ func (b *Bot) sendSchedule(chatID int64, catID string, lang string) {
 			for _, rec := range doneRecs {
 				timeStr := l10n.T(lang, "label_done")
 				if rec.DoneAt != nil {
-					timeStr = rec.DoneAt.Local().Format("02.01.2006 15:04")
+					timeStr = rec.DoneAt.In(loc).Format("02.01.2006 15:04")
 				} else if !rec.Timestamp.IsZero() {
-					timeStr = rec.Timestamp.Local().Format("02.01.2006 15:04")
+					timeStr = rec.Timestamp.In(loc).Format("02.01.2006 15:04")
 				}
 				text := l10n.T(lang, "msg_event_details", map[string]string{"Time": timeStr, "Type": rec.Type, "Note": rec.Note})
 				b.replyMarkdown(chatID, text)
@@ -1270,11 +1628,13 @@ func (b *Bot) sendSchedule(chatID int64, catID string, lang string) {
 			for _, rec := range plannedRecs {
 				timeStr := l10n.T(lang, "label_planned")
 				if rec.PlannedAt != nil {
-					timeStr = rec.PlannedAt.Local().Format("02.01.2006 15:04")
+					timeStr = rec.PlannedAt.In(loc).Format("02.01.2006 15:04")
 				}
 				text := l10n.T(lang, "msg_event_details", map[string]string{"Time": timeStr, "Type": rec.Type, "Note": rec.Note})
-				if rec.Recurrence != "" {
-					text += l10n.T(lang, "msg_recur_info", map[string]any{"Recurrence": rec.Recurrence, "Interval": rec.Interval})
+				if rec.CompletionAnchored() {
+					text += l10n.T(lang, "msg_recur_after_done", map[string]any{"Rule": rec.RRule})
+				} else if rec.RRule != "" {
+					text += l10n.T(lang, "msg_recur_info", map[string]any{"Rule": rec.RRule})
 				}
 
 				msg := tgbotapi.NewMessage(chatID, text)
@@ -1312,7 +1672,7 @@ func (b *Bot) markRecordDone(chatID int64, catID, recordID string, lang string)
 	rec, err := b.client.MarkRecordDone(catID, recordID, token)
 	if err != nil {
 		b.log.Errorf("mark record %s done: %v", recordID, err)
-		b.reply(chatID, l10n.T(lang, "err_mark_done"))
+		b.replyAPIError(chatID, lang, err, "err_mark_done")
 		return
 	}
 
@@ -1324,6 +1684,26 @@ func (b *Bot) markRecordDone(chatID int64, catID, recordID string, lang string)
 	b.sendSchedule(chatID, catID, lang)
 }
 
+// overrideOccurrence skips, snoozes or postpones the occurrence of a reminder.
+func (b *Bot) overrideOccurrence(chatID int64, recordID string, change storage.OccurrenceChange, lang string) {
+	loc := b.userLocation(chatID)
+	token, ok := b.ensureAuth(chatID, lang)
+	if !ok {
+		return
+	}
+	o, err := b.client.OverrideOccurrence(recordID, change, token)
+	if err != nil {
+		b.log.Errorf("override occurrence %s: %v", recordID, err)
+		b.replyAPIError(chatID, lang, err, "err_override_occurrence")
+		return
+	}
+	if o.PlannedAt == nil {
+		b.reply(chatID, l10n.T(lang, "msg_occurrence_skipped"))
+		return
+	}
+	b.reply(chatID, l10n.T(lang, "msg_occurrence_moved", map[string]string{"Time": o.PlannedAt.In(loc).Format("02.01.2006 15:04")}))
+}
+
 func (b *Bot) startRecordPlan(chatID int64, catID string, lang string) {
 	b.states[chatID] = &ConversationState{
 		Step:  "plan_type",
@@ -1374,7 +1754,7 @@ func (b *Bot) observeCat(chatID int64, id string, lang string) {
 
 	if err := b.client.CreateRecord(id, rec, token); err != nil {
 		b.log.Errorf("create observation record for cat %s: %v", id, err)
-		b.reply(chatID, l10n.T(lang, "err_api"))
+		b.replyAPIError(chatID, lang, err, "err_api")
 		return
 	}
 
@@ -1456,7 +1836,7 @@ func (b *Bot) applyCondition(chatID int64, id string, valStr string, lang string
 	}
 	if _, err := b.client.UpdateCat(id, *cat, token); err != nil {
 		b.log.Errorf("failed to set condition: %v", err)
-		b.reply(chatID, l10n.T(lang, "err_save_cond"))
+		b.replyAPIError(chatID, lang, err, "err_save_cond")
 		return
 	}
 	b.reply(chatID, l10n.T(lang, "msg_cond_updated"))
@@ -1538,10 +1918,10 @@ func (b *Bot) deleteCat(chatID int64, id string, lang string) {
 	}
 	if err := b.client.DeleteCat(id, token); err != nil {
 		b.log.Errorf("delete cat %s: %v", id, err)
-		b.reply(chatID, l10n.T(lang, "err_delete_cat"))
+		b.replyAPIError(chatID, lang, err, "err_delete_cat")
 	} else {
 		b.reply(chatID, l10n.T(lang, "msg_cat_deleted"))
-		b.sendCatsList(chatID, lang)
+		b.sendCatsList(chatID, lang, "")
 	}
 }
 
@@ -1550,13 +1930,24 @@ func (b *Bot) reply(chatID int64, text string) {
 	b.api.Send(msg)
 }
 
+// replyAPIError replies with the given error message, or with a permission hint
+// when the backend refused the action for the user's role.
+func (b *Bot) replyAPIError(chatID int64, lang string, err error, key string) {
+	if errors.Is(err, ErrForbidden) {
+		key = "err_forbidden"
+	}
+	b.reply(chatID, l10n.T(lang, key))
+}
+
 func (b *Bot) sendUpcomingEvents(chatID int64, lang string) {
-	// Show events for next 7 days
-	now := time.Now().UTC()
+	// Show events for next 7 days, in the user's time zone
+	loc := b.userLocation(chatID)
+	now := time.Now().In(loc)
 	start := now
 	end := now.AddDate(0, 0, 7)
 
-	recs, err := b.client.ListAllPlannedRecords(start, end)
+	token, _ := b.getToken(chatID)
+	recs, err := b.client.ListAllPlannedRecords(start, end, token)
 	if err != nil {
 		b.log.Errorf("failed to list planned records: %v", err)
 		b.reply(chatID, l10n.T(lang, "err_upcoming"))
@@ -1588,7 +1979,7 @@ func (b *Bot) sendUpcomingEvents(chatID int64, lang string) {
 
 		timeStr := l10n.T(lang, "label_planned")
 		if rec.PlannedAt != nil {
-			timeStr = rec.PlannedAt.Local().Format("02.01 15:04")
+			timeStr = rec.PlannedAt.In(loc).Format("02.01 15:04")
 		}
 
 		sb.WriteString(fmt.Sprintf("🔹 *%s*: %s (%s)\n", timeStr, catName, rec.Type))
@@ -1614,6 +2005,7 @@ func (b *Bot) remindersLoop(ctx context.Context) {
 			return
 		case <-ticker.C:
 			b.checkReminders()
+			b.checkOverdue()
 		}
 	}
 }
@@ -1624,7 +2016,7 @@ func (b *Bot) checkReminders() {
 	start := now
 	end := now.Add(30 * time.Minute)
 
-	recs, err := b.client.ListAllPlannedRecords(start, end)
+	recs, err := b.client.ListAllPlannedRecords(start, end, "")
 	if err != nil {
 		b.log.Errorf("failed to list planned records: %v", err)
 		return
@@ -1644,23 +2036,34 @@ func (b *Bot) checkReminders() {
 	b.log.WithField("count", len(users)).Debug("found bot users for reminders")
 
 	for _, rec := range recs {
-		// Fetch cat details to get the name
+		// Fetch cat details to get the name; without its organization nobody may be reminded
 		cat, err := b.client.GetCat(rec.CatID, "")
-		catName := l10n.T("en", "label_cat") // Default to EN for background loop
-		if err == nil {
-			catName = cat.Name
+		if err != nil || cat == nil {
+			b.log.WithError(err).WithField("cat_id", rec.CatID).Warn("skip reminder: cat not found")
+			continue
 		}
+		catName := cat.Name
+		orgID := cat.OrganizationID
 
 		for _, user := range users {
+			// Only members of the cat's organization are reminded
+			if !slices.Contains(user.OrganizationIDs, orgID) {
+				continue
+			}
 			var chatID int64
 			fmt.Sscanf(user.ProviderID, "%d", &chatID)
 			if chatID == 0 {
 				continue
 			}
 
-			// Try to mark as sent first (atomic check-and-set in backend)
+			// Try to mark as sent first (atomic check-and-set in backend).
+			// Snoozed and rescheduled occurrences are reminded of again.
+			notifID := rec.ID
+			if rec.Override != nil {
+				notifID += "@" + rec.Override.ID
+			}
 			notif := storage.BotNotification{
-				RecordID: rec.ID,
+				RecordID: notifID,
 				ChatID:   chatID,
 				SentAt:   time.Now(),
 			}
@@ -1677,7 +2080,8 @@ func (b *Bot) checkReminders() {
 			lang := "en" // Default for background loop
 			timeStr := l10n.T(lang, "label_today")
 			if rec.PlannedAt != nil {
-				timeStr = rec.PlannedAt.Local().Format("15:04")
+				// In the user's zone, else the one the plan repeats in
+				timeStr = rec.PlannedAt.In(zoneLocation(user.TimeZone, zoneLocation(rec.TimeZone, time.Local))).Format("15:04")
 			}
 			msgText := l10n.T(lang, "msg_reminder_title") + l10n.T(lang, "msg_reminder_body", map[string]string{
 				"Name": catName,
@@ -1693,12 +2097,26 @@ func (b *Bot) checkReminders() {
 			msg.ParseMode = "Markdown"
 
 			// Add inline button to see cat details
-			keyboard := tgbotapi.NewInlineKeyboardMarkup(
+			rows := [][]tgbotapi.InlineKeyboardButton{
 				tgbotapi.NewInlineKeyboardRow(
 					tgbotapi.NewInlineKeyboardButtonData(l10n.T(lang, "msg_view_cat"), "v:"+rec.CatID),
 				),
-			)
-			msg.ReplyMarkup = keyboard
+			}
+			// Occurrences of recurring records can be skipped, snoozed or postponed one by one
+			if rec.RRule != "" && !rec.CompletionAnchored() {
+				rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+					tgbotapi.NewInlineKeyboardButtonData(l10n.T(lang, "btn_skip_occurrence"), "os:"+rec.ID),
+					tgbotapi.NewInlineKeyboardButtonData(l10n.T(lang, "btn_snooze_occurrence"), "oz:"+rec.ID),
+					tgbotapi.NewInlineKeyboardButtonData(l10n.T(lang, "btn_postpone_occurrence"), "op:"+rec.ID),
+				))
+			}
+			// Plans that repeat after done are moved on by marking them done
+			if rec.CompletionAnchored() {
+				rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+					tgbotapi.NewInlineKeyboardButtonData(l10n.T(lang, "btn_mark_done"), "rd:"+rec.ID),
+				))
+			}
+			msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
 
 			if _, err := b.api.Send(msg); err != nil {
 				b.log.Errorf("failed to send reminder to chat %d: %v", chatID, err)
@@ -1707,6 +2125,106 @@ func (b *Bot) checkReminders() {
 	}
 }
 
+// checkOverdue escalates planned procedures nobody marked done: first to the volunteer who
+// planned it, then to the coordinators of the cat's organization (see Config).
+func (b *Bot) checkOverdue() {
+	if b.overdueRenotifyAfter <= 0 && b.overdueEscalateAfter <= 0 {
+		return
+	}
+	recs, err := b.client.ListOverdueRecords("")
+	if err != nil {
+		b.log.Errorf("failed to list overdue records: %v", err)
+		return
+	}
+	if len(recs) == 0 {
+		return
+	}
+	users, err := b.client.ListBotUsers()
+	if err != nil {
+		b.log.Errorf("failed to list bot users: %v", err)
+		return
+	}
+
+	now := time.Now()
+	cats := map[string]*storage.Cat{}
+	for _, rec := range recs {
+		if rec.PlannedAt == nil {
+			continue
+		}
+		late := now.Sub(*rec.PlannedAt)
+		renotify := b.overdueRenotifyAfter > 0 && late >= b.overdueRenotifyAfter
+		escalate := b.overdueEscalateAfter > 0 && late >= b.overdueEscalateAfter
+		if !renotify && !escalate {
+			continue
+		}
+		cat, ok := cats[rec.CatID]
+		if !ok {
+			cat, _ = b.client.GetCat(rec.CatID, "")
+			cats[rec.CatID] = cat
+		}
+		if cat == nil {
+			continue
+		}
+		// Moved occurrences are escalated again from their new time
+		notifID := rec.ID
+		if rec.Override != nil {
+			notifID += "@" + rec.Override.ID
+		}
+
+		for _, user := range users {
+			if !slices.Contains(user.OrganizationIDs, cat.OrganizationID) {
+				continue
+			}
+			var key, text string
+			switch {
+			case escalate && storage.HasRole(user.Role, storage.RoleCoordinator):
+				key = notifID + "@escalated"
+				text = "msg_overdue_escalated"
+			case renotify && user.ID == rec.UserID:
+				key = notifID + "@overdue"
+				text = "msg_overdue"
+			default:
+				continue
+			}
+			var chatID int64
+			fmt.Sscanf(user.ProviderID, "%d", &chatID)
+			if chatID == 0 {
+				continue
+			}
+			if err := b.client.MarkNotificationSent(storage.BotNotification{RecordID: key, ChatID: chatID, SentAt: now}); err != nil {
+				if err != ErrAlreadyExists {
+					b.log.Errorf("failed to mark overdue notification as sent for user %d: %v", chatID, err)
+				}
+				continue
+			}
+
+			lang := "en" // Default for background loop
+			loc := zoneLocation(user.TimeZone, zoneLocation(rec.TimeZone, time.Local))
+			msgText := l10n.T(lang, text, map[string]any{
+				"Name":  cat.Name,
+				"Type":  rec.Type,
+				"Time":  rec.PlannedAt.In(loc).Format("02.01 15:04"),
+				"Hours": int(late.Hours()),
+				"By":    rec.User.Name,
+			})
+			msg := tgbotapi.NewMessage(chatID, msgText)
+			msg.ParseMode = "Markdown"
+			buttons := []tgbotapi.InlineKeyboardButton{
+				tgbotapi.NewInlineKeyboardButtonData(l10n.T(lang, "msg_view_cat"), "v:"+rec.CatID),
+			}
+			// Marking the record itself done would end a calendar series, so occurrences are
+			// only completed through their virtual IDs
+			if _, _, virtual := storage.ParseVirtualRecordID(rec.ID); virtual || rec.RRule == "" || rec.CompletionAnchored() {
+				buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData(l10n.T(lang, "btn_mark_done"), "rd:"+rec.ID))
+			}
+			msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(buttons...))
+			if _, err := b.api.Send(msg); err != nil {
+				b.log.Errorf("failed to send overdue notice to chat %d: %v", chatID, err)
+			}
+		}
+	}
+}
+
 func (b *Bot) startHealthServer(ctx context.Context) {
 	if b.client == nil || b.api == nil {
 		return
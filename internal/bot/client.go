@@ -7,6 +7,9 @@ import (
 	"io"
 	"mime/multipart"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/maniack/catwatch/internal/storage"
@@ -61,23 +64,73 @@ func (c *APIClient) do(req *http.Request, token string) (*http.Response, error)
 	return resp, nil
 }
 
-func (c *APIClient) ListCats() ([]storage.Cat, error) {
-	req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/api/cats/", c.BaseURL), nil)
-	resp, err := c.do(req, "")
+// ListCats returns a page of cats visible to the user behind token (public organizations when empty),
+// sorted by name, and the cursor of the next page ("" on the last page).
+func (c *APIClient) ListCats(token, cursor string, limit int) ([]storage.Cat, string, error) {
+	q := url.Values{"sort": {"name"}, "limit": {strconv.Itoa(limit)}}
+	if cursor != "" {
+		q.Set("cursor", cursor)
+	}
+	req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/api/cats/?%s", c.BaseURL, q.Encode()), nil)
+	resp, err := c.do(req, token)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("api returned status: %d", resp.StatusCode)
+		return nil, "", fmt.Errorf("api returned status: %d", resp.StatusCode)
 	}
 
 	var cats []storage.Cat
 	if err := json.NewDecoder(resp.Body).Decode(&cats); err != nil {
+		return nil, "", err
+	}
+	return cats, nextCursor(resp.Header.Get("Link")), nil
+}
+
+// nextCursor extracts the cursor from a Link rel="next" header.
+func nextCursor(link string) string {
+	for _, part := range strings.Split(link, ",") {
+		target, params, ok := strings.Cut(strings.TrimSpace(part), ";")
+		if !ok || !strings.Contains(params, `rel="next"`) {
+			continue
+		}
+		u, err := url.Parse(strings.Trim(strings.TrimSpace(target), "<>"))
+		if err != nil {
+			return ""
+		}
+		return u.Query().Get("cursor")
+	}
+	return ""
+}
+
+// SearchResult is a cat found by Search with a highlighted excerpt (HTML, matches in <mark>).
+type SearchResult struct {
+	Cat       storage.Cat `json:"cat"`
+	Rank      float64     `json:"rank"`
+	Highlight string      `json:"highlight"`
+}
+
+// Search runs a full-text search over the cats visible to the user behind token.
+func (c *APIClient) Search(query, token string, limit int) ([]SearchResult, error) {
+	q := url.Values{"q": {query}, "limit": {strconv.Itoa(limit)}}
+	req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/api/search?%s", c.BaseURL, q.Encode()), nil)
+	resp, err := c.do(req, token)
+	if err != nil {
 		return nil, err
 	}
-	return cats, nil
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("api returned status: %d", resp.StatusCode)
+	}
+
+	var results []SearchResult
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		return nil, err
+	}
+	return results, nil
 }
 
 func (c *APIClient) GetCat(id string, token string) (*storage.Cat, error) {
@@ -113,6 +166,9 @@ func (c *APIClient) CreateRecord(catID string, record storage.Record, token stri
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusForbidden {
+		return ErrForbidden
+	}
 	if resp.StatusCode != http.StatusCreated {
 		return fmt.Errorf("api returned status: %d", resp.StatusCode)
 	}
@@ -160,6 +216,9 @@ func (c *APIClient) MarkRecordDone(catID, recordID, token string) (*storage.Reco
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusForbidden {
+		return nil, ErrForbidden
+	}
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("api returned status: %d", resp.StatusCode)
 	}
@@ -185,6 +244,9 @@ func (c *APIClient) CreateCat(cat storage.Cat, token string) (*storage.Cat, erro
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusForbidden {
+		return nil, ErrForbidden
+	}
 	if resp.StatusCode != http.StatusCreated {
 		return nil, fmt.Errorf("api returned status: %d", resp.StatusCode)
 	}
@@ -214,6 +276,9 @@ func (c *APIClient) UpdateCat(id string, cat storage.Cat, token string) (*storag
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusForbidden {
+		return nil, ErrForbidden
+	}
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("api returned status: %d", resp.StatusCode)
 	}
@@ -237,6 +302,9 @@ func (c *APIClient) DeleteCat(id string, token string) error {
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusForbidden {
+		return ErrForbidden
+	}
 	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("api returned status: %d", resp.StatusCode)
 	}
@@ -244,12 +312,12 @@ func (c *APIClient) DeleteCat(id string, token string) error {
 	return nil
 }
 
-func (c *APIClient) ListAllPlannedRecords(start, end time.Time) ([]storage.Record, error) {
+// ListAllPlannedRecords returns planned records of the user's organizations.
+// Without a token the BOT_API_KEY grants access to all organizations (used by the reminder loop).
+func (c *APIClient) ListAllPlannedRecords(start, end time.Time, token string) ([]storage.Record, error) {
 	url := fmt.Sprintf("%s/api/records/planned?start=%s&end=%s", c.BaseURL, start.Format(time.RFC3339), end.Format(time.RFC3339))
 	req, _ := http.NewRequest(http.MethodGet, url, nil)
-	resp, err := c.do(req, "") // Reminder loop doesn't have a user token, but should it?
-	// Actually, reminder loop might need a system token or BOT_API_KEY should be enough for some methods.
-	// For now, listAllPlannedRecords might remain public or use BOT_API_KEY.
+	resp, err := c.do(req, token)
 	if err != nil {
 		return nil, err
 	}
@@ -266,8 +334,31 @@ func (c *APIClient) ListAllPlannedRecords(start, end time.Time) ([]storage.Recor
 	return recs, nil
 }
 
-func (c *APIClient) AddCatLocation(catID string, lat, lon float64, name string, token string) error {
+// ListOverdueRecords returns planned records and occurrences whose time has passed without being
+// done, oldest first. With the bot key and no token it covers all organizations.
+func (c *APIClient) ListOverdueRecords(token string) ([]storage.Record, error) {
+	req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/api/records/overdue", c.BaseURL), nil)
+	resp, err := c.do(req, token)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("api returned status: %d", resp.StatusCode)
+	}
+
+	var recs []storage.Record
+	if err := json.NewDecoder(resp.Body).Decode(&recs); err != nil {
+		return nil, err
+	}
+	return recs, nil
+}
+
+// AddCatLocation records a sighting at the given position; a zero seenAt means now.
+func (c *APIClient) AddCatLocation(catID string, lat, lon float64, name string, seenAt time.Time, token string) error {
 	in := storage.CatLocation{
+		CreatedAt: seenAt,
 		Latitude:  lat,
 		Longitude: lon,
 		Name:      name,
@@ -282,6 +373,9 @@ func (c *APIClient) AddCatLocation(catID string, lat, lon float64, name string,
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusForbidden {
+		return ErrForbidden
+	}
 	if resp.StatusCode != http.StatusCreated {
 		return fmt.Errorf("api returned status: %d", resp.StatusCode)
 	}
@@ -350,8 +444,21 @@ func (c *APIClient) DeleteUser(token string) error {
 	return nil
 }
 
+// LocationSuggestion is the position read from the GPS data of an uploaded photo.
+type LocationSuggestion struct {
+	Latitude  float64    `json:"lat"`
+	Longitude float64    `json:"lon"`
+	TakenAt   *time.Time `json:"taken_at,omitempty"`
+}
+
+// UploadedImage is an uploaded image with the location proposed from its metadata, if any.
+type UploadedImage struct {
+	storage.Image
+	LocationSuggestion *LocationSuggestion `json:"location_suggestion,omitempty"`
+}
+
 // UploadCatImage uploads image bytes via multipart/form-data to the backend.
-func (c *APIClient) UploadCatImage(catID string, filename string, mime string, data []byte, token string) (*storage.Image, error) {
+func (c *APIClient) UploadCatImage(catID string, filename string, mime string, data []byte, token string) (*UploadedImage, error) {
 	var buf bytes.Buffer
 	mw := multipart.NewWriter(&buf)
 	fw, err := mw.CreateFormFile("file", filename)
@@ -377,16 +484,110 @@ func (c *APIClient) UploadCatImage(catID string, filename string, mime string, d
 		return nil, err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusForbidden {
+		return nil, ErrForbidden
+	}
 	if resp.StatusCode != http.StatusCreated {
 		return nil, fmt.Errorf("upload image status: %d", resp.StatusCode)
 	}
-	var out storage.Image
+	var out UploadedImage
 	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
 		return nil, err
 	}
 	return &out, nil
 }
 
+// CatMatch is a registered cat resembling a photo.
+type CatMatch struct {
+	Cat      storage.Cat `json:"cat"`
+	ImageID  string      `json:"image_id"`
+	Distance int         `json:"distance"`
+	Score    float64     `json:"score"`
+}
+
+// MatchCats returns the registered cats that look like the photo, most similar first.
+func (c *APIClient) MatchCats(filename string, data []byte, token string) ([]CatMatch, error) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	fw, err := mw.CreateFormFile("file", filename)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := fw.Write(data); err != nil {
+		return nil, err
+	}
+	_ = mw.Close()
+
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/api/cats/match", c.BaseURL), &buf)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	resp, err := c.do(req, token)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("match cats status: %d", resp.StatusCode)
+	}
+	var out []CatMatch
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetUser returns the user behind token.
+func (c *APIClient) GetUser(token string) (*storage.User, error) {
+	req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/api/user/", c.BaseURL), nil)
+	resp, err := c.do(req, token)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("api returned status: %d", resp.StatusCode)
+	}
+	var u storage.User
+	if err := json.NewDecoder(resp.Body).Decode(&u); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// SetPhotoLocationSuggestions turns location suggestions from photo GPS data on or off.
+func (c *APIClient) SetPhotoLocationSuggestions(enabled bool, token string) error {
+	body, _ := json.Marshal(map[string]bool{"photo_location_suggestions": enabled})
+	req, _ := http.NewRequest(http.MethodPatch, fmt.Sprintf("%s/api/user/", c.BaseURL), bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.do(req, token)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("api returned status: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// SetTimeZone sets the IANA time zone the user's times are shown in; "" clears it.
+func (c *APIClient) SetTimeZone(zone string, token string) error {
+	body, _ := json.Marshal(map[string]string{"time_zone": zone})
+	req, _ := http.NewRequest(http.MethodPatch, fmt.Sprintf("%s/api/user/", c.BaseURL), bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.do(req, token)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("api returned status: %d", resp.StatusCode)
+	}
+	return nil
+}
+
 // DeleteCatImage deletes an image by id for a cat.
 func (c *APIClient) DeleteCatImage(catID, imageID, token string) (*storage.Image, error) {
 	url := ""
@@ -404,6 +605,9 @@ func (c *APIClient) DeleteCatImage(catID, imageID, token string) (*storage.Image
 		return nil, err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusForbidden {
+		return nil, ErrForbidden
+	}
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("delete image status: %d", resp.StatusCode)
 	}
@@ -414,8 +618,61 @@ func (c *APIClient) DeleteCatImage(catID, imageID, token string) (*storage.Image
 	return &out, nil
 }
 
-func (c *APIClient) GetCatImageBinary(catID, imageID string) ([]byte, string, error) {
-	url := fmt.Sprintf("%s/api/cats/%s/images/%s", c.BaseURL, catID, imageID)
+// SetPrimaryImage makes an image the cover photo of its cat.
+func (c *APIClient) SetPrimaryImage(imageID, token string) (*storage.Image, error) {
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/api/images/%s/primary", c.BaseURL, imageID), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(req, token)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusForbidden {
+		return nil, ErrForbidden
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("set primary image status: %d", resp.StatusCode)
+	}
+	var out storage.Image
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// OverrideOccurrence skips, reschedules or snoozes one occurrence of a recurring record.
+func (c *APIClient) OverrideOccurrence(recordID string, change storage.OccurrenceChange, token string) (*storage.RecordOverride, error) {
+	body, err := json.Marshal(change)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/api/records/%s/override", c.BaseURL, recordID), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.do(req, token)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusForbidden {
+		return nil, ErrForbidden
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("override occurrence status: %d", resp.StatusCode)
+	}
+	var out storage.RecordOverride
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *APIClient) GetCatImageBinary(catID, imageID, size string) ([]byte, string, error) {
+	url := fmt.Sprintf("%s/api/cats/%s/images/%s?size=%s", c.BaseURL, catID, imageID, size)
 	req, _ := http.NewRequest(http.MethodGet, url, nil)
 	resp, err := c.do(req, "")
 	if err != nil {
@@ -455,6 +712,9 @@ func (c *APIClient) RegisterBotUser(chatID int64, name string) error {
 
 var ErrAlreadyExists = fmt.Errorf("already exists")
 
+// ErrForbidden is returned when the linked user's role does not allow the action.
+var ErrForbidden = fmt.Errorf("forbidden")
+
 func (c *APIClient) MarkNotificationSent(notification storage.BotNotification) error {
 	body, _ := json.Marshal(notification)
 
@@ -23,8 +23,9 @@ export default function CatCard({ cat, user, onUnliked }) {
 
   const onPointerUp = useDoubleTap(handleLike);
   
-  const freshImage = images && images.length > 0 
-    ? [...images].sort((a, b) => new Date(b.created_at) - new Date(a.created_at))[0]
+  // Images come in display order; the primary one is the cover
+  const freshImage = images && images.length > 0
+    ? (images.find(img => img.is_primary) || images[0])
     : null;
 
   const emojiForCondition = (c) => {
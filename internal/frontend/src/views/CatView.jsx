@@ -56,6 +56,11 @@ export default function CatView({ catId, user }) {
   const fetchCat = () => {
     api.get(`/api/cats/${catId}/`)
       .then(data => {
+        if (data.id && data.id !== catId) {
+          // The cat was merged into another one: the API followed the redirect
+          window.location.replace(`#/cat/view/${data.id}`);
+          return;
+        }
         setCat(data);
         setLikes(data.likes || 0);
         setLiked(!!data.liked);
@@ -219,9 +224,9 @@ export default function CatView({ catId, user }) {
               {cat.images && cat.images.length > 0 ? (
                 <div ref={carouselRef} id="catImages" className="carousel slide h-100" data-bs-ride="carousel" data-bs-interval="false" data-bs-touch="true">
                   <div className="carousel-inner h-100">
-                    {[...cat.images].sort((a,b)=>new Date(b.created_at)-new Date(a.created_at)).map((img, idx) => (
+                    {[...cat.images].sort((a,b)=>(b.is_primary?1:0)-(a.is_primary?1:0)).map((img, idx) => (
                       <div key={img.id} className={`carousel-item h-100 ${idx === 0 ? 'active' : ''}`}>
-                        <img src={`/api/cats/${cat.id}/images/${img.id}`} className="d-block w-100 h-100 object-fit-cover" alt="..." style={{touchAction:'manipulation'}} onDoubleClick={handleLike} onPointerUp={onPointerUp} />
+                        <img src={`/api/cats/${cat.id}/images/${img.id}`} className="d-block w-100 h-100 object-fit-cover" alt={img.title || cat.name} title={img.title || undefined} style={{touchAction:'manipulation'}} onDoubleClick={handleLike} onPointerUp={onPointerUp} />
                       </div>
                     ))}
                   </div>
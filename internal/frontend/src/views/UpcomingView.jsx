@@ -96,10 +96,11 @@ export default function UpcomingView({ user }) {
                         </span>
                       </div>
                       {rec.note && <p className="mb-0 small text-secondary fst-italic">"{rec.note}"</p>}
-                      {rec.recurrence && (
+                      {(rec.rrule || rec.recurrence) && (
                         <div className="mt-1 x-small text-info opacity-75">
                           <i className="fa-solid fa-arrows-rotate me-1"></i>
-                          Every {rec.interval} {rec.recurrence}
+                          {rec.rrule || `Every ${rec.interval} ${rec.recurrence}`}
+                          {rec.recurrence_anchor === 'done' && ' after done'}
                         </div>
                       )}
                     </div>
@@ -12,6 +12,8 @@ export default function UserView({ user }) {
   const [editing, setEditing] = useState(false);
   const [editForm, setEditForm] = useState({ name: '', email: '' });
   const [deleting, setDeleting] = useState(false);
+  const [calendarTokens, setCalendarTokens] = useState([]);
+  const [newFeed, setNewFeed] = useState(null);
 
   useEffect(() => {
     if (!user) return;
@@ -20,11 +22,13 @@ export default function UserView({ user }) {
     
     Promise.all([
       api.get('/api/user/likes'),
-      api.get('/api/user/audit')
+      api.get('/api/user/audit'),
+      api.get('/api/user/calendar-tokens')
     ])
-      .then(([likes, logs]) => {
+      .then(([likes, logs, tokens]) => {
         setLikedCats(likes || []);
         setAuditLogs(logs || []);
+        setCalendarTokens(tokens || []);
         setLoading(false);
       })
       .catch(err => {
@@ -67,6 +71,28 @@ export default function UserView({ user }) {
     }
   };
 
+  const handleCreateFeed = async () => {
+    try {
+      const created = await api.post('/api/user/calendar-tokens', {});
+      // The secret is only returned once; keep the URL on screen until the page is left
+      setNewFeed(created);
+      setCalendarTokens(prev => [{ id: created.id, created_at: created.created_at }, ...prev]);
+    } catch (err) {
+      alert('Could not create feed: ' + err.message);
+    }
+  };
+
+  const handleRevokeFeed = async (id) => {
+    if (!window.confirm('Revoke this feed? Calendars subscribed to it will stop updating.')) return;
+    try {
+      await api.del('/api/user/calendar-tokens/' + id);
+      setCalendarTokens(prev => prev.filter(t => t.id !== id));
+      if (newFeed && newFeed.id === id) setNewFeed(null);
+    } catch (err) {
+      alert('Revoke failed: ' + err.message);
+    }
+  };
+
   const handleDeleteAccount = async () => {
     if (!window.confirm('Are you absolutely sure you want to delete your account? This will remove your likes, bot links, and anonymize your records. This action is irreversible.')) {
       return;
@@ -195,6 +221,47 @@ export default function UserView({ user }) {
         </div>
       )}
 
+      <div className="d-flex justify-content-between align-items-center mt-5 mb-4">
+        <h4 className="fw-bold mb-0">
+          <i className="fa-solid fa-calendar-days text-primary me-2"></i>
+          Calendar Feeds
+        </h4>
+        <button className="btn btn-sm btn-outline-primary rounded-pill px-3" onClick={handleCreateFeed}>
+          <i className="fa-solid fa-plus me-1"></i> New feed
+        </button>
+      </div>
+
+      <div className="card border-0 bg-body-tertiary shadow-sm rounded-4 p-4 mb-5">
+        <p className="text-secondary small mb-3">
+          Subscribe to planned procedures in Google Calendar, Apple Calendar or Outlook. Add <code>/api/cats/&lt;id&gt;/records.ics</code> with the same token for a single cat.
+        </p>
+        {newFeed && (
+          <div className="alert alert-success small">
+            <div className="fw-medium mb-2">Copy this URL now, it will not be shown again:</div>
+            <input className="form-control form-control-sm font-monospace" readOnly value={newFeed.url} onFocus={e => e.target.select()} />
+          </div>
+        )}
+        {calendarTokens.length === 0 ? (
+          <div className="text-secondary small">No calendar feeds yet.</div>
+        ) : (
+          <ul className="list-group list-group-flush">
+            {calendarTokens.map(t => (
+              <li key={t.id} className="list-group-item bg-transparent d-flex justify-content-between align-items-center px-0">
+                <div className="small">
+                  <div className="fw-medium">Created {new Date(t.created_at).toLocaleString([], { dateStyle: 'short', timeStyle: 'short' })}</div>
+                  <div className="text-secondary">
+                    {t.last_used_at ? 'Last used ' + new Date(t.last_used_at).toLocaleString([], { dateStyle: 'short', timeStyle: 'short' }) : 'Never used'}
+                  </div>
+                </div>
+                <button className="btn btn-sm btn-outline-danger rounded-pill px-3" onClick={() => handleRevokeFeed(t.id)}>
+                  Revoke
+                </button>
+              </li>
+            ))}
+          </ul>
+        )}
+      </div>
+
       <div className="d-flex justify-content-between align-items-center mt-5 mb-4">
         <h4 className="fw-bold mb-0">
           <i className="fa-solid fa-clock-rotate-left text-primary me-2"></i>
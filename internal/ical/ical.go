@@ -0,0 +1,152 @@
+// Package ical writes iCalendar (RFC 5545) feeds of events, with recurrence rules, exceptions
+// and display alarms.
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ContentType is the media type of iCalendar feeds.
+const ContentType = "text/calendar; charset=utf-8"
+
+const (
+	utcLayout   = "20060102T150405Z"
+	localLayout = "20060102T150405"
+	// maxLine is the length in octets after which content lines are folded.
+	maxLine = 75
+)
+
+// Calendar is a VCALENDAR of published events.
+type Calendar struct {
+	ProdID string
+	Name   string
+	Events []Event
+}
+
+// Event is a VEVENT. Times are written in TZID, an IANA zone name, or in UTC when it is empty.
+// Events that change one occurrence of a recurring event share its UID and set RecurrenceID to
+// the original time of the occurrence.
+type Event struct {
+	UID          string
+	Stamp        time.Time
+	Start        time.Time
+	TZID         string
+	Duration     time.Duration
+	Summary      string
+	Description  string
+	URL          string
+	RRule        string
+	ExDates      []time.Time
+	RDates       []time.Time
+	RecurrenceID *time.Time
+	// Alarm is how long before Start a reminder is shown; zero adds none.
+	Alarm time.Duration
+}
+
+// Encode writes the calendar to w.
+func (c Calendar) Encode(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	line := func(name, value string) {
+		writeLine(bw, name+":"+value)
+	}
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", c.ProdID)
+	line("CALSCALE", "GREGORIAN")
+	line("METHOD", "PUBLISH")
+	if c.Name != "" {
+		line("X-WR-CALNAME", escapeText(c.Name))
+	}
+	for _, e := range c.Events {
+		e.encode(bw)
+	}
+	line("END", "VCALENDAR")
+	return bw.Flush()
+}
+
+func (e Event) encode(w *bufio.Writer) {
+	line := func(name, value string) {
+		writeLine(w, name+":"+value)
+	}
+	line("BEGIN", "VEVENT")
+	line("UID", e.UID)
+	line("DTSTAMP", e.Stamp.UTC().Format(utcLayout))
+	writeLine(w, e.timeProperty("DTSTART", e.Start))
+	if e.RecurrenceID != nil {
+		writeLine(w, e.timeProperty("RECURRENCE-ID", *e.RecurrenceID))
+	}
+	if e.Duration > 0 {
+		line("DURATION", formatDuration(e.Duration))
+	}
+	line("SUMMARY", escapeText(e.Summary))
+	if e.Description != "" {
+		line("DESCRIPTION", escapeText(e.Description))
+	}
+	if e.URL != "" {
+		line("URL", e.URL)
+	}
+	if e.RRule != "" {
+		line("RRULE", e.RRule)
+	}
+	for _, t := range e.ExDates {
+		writeLine(w, e.timeProperty("EXDATE", t))
+	}
+	for _, t := range e.RDates {
+		writeLine(w, e.timeProperty("RDATE", t))
+	}
+	if e.Alarm > 0 {
+		line("BEGIN", "VALARM")
+		line("ACTION", "DISPLAY")
+		line("DESCRIPTION", escapeText(e.Summary))
+		line("TRIGGER", "-"+formatDuration(e.Alarm))
+		line("END", "VALARM")
+	}
+	line("END", "VEVENT")
+}
+
+// timeProperty formats a date-time property in the zone of the event.
+func (e Event) timeProperty(name string, t time.Time) string {
+	if e.TZID == "" {
+		return name + ":" + t.UTC().Format(utcLayout)
+	}
+	if loc, err := time.LoadLocation(e.TZID); err == nil {
+		t = t.In(loc)
+	}
+	return name + ";TZID=" + e.TZID + ":" + t.Format(localLayout)
+}
+
+// formatDuration formats a positive duration as an RFC 5545 duration in whole minutes.
+func formatDuration(d time.Duration) string {
+	return fmt.Sprintf("PT%dM", int(d.Round(time.Minute)/time.Minute))
+}
+
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+// escapeText escapes a TEXT value.
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
+
+// writeLine writes a content line terminated by CRLF, folded after maxLine octets without
+// splitting UTF-8 sequences.
+func writeLine(w *bufio.Writer, s string) {
+	limit := maxLine
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		w.WriteString(s[:cut])
+		w.WriteString("\r\n ")
+		s = s[cut:]
+		// Continuation lines start with a space
+		limit = maxLine - 1
+	}
+	w.WriteString(s)
+	w.WriteString("\r\n")
+}
@@ -0,0 +1,87 @@
+package ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestEncode(t *testing.T) {
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	start := time.Date(2026, time.March, 28, 9, 0, 0, 0, berlin)
+	moved := start.AddDate(0, 0, 2)
+	cal := Calendar{
+		ProdID: "-//CatWatch//Test//EN",
+		Name:   "Cats, planned",
+		Events: []Event{
+			{
+				UID:         "rec-1@catwatch",
+				Stamp:       time.Date(2026, time.March, 1, 12, 0, 0, 0, time.UTC),
+				Start:       start.UTC(),
+				TZID:        "Europe/Berlin",
+				Duration:    30 * time.Minute,
+				Summary:     "feeding: Murka",
+				Description: "Wet food; half a can,\nthen water",
+				RRule:       "FREQ=DAILY",
+				ExDates:     []time.Time{start.AddDate(0, 0, 1)},
+				Alarm:       30 * time.Minute,
+			},
+			{
+				UID:          "rec-1@catwatch",
+				Stamp:        time.Date(2026, time.March, 2, 12, 0, 0, 0, time.UTC),
+				Start:        moved.Add(2 * time.Hour),
+				TZID:         "Europe/Berlin",
+				Summary:      "feeding: Murka",
+				RecurrenceID: &moved,
+			},
+			{
+				UID:     "rec-2@catwatch",
+				Stamp:   time.Date(2026, time.March, 1, 12, 0, 0, 0, time.UTC),
+				Start:   time.Date(2026, time.April, 1, 10, 0, 0, 0, time.UTC),
+				Summary: "vet_visit: " + strings.Repeat("Барсик ", 12),
+			},
+		},
+	}
+	var sb strings.Builder
+	if err := cal.Encode(&sb); err != nil {
+		t.Fatal(err)
+	}
+	out := sb.String()
+
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\nVERSION:2.0\r\n",
+		"X-WR-CALNAME:Cats\\, planned\r\n",
+		// Summer time starts on the 29th: local times are kept
+		"DTSTART;TZID=Europe/Berlin:20260328T090000\r\n",
+		"RRULE:FREQ=DAILY\r\n",
+		"EXDATE;TZID=Europe/Berlin:20260329T090000\r\n",
+		"DESCRIPTION:Wet food\\; half a can\\,\\nthen water\r\n",
+		"BEGIN:VALARM\r\nACTION:DISPLAY\r\nDESCRIPTION:feeding: Murka\r\nTRIGGER:-PT30M\r\nEND:VALARM\r\n",
+		"RECURRENCE-ID;TZID=Europe/Berlin:20260330T090000\r\n",
+		"DTSTART;TZID=Europe/Berlin:20260330T110000\r\n",
+		"DTSTART:20260401T100000Z\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in\n%s", want, out)
+		}
+	}
+	if strings.Count(out, "BEGIN:VEVENT") != 3 {
+		t.Errorf("expected 3 events")
+	}
+
+	// Long lines are folded at 75 octets without splitting characters
+	for _, l := range strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n") {
+		if len(l) > 75 {
+			t.Errorf("line longer than 75 octets: %q", l)
+		}
+		if !utf8.ValidString(l) {
+			t.Errorf("line splits a character: %q", l)
+		}
+	}
+	unfolded := strings.ReplaceAll(out, "\r\n ", "")
+	if !strings.Contains(unfolded, "SUMMARY:vet_visit: "+strings.Repeat("Барсик ", 12)+"\r\n") {
+		t.Errorf("folded summary does not unfold to the original")
+	}
+}
@@ -19,6 +19,7 @@ type CtxKey string
 const (
 	ContextRequestID CtxKey = "request_id"
 	ContextUserID    CtxKey = "user_id"
+	ContextUserRole  CtxKey = "user_role"
 	ContextLang      CtxKey = "lang"
 )
 
@@ -2,9 +2,9 @@ package mcp
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
-	"strings"
 	"time"
 
 	"github.com/maniack/catwatch/internal/logging"
@@ -40,12 +40,12 @@ func New(store *storage.Store) (*Server, error) {
 
 	mcp.AddTool(mcpServer, &mcp.Tool{
 		Name:        "search_cats",
-		Description: "Search for cats by name",
+		Description: "Full-text search over cat names, descriptions, colors, tags, location names and record notes. Words match by prefix; results are ranked and carry a highlighted excerpt",
 	}, s.searchCats)
 
 	mcp.AddTool(mcpServer, &mcp.Tool{
 		Name:        "get_cat_records",
-		Description: "Get feeding and medical history for a cat",
+		Description: "Get feeding and medical history for a cat. With start and end (RFC 3339), recurring planned records are expanded into their occurrences in that range",
 	}, s.getCatRecords)
 
 	// Mutating tools (require authorized MCP session)
@@ -63,7 +63,7 @@ func New(store *storage.Store) (*Server, error) {
 	}, s.deleteCat)
 	mcp.AddTool(mcpServer, &mcp.Tool{
 		Name:        "create_record",
-		Description: "Create a record for a cat (feeding, medical, etc.)",
+		Description: "Create a record for a cat (feeding, medical, etc.). Planned records repeat with an RFC 5545 rrule (e.g. FREQ=WEEKLY;BYDAY=MO,WE,FR) plus optional exdates and rdates, repeating at the same local time in time_zone (IANA, defaults to the organization's). With recurrence_anchor \"done\" the rule counts from each completion instead, e.g. FREQ=MONTHLY;INTERVAL=3 for every 3 months after the last treatment",
 	}, s.createRecord)
 	mcp.AddTool(mcpServer, &mcp.Tool{
 		Name:        "update_record",
@@ -71,8 +71,12 @@ func New(store *storage.Store) (*Server, error) {
 	}, s.updateRecord)
 	mcp.AddTool(mcpServer, &mcp.Tool{
 		Name:        "mark_record_done",
-		Description: "Mark a record (including virtual recurrence) as done",
+		Description: "Mark a record (including virtual recurrence) as done. Plans with recurrence_anchor \"done\" are stored as done and move to their next occurrence, counted from now",
 	}, s.markRecordDone)
+	mcp.AddTool(mcpServer, &mcp.Tool{
+		Name:        "override_occurrence",
+		Description: "Change one occurrence of a recurring record, by its virtual ID (or the record ID for the first one): action skipped, rescheduled (planned_at, or minutes to shift it), snoozed (minutes, 60 by default) or clear to undo",
+	}, s.overrideOccurrence)
 	mcp.AddTool(mcpServer, &mcp.Tool{
 		Name:        "toggle_like",
 		Description: "Toggle like for a cat by current user",
@@ -83,12 +87,24 @@ func New(store *storage.Store) (*Server, error) {
 	}, s.addCatLocation)
 	mcp.AddTool(mcpServer, &mcp.Tool{
 		Name:        "add_cat_image_by_url",
-		Description: "Attach an external image URL to a cat",
+		Description: "Add a photo to a cat from an http(s) URL; the backend downloads and stores it in the background",
 	}, s.addCatImageByURL)
 	mcp.AddTool(mcpServer, &mcp.Tool{
 		Name:        "delete_image",
 		Description: "Delete image by ID",
 	}, s.deleteImage)
+	mcp.AddTool(mcpServer, &mcp.Tool{
+		Name:        "list_deleted_cats",
+		Description: "List deleted cats that can still be restored",
+	}, s.listDeletedCats)
+	mcp.AddTool(mcpServer, &mcp.Tool{
+		Name:        "restore_cat",
+		Description: "Restore a deleted cat by ID",
+	}, s.restoreCat)
+	mcp.AddTool(mcpServer, &mcp.Tool{
+		Name:        "merge_cats",
+		Description: "Merge a duplicate cat into another one: photos, records, locations, likes and tags move to the target, the duplicate is deleted and its ID redirects to the target",
+	}, s.mergeCats)
 
 	s.mcpServer = mcpServer
 	return s, nil
@@ -110,7 +126,7 @@ type ListCatsArgs struct {
 
 func (s *Server) listCats(ctx context.Context, request *mcp.CallToolRequest, input ListCatsArgs) (*mcp.CallToolResult, any, error) {
 	var cats []storage.Cat
-	query := s.store.DB.Preload("Tags").Order("created_at DESC")
+	query := s.readScope(ctx).Cats(s.store.DB).Preload("Tags").Order("created_at DESC")
 	if input.Limit > 0 {
 		query = query.Limit(input.Limit)
 	}
@@ -125,39 +141,99 @@ type GetCatArgs struct {
 }
 
 func (s *Server) getCat(ctx context.Context, request *mcp.CallToolRequest, input GetCatArgs) (*mcp.CallToolResult, any, error) {
+	// Cats merged into another one resolve to the surviving cat
+	if to, ok := s.store.CatRedirect(input.ID); ok {
+		input.ID = to
+	}
 	var cat storage.Cat
-	if err := s.store.DB.Preload("Locations").Preload("Images").Preload("Tags").Preload("Records").First(&cat, "id = ?", input.ID).Error; err != nil {
+	if err := s.readScope(ctx).Cats(s.store.DB).Preload("Locations").Preload("Images", storage.OrderedImages).Preload("Tags").Preload("Records").First(&cat, "id = ?", input.ID).Error; err != nil {
 		return nil, nil, err
 	}
+	if !s.memberScope(ctx).Allows(cat.OrganizationID) {
+		cat.Records = doneRecords(cat.Records)
+	}
 	return nil, cat, nil
 }
 
 type SearchCatsArgs struct {
 	Query string `json:"query"`
+	Limit int    `json:"limit"`
+}
+
+// SearchResult is a cat found by search_cats with its rank and a highlighted excerpt.
+type SearchResult struct {
+	storage.Cat
+	Rank      float64 `json:"rank"`
+	Highlight string  `json:"highlight"`
 }
 
 func (s *Server) searchCats(ctx context.Context, request *mcp.CallToolRequest, input SearchCatsArgs) (*mcp.CallToolResult, any, error) {
-	var cats []storage.Cat
-	if err := s.store.DB.Preload("Tags").Where("name LIKE ?", "%"+input.Query+"%").Find(&cats).Error; err != nil {
+	limit := input.Limit
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	scope := s.readScope(ctx)
+	hits, err := s.store.Search(scope, input.Query, limit)
+	if err != nil {
 		return nil, nil, err
 	}
-	return nil, cats, nil
+	ids := make([]string, len(hits))
+	for i, h := range hits {
+		ids[i] = h.CatID
+	}
+	var cats []storage.Cat
+	if len(ids) > 0 {
+		if err := scope.Cats(s.store.DB).Preload("Tags").Where("cats.id IN ?", ids).Find(&cats).Error; err != nil {
+			return nil, nil, err
+		}
+	}
+	byID := make(map[string]storage.Cat, len(cats))
+	for _, c := range cats {
+		byID[c.ID] = c
+	}
+	out := []SearchResult{}
+	for _, h := range hits {
+		if c, ok := byID[h.CatID]; ok {
+			out = append(out, SearchResult{Cat: c, Rank: h.Rank, Highlight: h.Highlight})
+		}
+	}
+	return nil, out, nil
 }
 
 type GetCatRecordsArgs struct {
 	CatID string `json:"cat_id"`
 	Limit int    `json:"limit"`
+	// With both set, members get recurring planned records as their occurrences in the range
+	Start *time.Time `json:"start,omitempty"`
+	End   *time.Time `json:"end,omitempty"`
 }
 
 func (s *Server) getCatRecords(ctx context.Context, request *mcp.CallToolRequest, input GetCatRecordsArgs) (*mcp.CallToolResult, any, error) {
+	orgID, err := s.catOrganization(input.CatID)
+	if err != nil || !s.readScope(ctx).Allows(orgID) {
+		return nil, nil, gorm.ErrRecordNotFound
+	}
 	var records []storage.Record
 	query := s.store.DB.Where("cat_id = ?", input.CatID).Order("timestamp DESC")
-	if input.Limit > 0 {
+	member := s.memberScope(ctx).Allows(orgID)
+	if !member {
+		query = query.Where("done_at IS NOT NULL")
+	}
+	expand := member && input.Start != nil && input.End != nil
+	if input.Limit > 0 && !expand {
 		query = query.Limit(input.Limit)
 	}
 	if err := query.Find(&records).Error; err != nil {
 		return nil, nil, err
 	}
+	if expand {
+		if records, err = s.store.ExpandPlannedRecords(records, *input.Start, *input.End); err != nil {
+			return nil, nil, err
+		}
+		if input.Limit > 0 && len(records) > input.Limit {
+			records = records[:input.Limit]
+		}
+	}
 	return nil, records, nil
 }
 
@@ -171,6 +247,95 @@ func uidFromCtx(ctx context.Context) string {
 	return ""
 }
 
+// roleFromCtx returns the caller's role; authenticated callers without a role claim get storage.DefaultRole.
+func roleFromCtx(ctx context.Context) string {
+	if v, ok := ctx.Value(logging.ContextUserRole).(string); ok && v != "" {
+		return v
+	}
+	if uidFromCtx(ctx) != "" {
+		return storage.DefaultRole
+	}
+	return ""
+}
+
+var (
+	errUnauthorized = errors.New("unauthorized")
+	errForbidden    = errors.New("forbidden: insufficient role")
+)
+
+// requireRole mirrors backend.RequireRole for tool calls.
+func requireRole(ctx context.Context, role string) error {
+	if uidFromCtx(ctx) == "" {
+		return errUnauthorized
+	}
+	if !storage.HasRole(roleFromCtx(ctx), role) {
+		return errForbidden
+	}
+	return nil
+}
+
+// memberScope returns the organizations the caller belongs to (all for admins).
+func (s *Server) memberScope(ctx context.Context) storage.OrgScope {
+	return s.store.MemberScope(uidFromCtx(ctx), roleFromCtx(ctx))
+}
+
+// readScope returns the caller's organizations plus public ones.
+func (s *Server) readScope(ctx context.Context) storage.OrgScope {
+	return s.store.ReadScope(uidFromCtx(ctx), roleFromCtx(ctx))
+}
+
+func (s *Server) catOrganization(catID string) (string, error) {
+	var cat storage.Cat
+	if err := s.store.DB.Select("id", "organization_id").First(&cat, "id = ?", catID).Error; err != nil {
+		return "", err
+	}
+	return cat.OrganizationID, nil
+}
+
+// requireCatMember fails with gorm.ErrRecordNotFound unless the cat belongs to one of the caller's organizations.
+func (s *Server) requireCatMember(ctx context.Context, catID string) error {
+	orgID, err := s.catOrganization(catID)
+	if err != nil {
+		return err
+	}
+	if !s.memberScope(ctx).Allows(orgID) {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// audit writes an audit log entry for a change made by a tool; before and after are
+// entity snapshots stored as a JSON diff (see storage.AuditDelta). A non-nil err marks a failure.
+func (s *Server) audit(ctx context.Context, tool, targetType, targetID string, before, after any, err error) {
+	entry := storage.AuditLog{
+		UserID:     uidFromCtx(ctx),
+		Method:     "MCP",
+		Route:      tool,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Status:     "success",
+	}
+	if err != nil {
+		entry.Status = "error"
+		entry.Delta = fmt.Sprintf(`{"error":%q}`, err.Error())
+	} else if delta, derr := storage.AuditDelta(before, after); derr == nil {
+		entry.Delta = delta
+	}
+	if werr := s.store.WriteAudit(&entry); werr != nil {
+		logging.L().WithError(werr).Error("failed to write audit log")
+	}
+}
+
+func doneRecords(recs []storage.Record) []storage.Record {
+	var out []storage.Record
+	for _, r := range recs {
+		if r.DoneAt != nil {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
 func (s *Server) updateCatLastSeenFromRecord(rec storage.Record) {
 	var lastSeen *time.Time
 	if rec.DoneAt != nil {
@@ -189,9 +354,20 @@ func (s *Server) updateCatLastSeenFromRecord(rec storage.Record) {
 
 // createCat creates a new cat. Input mirrors storage.Cat fields; ID will be generated if empty.
 func (s *Server) createCat(ctx context.Context, request *mcp.CallToolRequest, in storage.Cat) (*mcp.CallToolResult, any, error) {
+	if err := requireRole(ctx, storage.RoleVolunteer); err != nil {
+		return nil, nil, err
+	}
 	if in.ID == "" {
 		in.ID = storage.NewUUID()
 	}
+	if in.OrganizationID == "" {
+		if orgs := s.store.UserOrganizationIDs(uidFromCtx(ctx)); len(orgs) > 0 {
+			in.OrganizationID = orgs[0]
+		}
+	}
+	if !s.memberScope(ctx).Allows(in.OrganizationID) {
+		return nil, nil, errForbidden
+	}
 	// normalize condition 1..5 (default 3)
 	if in.Condition < 1 || in.Condition > 5 {
 		in.Condition = 3
@@ -211,16 +387,34 @@ func (s *Server) createCat(ctx context.Context, request *mcp.CallToolRequest, in
 		}
 	}
 	if err := s.store.DB.Create(&in).Error; err != nil {
+		s.audit(ctx, "create_cat", "cat", in.ID, nil, nil, err)
 		return nil, nil, err
 	}
+	s.audit(ctx, "create_cat", "cat", in.ID, nil, in, nil)
 	return nil, in, nil
 }
 
 // updateCat updates existing cat by full save (handles tags M2M correctly).
 func (s *Server) updateCat(ctx context.Context, request *mcp.CallToolRequest, in storage.Cat) (*mcp.CallToolResult, any, error) {
+	if err := requireRole(ctx, storage.RoleVolunteer); err != nil {
+		return nil, nil, err
+	}
 	if in.ID == "" {
 		return nil, nil, gorm.ErrMissingWhereClause
 	}
+	orgID, err := s.catOrganization(in.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	scope := s.memberScope(ctx)
+	if !scope.Allows(orgID) {
+		return nil, nil, gorm.ErrRecordNotFound
+	}
+	if in.OrganizationID == "" {
+		in.OrganizationID = orgID
+	} else if !scope.Allows(in.OrganizationID) {
+		return nil, nil, errForbidden
+	}
 	if in.Condition < 1 || in.Condition > 5 {
 		in.Condition = 3
 	}
@@ -237,11 +431,17 @@ func (s *Server) updateCat(ctx context.Context, request *mcp.CallToolRequest, in
 			}
 		}
 	}
+	var before storage.Cat
+	_ = s.store.DB.Preload("Locations").Preload("Images").Preload("Tags").First(&before, "id = ?", in.ID).Error
+	// Photo retention is set by coordinators through its own endpoint
+	in.ImageRetention = before.ImageRetention
 	if err := s.store.DB.Save(&in).Error; err != nil {
+		s.audit(ctx, "update_cat", "cat", in.ID, nil, nil, err)
 		return nil, nil, err
 	}
 	var out storage.Cat
 	_ = s.store.DB.Preload("Locations").Preload("Images").Preload("Tags").First(&out, "id = ?", in.ID).Error
+	s.audit(ctx, "update_cat", "cat", in.ID, before, out, nil)
 	return nil, out, nil
 }
 
@@ -251,17 +451,30 @@ type DeleteCatArgs struct {
 }
 
 func (s *Server) deleteCat(ctx context.Context, request *mcp.CallToolRequest, input DeleteCatArgs) (*mcp.CallToolResult, any, error) {
+	if err := requireRole(ctx, storage.RoleCoordinator); err != nil {
+		return nil, nil, err
+	}
 	if input.ID == "" {
 		return nil, nil, gorm.ErrMissingWhereClause
 	}
+	if err := s.requireCatMember(ctx, input.ID); err != nil {
+		return nil, nil, err
+	}
+	var before storage.Cat
+	_ = s.store.DB.Preload("Tags").First(&before, "id = ?", input.ID).Error
 	if err := s.store.DB.Delete(&storage.Cat{}, "id = ?", input.ID).Error; err != nil {
+		s.audit(ctx, "delete_cat", "cat", input.ID, nil, nil, err)
 		return nil, nil, err
 	}
+	s.audit(ctx, "delete_cat", "cat", input.ID, before, nil, nil)
 	return nil, map[string]any{"status": "ok"}, nil
 }
 
 // createRecord creates a new record for a cat and updates cat's last_seen when applicable.
 func (s *Server) createRecord(ctx context.Context, request *mcp.CallToolRequest, in storage.Record) (*mcp.CallToolResult, any, error) {
+	if err := requireRole(ctx, storage.RoleVolunteer); err != nil {
+		return nil, nil, err
+	}
 	uid := uidFromCtx(ctx)
 	if in.ID == "" {
 		in.ID = storage.NewUUID()
@@ -269,29 +482,58 @@ func (s *Server) createRecord(ctx context.Context, request *mcp.CallToolRequest,
 	if in.CatID == "" {
 		return nil, nil, gorm.ErrMissingWhereClause
 	}
+	if err := s.requireCatMember(ctx, in.CatID); err != nil {
+		return nil, nil, err
+	}
 	in.UserID = uid
+	if in.PlannedAt != nil && in.TimeZone == "" {
+		in.TimeZone = s.store.CatTimeZone(in.CatID)
+	}
+	if err := in.NormalizeRecurrence(); err != nil {
+		return nil, nil, err
+	}
 	if in.PlannedAt == nil && in.DoneAt == nil && in.Timestamp.IsZero() {
 		in.Timestamp = time.Now()
 	}
 	if err := s.store.DB.Create(&in).Error; err != nil {
+		s.audit(ctx, "create_record", "record", in.ID, nil, nil, err)
 		return nil, nil, err
 	}
+	s.audit(ctx, "create_record", "record", in.ID, nil, in, nil)
 	s.updateCatLastSeenFromRecord(in)
 	return nil, in, nil
 }
 
 // updateRecord updates fields of an existing record.
 func (s *Server) updateRecord(ctx context.Context, request *mcp.CallToolRequest, in storage.Record) (*mcp.CallToolResult, any, error) {
+	if err := requireRole(ctx, storage.RoleVolunteer); err != nil {
+		return nil, nil, err
+	}
 	if in.ID == "" {
 		return nil, nil, gorm.ErrMissingWhereClause
 	}
+	var existing storage.Record
+	if err := s.memberScope(ctx).ByCat(s.store.DB).First(&existing, "id = ?", in.ID).Error; err != nil {
+		return nil, nil, err
+	}
+	// Records cannot be moved to a cat outside the caller's organizations
+	if in.CatID != "" && in.CatID != existing.CatID {
+		if err := s.requireCatMember(ctx, in.CatID); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := in.NormalizeRecurrence(); err != nil {
+		return nil, nil, err
+	}
 	if err := s.store.DB.Model(&storage.Record{ID: in.ID}).Updates(in).Error; err != nil {
+		s.audit(ctx, "update_record", "record", in.ID, nil, nil, err)
 		return nil, nil, err
 	}
 	var out storage.Record
 	if err := s.store.DB.First(&out, "id = ?", in.ID).Error; err != nil {
 		return nil, nil, err
 	}
+	s.audit(ctx, "update_record", "record", in.ID, existing, out, nil)
 	s.updateCatLastSeenFromRecord(out)
 	return nil, out, nil
 }
@@ -303,50 +545,95 @@ type MarkRecordDoneArgs struct {
 }
 
 func (s *Server) markRecordDone(ctx context.Context, request *mcp.CallToolRequest, input MarkRecordDoneArgs) (*mcp.CallToolResult, any, error) {
+	if err := requireRole(ctx, storage.RoleVolunteer); err != nil {
+		return nil, nil, err
+	}
 	now := time.Now()
-	if strings.HasPrefix(input.ID, "virtual-") {
-		idAndDate := strings.TrimPrefix(input.ID, "virtual-")
-		lastDash := strings.LastIndex(idAndDate, "-")
-		if lastDash != -1 {
-			originalID := idAndDate[:lastDash]
-			dateStr := idAndDate[lastDash+1:]
-			var orig storage.Record
-			if err := s.store.DB.First(&orig, "id = ?", originalID).Error; err == nil {
-				occurrenceDate, _ := time.Parse("20060102", dateStr)
-				if orig.PlannedAt != nil {
-					planned := time.Date(occurrenceDate.Year(), occurrenceDate.Month(), occurrenceDate.Day(),
-						orig.PlannedAt.Hour(), orig.PlannedAt.Minute(), orig.PlannedAt.Second(), orig.PlannedAt.Nanosecond(), orig.PlannedAt.Location())
-					newRec := orig
-					newRec.ID = storage.NewUUID()
-					newRec.PlannedAt = &planned
-					newRec.DoneAt = &now
-					newRec.Recurrence = ""
-					newRec.CreatedAt = now
-					if err := s.store.DB.Create(&newRec).Error; err != nil {
-						return nil, nil, err
-					}
-					s.updateCatLastSeenFromRecord(newRec)
-					return nil, newRec, nil
-				}
-			}
+	if _, _, ok := storage.ParseVirtualRecordID(input.ID); ok {
+		newRec, err := s.store.CompleteOccurrence(s.occurrenceLookup(ctx, input.CatID), input.ID, uidFromCtx(ctx), now)
+		if err != nil {
+			s.audit(ctx, "mark_record_done", "record", input.ID, nil, nil, err)
+			return nil, nil, err
 		}
+		s.audit(ctx, "mark_record_done", "record", newRec.ID, nil, *newRec, nil)
+		s.updateCatLastSeenFromRecord(*newRec)
+		return nil, *newRec, nil
 	}
 	// Non-virtual: update existing
-	db := s.store.DB.Model(&storage.Record{}).Where("id = ?", input.ID)
-	if input.CatID != "" {
-		db = db.Where("cat_id = ?", input.CatID)
+	var before storage.Record
+	if err := s.occurrenceLookup(ctx, input.CatID).Where("id = ?", input.ID).First(&before).Error; err != nil {
+		s.audit(ctx, "mark_record_done", "record", input.ID, nil, nil, err)
+		return nil, nil, err
 	}
-	if err := db.Update("done_at", &now).Error; err != nil {
+	if before.DoneAt == nil && before.CompletionAnchored() {
+		done, plan, err := s.store.CompleteAnchoredRecord(s.occurrenceLookup(ctx, input.CatID), input.ID, now)
+		if err != nil {
+			s.audit(ctx, "mark_record_done", "record", input.ID, nil, nil, err)
+			return nil, nil, err
+		}
+		if done.ID != plan.ID {
+			s.audit(ctx, "mark_record_done", "record", done.ID, nil, *done, nil)
+		}
+		s.audit(ctx, "mark_record_done", "record", plan.ID, before, *plan, nil)
+		s.updateCatLastSeenFromRecord(*done)
+		return nil, *done, nil
+	}
+	res := s.occurrenceLookup(ctx, input.CatID).Where("id = ?", input.ID).Update("done_at", &now)
+	err := res.Error
+	if err == nil && res.RowsAffected == 0 {
+		err = gorm.ErrRecordNotFound
+	}
+	if err != nil {
+		s.audit(ctx, "mark_record_done", "record", input.ID, nil, nil, err)
 		return nil, nil, err
 	}
 	var out storage.Record
-	if err := s.store.DB.First(&out, "id = ?", input.ID).Error; err != nil {
+	if err := s.occurrenceLookup(ctx, input.CatID).Where("id = ?", input.ID).First(&out).Error; err != nil {
 		return nil, nil, err
 	}
+	s.audit(ctx, "mark_record_done", "record", out.ID, before, out, nil)
 	s.updateCatLastSeenFromRecord(out)
 	return nil, out, nil
 }
 
+// occurrenceLookup returns the records of the caller's organizations, of the cat if given.
+func (s *Server) occurrenceLookup(ctx context.Context, catID string) *gorm.DB {
+	db := s.memberScope(ctx).ByCat(s.store.DB.Model(&storage.Record{}))
+	if catID != "" {
+		db = db.Where("cat_id = ?", catID)
+	}
+	return db
+}
+
+// OverrideOccurrenceArgs skips, reschedules or snoozes one occurrence; Action "clear" puts it back.
+type OverrideOccurrenceArgs struct {
+	ID    string `json:"id"`
+	CatID string `json:"cat_id,omitempty"`
+	storage.OccurrenceChange
+}
+
+func (s *Server) overrideOccurrence(ctx context.Context, request *mcp.CallToolRequest, input OverrideOccurrenceArgs) (*mcp.CallToolResult, any, error) {
+	if err := requireRole(ctx, storage.RoleVolunteer); err != nil {
+		return nil, nil, err
+	}
+	db := s.occurrenceLookup(ctx, input.CatID)
+	if input.Action == "clear" {
+		prev, err := s.store.ClearOccurrenceOverride(db, input.ID)
+		s.audit(ctx, "override_occurrence", "record", input.ID, prev, nil, err)
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, map[string]any{"status": "ok"}, nil
+	}
+	o, prev, err := s.store.OverrideOccurrence(db, input.ID, uidFromCtx(ctx), input.OccurrenceChange, time.Now())
+	if err != nil {
+		s.audit(ctx, "override_occurrence", "record", input.ID, nil, nil, err)
+		return nil, nil, err
+	}
+	s.audit(ctx, "override_occurrence", "record", input.ID, prev, o, nil)
+	return nil, o, nil
+}
+
 // toggleLike toggles like for current user on a cat and returns likes count and state.
 type ToggleLikeArgs struct {
 	CatID string `json:"cat_id"`
@@ -355,7 +642,10 @@ type ToggleLikeArgs struct {
 func (s *Server) toggleLike(ctx context.Context, request *mcp.CallToolRequest, input ToggleLikeArgs) (*mcp.CallToolResult, any, error) {
 	uid := uidFromCtx(ctx)
 	if uid == "" {
-		return nil, nil, gorm.ErrInvalidData
+		return nil, nil, errUnauthorized
+	}
+	if orgID, err := s.catOrganization(input.CatID); err != nil || !s.readScope(ctx).Allows(orgID) {
+		return nil, nil, gorm.ErrRecordNotFound
 	}
 	cur, err := s.store.IsLikedByUser(input.CatID, uid)
 	if err != nil {
@@ -379,9 +669,15 @@ type AddCatLocationArgs struct {
 }
 
 func (s *Server) addCatLocation(ctx context.Context, request *mcp.CallToolRequest, in AddCatLocationArgs) (*mcp.CallToolResult, any, error) {
+	if err := requireRole(ctx, storage.RoleVolunteer); err != nil {
+		return nil, nil, err
+	}
 	if in.CatID == "" {
 		return nil, nil, gorm.ErrMissingWhereClause
 	}
+	if err := s.requireCatMember(ctx, in.CatID); err != nil {
+		return nil, nil, err
+	}
 	loc := storage.CatLocation{
 		ID:          storage.NewUUID(),
 		CatID:       in.CatID,
@@ -396,8 +692,10 @@ func (s *Server) addCatLocation(ctx context.Context, request *mcp.CallToolReques
 		loc.CreatedAt = time.Now()
 	}
 	if err := s.store.DB.Create(&loc).Error; err != nil {
+		s.audit(ctx, "add_cat_location", "location", loc.ID, nil, nil, err)
 		return nil, nil, err
 	}
+	s.audit(ctx, "add_cat_location", "location", loc.ID, nil, loc, nil)
 	// Auto observation record
 	uid := uidFromCtx(ctx)
 	obs := storage.Record{
@@ -413,6 +711,7 @@ func (s *Server) addCatLocation(ctx context.Context, request *mcp.CallToolReques
 	// Add fmt import via fully qualified call requires fmt, so ensure import - use fmt.Sprintf here
 	if err := s.store.DB.Create(&obs).Error; err == nil {
 		// ignore errors silently for auto record
+		s.audit(ctx, "add_cat_location", "record", obs.ID, nil, obs, nil)
 		s.updateCatLastSeenFromRecord(obs)
 	}
 	// Update Cat.LastSeen if this is fresh
@@ -431,14 +730,30 @@ type AddCatImageByURLArgs struct {
 }
 
 func (s *Server) addCatImageByURL(ctx context.Context, request *mcp.CallToolRequest, in AddCatImageByURLArgs) (*mcp.CallToolResult, any, error) {
+	if err := requireRole(ctx, storage.RoleVolunteer); err != nil {
+		return nil, nil, err
+	}
 	if in.CatID == "" || in.URL == "" {
 		return nil, nil, gorm.ErrInvalidData
 	}
-	img := storage.Image{ID: storage.NewUUID(), CatID: in.CatID, URL: in.URL, MIME: in.MIME, Title: in.Title}
-	if err := s.store.DB.Create(&img).Error; err != nil {
+	if err := storage.ValidateImageURL(in.URL); err != nil {
+		return nil, nil, err
+	}
+	if err := s.requireCatMember(ctx, in.CatID); err != nil {
+		return nil, nil, err
+	}
+	// The backend downloads the image and stores it like an upload
+	img := storage.Image{ID: storage.NewUUID(), CatID: in.CatID, URL: in.URL, MIME: in.MIME, Title: in.Title, FetchStatus: storage.ImageFetchPending}
+	err := s.store.PlaceNewImage(&img)
+	if err == nil {
+		err = s.store.DB.Create(&img).Error
+	}
+	if err != nil {
+		s.audit(ctx, "add_cat_image_by_url", "image", img.ID, nil, nil, err)
 		return nil, nil, err
 	}
 	_ = s.store.DB.First(&img, "id = ?", img.ID).Error
+	s.audit(ctx, "add_cat_image_by_url", "image", img.ID, nil, img, nil)
 	return nil, img, nil
 }
 
@@ -448,18 +763,113 @@ type DeleteImageArgs struct {
 }
 
 func (s *Server) deleteImage(ctx context.Context, request *mcp.CallToolRequest, in DeleteImageArgs) (*mcp.CallToolResult, any, error) {
+	if err := requireRole(ctx, storage.RoleCoordinator); err != nil {
+		return nil, nil, err
+	}
 	if in.ImageID == "" {
 		return nil, nil, gorm.ErrInvalidData
 	}
 	// Return the image row prior to deletion if exists
 	var img storage.Image
-	if err := s.store.DB.First(&img, "id = ?", in.ImageID).Error; err != nil {
+	if err := s.memberScope(ctx).ByCat(s.store.DB).First(&img, "id = ?", in.ImageID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, nil, err
 		}
 	}
-	if err := s.store.DB.Delete(&storage.Image{}, "id = ?", in.ImageID).Error; err != nil {
+	if err := s.store.DeleteImage(in.ImageID); err != nil {
+		s.audit(ctx, "delete_image", "image", in.ImageID, nil, nil, err)
 		return nil, nil, err
 	}
+	s.audit(ctx, "delete_image", "image", in.ImageID, img, nil, nil)
 	return nil, img, nil
 }
+
+// listDeletedCats returns cats in the trash of the caller's organizations.
+type ListDeletedCatsArgs struct{}
+
+func (s *Server) listDeletedCats(ctx context.Context, request *mcp.CallToolRequest, _ ListDeletedCatsArgs) (*mcp.CallToolResult, any, error) {
+	if err := requireRole(ctx, storage.RoleCoordinator); err != nil {
+		return nil, nil, err
+	}
+	cats, err := s.store.ListDeletedCats(s.memberScope(ctx))
+	if err != nil {
+		return nil, nil, err
+	}
+	return nil, cats, nil
+}
+
+// restoreCat takes a cat out of the trash.
+type RestoreCatArgs struct {
+	ID string `json:"id"`
+}
+
+func (s *Server) restoreCat(ctx context.Context, request *mcp.CallToolRequest, input RestoreCatArgs) (*mcp.CallToolResult, any, error) {
+	if err := requireRole(ctx, storage.RoleCoordinator); err != nil {
+		return nil, nil, err
+	}
+	if input.ID == "" {
+		return nil, nil, gorm.ErrMissingWhereClause
+	}
+	before, err := s.store.GetDeletedCat(input.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !s.memberScope(ctx).Allows(before.OrganizationID) {
+		return nil, nil, gorm.ErrRecordNotFound
+	}
+	cat, err := s.store.RestoreCat(input.ID)
+	if err != nil {
+		s.audit(ctx, "restore_cat", "cat", input.ID, nil, nil, err)
+		return nil, nil, err
+	}
+	s.audit(ctx, "restore_cat", "cat", input.ID, map[string]any{"deleted_at": before.DeletedAt}, map[string]any{"deleted_at": nil}, nil)
+	return nil, cat, nil
+}
+
+// mergeCats merges the cat SourceID into TargetID.
+type MergeCatsArgs struct {
+	SourceID string `json:"source_id"`
+	TargetID string `json:"target_id"`
+}
+
+func (s *Server) mergeCats(ctx context.Context, request *mcp.CallToolRequest, input MergeCatsArgs) (*mcp.CallToolResult, any, error) {
+	if err := requireRole(ctx, storage.RoleCoordinator); err != nil {
+		return nil, nil, err
+	}
+	if input.SourceID == "" || input.TargetID == "" {
+		return nil, nil, gorm.ErrMissingWhereClause
+	}
+	if input.SourceID == input.TargetID {
+		return nil, nil, storage.ErrMergeSelf
+	}
+	for _, id := range []string{input.SourceID, input.TargetID} {
+		if err := s.requireCatMember(ctx, id); err != nil {
+			return nil, nil, err
+		}
+	}
+	var source, target storage.Cat
+	if err := s.store.DB.Preload("Tags").First(&source, "id = ?", input.SourceID).Error; err != nil {
+		return nil, nil, err
+	}
+	if err := s.store.DB.Preload("Tags").First(&target, "id = ?", input.TargetID).Error; err != nil {
+		return nil, nil, err
+	}
+	if source.OrganizationID != target.OrganizationID {
+		return nil, nil, errors.New("cats belong to different organizations")
+	}
+	cat, err := s.store.MergeCats(input.SourceID, input.TargetID)
+	if err != nil {
+		s.audit(ctx, "merge_cats", "cat", input.TargetID, nil, nil, err)
+		return nil, nil, err
+	}
+	s.audit(ctx, "merge_cats", "cat", input.SourceID, source, mergedCat{Cat: source, MergedInto: input.TargetID}, nil)
+	s.audit(ctx, "merge_cats", "cat", input.TargetID, target, mergedCat{Cat: *cat, MergedFrom: input.SourceID}, nil)
+	return nil, cat, nil
+}
+
+// mergedCat is a cat snapshot in the audit entries of merge_cats.
+type mergedCat struct {
+	storage.Cat
+	MergedFrom string `json:"merged_from,omitempty"`
+	MergedInto string `json:"merged_into,omitempty"`
+}
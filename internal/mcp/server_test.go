@@ -2,6 +2,7 @@ package mcp
 
 import (
 	"context"
+	"strings"
 	"testing"
 	"time"
 
@@ -79,6 +80,17 @@ func TestMCPMutationsBasicFlow(t *testing.T) {
 		t.Fatalf("expected last_seen to be set after done record")
 	}
 
+	// 5a. Full-text search finds the cat by a done record note
+	st.DB.Model(&storage.Record{}).Where("id = ?", createdRec.ID).Update("note", "ate sardines")
+	_, hitsAny, err := s.searchCats(ctx, nil, SearchCatsArgs{Query: "sardine"})
+	if err != nil {
+		t.Fatalf("searchCats: %v", err)
+	}
+	hits := hitsAny.([]SearchResult)
+	if len(hits) != 1 || hits[0].ID != created.ID || !strings.Contains(hits[0].Highlight, "<mark>sardines</mark>") {
+		t.Fatalf("unexpected search results: %+v", hits)
+	}
+
 	// 6. Add location
 	_, locAny, err := s.addCatLocation(ctx, nil, AddCatLocationArgs{CatID: created.ID, Latitude: 1.23, Longitude: 4.56})
 	if err != nil {
@@ -98,12 +110,51 @@ func TestMCPMutationsBasicFlow(t *testing.T) {
 	if img.ID == "" || img.URL == "" {
 		t.Fatalf("expected image created")
 	}
-	_, _, err = s.deleteImage(ctx, nil, DeleteImageArgs{ImageID: img.ID})
+	// Deleting photos requires the coordinator role
+	if _, _, err = s.deleteImage(ctx, nil, DeleteImageArgs{ImageID: img.ID}); err == nil {
+		t.Fatalf("expected deleteImage to be denied for a volunteer")
+	}
+	coordCtx := context.WithValue(ctx, logging.ContextUserRole, storage.RoleCoordinator)
+	_, _, err = s.deleteImage(coordCtx, nil, DeleteImageArgs{ImageID: img.ID})
 	if err != nil {
 		t.Fatalf("deleteImage: %v", err)
 	}
 }
 
+func TestMCPRoleEnforcement(t *testing.T) {
+	st := newTestStore(t)
+	s, _ := New(st)
+
+	// Anonymous callers cannot mutate
+	if _, _, err := s.createCat(context.Background(), nil, storage.Cat{Name: "Anon"}); err == nil {
+		t.Fatalf("expected createCat to fail without user")
+	}
+
+	viewer := context.WithValue(context.Background(), logging.ContextUserID, "viewer-1")
+	viewer = context.WithValue(viewer, logging.ContextUserRole, storage.RoleViewer)
+	if _, _, err := s.createCat(viewer, nil, storage.Cat{Name: "Viewer Cat"}); err == nil {
+		t.Fatalf("expected createCat to be denied for a viewer")
+	}
+
+	cat := storage.Cat{ID: storage.NewUUID(), Name: "Role Cat"}
+	if err := st.DB.Create(&cat).Error; err != nil {
+		t.Fatalf("create cat: %v", err)
+	}
+	// Viewers may still like cats
+	if _, _, err := s.toggleLike(viewer, nil, ToggleLikeArgs{CatID: cat.ID}); err != nil {
+		t.Fatalf("toggleLike as viewer: %v", err)
+	}
+
+	volunteer := context.WithValue(context.Background(), logging.ContextUserID, "vol-1")
+	if _, _, err := s.deleteCat(volunteer, nil, DeleteCatArgs{ID: cat.ID}); err == nil {
+		t.Fatalf("expected deleteCat to be denied for a volunteer")
+	}
+	admin := context.WithValue(volunteer, logging.ContextUserRole, storage.RoleAdmin)
+	if _, _, err := s.deleteCat(admin, nil, DeleteCatArgs{ID: cat.ID}); err != nil {
+		t.Fatalf("deleteCat as admin: %v", err)
+	}
+}
+
 func TestMCPVirtualRecordDone(t *testing.T) {
 	st := newTestStore(t)
 	s, _ := New(st)
@@ -129,3 +180,147 @@ func TestMCPVirtualRecordDone(t *testing.T) {
 		t.Fatalf("expected new instance with done_at")
 	}
 }
+
+func TestMCPAudit(t *testing.T) {
+	st := newTestStore(t)
+	s, err := New(st)
+	if err != nil {
+		t.Fatalf("new MCP server: %v", err)
+	}
+	ctx := context.WithValue(context.Background(), logging.ContextUserID, "u-audit")
+
+	_, outAny, err := s.createCat(ctx, nil, storage.Cat{Name: "Audit Cat", Condition: 3})
+	if err != nil {
+		t.Fatalf("createCat: %v", err)
+	}
+	cat := outAny.(storage.Cat)
+	cat.Condition = 2
+	if _, _, err := s.updateCat(ctx, nil, cat); err != nil {
+		t.Fatalf("updateCat: %v", err)
+	}
+
+	var n int64
+	st.DB.Model(&storage.AuditLog{}).Where("target_id = ?", cat.ID).Count(&n)
+	if n != 2 {
+		t.Fatalf("expected 2 audit entries, got %d", n)
+	}
+	var upd storage.AuditLog
+	st.DB.Where("target_id = ? AND route = ?", cat.ID, "update_cat").First(&upd)
+	if upd.Method != "MCP" || upd.Route != "update_cat" || upd.UserID != "u-audit" || upd.Status != "success" {
+		t.Fatalf("unexpected audit entry: %+v", upd)
+	}
+	if !strings.Contains(upd.Delta, `"condition":{"after":2,"before":3}`) {
+		t.Fatalf("expected condition diff, got %s", upd.Delta)
+	}
+}
+
+func TestMCPMergeCats(t *testing.T) {
+	st := newTestStore(t)
+	s, _ := New(st)
+	ctx := context.WithValue(context.Background(), logging.ContextUserID, "u-merge")
+	coord := context.WithValue(ctx, logging.ContextUserRole, storage.RoleCoordinator)
+
+	var ids []string
+	for _, name := range []string{"Merge Source", "Merge Target"} {
+		_, out, err := s.createCat(ctx, nil, storage.Cat{Name: name})
+		if err != nil {
+			t.Fatalf("createCat: %v", err)
+		}
+		ids = append(ids, out.(storage.Cat).ID)
+	}
+	source, target := ids[0], ids[1]
+	if _, _, err := s.createRecord(ctx, nil, storage.Record{CatID: source, Type: "feeding"}); err != nil {
+		t.Fatalf("createRecord: %v", err)
+	}
+
+	if _, _, err := s.mergeCats(ctx, nil, MergeCatsArgs{SourceID: source, TargetID: target}); err == nil {
+		t.Fatalf("expected merge_cats to be denied for a volunteer")
+	}
+	if _, _, err := s.mergeCats(coord, nil, MergeCatsArgs{SourceID: target, TargetID: target}); err == nil {
+		t.Fatalf("expected merging a cat into itself to fail")
+	}
+	if _, _, err := s.mergeCats(coord, nil, MergeCatsArgs{SourceID: source, TargetID: target}); err != nil {
+		t.Fatalf("mergeCats: %v", err)
+	}
+
+	// The old ID resolves to the target, which now has the record
+	_, out, err := s.getCat(ctx, nil, GetCatArgs{ID: source})
+	if err != nil {
+		t.Fatalf("getCat by merged ID: %v", err)
+	}
+	if cat := out.(storage.Cat); cat.ID != target || len(cat.Records) != 1 {
+		t.Fatalf("expected target with 1 record, got %s with %d", cat.ID, len(cat.Records))
+	}
+	if _, _, err := s.restoreCat(coord, nil, RestoreCatArgs{ID: source}); err == nil {
+		t.Fatalf("expected restoring a merged cat to fail")
+	}
+	var audits int64
+	st.DB.Model(&storage.AuditLog{}).Where("route = ? AND status = ?", "merge_cats", "success").Count(&audits)
+	if audits != 2 {
+		t.Fatalf("expected 2 merge audit entries, got %d", audits)
+	}
+}
+
+func TestMCPCompletionAnchoredDone(t *testing.T) {
+	st := newTestStore(t)
+	s, _ := New(st)
+	ctx := context.WithValue(context.Background(), logging.ContextUserID, "u-3")
+	cat := storage.Cat{ID: storage.NewUUID(), Name: "Deworming Cat"}
+	if err := st.DB.Create(&cat).Error; err != nil {
+		t.Fatalf("create cat: %v", err)
+	}
+	planned := time.Date(2026, time.January, 10, 10, 0, 0, 0, time.UTC)
+	rec := storage.Record{ID: storage.NewUUID(), CatID: cat.ID, Type: "medication", PlannedAt: &planned, RRule: "FREQ=MONTHLY;INTERVAL=3", RecurrenceAnchor: storage.RecurrenceAnchorDone}
+	if err := st.DB.Create(&rec).Error; err != nil {
+		t.Fatalf("create record: %v", err)
+	}
+	_, anyOut, err := s.markRecordDone(ctx, nil, MarkRecordDoneArgs{ID: rec.ID, CatID: cat.ID})
+	if err != nil {
+		t.Fatalf("markRecordDone: %v", err)
+	}
+	if done := anyOut.(storage.Record); done.ID == rec.ID || done.DoneAt == nil {
+		t.Fatalf("expected a new done record, got %+v", done)
+	}
+	var plan storage.Record
+	if err := st.DB.First(&plan, "id = ?", rec.ID).Error; err != nil {
+		t.Fatalf("load plan: %v", err)
+	}
+	if plan.DoneAt != nil || plan.PlannedAt == nil || !plan.PlannedAt.After(time.Now().AddDate(0, 2, 27)) || plan.PlannedAt.Hour() != 10 {
+		t.Fatalf("plan was not moved on: %+v", plan)
+	}
+}
+
+func TestMCPMarkRecordDoneOtherOrganization(t *testing.T) {
+	st := newTestStore(t)
+	s, _ := New(st)
+	ctx := context.WithValue(context.Background(), logging.ContextUserID, "u-outsider")
+
+	org := storage.Organization{ID: storage.NewUUID(), Name: "Closed Shelter", Slug: "closed-shelter-mcp"}
+	if err := st.DB.Create(&org).Error; err != nil {
+		t.Fatalf("create organization: %v", err)
+	}
+	cat := storage.Cat{ID: storage.NewUUID(), OrganizationID: org.ID, Name: "Hidden Cat"}
+	if err := st.DB.Create(&cat).Error; err != nil {
+		t.Fatalf("create cat: %v", err)
+	}
+	planned := time.Now().Add(time.Hour)
+	rec := storage.Record{ID: storage.NewUUID(), CatID: cat.ID, Type: "feeding", PlannedAt: &planned}
+	if err := st.DB.Create(&rec).Error; err != nil {
+		t.Fatalf("create record: %v", err)
+	}
+
+	_, out, err := s.markRecordDone(ctx, nil, MarkRecordDoneArgs{ID: rec.ID})
+	if err == nil || out != nil {
+		t.Fatalf("expected records of other organizations to be out of reach, got %v", out)
+	}
+	var got storage.Record
+	st.DB.First(&got, "id = ?", rec.ID)
+	if got.DoneAt != nil {
+		t.Fatalf("record of another organization was marked done")
+	}
+	var audits int64
+	st.DB.Model(&storage.AuditLog{}).Where("target_id = ? AND status = ?", rec.ID, "success").Count(&audits)
+	if audits != 0 {
+		t.Fatalf("expected no successful audit entry, got %d", audits)
+	}
+}
@@ -68,9 +68,17 @@ var (
 		Namespace: "catwatch",
 		Subsystem: "images",
 		Name:      "pruned_total",
-		Help:      "Number of images deleted by cleanup worker",
+		Help:      "Number of images moved to the trash by cleanup worker",
 	})
 
+	// Image URL fetcher: downloads by result (stored, retry, failed)
+	ImageFetchTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "catwatch",
+		Subsystem: "images",
+		Name:      "fetch_total",
+		Help:      "Number of external image downloads by result",
+	}, []string{"result"})
+
 	// Cat condition gauge: 1..5 per cat
 	CatCondition = prometheus.NewGaugeVec(prometheus.GaugeOpts{
 		Namespace: "catwatch",
@@ -94,6 +102,14 @@ var (
 		Name:      "total",
 		Help:      "Total number of cat records by type and cat_id",
 	}, []string{"cat_id", "type"})
+
+	// Overdue procedures gauge: planned records past their time and not done, by type
+	RecordsOverdue = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "catwatch",
+		Subsystem: "records",
+		Name:      "overdue",
+		Help:      "Number of overdue planned procedures by type",
+	}, []string{"type"})
 )
 
 // Init initializes metrics and registers collectors (idempotent).
@@ -112,9 +128,11 @@ func Init() {
 	prometheus.MustRegister(ImageOptDecodeErrors)
 	prometheus.MustRegister(ImageOptDBErrors)
 	prometheus.MustRegister(ImagesPrunedTotal)
+	prometheus.MustRegister(ImageFetchTotal)
 	prometheus.MustRegister(CatCondition)
 	prometheus.MustRegister(CatLikes)
 	prometheus.MustRegister(RecordsTotal)
+	prometheus.MustRegister(RecordsOverdue)
 	initOnce.done = true
 }
 
@@ -146,3 +164,11 @@ func SetCatLikes(catID string, likes int) {
 func IncRecord(recordType, catID string) {
 	RecordsTotal.WithLabelValues(catID, recordType).Inc()
 }
+
+// SetOverdueRecords replaces the overdue gauge with the given counts per record type
+func SetOverdueRecords(counts map[string]int) {
+	RecordsOverdue.Reset()
+	for recordType, n := range counts {
+		RecordsOverdue.WithLabelValues(recordType).Set(float64(n))
+	}
+}
@@ -0,0 +1,121 @@
+package recurrence
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func days(ts []time.Time) []string {
+	out := make([]string, len(ts))
+	for i, t := range ts {
+		out[i] = t.Format(DayLayout)
+	}
+	return out
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestParse(t *testing.T) {
+	r, err := Parse("RRULE:freq=monthly;byday=1SA;count=3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := r.String(); got != "FREQ=MONTHLY;COUNT=3;BYDAY=1SA" {
+		t.Fatalf("canonical form: %s", got)
+	}
+	for _, bad := range []string{
+		"",
+		"FREQ=HOURLY",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;COUNT=2;UNTIL=20260101",
+		"FREQ=WEEKLY;BYDAY=1MO",
+		"FREQ=WEEKLY;BYMONTHDAY=1",
+		"FREQ=DAILY;BYHOUR=9",
+		"FREQ=DAILY;FREQ=WEEKLY",
+		"FREQ=MONTHLY;BYSETPOS=1",
+		"FREQ=MONTHLY;BYDAY=XX",
+	} {
+		if _, err := Parse(bad); !errors.Is(err, ErrInvalidRule) {
+			t.Errorf("%q: expected an invalid rule, got %v", bad, err)
+		}
+	}
+}
+
+func TestBetween(t *testing.T) {
+	loc := time.FixedZone("MSK", 3*3600)
+	// Monday, 5 January 2026, 09:30
+	start := time.Date(2026, time.January, 5, 9, 30, 0, 0, loc)
+	cases := []struct {
+		rule string
+		to   time.Time
+		want []string
+	}{
+		{"FREQ=WEEKLY;BYDAY=MO,WE,FR", start.AddDate(0, 0, 9), []string{"20260105", "20260107", "20260109", "20260112", "20260114"}},
+		{"FREQ=MONTHLY;BYDAY=1SA", start.AddDate(0, 3, 0), []string{"20260105", "20260207", "20260307", "20260404"}},
+		{"FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1", start.AddDate(0, 2, 0), []string{"20260105", "20260130", "20260227"}},
+		{"FREQ=MONTHLY;BYMONTHDAY=-1;COUNT=3", start.AddDate(1, 0, 0), []string{"20260105", "20260131", "20260228"}},
+		{"FREQ=DAILY;INTERVAL=2;UNTIL=20260109T063000Z", start.AddDate(0, 1, 0), []string{"20260105", "20260107", "20260109"}},
+		{"FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=29", start.AddDate(9, 0, 0), []string{"20260105", "20280229", "20320229"}},
+		{"FREQ=WEEKLY;INTERVAL=2;WKST=SU;BYDAY=TU,SU", start.AddDate(0, 0, 20), []string{"20260105", "20260106", "20260118", "20260120"}},
+	}
+	for _, c := range cases {
+		r, err := Parse(c.rule)
+		if err != nil {
+			t.Fatalf("%s: %v", c.rule, err)
+		}
+		got := Set{Start: start, Rule: r}.Between(start, c.to)
+		if !equal(days(got), c.want) {
+			t.Errorf("%s: got %v, want %v", c.rule, days(got), c.want)
+		}
+		for _, o := range got {
+			if o.Hour() != 9 || o.Minute() != 30 || o.Location() != loc {
+				t.Errorf("%s: occurrence %v lost the start time", c.rule, o)
+			}
+		}
+	}
+}
+
+func TestExDatesAndRDates(t *testing.T) {
+	start := time.Date(2026, time.January, 6, 18, 0, 0, 0, time.UTC)
+	set := Set{
+		Start:   start,
+		Rule:    FromLegacy("weekly", 1, nil),
+		ExDates: []time.Time{time.Date(2026, time.January, 13, 0, 0, 0, 0, time.UTC)},
+		RDates:  []time.Time{time.Date(2026, time.January, 15, 10, 0, 0, 0, time.UTC), time.Date(2026, time.January, 20, 9, 0, 0, 0, time.UTC)},
+	}
+	got := days(set.Between(start.AddDate(0, 0, 1), start.AddDate(0, 0, 21)))
+	if want := []string{"20260115", "20260120", "20260127"}; !equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if _, ok := set.On("20260113"); ok {
+		t.Fatal("excluded day is still an occurrence")
+	}
+	if o, ok := set.On("20260120"); !ok || o.Hour() != 18 {
+		t.Fatalf("rule occurrence should win over an RDATE on the same day: %v %v", o, ok)
+	}
+	if _, ok := set.On("20260121"); ok {
+		t.Fatal("unexpected occurrence")
+	}
+}
+
+func TestNeverMatchingRuleStops(t *testing.T) {
+	r, err := Parse("FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)
+	if got := (Set{Start: start, Rule: r}).Between(start.AddDate(0, 0, 1), start.AddDate(1000, 0, 0)); len(got) != 0 {
+		t.Fatalf("unexpected occurrences: %v", days(got))
+	}
+}
@@ -0,0 +1,284 @@
+// Package recurrence expands RFC 5545 recurrence rules (RRULE) together with EXDATE and RDATE
+// lists. It covers what planned records need: DAILY, WEEKLY, MONTHLY and YEARLY rules with
+// INTERVAL, COUNT, UNTIL, BYDAY (with ordinals such as 1SA or -1FR), BYMONTHDAY, BYMONTH,
+// BYSETPOS and WKST. Occurrences are whole days at the time of day of the first one, so a rule
+// yields at most one occurrence per day and occurrences can be identified by their date.
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequency is the FREQ of a rule.
+type Frequency int
+
+const (
+	Daily Frequency = iota + 1
+	Weekly
+	Monthly
+	Yearly
+)
+
+var freqNames = map[Frequency]string{Daily: "DAILY", Weekly: "WEEKLY", Monthly: "MONTHLY", Yearly: "YEARLY"}
+
+func (f Frequency) String() string { return freqNames[f] }
+
+var dayNames = [...]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// WeekdayNum is an entry of BYDAY: a weekday, or with N set the Nth such weekday of the month
+// or year (negative N counts from the end).
+type WeekdayNum struct {
+	N   int
+	Day time.Weekday
+}
+
+func (w WeekdayNum) String() string {
+	if w.N == 0 {
+		return dayNames[w.Day]
+	}
+	return strconv.Itoa(w.N) + dayNames[w.Day]
+}
+
+// Rule is a parsed RRULE.
+type Rule struct {
+	Freq       Frequency
+	Interval   int       // at least 1
+	Count      int       // 0 means no limit
+	Until      time.Time // zero means no end; inclusive
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByMonth    []time.Month
+	BySetPos   []int
+	WeekStart  time.Weekday
+}
+
+// ErrInvalidRule is wrapped by the errors of Parse.
+var ErrInvalidRule = errors.New("invalid recurrence rule")
+
+func invalid(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrInvalidRule, fmt.Sprintf(format, args...))
+}
+
+// Parse reads an RRULE value such as "FREQ=WEEKLY;BYDAY=MO,WE,FR". The "RRULE:" prefix is optional.
+func Parse(s string) (*Rule, error) {
+	s = strings.TrimSpace(s)
+	if len(s) >= 6 && strings.EqualFold(s[:6], "RRULE:") {
+		s = s[6:]
+	}
+	r := &Rule{Interval: 1, WeekStart: time.Monday}
+	seen := map[string]bool{}
+	for _, part := range strings.Split(s, ";") {
+		if part == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(part, "=")
+		key = strings.ToUpper(strings.TrimSpace(key))
+		val = strings.ToUpper(strings.TrimSpace(val))
+		if !ok || val == "" {
+			return nil, invalid("%q is not KEY=VALUE", part)
+		}
+		if seen[key] {
+			return nil, invalid("%s given twice", key)
+		}
+		seen[key] = true
+		var err error
+		switch key {
+		case "FREQ":
+			r.Freq = 0
+			for f, name := range freqNames {
+				if name == val {
+					r.Freq = f
+				}
+			}
+			if r.Freq == 0 {
+				err = invalid("unsupported frequency %s", val)
+			}
+		case "INTERVAL":
+			r.Interval, err = positive(key, val)
+		case "COUNT":
+			r.Count, err = positive(key, val)
+		case "UNTIL":
+			r.Until, err = ParseTime(val, time.UTC)
+		case "BYDAY":
+			r.ByDay, err = parseByDay(val)
+		case "BYMONTHDAY":
+			r.ByMonthDay, err = parseInts(key, val, 31)
+		case "BYMONTH":
+			var months []int
+			months, err = parseInts(key, val, 12)
+			for _, m := range months {
+				if m < 0 {
+					err = invalid("BYMONTH must be 1 to 12")
+				}
+				r.ByMonth = append(r.ByMonth, time.Month(m))
+			}
+		case "BYSETPOS":
+			r.BySetPos, err = parseInts(key, val, 366)
+		case "WKST":
+			var d WeekdayNum
+			d, err = parseWeekday(val)
+			if d.N != 0 {
+				err = invalid("WKST takes a weekday")
+			}
+			r.WeekStart = d.Day
+		default:
+			err = invalid("unsupported part %s", key)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return r, r.validate()
+}
+
+func (r *Rule) validate() error {
+	if r.Freq == 0 {
+		return invalid("FREQ is required")
+	}
+	if r.Count > 0 && !r.Until.IsZero() {
+		return invalid("COUNT and UNTIL cannot be combined")
+	}
+	for _, d := range r.ByDay {
+		if d.N != 0 && r.Freq != Monthly && r.Freq != Yearly {
+			return invalid("BYDAY ordinals need a MONTHLY or YEARLY rule")
+		}
+	}
+	if len(r.ByMonthDay) > 0 && r.Freq == Weekly {
+		return invalid("BYMONTHDAY cannot be used with a WEEKLY rule")
+	}
+	if len(r.BySetPos) > 0 && len(r.ByDay) == 0 && len(r.ByMonthDay) == 0 && len(r.ByMonth) == 0 {
+		return invalid("BYSETPOS needs another BYxxx part")
+	}
+	return nil
+}
+
+func positive(key, val string) (int, error) {
+	n, err := strconv.Atoi(val)
+	if err != nil || n < 1 {
+		return 0, invalid("%s must be a positive number", key)
+	}
+	return n, nil
+}
+
+// parseInts reads a list of non-zero numbers within ±limit.
+func parseInts(key, val string, limit int) ([]int, error) {
+	var out []int
+	for _, f := range strings.Split(val, ",") {
+		n, err := strconv.Atoi(f)
+		if err != nil || n == 0 || n > limit || n < -limit {
+			return nil, invalid("%s value %q is out of range", key, f)
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
+
+func parseByDay(val string) ([]WeekdayNum, error) {
+	var out []WeekdayNum
+	for _, f := range strings.Split(val, ",") {
+		d, err := parseWeekday(f)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, nil
+}
+
+func parseWeekday(s string) (WeekdayNum, error) {
+	if len(s) < 2 {
+		return WeekdayNum{}, invalid("unknown weekday %q", s)
+	}
+	i := slices.Index(dayNames[:], s[len(s)-2:])
+	if i < 0 {
+		return WeekdayNum{}, invalid("unknown weekday %q", s)
+	}
+	w := WeekdayNum{Day: time.Weekday(i)}
+	if num := s[:len(s)-2]; num != "" {
+		n, err := strconv.Atoi(num)
+		if err != nil || n == 0 || n > 53 || n < -53 {
+			return WeekdayNum{}, invalid("bad ordinal in %q", s)
+		}
+		w.N = n
+	}
+	return w, nil
+}
+
+// ParseTime reads an iCalendar DATE or DATE-TIME value ("20260105", "20260105T090000" or
+// "20260105T090000Z"). Values without Z are read in loc.
+func ParseTime(s string, loc *time.Location) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102T150405", "20060102"} {
+		if len(s) != len(layout) {
+			continue
+		}
+		if strings.HasSuffix(layout, "Z") {
+			if t, err := time.Parse(layout, s); err == nil {
+				return t, nil
+			}
+			continue
+		}
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, invalid("bad date %q", s)
+}
+
+// String formats the rule as an RRULE value without the "RRULE:" prefix.
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + r.Freq.String()}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	join := func(key string, n int, item func(int) string) {
+		if n == 0 {
+			return
+		}
+		items := make([]string, n)
+		for i := range items {
+			items[i] = item(i)
+		}
+		parts = append(parts, key+"="+strings.Join(items, ","))
+	}
+	join("BYMONTH", len(r.ByMonth), func(i int) string { return strconv.Itoa(int(r.ByMonth[i])) })
+	join("BYMONTHDAY", len(r.ByMonthDay), func(i int) string { return strconv.Itoa(r.ByMonthDay[i]) })
+	join("BYDAY", len(r.ByDay), func(i int) string { return r.ByDay[i].String() })
+	join("BYSETPOS", len(r.BySetPos), func(i int) string { return strconv.Itoa(r.BySetPos[i]) })
+	if r.WeekStart != time.Monday {
+		parts = append(parts, "WKST="+dayNames[r.WeekStart])
+	}
+	return strings.Join(parts, ";")
+}
+
+// FromLegacy converts the older daily/weekly/monthly/yearly form with an interval and an
+// optional inclusive end into a rule. It returns nil for an unknown frequency.
+func FromLegacy(freq string, interval int, until *time.Time) *Rule {
+	r := &Rule{Interval: max(interval, 1), WeekStart: time.Monday}
+	switch strings.ToLower(strings.TrimSpace(freq)) {
+	case "daily":
+		r.Freq = Daily
+	case "weekly":
+		r.Freq = Weekly
+	case "monthly":
+		r.Freq = Monthly
+	case "yearly":
+		r.Freq = Yearly
+	default:
+		return nil
+	}
+	if until != nil {
+		r.Until = until.UTC()
+	}
+	return r
+}
@@ -0,0 +1,290 @@
+package recurrence
+
+import (
+	"slices"
+	"time"
+)
+
+// DayLayout formats the date part of occurrence identifiers.
+const DayLayout = "20060102"
+
+// MaxOccurrences caps the number of occurrences returned by Set.Between.
+const MaxOccurrences = 1000
+
+// horizonYears stops the expansion of rules that never match, e.g. BYMONTH=2;BYMONTHDAY=30.
+const horizonYears = 500
+
+// Set is a recurrence set: the rule starting at Start (DTSTART, which is always the first
+// occurrence), plus the RDates and minus the days listed in ExDates. Occurrences keep the time of
+// day and location of Start; EXDATE matches a whole day in that location.
+type Set struct {
+	Start   time.Time
+	Rule    *Rule
+	ExDates []time.Time
+	RDates  []time.Time
+}
+
+// DayKey returns the identifier of the day of t in the location of the set.
+func (s Set) DayKey(t time.Time) string {
+	return t.In(s.Start.Location()).Format(DayLayout)
+}
+
+// Between returns the occurrences within [from, to], sorted, at most one per day.
+func (s Set) Between(from, to time.Time) []time.Time {
+	excluded := map[string]bool{}
+	for _, d := range s.ExDates {
+		excluded[s.DayKey(d)] = true
+	}
+	seen := map[string]bool{}
+	var out []time.Time
+	add := func(t time.Time) {
+		key := s.DayKey(t)
+		if excluded[key] || seen[key] || t.Before(from) || t.After(to) {
+			return
+		}
+		seen[key] = true
+		out = append(out, t)
+	}
+	s.each(func(t time.Time) bool {
+		if t.After(to) || len(out) >= MaxOccurrences {
+			return false
+		}
+		add(t)
+		return true
+	})
+	for _, d := range s.RDates {
+		add(d.In(s.Start.Location()))
+	}
+	slices.SortFunc(out, func(a, b time.Time) int { return a.Compare(b) })
+	if len(out) > MaxOccurrences {
+		out = out[:MaxOccurrences]
+	}
+	return out
+}
+
+// On returns the occurrence on the day identified by key (see DayKey), if there is one.
+func (s Set) On(key string) (time.Time, bool) {
+	day, err := time.ParseInLocation(DayLayout, key, s.Start.Location())
+	if err != nil {
+		return time.Time{}, false
+	}
+	occ := s.Between(day, day.AddDate(0, 0, 1).Add(-time.Nanosecond))
+	if len(occ) == 0 {
+		return time.Time{}, false
+	}
+	return occ[0], true
+}
+
+// each calls fn with the occurrences of the rule in order, DTSTART first, until fn returns false
+// or the rule ends. EXDATE and RDATE are not applied.
+func (s Set) each(fn func(time.Time) bool) {
+	r := s.Rule
+	startDay := civil(s.Start)
+	at := func(c time.Time) time.Time {
+		return time.Date(c.Year(), c.Month(), c.Day(), s.Start.Hour(), s.Start.Minute(), s.Start.Second(), s.Start.Nanosecond(), s.Start.Location())
+	}
+	emitted := 0
+	emit := func(c time.Time) bool {
+		t := at(c)
+		if !r.Until.IsZero() && t.After(r.Until) {
+			return false
+		}
+		emitted++
+		if !fn(t) {
+			return false
+		}
+		return r.Count == 0 || emitted < r.Count
+	}
+	if !emit(startDay) {
+		return
+	}
+	horizon := startDay.AddDate(horizonYears, 0, 0)
+	for k := 0; ; k++ {
+		periodStart, days := r.period(startDay, k)
+		if periodStart.After(horizon) {
+			return
+		}
+		for _, c := range days {
+			if !c.After(startDay) {
+				continue
+			}
+			if !emit(c) {
+				return
+			}
+		}
+	}
+}
+
+// period returns the first day of the k-th period of the rule and its occurrence days, sorted.
+// Days are civil dates at midnight UTC.
+func (r *Rule) period(startDay time.Time, k int) (time.Time, []time.Time) {
+	step := k * r.Interval
+	var first time.Time
+	var days []time.Time
+	switch r.Freq {
+	case Daily:
+		first = startDay.AddDate(0, 0, step)
+		if r.matchesDay(first) {
+			days = []time.Time{first}
+		}
+	case Weekly:
+		back := (int(startDay.Weekday()) - int(r.WeekStart) + 7) % 7
+		first = startDay.AddDate(0, 0, 7*step-back)
+		for i := range 7 {
+			d := first.AddDate(0, 0, i)
+			if r.matchesWeekday(d, startDay) && r.matchesMonth(d.Month()) {
+				days = append(days, d)
+			}
+		}
+	case Monthly:
+		first = time.Date(startDay.Year(), startDay.Month()+time.Month(step), 1, 0, 0, 0, 0, time.UTC)
+		if r.matchesMonth(first.Month()) {
+			days = r.monthDays(first.Year(), first.Month(), startDay.Day())
+		}
+	case Yearly:
+		first = time.Date(startDay.Year()+step, time.January, 1, 0, 0, 0, 0, time.UTC)
+		days = r.yearDays(first.Year(), startDay)
+	}
+	return first, r.setPos(days)
+}
+
+// matchesDay applies the BYxxx filters of a DAILY rule.
+func (r *Rule) matchesDay(d time.Time) bool {
+	if !r.matchesMonth(d.Month()) {
+		return false
+	}
+	if len(r.ByMonthDay) > 0 && !slices.Contains(resolveMonthDays(r.ByMonthDay, d.Year(), d.Month()), d.Day()) {
+		return false
+	}
+	if len(r.ByDay) > 0 && !slices.ContainsFunc(r.ByDay, func(w WeekdayNum) bool { return w.Day == d.Weekday() }) {
+		return false
+	}
+	return true
+}
+
+func (r *Rule) matchesWeekday(d, startDay time.Time) bool {
+	if len(r.ByDay) == 0 {
+		return d.Weekday() == startDay.Weekday()
+	}
+	return slices.ContainsFunc(r.ByDay, func(w WeekdayNum) bool { return w.Day == d.Weekday() })
+}
+
+func (r *Rule) matchesMonth(m time.Month) bool {
+	return len(r.ByMonth) == 0 || slices.Contains(r.ByMonth, m)
+}
+
+// monthDays returns the days of the month selected by BYMONTHDAY and BYDAY (both when both are
+// set), or defaultDay when neither is.
+func (r *Rule) monthDays(y int, m time.Month, defaultDay int) []time.Time {
+	n := daysIn(y, m)
+	var days []time.Time
+	for d := 1; d <= n; d++ {
+		ok := d == defaultDay
+		if len(r.ByMonthDay) > 0 || len(r.ByDay) > 0 {
+			ok = true
+			if len(r.ByMonthDay) > 0 {
+				ok = slices.Contains(resolveMonthDays(r.ByMonthDay, y, m), d)
+			}
+			if ok && len(r.ByDay) > 0 {
+				ok = matchesNth(r.ByDay, time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Weekday(), d, n)
+			}
+		}
+		if ok {
+			days = append(days, time.Date(y, m, d, 0, 0, 0, 0, time.UTC))
+		}
+	}
+	return days
+}
+
+// yearDays returns the days of a YEARLY period. BYDAY ordinals count within the year unless
+// BYMONTH or BYMONTHDAY narrow the rule to months.
+func (r *Rule) yearDays(y int, startDay time.Time) []time.Time {
+	if len(r.ByDay) > 0 && len(r.ByMonth) == 0 && len(r.ByMonthDay) == 0 {
+		jan1 := time.Date(y, time.January, 1, 0, 0, 0, 0, time.UTC)
+		n := int(jan1.AddDate(1, 0, 0).Sub(jan1).Hours() / 24)
+		var days []time.Time
+		for i := 1; i <= n; i++ {
+			d := jan1.AddDate(0, 0, i-1)
+			if matchesNth(r.ByDay, d.Weekday(), i, n) {
+				days = append(days, d)
+			}
+		}
+		return days
+	}
+	months := r.ByMonth
+	if len(months) == 0 {
+		if len(r.ByMonthDay) > 0 || len(r.ByDay) > 0 {
+			months = []time.Month{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}
+		} else {
+			months = []time.Month{startDay.Month()}
+		}
+	}
+	months = slices.Sorted(slices.Values(months))
+	var days []time.Time
+	for _, m := range slices.Compact(months) {
+		days = append(days, r.monthDays(y, m, startDay.Day())...)
+	}
+	return days
+}
+
+// setPos applies BYSETPOS to the sorted days of a period.
+func (r *Rule) setPos(days []time.Time) []time.Time {
+	if len(r.BySetPos) == 0 || len(days) == 0 {
+		return days
+	}
+	var out []time.Time
+	for _, p := range r.BySetPos {
+		i := p - 1
+		if p < 0 {
+			i = len(days) + p
+		}
+		if i >= 0 && i < len(days) && !slices.ContainsFunc(out, days[i].Equal) {
+			out = append(out, days[i])
+		}
+	}
+	slices.SortFunc(out, func(a, b time.Time) int { return a.Compare(b) })
+	return out
+}
+
+// matchesNth reports whether the i-th day (1-based) of a span of n days, falling on wd, is
+// selected by byDay. Ordinals count occurrences of the weekday within the span.
+func matchesNth(byDay []WeekdayNum, wd time.Weekday, i, n int) bool {
+	for _, w := range byDay {
+		if w.Day != wd {
+			continue
+		}
+		switch {
+		case w.N == 0:
+			return true
+		case w.N > 0 && (i-1)/7+1 == w.N:
+			return true
+		case w.N < 0 && (n-i)/7+1 == -w.N:
+			return true
+		}
+	}
+	return false
+}
+
+func resolveMonthDays(byMonthDay []int, y int, m time.Month) []int {
+	n := daysIn(y, m)
+	var out []int
+	for _, d := range byMonthDay {
+		if d < 0 {
+			d = n + d + 1
+		}
+		if d >= 1 && d <= n {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+func daysIn(y int, m time.Month) int {
+	return time.Date(y, m+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// civil returns the calendar date of t (in its own location) as midnight UTC.
+func civil(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,179 @@
+package storage
+
+import (
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// auditIgnoredFields change on every write and only add noise to diffs.
+var auditIgnoredFields = map[string]bool{
+	"updated_at": true,
+}
+
+// AuditDelta returns a JSON object with the fields that differ between before and after:
+//
+//	{"condition": {"before": 3, "after": 1}}
+//
+// Either side may be nil: creations only carry "after", deletions only "before", and
+// empty values are omitted for them. Values are compared by their JSON representation.
+func AuditDelta(before, after any) (string, error) {
+	b, err := auditFields(before)
+	if err != nil {
+		return "", err
+	}
+	a, err := auditFields(after)
+	if err != nil {
+		return "", err
+	}
+
+	delta := map[string]map[string]any{}
+	for k, av := range a {
+		bv, ok := b[k]
+		switch {
+		case before == nil:
+			if !auditEmpty(av) {
+				delta[k] = map[string]any{"after": av}
+			}
+		case !ok || !reflect.DeepEqual(av, bv):
+			delta[k] = map[string]any{"before": bv, "after": av}
+		}
+	}
+	for k, bv := range b {
+		if _, ok := a[k]; ok {
+			continue
+		}
+		if after == nil {
+			if !auditEmpty(bv) {
+				delta[k] = map[string]any{"before": bv}
+			}
+			continue
+		}
+		delta[k] = map[string]any{"before": bv, "after": nil}
+	}
+	if len(delta) == 0 {
+		return "", nil
+	}
+	out, err := json.Marshal(delta)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+func auditFields(v any) (map[string]any, error) {
+	fields := map[string]any{}
+	if v == nil || (reflect.ValueOf(v).Kind() == reflect.Pointer && reflect.ValueOf(v).IsNil()) {
+		return fields, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		// Not a JSON object: record the value as a whole
+		var raw any
+		_ = json.Unmarshal(data, &raw)
+		return map[string]any{"value": raw}, nil
+	}
+	for k := range auditIgnoredFields {
+		delete(fields, k)
+	}
+	return fields, nil
+}
+
+func auditEmpty(v any) bool {
+	switch t := v.(type) {
+	case nil:
+		return true
+	case string:
+		return t == ""
+	case float64:
+		return t == 0
+	case bool:
+		return !t
+	case []any:
+		return len(t) == 0
+	case map[string]any:
+		return len(t) == 0
+	}
+	return false
+}
+
+// AuditFilter selects audit log entries. Empty fields do not filter.
+type AuditFilter struct {
+	TargetType string
+	TargetID   string
+	UserID     string
+	Route      string
+	Status     string // success or error
+	StatusCode int
+	Since      *time.Time
+	Until      *time.Time
+	// Scope limits entries to changes of cats, their records, photos and locations, and
+	// organizations within it; nil returns every entry.
+	Scope *OrgScope
+
+	// Keyset cursor: entries strictly older than (AfterTimestamp, AfterID) in the
+	// newest-first order are returned.
+	AfterTimestamp time.Time
+	AfterID        string
+
+	Limit int
+}
+
+// auditScope matches entries whose target belongs to one of the organizations of the scope.
+// Deleted cats and photos still count, so their history stays visible.
+func (s *Store) auditScope(scope OrgScope) *gorm.DB {
+	cats := s.DB.Unscoped().Model(&Cat{}).Select("id").Where("organization_id IN ?", scope.IDs)
+	return s.DB.Where("target_type = ? AND target_id IN (?)", "cat", cats).
+		Or("target_type = ? AND target_id IN (?)", "record", s.DB.Model(&Record{}).Select("id").Where("cat_id IN (?)", cats)).
+		Or("target_type = ? AND target_id IN (?)", "image", s.DB.Unscoped().Model(&Image{}).Select("id").Where("cat_id IN (?)", cats)).
+		Or("target_type = ? AND target_id IN (?)", "location", s.DB.Model(&CatLocation{}).Select("id").Where("cat_id IN (?)", cats)).
+		Or("target_type = ? AND target_id IN ?", "organization", scope.IDs)
+}
+
+// QueryAuditLogs returns entries matching the filter, newest first.
+// The (timestamp, id) pair of the last entry continues the listing via AfterTimestamp/AfterID.
+func (s *Store) QueryAuditLogs(f AuditFilter) ([]AuditLog, error) {
+	db := s.DB.Model(&AuditLog{})
+	if f.TargetType != "" {
+		db = db.Where("target_type = ?", f.TargetType)
+	}
+	if f.TargetID != "" {
+		db = db.Where("target_id = ?", f.TargetID)
+	}
+	if f.UserID != "" {
+		db = db.Where("user_id = ?", f.UserID)
+	}
+	if f.Route != "" {
+		db = db.Where("route = ?", f.Route)
+	}
+	if f.Status != "" {
+		db = db.Where("status = ?", f.Status)
+	}
+	if f.StatusCode != 0 {
+		db = db.Where("status_code = ?", f.StatusCode)
+	}
+	if f.Scope != nil && !f.Scope.All {
+		db = db.Where(s.auditScope(*f.Scope))
+	}
+	if f.Since != nil {
+		db = db.Where("timestamp >= ?", f.Since.UTC())
+	}
+	if f.Until != nil {
+		db = db.Where("timestamp < ?", f.Until.UTC())
+	}
+	if f.AfterID != "" {
+		ts := f.AfterTimestamp.UTC()
+		db = db.Where("timestamp < ? OR (timestamp = ? AND id < ?)", ts, ts, f.AfterID)
+	}
+	if f.Limit > 0 {
+		db = db.Limit(f.Limit)
+	}
+	var logs []AuditLog
+	err := db.Order("timestamp DESC").Order("id DESC").Find(&logs).Error
+	return logs, err
+}
@@ -0,0 +1,299 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// The audit log is a hash chain: every entry gets the next sequence number, the hash of
+// the previous entry and a hash over its own content including that previous hash.
+// Modifying, inserting or deleting a row therefore breaks the chain at that point.
+//
+// Pruning deletes a prefix of the chain. Before deleting, a checkpoint entry is appended
+// that names the last pruned entry (sequence and hash) and is signed with an HMAC key,
+// so the remaining chain stays verifiable from its new start.
+
+// AuditKindCheckpoint marks checkpoint entries written by PruneAuditLogs.
+const AuditKindCheckpoint = "checkpoint"
+
+// auditWriteRetries bounds retries when concurrent writers race for the same sequence number.
+const auditWriteRetries = 5
+
+// AuditCheckpoint is the Delta payload of a checkpoint entry.
+type AuditCheckpoint struct {
+	PrunedThroughSeq  int64  `json:"pruned_through_seq"`
+	PrunedThroughHash string `json:"pruned_through_hash"`
+	PrunedCount       int64  `json:"pruned_count"`
+}
+
+// ComputeHash returns the chain hash of the entry (hex SHA-256). The signature is not covered.
+func (e *AuditLog) ComputeHash() string {
+	var seq int64
+	if e.Seq != nil {
+		seq = *e.Seq
+	}
+	// A JSON array keeps field boundaries unambiguous
+	content, _ := json.Marshal([]string{
+		strconv.FormatInt(seq, 10),
+		e.ID,
+		e.Timestamp.UTC().Format(time.RFC3339Nano),
+		e.UserID,
+		e.Method,
+		e.Route,
+		e.TargetType,
+		e.TargetID,
+		e.Status,
+		strconv.Itoa(e.StatusCode),
+		e.RequestID,
+		e.Delta,
+		e.Kind,
+		e.PrevHash,
+	})
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+func signAuditHash(key []byte, hash string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(hash))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// WriteAudit appends an entry to the audit chain, filling in the ID and timestamp when missing.
+func (s *Store) WriteAudit(entry *AuditLog) error {
+	if entry.ID == "" {
+		entry.ID = NewUUID()
+	}
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+	// Postgres keeps microseconds; hash what will be read back
+	entry.Timestamp = entry.Timestamp.UTC().Truncate(time.Microsecond)
+
+	var err error
+	for attempt := 0; attempt < auditWriteRetries; attempt++ {
+		if err = s.DB.Transaction(func(tx *gorm.DB) error {
+			return appendAudit(tx, entry)
+		}); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// appendAudit links the entry to the current head of the chain and inserts it.
+// The unique index on seq rejects concurrent writers that read the same head.
+func appendAudit(tx *gorm.DB, entry *AuditLog) error {
+	var head AuditLog
+	res := tx.Where("seq IS NOT NULL").Order("seq DESC").Limit(1).Find(&head)
+	if res.Error != nil {
+		return res.Error
+	}
+	seq := int64(1)
+	entry.PrevHash = ""
+	if res.RowsAffected > 0 {
+		seq = *head.Seq + 1
+		entry.PrevHash = head.Hash
+	}
+	entry.Seq = &seq
+	entry.Hash = entry.ComputeHash()
+	return tx.Create(entry).Error
+}
+
+// sealAuditLogs links entries written before the hash chain existed, oldest first.
+func (s *Store) sealAuditLogs() error {
+	var legacy []AuditLog
+	if err := s.DB.Where("seq IS NULL").Order("timestamp ASC").Order("id ASC").Find(&legacy).Error; err != nil {
+		return err
+	}
+	if len(legacy) == 0 {
+		return nil
+	}
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		var head AuditLog
+		if err := tx.Where("seq IS NOT NULL").Order("seq DESC").Limit(1).Find(&head).Error; err != nil {
+			return err
+		}
+		var seq int64
+		if head.Seq != nil {
+			seq = *head.Seq
+		}
+		prevHash := head.Hash
+		for i := range legacy {
+			e := &legacy[i]
+			seq++
+			e.Seq = &seq
+			e.PrevHash = prevHash
+			e.Hash = e.ComputeHash()
+			if err := tx.Model(e).Updates(map[string]any{"seq": seq, "prev_hash": e.PrevHash, "hash": e.Hash}).Error; err != nil {
+				return err
+			}
+			prevHash = e.Hash
+		}
+		return nil
+	})
+}
+
+// PruneAuditLogs deletes entries older than before. The deleted range is always a prefix of
+// the chain (up to the newest entry older than before), and a checkpoint signed with key is
+// appended first so that VerifyAuditChain can still validate the remaining entries.
+func (s *Store) PruneAuditLogs(before time.Time, key []byte) (int64, error) {
+	if len(key) == 0 {
+		return 0, errors.New("audit key is required to sign checkpoints")
+	}
+	var deleted int64
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		var last AuditLog
+		res := tx.Where("seq IS NOT NULL AND timestamp < ?", before.UTC()).Order("seq DESC").Limit(1).Find(&last)
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		var count int64
+		if err := tx.Model(&AuditLog{}).Where("seq <= ?", *last.Seq).Count(&count).Error; err != nil {
+			return err
+		}
+		delta, _ := json.Marshal(AuditCheckpoint{
+			PrunedThroughSeq:  *last.Seq,
+			PrunedThroughHash: last.Hash,
+			PrunedCount:       count,
+		})
+		cp := &AuditLog{
+			ID:         NewUUID(),
+			Timestamp:  time.Now().UTC().Truncate(time.Microsecond),
+			Method:     "PRUNE",
+			Route:      "audit",
+			TargetType: "audit",
+			Status:     "success",
+			Delta:      string(delta),
+			Kind:       AuditKindCheckpoint,
+		}
+		if err := appendAudit(tx, cp); err != nil {
+			return fmt.Errorf("write checkpoint: %w", err)
+		}
+		if err := tx.Model(cp).Update("signature", signAuditHash(key, cp.Hash)).Error; err != nil {
+			return err
+		}
+		res = tx.Where("seq <= ?", *last.Seq).Delete(&AuditLog{})
+		deleted = res.RowsAffected
+		return res.Error
+	})
+	return deleted, err
+}
+
+// Audit chain problem kinds reported by VerifyAuditChain.
+const (
+	AuditProblemGap          = "gap"           // missing sequence numbers
+	AuditProblemModified     = "modified"      // content does not match the stored hash
+	AuditProblemBrokenLink   = "broken_link"   // prev_hash does not match the previous entry
+	AuditProblemBadSignature = "bad_signature" // checkpoint signature is invalid
+	AuditProblemUnsealed     = "unsealed"      // entry is not part of the chain
+)
+
+// AuditProblem describes one inconsistency found in the audit chain.
+type AuditProblem struct {
+	Seq    int64
+	ID     string
+	Kind   string
+	Detail string
+}
+
+// AuditVerifyReport is the result of VerifyAuditChain.
+type AuditVerifyReport struct {
+	Checked     int64
+	FirstSeq    int64
+	LastSeq     int64
+	HeadHash    string
+	Checkpoints int
+	Problems    []AuditProblem
+}
+
+// OK reports whether the chain is intact.
+func (r *AuditVerifyReport) OK() bool { return len(r.Problems) == 0 }
+
+// VerifyAuditChain walks the audit chain in sequence order and reports gaps, modified rows,
+// broken links and invalid checkpoint signatures. Checkpoint signatures are only checked
+// when key is not empty.
+func (s *Store) VerifyAuditChain(key []byte) (*AuditVerifyReport, error) {
+	rep := &AuditVerifyReport{}
+
+	var unsealed []AuditLog
+	if err := s.DB.Select("id").Where("seq IS NULL").Find(&unsealed).Error; err != nil {
+		return nil, err
+	}
+	for _, e := range unsealed {
+		rep.Problems = append(rep.Problems, AuditProblem{ID: e.ID, Kind: AuditProblemUnsealed, Detail: "entry has no sequence number"})
+	}
+
+	var (
+		prev       *AuditLog
+		firstPrev  string
+		prunedTo   = map[int64]string{} // pruned_through_seq -> hash from valid checkpoints
+		afterSeq   int64
+		batchLimit = 1000
+	)
+	for {
+		var batch []AuditLog
+		if err := s.DB.Where("seq > ?", afterSeq).Order("seq ASC").Limit(batchLimit).Find(&batch).Error; err != nil {
+			return nil, err
+		}
+		for i := range batch {
+			e := batch[i]
+			seq := *e.Seq
+			rep.Checked++
+			if prev == nil {
+				rep.FirstSeq = seq
+				firstPrev = e.PrevHash
+			} else if seq != *prev.Seq+1 {
+				rep.Problems = append(rep.Problems, AuditProblem{Seq: seq, ID: e.ID, Kind: AuditProblemGap,
+					Detail: fmt.Sprintf("entries %d..%d are missing", *prev.Seq+1, seq-1)})
+			} else if e.PrevHash != prev.Hash {
+				rep.Problems = append(rep.Problems, AuditProblem{Seq: seq, ID: e.ID, Kind: AuditProblemBrokenLink,
+					Detail: "prev_hash does not match the previous entry"})
+			}
+			if e.ComputeHash() != e.Hash {
+				rep.Problems = append(rep.Problems, AuditProblem{Seq: seq, ID: e.ID, Kind: AuditProblemModified,
+					Detail: "content does not match the stored hash"})
+			}
+			if e.Kind == AuditKindCheckpoint {
+				rep.Checkpoints++
+				validSig := true
+				if len(key) > 0 && !hmac.Equal([]byte(signAuditHash(key, e.Hash)), []byte(e.Signature)) {
+					validSig = false
+					rep.Problems = append(rep.Problems, AuditProblem{Seq: seq, ID: e.ID, Kind: AuditProblemBadSignature,
+						Detail: "checkpoint signature is invalid"})
+				}
+				var cp AuditCheckpoint
+				if validSig && json.Unmarshal([]byte(e.Delta), &cp) == nil {
+					prunedTo[cp.PrunedThroughSeq] = cp.PrunedThroughHash
+				}
+			}
+			prev = &batch[i]
+			afterSeq = seq
+		}
+		if len(batch) < batchLimit {
+			break
+		}
+	}
+	if prev == nil {
+		return rep, nil
+	}
+	rep.LastSeq = *prev.Seq
+	rep.HeadHash = prev.Hash
+
+	// The chain must start at 1 or right after a pruned range recorded by a checkpoint
+	if rep.FirstSeq != 1 || firstPrev != "" {
+		if h, ok := prunedTo[rep.FirstSeq-1]; !ok || h != firstPrev {
+			rep.Problems = append(rep.Problems, AuditProblem{Seq: rep.FirstSeq, Kind: AuditProblemGap,
+				Detail: fmt.Sprintf("entries before %d are missing and not covered by a checkpoint", rep.FirstSeq)})
+		}
+	}
+	return rep, nil
+}
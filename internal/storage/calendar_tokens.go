@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// CalendarToken grants read access to the iCalendar feeds of a user's planned records. Only the
+// SHA-256 hash of the secret is stored; the secret is shown once, when the token is created.
+type CalendarToken struct {
+	ID         string     `gorm:"type:char(36);primaryKey" json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UserID     string     `gorm:"type:char(36);index" json:"user_id"`
+	TokenHash  string     `gorm:"size:64;uniqueIndex" json:"-"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+func hashCalendarToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateCalendarToken issues a calendar token for the user and returns it with its secret.
+func (s *Store) CreateCalendarToken(userID string) (*CalendarToken, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, "", err
+	}
+	secret := hex.EncodeToString(b)
+	t := &CalendarToken{ID: NewUUID(), UserID: userID, TokenHash: hashCalendarToken(secret)}
+	if err := s.DB.Create(t).Error; err != nil {
+		return nil, "", err
+	}
+	return t, secret, nil
+}
+
+// CalendarTokenUser returns the user a calendar token secret belongs to and records its use,
+// or gorm.ErrRecordNotFound for unknown and revoked tokens.
+func (s *Store) CalendarTokenUser(secret string) (*User, error) {
+	var t CalendarToken
+	if err := s.DB.Where("token_hash = ?", hashCalendarToken(secret)).First(&t).Error; err != nil {
+		return nil, err
+	}
+	var u User
+	if err := s.DB.First(&u, "id = ?", t.UserID).Error; err != nil {
+		return nil, err
+	}
+	s.DB.Model(&CalendarToken{}).Where("id = ?", t.ID).UpdateColumn("last_used_at", time.Now())
+	return &u, nil
+}
+
+// ListCalendarTokens returns the calendar tokens of a user, newest first.
+func (s *Store) ListCalendarTokens(userID string) ([]CalendarToken, error) {
+	tokens := []CalendarToken{}
+	err := s.DB.Where("user_id = ?", userID).Order("created_at DESC").Find(&tokens).Error
+	return tokens, err
+}
+
+// RevokeCalendarToken deletes a calendar token of the user and returns it, or
+// gorm.ErrRecordNotFound if the user has no such token.
+func (s *Store) RevokeCalendarToken(userID, id string) (*CalendarToken, error) {
+	var t CalendarToken
+	if err := s.DB.Where("id = ? AND user_id = ?", id, userID).First(&t).Error; err != nil {
+		return nil, err
+	}
+	if err := s.DB.Delete(&t).Error; err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"time"
+)
+
+// Sort keys accepted by QueryCats.
+const (
+	CatSortCreated   = "created_at"
+	CatSortUpdated   = "updated_at"
+	CatSortLastSeen  = "last_seen"
+	CatSortName      = "name"
+	CatSortCondition = "condition"
+)
+
+// catSortExprs maps sort keys to SQL expressions. Cats that were never seen sort by their creation time.
+var catSortExprs = map[string]string{
+	CatSortCreated:   "cats.created_at",
+	CatSortUpdated:   "cats.updated_at",
+	CatSortLastSeen:  "COALESCE(cats.last_seen, cats.created_at)",
+	CatSortName:      "cats.name",
+	CatSortCondition: "cats.condition",
+}
+
+// ValidCatSort reports whether key is a known sort key.
+func ValidCatSort(key string) bool {
+	_, ok := catSortExprs[key]
+	return ok
+}
+
+// CatFilter selects and orders cats for QueryCats. Zero values do not filter.
+type CatFilter struct {
+	Tags           []string // cats carrying all of these tag names
+	Gender         string
+	Sterilized     *bool
+	NeedAttention  *bool
+	ConditionMin   int
+	ConditionMax   int
+	LastSeenAfter  *time.Time // cats that were never seen do not match last_seen bounds
+	LastSeenBefore *time.Time
+	CreatedAfter   *time.Time
+	CreatedBefore  *time.Time
+
+	Sort string // one of the CatSort keys, CatSortCreated when empty
+	Desc bool
+
+	// Keyset cursor: cats following (AfterValue, AfterID) in the sort order are returned.
+	// AfterValue is the CatSortValue of the last cat of the previous page.
+	AfterValue any
+	AfterID    string
+
+	Limit int
+}
+
+// CatSortValue returns the value of the sort key for the cat, as compared by QueryCats.
+func CatSortValue(c *Cat, key string) any {
+	switch key {
+	case CatSortUpdated:
+		return c.UpdatedAt
+	case CatSortLastSeen:
+		if c.LastSeen != nil {
+			return *c.LastSeen
+		}
+		return c.CreatedAt
+	case CatSortName:
+		return c.Name
+	case CatSortCondition:
+		return c.Condition
+	default:
+		return c.CreatedAt
+	}
+}
+
+// QueryCats returns cats within the scope matching the filter, with locations, images (without data) and tags.
+func (s *Store) QueryCats(scope OrgScope, f CatFilter) ([]Cat, error) {
+	db := scope.Cats(s.DB.Model(&Cat{}))
+	for _, tag := range f.Tags {
+		db = db.Where("cats.id IN (SELECT cat_tags.cat_id FROM cat_tags JOIN tags ON tags.id = cat_tags.tag_id WHERE tags.name = ?)", tag)
+	}
+	if f.Gender != "" {
+		db = db.Where("cats.gender = ?", f.Gender)
+	}
+	if f.Sterilized != nil {
+		db = db.Where("cats.is_sterilized = ?", *f.Sterilized)
+	}
+	if f.NeedAttention != nil {
+		db = db.Where("cats.need_attention = ?", *f.NeedAttention)
+	}
+	if f.ConditionMin > 0 {
+		db = db.Where("cats.condition >= ?", f.ConditionMin)
+	}
+	if f.ConditionMax > 0 {
+		db = db.Where("cats.condition <= ?", f.ConditionMax)
+	}
+	if f.LastSeenAfter != nil {
+		db = db.Where("cats.last_seen >= ?", *f.LastSeenAfter)
+	}
+	if f.LastSeenBefore != nil {
+		db = db.Where("cats.last_seen < ?", *f.LastSeenBefore)
+	}
+	if f.CreatedAfter != nil {
+		db = db.Where("cats.created_at >= ?", *f.CreatedAfter)
+	}
+	if f.CreatedBefore != nil {
+		db = db.Where("cats.created_at < ?", *f.CreatedBefore)
+	}
+
+	expr, ok := catSortExprs[f.Sort]
+	if !ok {
+		expr = catSortExprs[CatSortCreated]
+	}
+	dir, cmp := "ASC", ">"
+	if f.Desc {
+		dir, cmp = "DESC", "<"
+	}
+	if f.AfterID != "" {
+		db = db.Where("("+expr+" "+cmp+" ? OR ("+expr+" = ? AND cats.id "+cmp+" ?))", f.AfterValue, f.AfterValue, f.AfterID)
+	}
+	if f.Limit > 0 {
+		db = db.Limit(f.Limit)
+	}
+
+	var cats []Cat
+	err := db.Preload("Locations").
+		Preload("Images", OrderedImages).
+		Preload("Tags").
+		Order(expr + " " + dir).Order("cats.id " + dir).
+		Find(&cats).Error
+	return cats, err
+}
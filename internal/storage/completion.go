@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/maniack/catwatch/internal/recurrence"
+	"gorm.io/gorm"
+)
+
+// Values of Record.RecurrenceAnchor.
+const (
+	// RecurrenceAnchorSchedule repeats on the calendar from planned_at (the default).
+	RecurrenceAnchorSchedule = "schedule"
+	// RecurrenceAnchorDone repeats from the time the previous occurrence was done,
+	// e.g. deworming every 3 months after the last one.
+	RecurrenceAnchorDone = "done"
+)
+
+// CompletionAnchored reports whether the record repeats from its completion.
+func (r Record) CompletionAnchored() bool {
+	return r.RecurrenceAnchor == RecurrenceAnchorDone && (r.RRule != "" || r.Recurrence != "")
+}
+
+// NextAfterDone returns the occurrence that follows a completion-anchored plan done at doneAt,
+// at the wall-clock time of planned_at in the plan's zone, and the rule left for it: COUNT counts
+// down with every completion. It returns false when the rule has no further occurrence.
+func (r Record) NextAfterDone(doneAt time.Time) (time.Time, string, bool) {
+	if r.PlannedAt == nil {
+		return time.Time{}, "", false
+	}
+	var rule *recurrence.Rule
+	if r.RRule != "" {
+		var err error
+		if rule, err = recurrence.Parse(r.RRule); err != nil {
+			return time.Time{}, "", false
+		}
+	} else if rule = recurrence.FromLegacy(r.Recurrence, r.Interval, r.EndDate); rule == nil {
+		return time.Time{}, "", false
+	}
+	if rule.Count == 1 {
+		return time.Time{}, "", false
+	}
+	loc := r.Location()
+	d, p := doneAt.In(loc), r.PlannedAt.In(loc)
+	start := time.Date(d.Year(), d.Month(), d.Day(), p.Hour(), p.Minute(), p.Second(), 0, loc)
+	unbounded := *rule
+	unbounded.Count = 0
+	set := recurrence.Set{Start: start, Rule: &unbounded}
+	// The start itself is the completion; the next occurrence is the first one after it
+	next := set.Between(start.Add(time.Second), start.AddDate(100, 0, 0))
+	if len(next) == 0 {
+		return time.Time{}, "", false
+	}
+	if rule.Count > 1 {
+		rule.Count--
+	}
+	return next[0], rule.String(), true
+}
+
+// CompleteAnchoredRecord marks a completion-anchored plan with the given ID, looked up in db, done
+// at doneAt. The completion is stored as a new done record and the plan moves on to the next
+// occurrence (see NextAfterDone); when there is none the plan itself is done. It returns the done
+// record and the plan, or gorm.ErrRecordNotFound if there is no such open plan.
+func (s *Store) CompleteAnchoredRecord(db *gorm.DB, id string, doneAt time.Time) (*Record, *Record, error) {
+	var rec Record
+	if err := db.Where("id = ? AND done_at IS NULL", id).First(&rec).Error; err != nil {
+		return nil, nil, err
+	}
+	if !rec.CompletionAnchored() {
+		return nil, nil, gorm.ErrRecordNotFound
+	}
+	next, rule, ok := rec.NextAfterDone(doneAt)
+	if !ok {
+		if err := s.DB.Model(&Record{}).Where("id = ?", rec.ID).Update("done_at", &doneAt).Error; err != nil {
+			return nil, nil, err
+		}
+		rec.DoneAt = &doneAt
+		return &rec, &rec, nil
+	}
+	done := rec
+	done.ID = NewUUID()
+	done.CreatedAt = doneAt
+	done.DoneAt = &doneAt
+	// The completion does not repeat; the plan does
+	done.Recurrence, done.Interval, done.EndDate = "", 0, nil
+	done.RRule, done.RecurrenceAnchor = "", ""
+	plan := rec
+	plan.PlannedAt = &next
+	plan.RRule = rule
+	if err := plan.NormalizeRecurrence(); err != nil {
+		return nil, nil, err
+	}
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&done).Error; err != nil {
+			return err
+		}
+		return tx.Model(&Record{}).Where("id = ?", rec.ID).
+			Updates(map[string]any{"planned_at": plan.PlannedAt, "rrule": plan.RRule, "recurrence": plan.Recurrence, "interval": plan.Interval}).Error
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &done, &plan, nil
+}
@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"time"
+)
+
+// Fetch states of images added by URL.
+const (
+	ImageFetchPending = "pending"
+	ImageFetchFailed  = "failed"
+)
+
+// ValidateImageURL checks that an image URL can be downloaded by the backend.
+func ValidateImageURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("url must be an absolute http or https URL")
+	}
+	if u.User != nil {
+		return errors.New("url must not contain credentials")
+	}
+	return nil
+}
+
+// ListImagesToFetch returns up to limit images waiting to be downloaded. Images that failed
+// before are retried once retryAfter has passed since the last attempt.
+func (s *Store) ListImagesToFetch(limit int, retryAfter time.Duration) ([]Image, error) {
+	var imgs []Image
+	err := s.DB.Where("fetch_status = ?", ImageFetchPending).
+		Where("fetch_attempts = 0 OR updated_at < ?", time.Now().Add(-retryAfter)).
+		Order("updated_at").Limit(limit).Find(&imgs).Error
+	return imgs, err
+}
+
+// SaveFetchedImage stores the downloaded bytes of an image added by URL like an upload and
+// queues it for the optimizer. The URL is kept as SourceURL.
+func (s *Store) SaveFetchedImage(ctx context.Context, img *Image, data []byte, mime string) error {
+	img.MIME = mime
+	if err := s.SetImageData(ctx, img, data); err != nil {
+		return err
+	}
+	res := s.DB.Model(&Image{}).Where("id = ? AND fetch_status = ?", img.ID, ImageFetchPending).Updates(map[string]any{
+		"data":         img.Data,
+		"blob_key":     img.BlobKey,
+		"mime":         mime,
+		"source_url":   img.URL,
+		"url":          "",
+		"fetch_status": "",
+		"fetch_error":  "",
+		"optimized":    false,
+	})
+	if res.Error == nil && res.RowsAffected == 0 {
+		res.Error = errors.New("image was deleted or changed while downloading")
+	}
+	if res.Error != nil {
+		if img.BlobKey != "" {
+			_ = s.blobs.Delete(ctx, img.BlobKey)
+		}
+		return res.Error
+	}
+	img.SourceURL, img.URL, img.FetchStatus, img.FetchError = img.URL, "", "", ""
+	return nil
+}
+
+// MarkImageFetchFailed records a failed download. The image stays pending for another attempt
+// unless the failure is final or maxAttempts is reached. It reports whether the image gave up.
+func (s *Store) MarkImageFetchFailed(id, reason string, final bool, maxAttempts int) (bool, error) {
+	var img Image
+	if err := s.DB.Select("id", "fetch_attempts").First(&img, "id = ?", id).Error; err != nil {
+		return false, err
+	}
+	attempts := img.FetchAttempts + 1
+	status := ImageFetchPending
+	if final || attempts >= maxAttempts {
+		status = ImageFetchFailed
+	}
+	err := s.DB.Model(&Image{}).Where("id = ?", id).Updates(map[string]any{
+		"fetch_status":   status,
+		"fetch_error":    reason,
+		"fetch_attempts": attempts,
+	}).Error
+	return status == ImageFetchFailed, err
+}
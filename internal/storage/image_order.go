@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// ErrImageOrder is returned when a new photo order does not list exactly the photos of the cat.
+var ErrImageOrder = errors.New("order must list every photo of the cat exactly once")
+
+// OrderedImages loads the photos of cats in their display order, without the image bytes.
+// Use it with Preload("Images", OrderedImages).
+func OrderedImages(db *gorm.DB) *gorm.DB {
+	return db.Omit("data").Order("position, created_at")
+}
+
+// PrimaryImage returns the cover photo among imgs: the primary one, or else the first in order.
+func PrimaryImage(imgs []Image) (Image, bool) {
+	if len(imgs) == 0 {
+		return Image{}, false
+	}
+	first := imgs[0]
+	for _, im := range imgs {
+		if im.IsPrimary {
+			return im, true
+		}
+		if im.Position < first.Position || (im.Position == first.Position && im.CreatedAt.Before(first.CreatedAt)) {
+			first = im
+		}
+	}
+	return first, true
+}
+
+// PlaceNewImage puts a photo that is about to be created after the other photos of its cat.
+// The first photo of a cat becomes its primary photo.
+func (s *Store) PlaceNewImage(img *Image) error {
+	var last struct {
+		Count     int64
+		Position  int
+		Primaries int64
+	}
+	err := s.DB.Model(&Image{}).Where("cat_id = ?", img.CatID).
+		Select("COUNT(*) AS count, COALESCE(MAX(position), 0) AS position, " +
+			"COALESCE(SUM(CASE WHEN is_primary THEN 1 ELSE 0 END), 0) AS primaries").
+		Scan(&last).Error
+	if err != nil {
+		return err
+	}
+	if last.Count > 0 {
+		img.Position = last.Position + 1
+	}
+	img.IsPrimary = last.Primaries == 0
+	return nil
+}
+
+// ReorderImages sets the order of the photos of a cat; ids must list all of them.
+func (s *Store) ReorderImages(catID string, ids []string) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		var existing []string
+		if err := tx.Model(&Image{}).Where("cat_id = ?", catID).Pluck("id", &existing).Error; err != nil {
+			return err
+		}
+		if len(existing) != len(ids) {
+			return ErrImageOrder
+		}
+		want := make(map[string]bool, len(existing))
+		for _, id := range existing {
+			want[id] = true
+		}
+		for i, id := range ids {
+			if !want[id] {
+				return ErrImageOrder
+			}
+			delete(want, id) // each only once
+			if err := tx.Model(&Image{}).Where("id = ?", id).Update("position", i).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// SetPrimaryImage makes a photo the cover of its cat.
+func (s *Store) SetPrimaryImage(catID, imageID string) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&Image{}).Where("id = ? AND cat_id = ?", imageID, catID).Update("is_primary", true)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Model(&Image{}).Where("cat_id = ? AND id <> ? AND is_primary = ?", catID, imageID, true).
+			Update("is_primary", false).Error
+	})
+}
+
+// ensurePrimaryImage makes the first photo of a cat primary if none is.
+func ensurePrimaryImage(tx *gorm.DB, catID string) error {
+	var n int64
+	if err := tx.Model(&Image{}).Where("cat_id = ? AND is_primary = ?", catID, true).Count(&n).Error; err != nil || n > 0 {
+		return err
+	}
+	var first Image
+	err := tx.Select("id").Where("cat_id = ?", catID).Order("position, created_at").First(&first).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Model(&Image{}).Where("id = ?", first.ID).Update("is_primary", true).Error
+}
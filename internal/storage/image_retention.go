@@ -0,0 +1,160 @@
+package storage
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ImagePrunePlan lists the photos of a cat that exceed its retention and go to the trash
+// on the next cleanup.
+type ImagePrunePlan struct {
+	CatID   string  `json:"cat_id"`
+	CatName string  `json:"cat_name"`
+	Keep    int     `json:"keep"`
+	Images  []Image `json:"images"`
+}
+
+// CatImageRetention returns how many photos of the cat are kept: its own setting, or else
+// keepDefault. 0 keeps all of them.
+func CatImageRetention(c Cat, keepDefault int) int {
+	keep := keepDefault
+	if c.ImageRetention != nil {
+		keep = *c.ImageRetention
+	}
+	if keep < 0 {
+		keep = 0
+	}
+	return keep
+}
+
+// pruneCandidates returns the photos of a cat beyond the newest keepN, newest first. The primary
+// and pinned photos are exempt and do not count towards keepN. keepN 0 keeps everything.
+func (s *Store) pruneCandidates(catID string, keepN int) ([]Image, error) {
+	if keepN <= 0 {
+		return nil, nil
+	}
+	var imgs []Image
+	err := s.DB.Omit("data").Where("cat_id = ? AND is_primary = ? AND pinned = ?", catID, false, false).
+		Order("created_at DESC, id DESC").Offset(keepN).Find(&imgs).Error
+	return imgs, err
+}
+
+// PruneOldCatImages keeps only the newest keepN photos of the cat and moves the rest to the trash.
+// The primary and pinned photos are always kept on top of them; 0 keeps all photos.
+// Returns the number of trashed images.
+func (s *Store) PruneOldCatImages(catID string, keepN int) (int64, error) {
+	imgs, err := s.pruneCandidates(catID, keepN)
+	if err != nil || len(imgs) == 0 {
+		return 0, err
+	}
+	return s.trashImages(imgs)
+}
+
+// PlanImagePrune returns what the cleanup would move to the trash for the cats in the scope,
+// using keepDefault for cats without their own retention. Cats with nothing to prune are left out.
+func (s *Store) PlanImagePrune(scope OrgScope, keepDefault int) ([]ImagePrunePlan, error) {
+	var cats []Cat
+	if err := scope.Cats(s.DB.Select("id", "name", "image_retention")).Order("id").Find(&cats).Error; err != nil {
+		return nil, err
+	}
+	var plans []ImagePrunePlan
+	for _, c := range cats {
+		keep := CatImageRetention(c, keepDefault)
+		imgs, err := s.pruneCandidates(c.ID, keep)
+		if err != nil {
+			return plans, err
+		}
+		if len(imgs) > 0 {
+			plans = append(plans, ImagePrunePlan{CatID: c.ID, CatName: c.Name, Keep: keep, Images: imgs})
+		}
+	}
+	return plans, nil
+}
+
+// PruneAllCatsImages moves photos beyond the retention of every cat to the trash and returns
+// the number of trashed images.
+func (s *Store) PruneAllCatsImages(keepDefault int) (int64, error) {
+	plans, err := s.PlanImagePrune(OrgScope{All: true}, keepDefault)
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	for _, p := range plans {
+		n, err := s.trashImages(p.Images)
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// trashImages soft-deletes images; their bytes and renditions stay until they are purged.
+func (s *Store) trashImages(imgs []Image) (int64, error) {
+	ids := make([]string, len(imgs))
+	for i, im := range imgs {
+		ids[i] = im.ID
+	}
+	res := s.DB.Where("id IN ? AND is_primary = ? AND pinned = ?", ids, false, false).Delete(&Image{})
+	return res.RowsAffected, res.Error
+}
+
+// ListTrashedImages returns photos in the trash within the scope, most recently trashed first.
+func (s *Store) ListTrashedImages(scope OrgScope) ([]Image, error) {
+	var imgs []Image
+	err := scope.ByCat(s.DB.Unscoped().Omit("data")).
+		Where("deleted_at IS NOT NULL").
+		Where("cat_id IN (?)", s.DB.Model(&Cat{}).Select("id")).
+		Order("deleted_at DESC").
+		Find(&imgs).Error
+	return imgs, err
+}
+
+// GetTrashedImage returns a photo in the trash or gorm.ErrRecordNotFound.
+func (s *Store) GetTrashedImage(id string) (*Image, error) {
+	var img Image
+	if err := s.DB.Unscoped().Omit("data").Where("deleted_at IS NOT NULL").First(&img, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &img, nil
+}
+
+// RestoreImage takes a photo out of the trash. It is pinned so that the next cleanup keeps it.
+// Returns gorm.ErrRecordNotFound if it is not in the trash.
+func (s *Store) RestoreImage(id string) (*Image, error) {
+	res := s.DB.Unscoped().Model(&Image{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Updates(map[string]any{"deleted_at": nil, "pinned": true})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	var img Image
+	if err := OrderedImages(s.DB).First(&img, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &img, nil
+}
+
+// PurgeTrashedImages permanently removes photos that were moved to the trash before the cutoff
+// and returns them.
+func (s *Store) PurgeTrashedImages(before time.Time) ([]Image, error) {
+	var imgs []Image
+	if err := s.DB.Unscoped().Omit("data").Where("deleted_at IS NOT NULL AND deleted_at < ?", before).Find(&imgs).Error; err != nil {
+		return nil, err
+	}
+	if len(imgs) == 0 {
+		return nil, nil
+	}
+	ids := make([]string, len(imgs))
+	for i, im := range imgs {
+		ids[i] = im.ID
+	}
+	if _, err := s.deleteImages(ids); err != nil {
+		return nil, err
+	}
+	return imgs, nil
+}
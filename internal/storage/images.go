@@ -0,0 +1,284 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/maniack/catwatch/internal/blobstore"
+	"github.com/maniack/catwatch/internal/logging"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Image sizes served by the API. The original is always kept as uploaded; the other sizes
+// are renditions generated by the backend.
+const (
+	ImageSizeThumb    = "thumb"
+	ImageSizeCard     = "card"
+	ImageSizeFull     = "full"
+	ImageSizeOriginal = "original"
+)
+
+// ImageRendition is a resized copy of an image. A rendition without bytes means the original
+// is already small enough for this size and is served instead.
+type ImageRendition struct {
+	ImageID   string `gorm:"type:char(36);primaryKey"`
+	Size      string `gorm:"size:16;primaryKey"`
+	CreatedAt time.Time
+	MIME      string
+	Data      []byte
+	BlobKey   string
+}
+
+// HasData reports whether the rendition has its own bytes.
+func (r *ImageRendition) HasData() bool { return r.BlobKey != "" || len(r.Data) > 0 }
+
+// HasData reports whether the image bytes are stored by us (as opposed to an external URL).
+func (im *Image) HasData() bool { return im.BlobKey != "" || len(im.Data) > 0 }
+
+// SetBlobStore moves new image bytes out of the database into bs. Bytes already in the
+// database stay readable; MoveImagesToBlobStore migrates them.
+func (s *Store) SetBlobStore(bs blobstore.Store) { s.blobs = bs }
+
+// BlobStore returns the configured blob store or nil.
+func (s *Store) BlobStore() blobstore.Store { return s.blobs }
+
+func imageBlobKey(imageID, size string) string {
+	return "images/" + imageID + "/" + size
+}
+
+// putBlob stores data under key in the blob store, or returns it for the database column.
+func (s *Store) putBlob(ctx context.Context, key string, data []byte, mime string) (string, []byte, error) {
+	if s.blobs == nil || len(data) == 0 {
+		return "", data, nil
+	}
+	if err := s.blobs.Put(ctx, key, bytes.NewReader(data), int64(len(data)), mime); err != nil {
+		return "", nil, err
+	}
+	return key, nil, nil
+}
+
+// SetImageData assigns the original bytes of a new image, writing them to the blob store
+// when one is configured. The image must not have been saved yet.
+func (s *Store) SetImageData(ctx context.Context, img *Image, data []byte) error {
+	key, inline, err := s.putBlob(ctx, imageBlobKey(img.ID, ImageSizeOriginal), data, img.MIME)
+	if err != nil {
+		return err
+	}
+	img.BlobKey, img.Data = key, inline
+	return nil
+}
+
+// OpenBlob streams the bytes stored under key.
+func (s *Store) OpenBlob(ctx context.Context, key string) (io.ReadCloser, error) {
+	if s.blobs == nil {
+		return nil, blobstore.ErrNotFound
+	}
+	return s.blobs.Get(ctx, key)
+}
+
+// ImageData returns the original bytes of an image wherever they are stored.
+func (s *Store) ImageData(ctx context.Context, img *Image) ([]byte, error) {
+	if img.BlobKey == "" {
+		return img.Data, nil
+	}
+	rc, err := s.OpenBlob(ctx, img.BlobKey)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// ListImagesToOptimize returns up to 'limit' images whose renditions haven't been generated yet.
+func (s *Store) ListImagesToOptimize(limit int) ([]Image, error) {
+	var imgs []Image
+	err := s.DB.Where("optimized = ? OR optimized IS NULL", false).Order("created_at").Limit(limit).Find(&imgs).Error
+	return imgs, err
+}
+
+// MarkImageOptimizedEmpty marks an image as optimized when there is no image data to process.
+func (s *Store) MarkImageOptimizedEmpty(id string) error {
+	return s.DB.Model(&Image{}).Where("id = ?", id).Update("optimized", true).Error
+}
+
+// SaveImageRenditions stores the renditions of an image, replacing existing ones of the same
+// size, and marks the image optimized once all sizes are present.
+func (s *Store) SaveImageRenditions(ctx context.Context, id string, rends []ImageRendition, complete bool) error {
+	for i := range rends {
+		r := &rends[i]
+		r.ImageID = id
+		key, inline, err := s.putBlob(ctx, imageBlobKey(id, r.Size), r.Data, r.MIME)
+		if err != nil {
+			return err
+		}
+		r.BlobKey, r.Data = key, inline
+	}
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		for i := range rends {
+			if err := tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&rends[i]).Error; err != nil {
+				return err
+			}
+		}
+		if !complete {
+			return nil
+		}
+		return tx.Model(&Image{}).Where("id = ?", id).Updates(map[string]any{"optimized": true, "updated_at": time.Now()}).Error
+	})
+}
+
+// SetImagePHash stores the perceptual hash of an image.
+func (s *Store) SetImagePHash(id string, hash uint64) error {
+	h := int64(hash) // stored as a signed 64-bit column
+	return s.DB.Model(&Image{}).Where("id = ?", id).Update("phash", &h).Error
+}
+
+// ImageHash is the perceptual hash of a photo of a cat.
+type ImageHash struct {
+	ImageID string
+	CatID   string
+	PHash   uint64
+}
+
+// ImageHashes returns the hashes of the photos of the cats in scope, skipping deleted cats.
+func (s *Store) ImageHashes(scope OrgScope) ([]ImageHash, error) {
+	var rows []struct {
+		ID    string
+		CatID string
+		PHash int64 `gorm:"column:phash"`
+	}
+	q := s.DB.Model(&Image{}).
+		Joins("JOIN cats ON cats.id = images.cat_id AND cats.deleted_at IS NULL").
+		Where("images.phash IS NOT NULL").
+		Select("images.id, images.cat_id, images.phash")
+	if err := scope.Cats(q).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	out := make([]ImageHash, len(rows))
+	for i, r := range rows {
+		out[i] = ImageHash{ImageID: r.ID, CatID: r.CatID, PHash: uint64(r.PHash)}
+	}
+	return out, nil
+}
+
+// GetImageRendition returns the rendition of an image, or gorm.ErrRecordNotFound when it has
+// not been generated yet.
+func (s *Store) GetImageRendition(id, size string) (*ImageRendition, error) {
+	var r ImageRendition
+	if err := s.DB.Where("image_id = ? AND size = ?", id, size).First(&r).Error; err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// imageBlobKeys lists the blob keys of the images selected by ids (a slice or subquery).
+func imageBlobKeys(db *gorm.DB, ids any) ([]string, error) {
+	var keys, rendKeys []string
+	if err := db.Unscoped().Model(&Image{}).Where("id IN (?) AND blob_key <> ''", ids).Pluck("blob_key", &keys).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Model(&ImageRendition{}).Where("image_id IN (?) AND blob_key <> ''", ids).Pluck("blob_key", &rendKeys).Error; err != nil {
+		return nil, err
+	}
+	return append(keys, rendKeys...), nil
+}
+
+// deleteBlobs removes blobs whose rows are gone. Failures only leave orphaned objects behind,
+// so they are logged rather than returned.
+func (s *Store) deleteBlobs(keys []string) {
+	if s.blobs == nil {
+		return
+	}
+	for _, key := range keys {
+		if err := s.blobs.Delete(context.Background(), key); err != nil {
+			logging.L().WithError(err).WithField("key", key).Warn("blobs: failed to delete")
+		}
+	}
+}
+
+// deleteImages permanently removes images with their renditions, trashed ones included, and
+// returns the number of deleted images.
+func (s *Store) deleteImages(ids []string) (int64, error) {
+	keys, err := imageBlobKeys(s.DB, ids)
+	if err != nil {
+		return 0, err
+	}
+	var deleted int64
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("image_id IN ?", ids).Delete(&ImageRendition{}).Error; err != nil {
+			return err
+		}
+		res := tx.Unscoped().Where("id IN ?", ids).Delete(&Image{})
+		deleted = res.RowsAffected
+		return res.Error
+	})
+	if err == nil {
+		s.deleteBlobs(keys)
+	}
+	return deleted, err
+}
+
+// DeleteImage removes an image together with its renditions. When it was the primary photo,
+// the next photo of the cat takes its place.
+func (s *Store) DeleteImage(id string) error {
+	var img Image
+	if err := s.DB.Select("id", "cat_id", "is_primary").First(&img, "id = ?", id).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if _, err := s.deleteImages([]string{id}); err != nil {
+		return err
+	}
+	if img.IsPrimary {
+		return ensurePrimaryImage(s.DB, img.CatID)
+	}
+	return nil
+}
+
+// MoveImagesToBlobStore copies image and rendition bytes still kept in the database to the
+// blob store and clears the columns, at most batch rows at a time. It returns the number of
+// moved rows and can be interrupted and resumed.
+func (s *Store) MoveImagesToBlobStore(ctx context.Context, batch int) (int, error) {
+	if s.blobs == nil {
+		return 0, nil
+	}
+	moved := 0
+	for {
+		var imgs []Image
+		// Photos in the trash are moved as well, they may still be restored
+		if err := s.DB.Unscoped().Where("blob_key = '' AND data IS NOT NULL AND length(data) > 0").Order("id").Limit(batch).Find(&imgs).Error; err != nil {
+			return moved, err
+		}
+		for _, im := range imgs {
+			key := imageBlobKey(im.ID, ImageSizeOriginal)
+			if err := s.blobs.Put(ctx, key, bytes.NewReader(im.Data), int64(len(im.Data)), im.MIME); err != nil {
+				return moved, err
+			}
+			if err := s.DB.Unscoped().Model(&Image{}).Where("id = ? AND blob_key = ''", im.ID).
+				Updates(map[string]any{"blob_key": key, "data": nil}).Error; err != nil {
+				return moved, err
+			}
+			moved++
+		}
+		var rends []ImageRendition
+		if err := s.DB.Where("blob_key = '' AND data IS NOT NULL AND length(data) > 0").Order("image_id").Order("size").Limit(batch).Find(&rends).Error; err != nil {
+			return moved, err
+		}
+		for _, r := range rends {
+			key := imageBlobKey(r.ImageID, r.Size)
+			if err := s.blobs.Put(ctx, key, bytes.NewReader(r.Data), int64(len(r.Data)), r.MIME); err != nil {
+				return moved, err
+			}
+			if err := s.DB.Model(&ImageRendition{}).Where("image_id = ? AND size = ? AND blob_key = ''", r.ImageID, r.Size).
+				Updates(map[string]any{"blob_key": key, "data": nil}).Error; err != nil {
+				return moved, err
+			}
+			moved++
+		}
+		if len(imgs) < batch && len(rends) < batch {
+			return moved, nil
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package storage
+
+import (
+	"sync"
+	"time"
+)
+
+// LikeStat is the number of likes of a cat and whether a given user liked it.
+type LikeStat struct {
+	Count int64
+	Liked bool
+}
+
+func (s *Store) IsLikedByUser(catID, userID string) (bool, error) {
+	var n int64
+	err := s.DB.Model(&Like{}).Where("cat_id = ? AND user_id = ?", catID, userID).Count(&n).Error
+	return n > 0, err
+}
+
+func (s *Store) SetLike(catID, userID string, like bool) error {
+	defer s.likes.invalidate(catID, userID)
+	if like {
+		l := &Like{ID: NewUUID(), CatID: catID, UserID: userID}
+		return s.DB.Create(l).Error
+	}
+	return s.DB.Where("cat_id = ? AND user_id = ?", catID, userID).Delete(&Like{}).Error
+}
+
+func (s *Store) LikesCount(catID string) (int64, error) {
+	var n int64
+	err := s.DB.Model(&Like{}).Where("cat_id = ?", catID).Count(&n).Error
+	return n, err
+}
+
+// LikeStats returns like counts for the cats and whether userID liked them, using a constant
+// number of queries regardless of the number of cats. Every requested cat is present in the
+// result; an empty userID never sets Liked.
+func (s *Store) LikeStats(catIDs []string, userID string) (map[string]LikeStat, error) {
+	stats := make(map[string]LikeStat, len(catIDs))
+	if len(catIDs) == 0 {
+		return stats, nil
+	}
+	if s.likes != nil {
+		return s.cachedLikeStats(catIDs, userID)
+	}
+
+	var rows []struct {
+		CatID string
+		Count int64
+		Liked int64
+	}
+	err := s.DB.Model(&Like{}).
+		Select("cat_id, COUNT(*) AS count, SUM(CASE WHEN user_id = ? THEN 1 ELSE 0 END) AS liked", userID).
+		Where("cat_id IN ?", catIDs).
+		Group("cat_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range catIDs {
+		stats[id] = LikeStat{}
+	}
+	for _, r := range rows {
+		stats[r.CatID] = LikeStat{Count: r.Count, Liked: userID != "" && r.Liked > 0}
+	}
+	return stats, nil
+}
+
+// likeCounts counts likes per cat in one query. Cats without likes are absent.
+func (s *Store) likeCounts(catIDs []string) (map[string]int64, error) {
+	var rows []struct {
+		CatID string
+		Count int64
+	}
+	err := s.DB.Model(&Like{}).Select("cat_id, COUNT(*) AS count").Where("cat_id IN ?", catIDs).Group("cat_id").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int64, len(rows))
+	for _, r := range rows {
+		counts[r.CatID] = r.Count
+	}
+	return counts, nil
+}
+
+// EnableLikeCache keeps like counts and the cats liked by each user in memory for ttl.
+// Changes made through SetLike, DeleteUser, merges and purges invalidate the cache immediately; changes made
+// by other processes sharing the database become visible after ttl.
+func (s *Store) EnableLikeCache(ttl time.Duration) {
+	if ttl <= 0 {
+		s.likes = nil
+		return
+	}
+	s.likes = &likeCache{ttl: ttl}
+	s.likes.reset()
+}
+
+type likeCache struct {
+	ttl time.Duration
+
+	mu     sync.Mutex
+	gen    uint64                     // bumped on invalidation so that loads started earlier are not stored
+	counts map[string]cachedLikeCount // by cat ID
+	liked  map[string]cachedUserLikes // by user ID
+}
+
+type cachedLikeCount struct {
+	n       int64
+	expires time.Time
+}
+
+type cachedUserLikes struct {
+	cats    map[string]bool
+	expires time.Time
+}
+
+func (c *likeCache) invalidate(catID, userID string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	delete(c.counts, catID)
+	delete(c.liked, userID)
+}
+
+// forgetCat drops the cached count of a cat and the cat from the cached likes of every user.
+func (c *likeCache) forgetCat(catID string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	delete(c.counts, catID)
+	for uid, l := range c.liked {
+		if l.cats[catID] {
+			delete(c.liked, uid)
+		}
+	}
+}
+
+func (c *likeCache) reset() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	c.counts = map[string]cachedLikeCount{}
+	c.liked = map[string]cachedUserLikes{}
+}
+
+// cachedLikeStats serves LikeStats from the cache, loading missing counts in one query
+// and the user's liked cats in another.
+func (s *Store) cachedLikeStats(catIDs []string, userID string) (map[string]LikeStat, error) {
+	c := s.likes
+	now := time.Now()
+
+	c.mu.Lock()
+	stats := make(map[string]LikeStat, len(catIDs))
+	var missing []string
+	for _, id := range catIDs {
+		if e, ok := c.counts[id]; ok && now.Before(e.expires) {
+			stats[id] = LikeStat{Count: e.n}
+		} else {
+			missing = append(missing, id)
+		}
+	}
+	userLikes, haveUser := c.liked[userID]
+	haveUser = haveUser && now.Before(userLikes.expires)
+	gen := c.gen
+	c.mu.Unlock()
+
+	if len(missing) > 0 {
+		counts, err := s.likeCounts(missing)
+		if err != nil {
+			return nil, err
+		}
+		c.mu.Lock()
+		for _, id := range missing {
+			stats[id] = LikeStat{Count: counts[id]}
+			if c.gen == gen {
+				c.counts[id] = cachedLikeCount{n: counts[id], expires: now.Add(c.ttl)}
+			}
+		}
+		c.mu.Unlock()
+	}
+
+	if userID == "" {
+		return stats, nil
+	}
+	if !haveUser {
+		var ids []string
+		if err := s.DB.Model(&Like{}).Where("user_id = ?", userID).Pluck("cat_id", &ids).Error; err != nil {
+			return nil, err
+		}
+		userLikes = cachedUserLikes{cats: make(map[string]bool, len(ids)), expires: now.Add(c.ttl)}
+		for _, id := range ids {
+			userLikes.cats[id] = true
+		}
+		c.mu.Lock()
+		if c.gen == gen {
+			c.liked[userID] = userLikes
+		}
+		c.mu.Unlock()
+	}
+	for id, st := range stats {
+		st.Liked = userLikes.cats[id]
+		stats[id] = st
+	}
+	return stats, nil
+}
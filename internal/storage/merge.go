@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrCatMerged is returned when restoring a cat that was merged into another one.
+	ErrCatMerged = errors.New("cat was merged into another cat")
+	// ErrMergeSelf is returned when a cat is merged into itself.
+	ErrMergeSelf = errors.New("cannot merge a cat into itself")
+)
+
+// MergeCats moves the images, records, locations, likes and tags of the source cat to the target,
+// soft-deletes the source and leaves a CatRedirect from its ID. The photos of the source are put
+// after those of the target, and likes the user already gave the target are dropped. The target
+// keeps the later LastSeen and the condition of the cat seen most recently; NeedAttention and
+// IsSterilized are kept if either cat had them. Returns the updated target.
+func (s *Store) MergeCats(sourceID, targetID string) (*Cat, error) {
+	if sourceID == targetID {
+		return nil, ErrMergeSelf
+	}
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		var source, target Cat
+		if err := tx.First(&source, "id = ?", sourceID).Error; err != nil {
+			return err
+		}
+		if err := tx.First(&target, "id = ?", targetID).Error; err != nil {
+			return err
+		}
+
+		// Photos of the source follow those of the target; the target keeps its cover
+		var last struct {
+			Count    int64
+			Position int
+		}
+		if err := tx.Model(&Image{}).Where("cat_id = ?", targetID).
+			Select("COUNT(*) AS count, COALESCE(MAX(position), 0) AS position").Scan(&last).Error; err != nil {
+			return err
+		}
+		if last.Count > 0 {
+			if err := tx.Unscoped().Model(&Image{}).Where("cat_id = ?", sourceID).
+				Updates(map[string]any{"position": gorm.Expr("position + ?", last.Position+1), "is_primary": false}).Error; err != nil {
+				return err
+			}
+		}
+		// Trashed photos follow as well, so they can still be restored
+		for _, model := range []any{&Image{}, &Record{}, &CatLocation{}} {
+			if err := tx.Unscoped().Model(model).Where("cat_id = ?", sourceID).Update("cat_id", targetID).Error; err != nil {
+				return err
+			}
+		}
+		targetLikers := tx.Model(&Like{}).Select("user_id").Where("cat_id = ?", targetID)
+		if err := tx.Where("cat_id = ? AND user_id IN (?)", sourceID, targetLikers).Delete(&Like{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&Like{}).Where("cat_id = ?", sourceID).Update("cat_id", targetID).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec(`INSERT INTO cat_tags (cat_id, tag_id)
+			SELECT ?, tag_id FROM cat_tags WHERE cat_id = ? AND tag_id NOT IN (SELECT tag_id FROM cat_tags WHERE cat_id = ?)`,
+			targetID, sourceID, targetID).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM cat_tags WHERE cat_id = ?", sourceID).Error; err != nil {
+			return err
+		}
+
+		updates := map[string]any{
+			"need_attention": target.NeedAttention || source.NeedAttention,
+			"is_sterilized":  target.IsSterilized || source.IsSterilized,
+		}
+		if source.LastSeen != nil && (target.LastSeen == nil || source.LastSeen.After(*target.LastSeen)) {
+			updates["last_seen"] = source.LastSeen
+			updates["condition"] = source.Condition
+		}
+		if err := tx.Model(&Cat{}).Where("id = ?", targetID).Updates(updates).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&Cat{}, "id = ?", sourceID).Error; err != nil {
+			return err
+		}
+
+		// Links to cats merged into the source earlier now lead to the target as well
+		if err := tx.Model(&CatRedirect{}).Where("to_id = ?", sourceID).Update("to_id", targetID).Error; err != nil {
+			return err
+		}
+		return tx.Save(&CatRedirect{FromID: sourceID, ToID: targetID}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.likes.reset()
+
+	var cat Cat
+	if err := s.DB.Preload("Tags").First(&cat, "id = ?", targetID).Error; err != nil {
+		return nil, err
+	}
+	return &cat, nil
+}
+
+// CatRedirect returns the ID of the cat the given cat was merged into.
+func (s *Store) CatRedirect(id string) (string, bool) {
+	var r CatRedirect
+	if err := s.DB.First(&r, "from_id = ?", id).Error; err != nil {
+		return "", false
+	}
+	return r.ToID, true
+}
@@ -0,0 +1,195 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/maniack/catwatch/internal/logging"
+	"gorm.io/gorm"
+)
+
+// Migration is one step of the schema history. Migrations run in Version order, each in its
+// own transaction together with its schema_migrations row.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error // nil when the migration cannot be rolled back
+}
+
+// SchemaMigration records an applied migration.
+type SchemaMigration struct {
+	Version   int64 `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+func (SchemaMigration) TableName() string { return "schema_migrations" }
+
+// MigrationState describes a migration known to this build or recorded in the database.
+type MigrationState struct {
+	Version    int64
+	Name       string
+	AppliedAt  *time.Time
+	Reversible bool
+	Unknown    bool // applied by a newer build
+}
+
+// ErrIrreversibleMigration is returned by MigrateDown for migrations without a Down step.
+var ErrIrreversibleMigration = errors.New("migration cannot be rolled back")
+
+// migrationLockKey identifies the Postgres advisory lock held while migrating, so that
+// replicas starting at the same time apply each migration once.
+const migrationLockKey int64 = 0x63617477617463 // "catwatc"
+
+// Migrations returns the migrations known to this build in version order.
+func Migrations() []Migration {
+	out := append([]Migration(nil), migrations...)
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out
+}
+
+// withMigrationLock runs fn with the migration lock held. On Postgres the lock is a session
+// advisory lock, so fn receives a database handle pinned to the locking connection.
+func (s *Store) withMigrationLock(fn func(db *gorm.DB) error) error {
+	if s.DB.Dialector.Name() != "postgres" {
+		// SQLite serializes writers itself
+		return fn(s.DB)
+	}
+	return s.DB.Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("SELECT pg_advisory_lock(?)", migrationLockKey).Error; err != nil {
+			return fmt.Errorf("acquire migration lock: %w", err)
+		}
+		defer conn.Exec("SELECT pg_advisory_unlock(?)", migrationLockKey)
+		return fn(conn)
+	})
+}
+
+func appliedMigrations(db *gorm.DB) (map[int64]SchemaMigration, error) {
+	applied := map[int64]SchemaMigration{}
+	if !db.Migrator().HasTable(&SchemaMigration{}) {
+		return applied, nil
+	}
+	var rows []SchemaMigration
+	if err := db.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		applied[r.Version] = r
+	}
+	return applied, nil
+}
+
+// MigrationStatus lists known migrations with their state, followed by applied migrations
+// this build does not know about.
+func (s *Store) MigrationStatus() ([]MigrationState, error) {
+	applied, err := appliedMigrations(s.DB)
+	if err != nil {
+		return nil, err
+	}
+	var out []MigrationState
+	for _, m := range Migrations() {
+		st := MigrationState{Version: m.Version, Name: m.Name, Reversible: m.Down != nil}
+		if a, ok := applied[m.Version]; ok {
+			st.AppliedAt = &a.AppliedAt
+			delete(applied, m.Version)
+		}
+		out = append(out, st)
+	}
+	var unknown []MigrationState
+	for _, a := range applied {
+		unknown = append(unknown, MigrationState{Version: a.Version, Name: a.Name, AppliedAt: &a.AppliedAt, Unknown: true})
+	}
+	sort.Slice(unknown, func(i, j int) bool { return unknown[i].Version < unknown[j].Version })
+	return append(out, unknown...), nil
+}
+
+// PendingMigrations returns the known migrations that have not been applied yet.
+func (s *Store) PendingMigrations() ([]Migration, error) {
+	applied, err := appliedMigrations(s.DB)
+	if err != nil {
+		return nil, err
+	}
+	var pending []Migration
+	for _, m := range Migrations() {
+		if _, ok := applied[m.Version]; !ok {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// MigrateUp applies pending migrations up to and including version target (all when target is 0)
+// and returns the applied ones.
+func (s *Store) MigrateUp(target int64) ([]Migration, error) {
+	log := logging.L()
+	var done []Migration
+	err := s.withMigrationLock(func(db *gorm.DB) error {
+		if err := db.AutoMigrate(&SchemaMigration{}); err != nil {
+			return fmt.Errorf("create schema_migrations: %w", err)
+		}
+		// Read under the lock: another replica may have migrated while we waited
+		applied, err := appliedMigrations(db)
+		if err != nil {
+			return err
+		}
+		for _, m := range Migrations() {
+			if target > 0 && m.Version > target {
+				break
+			}
+			if _, ok := applied[m.Version]; ok {
+				continue
+			}
+			log.Infof("Applying migration %d_%s...", m.Version, m.Name)
+			err := db.Transaction(func(tx *gorm.DB) error {
+				if err := m.Up(tx); err != nil {
+					return err
+				}
+				return tx.Create(&SchemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now().UTC()}).Error
+			})
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %w", m.Version, m.Name, err)
+			}
+			done = append(done, m)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// MigrateDown rolls back the latest steps applied migrations and returns the rolled back ones.
+func (s *Store) MigrateDown(steps int) ([]Migration, error) {
+	log := logging.L()
+	var done []Migration
+	err := s.withMigrationLock(func(db *gorm.DB) error {
+		applied, err := appliedMigrations(db)
+		if err != nil {
+			return err
+		}
+		known := Migrations()
+		for i := len(known) - 1; i >= 0 && len(done) < steps; i-- {
+			m := known[i]
+			if _, ok := applied[m.Version]; !ok {
+				continue
+			}
+			if m.Down == nil {
+				return fmt.Errorf("migration %d_%s: %w", m.Version, m.Name, ErrIrreversibleMigration)
+			}
+			log.Infof("Rolling back migration %d_%s...", m.Version, m.Name)
+			err := db.Transaction(func(tx *gorm.DB) error {
+				if err := m.Down(tx); err != nil {
+					return err
+				}
+				return tx.Delete(&SchemaMigration{}, "version = ?", m.Version).Error
+			})
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %w", m.Version, m.Name, err)
+			}
+			done = append(done, m)
+		}
+		return nil
+	})
+	return done, err
+}
@@ -0,0 +1,315 @@
+package storage
+
+import (
+	"slices"
+	"time"
+
+	"github.com/maniack/catwatch/internal/recurrence"
+	"gorm.io/gorm"
+)
+
+// migrations is the schema history. Append new steps with the next version; never edit or
+// renumber a step that has been released. The first steps reproduce what Open used to do at
+// every startup and are idempotent, so databases created before versioning adopt them as is.
+// Steps add their own tables and columns only, never migrate a whole live model: that would
+// also add the columns of later steps, whose rollbacks would then leave a schema no version had.
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "baseline",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(baselineModels()...)
+		},
+	},
+	{
+		Version: 2,
+		Name:    "default_organization",
+		Up: func(tx *gorm.DB) error {
+			_, err := (&Store{DB: tx}).EnsureDefaultOrganization()
+			return err
+		},
+	},
+	{
+		Version: 3,
+		Name:    "search_index",
+		Up: func(tx *gorm.DB) error {
+			idx := dialectSearchIndex(tx)
+			if err := idx.install(tx); err != nil {
+				return err
+			}
+			return idx.rebuild(tx)
+		},
+		Down: func(tx *gorm.DB) error {
+			return dialectSearchIndex(tx).uninstall(tx)
+		},
+	},
+	{
+		Version: 4,
+		Name:    "seal_audit_logs",
+		Up: func(tx *gorm.DB) error {
+			return (&Store{DB: tx}).sealAuditLogs()
+		},
+	},
+	{
+		Version: 5,
+		Name:    "image_renditions",
+		Up: func(tx *gorm.DB) error {
+			// The table as introduced; blob_key follows in image_blob_keys
+			type ImageRendition struct {
+				ImageID   string `gorm:"type:char(36);primaryKey"`
+				Size      string `gorm:"size:16;primaryKey"`
+				CreatedAt time.Time
+				MIME      string
+				Data      []byte
+			}
+			if err := tx.AutoMigrate(&ImageRendition{}); err != nil {
+				return err
+			}
+			// Images optimized before were overwritten in place; generate renditions from what is left
+			return tx.Unscoped().Model(&Image{}).Where("optimized = ?", true).Update("optimized", false).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&ImageRendition{})
+		},
+	},
+	{
+		Version: 6,
+		Name:    "image_blob_keys",
+		Up: func(tx *gorm.DB) error {
+			for _, model := range []any{&Image{}, &ImageRendition{}} {
+				if err := addColumns(tx, model, "BlobKey"); err != nil {
+					return err
+				}
+				if err := tx.Unscoped().Model(model).Where("blob_key IS NULL").Update("blob_key", "").Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 7,
+		Name:    "user_photo_location_opt_in",
+		Up: func(tx *gorm.DB) error {
+			return addColumns(tx, &User{}, "PhotoLocationSuggestions")
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&User{}, "PhotoLocationSuggestions")
+		},
+	},
+	{
+		Version: 8,
+		Name:    "image_phash",
+		Up: func(tx *gorm.DB) error {
+			if err := addColumns(tx, &Image{}, "PHash"); err != nil {
+				return err
+			}
+			// Hashes are computed by the optimizer; run it again over stored photos
+			return tx.Unscoped().Model(&Image{}).
+				Where("optimized = ? AND (blob_key <> '' OR data IS NOT NULL)", true).
+				Update("optimized", false).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&Image{}, "PHash")
+		},
+	},
+	{
+		Version: 9,
+		Name:    "cat_redirects",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&CatRedirect{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&CatRedirect{})
+		},
+	},
+	{
+		Version: 10,
+		Name:    "image_url_fetch",
+		Up: func(tx *gorm.DB) error {
+			if err := addColumns(tx, &Image{}, "SourceURL", "FetchStatus", "FetchError", "FetchAttempts"); err != nil {
+				return err
+			}
+			// Download the external images added so far
+			return tx.Unscoped().Model(&Image{}).
+				Where("url <> '' AND blob_key = '' AND data IS NULL").
+				Update("fetch_status", ImageFetchPending).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			for _, col := range []string{"SourceURL", "FetchStatus", "FetchError", "FetchAttempts"} {
+				if err := tx.Migrator().DropColumn(&Image{}, col); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 11,
+		Name:    "image_order",
+		Up: func(tx *gorm.DB) error {
+			if err := addColumns(tx, &Image{}, "Position", "IsPrimary", "Pinned"); err != nil {
+				return err
+			}
+			// Keep the order clients used so far: newest first, the newest one as the cover
+			var imgs []Image
+			if err := tx.Unscoped().Select("id", "cat_id").Order("cat_id, created_at DESC, id DESC").Find(&imgs).Error; err != nil {
+				return err
+			}
+			pos, cat := 0, ""
+			for _, im := range imgs {
+				if im.CatID != cat {
+					pos, cat = 0, im.CatID
+				}
+				if err := tx.Unscoped().Model(&Image{}).Where("id = ?", im.ID).
+					UpdateColumns(map[string]any{"position": pos, "is_primary": pos == 0}).Error; err != nil {
+					return err
+				}
+				pos++
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			for _, col := range []string{"Position", "IsPrimary", "Pinned"} {
+				if err := tx.Migrator().DropColumn(&Image{}, col); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 12,
+		Name:    "image_retention",
+		Up: func(tx *gorm.DB) error {
+			if err := addColumns(tx, &Cat{}, "ImageRetention"); err != nil {
+				return err
+			}
+			return addColumns(tx, &Image{}, "DeletedAt")
+		},
+		// Photos still in the trash reappear
+		Down: func(tx *gorm.DB) error {
+			// Recreating the cats table, as the SQLite migrator does, would break the search triggers
+			if err := tx.Exec("ALTER TABLE cats DROP COLUMN image_retention").Error; err != nil {
+				return err
+			}
+			return tx.Migrator().DropColumn(&Image{}, "DeletedAt")
+		},
+	},
+	{
+		Version: 13,
+		Name:    "record_rrule",
+		Up: func(tx *gorm.DB) error {
+			if err := addColumns(tx, &Record{}, "RRule", "ExDates", "RDates"); err != nil {
+				return err
+			}
+			var recs []Record
+			if err := tx.Where("recurrence <> '' AND (rrule IS NULL OR rrule = '')").Find(&recs).Error; err != nil {
+				return err
+			}
+			for _, rec := range recs {
+				rule := recurrence.FromLegacy(rec.Recurrence, rec.Interval, rec.EndDate)
+				if rule == nil {
+					continue
+				}
+				if err := tx.Model(&Record{}).Where("id = ?", rec.ID).UpdateColumn("rrule", rule.String()).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			// Records feed the search triggers, see image_retention
+			for _, col := range []string{"rrule", "exdates", "rdates"} {
+				if err := tx.Exec("ALTER TABLE records DROP COLUMN " + col).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 14,
+		Name:    "record_overrides",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&RecordOverride{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&RecordOverride{})
+		},
+	},
+	{
+		Version: 15,
+		Name:    "time_zones",
+		Up: func(tx *gorm.DB) error {
+			// Existing records keep no zone and repeat in the one their planned_at is read in,
+			// so the IDs of their occurrences do not change.
+			for _, model := range []any{&Organization{}, &User{}, &Record{}} {
+				if err := addColumns(tx, model, "TimeZone"); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Exec("ALTER TABLE records DROP COLUMN time_zone").Error; err != nil {
+				return err
+			}
+			if err := tx.Migrator().DropColumn(&User{}, "time_zone"); err != nil {
+				return err
+			}
+			return tx.Migrator().DropColumn(&Organization{}, "time_zone")
+		},
+	},
+	{
+		Version: 16,
+		Name:    "record_recurrence_anchor",
+		Up: func(tx *gorm.DB) error {
+			return addColumns(tx, &Record{}, "RecurrenceAnchor")
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Exec("ALTER TABLE records DROP COLUMN recurrence_anchor").Error
+		},
+	},
+	{
+		Version: 17,
+		Name:    "calendar_tokens",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&CalendarToken{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&CalendarToken{})
+		},
+	},
+}
+
+// addColumns adds the fields of model that its table lacks, with their indexes.
+func addColumns(tx *gorm.DB, model any, fields ...string) error {
+	m := tx.Migrator()
+	for _, f := range fields {
+		if !m.HasColumn(model, f) {
+			if err := m.AddColumn(model, f); err != nil {
+				return err
+			}
+		}
+	}
+	stmt := &gorm.Statement{DB: tx}
+	if err := stmt.Parse(model); err != nil {
+		return err
+	}
+	for _, idx := range stmt.Schema.ParseIndexes() {
+		for _, opt := range idx.Fields {
+			if slices.Contains(fields, opt.Name) && !m.HasIndex(model, idx.Name) {
+				if err := m.CreateIndex(model, idx.Name); err != nil {
+					return err
+				}
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func dialectSearchIndex(tx *gorm.DB) searchIndex {
+	return newSearchIndex(tx.Dialector.Name() == "postgres")
+}
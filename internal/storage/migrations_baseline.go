@@ -0,0 +1,169 @@
+package storage
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// baselineModels returns the models as they were when schema versioning started, for the
+// baseline migration. They are frozen copies: later columns are added by the steps that
+// introduced them, so never change these types along with the live models. The type names
+// match the live ones because GORM derives table, join column and constraint names from them.
+func baselineModels() []any {
+	type User struct {
+		ID        string `gorm:"type:char(36);primaryKey"`
+		CreatedAt time.Time
+		UpdatedAt time.Time
+
+		Provider   string
+		ProviderID string `gorm:"uniqueIndex"`
+		Email      string `gorm:"index"`
+		Name       string
+		AvatarURL  string
+		Role       string `gorm:"type:varchar(16);default:volunteer;index"`
+	}
+	type Tag struct {
+		ID        string `gorm:"type:char(36);primaryKey"`
+		CreatedAt time.Time
+
+		Name string `gorm:"uniqueIndex"`
+	}
+	type CatLocation struct {
+		ID        string `gorm:"type:char(36);primaryKey"`
+		CreatedAt time.Time
+
+		CatID string `gorm:"type:char(36);index"`
+
+		Name        string
+		Description string
+		Latitude    float64
+		Longitude   float64
+	}
+	type Image struct {
+		ID        string `gorm:"type:char(36);primaryKey"`
+		CreatedAt time.Time
+		UpdatedAt time.Time
+
+		CatID     string `gorm:"type:char(36);index"`
+		URL       string
+		Data      []byte
+		MIME      string
+		Title     string
+		Optimized bool `gorm:"index;default:false"`
+	}
+	type Record struct {
+		ID        string `gorm:"type:char(36);primaryKey"`
+		CreatedAt time.Time
+
+		CatID  string `gorm:"type:char(36);index"`
+		UserID string `gorm:"type:char(36);index"`
+		User   User
+
+		Type      string
+		Note      string
+		Timestamp time.Time
+		PlannedAt *time.Time
+		DoneAt    *time.Time
+
+		Recurrence string
+		Interval   int
+		EndDate    *time.Time
+	}
+	type Cat struct {
+		ID        string `gorm:"type:char(36);primaryKey"`
+		CreatedAt time.Time
+		UpdatedAt time.Time
+		DeletedAt gorm.DeletedAt `gorm:"index"`
+
+		OrganizationID string `gorm:"type:char(36);index"`
+
+		Name          string
+		Description   string
+		Color         string
+		BirthDate     *time.Time
+		Gender        string
+		IsSterilized  bool
+		NeedAttention bool
+		Condition     int `gorm:"type:integer;default:3"`
+
+		LastSeen *time.Time
+
+		Locations []CatLocation `gorm:"constraint:OnDelete:CASCADE;"`
+		Images    []Image       `gorm:"constraint:OnDelete:CASCADE;"`
+		Records   []Record      `gorm:"constraint:OnDelete:CASCADE;"`
+		Tags      []Tag         `gorm:"many2many:cat_tags;"`
+	}
+	type BotNotification struct {
+		RecordID string `gorm:"primaryKey;index"`
+		ChatID   int64  `gorm:"primaryKey;index"`
+		SentAt   time.Time
+	}
+	type AuditLog struct {
+		ID         string    `gorm:"type:char(36);primaryKey"`
+		Timestamp  time.Time `gorm:"index"`
+		UserID     string    `gorm:"type:char(36);index"`
+		Method     string
+		Route      string
+		TargetType string `gorm:"index"`
+		TargetID   string `gorm:"index"`
+		Status     string
+		StatusCode int
+		RequestID  string
+		Delta      string
+
+		Seq       *int64 `gorm:"uniqueIndex"`
+		Kind      string `gorm:"type:varchar(16)"`
+		PrevHash  string `gorm:"type:char(64)"`
+		Hash      string `gorm:"type:char(64)"`
+		Signature string
+	}
+	type BotLink struct {
+		ChatID    int64  `gorm:"primaryKey"`
+		UserID    string `gorm:"type:char(36);index"`
+		CreatedAt time.Time
+	}
+	type Setting struct {
+		Key       string `gorm:"primaryKey"`
+		CreatedAt time.Time
+		UpdatedAt time.Time
+
+		Value string
+	}
+	type Like struct {
+		ID        string `gorm:"type:char(36);primaryKey"`
+		CreatedAt time.Time
+
+		CatID  string `gorm:"type:char(36);index:idx_cat_user,unique"`
+		UserID string `gorm:"type:char(36);index:idx_cat_user,unique"`
+	}
+	type Organization struct {
+		ID        string `gorm:"type:char(36);primaryKey"`
+		CreatedAt time.Time
+		UpdatedAt time.Time
+
+		Name   string
+		Slug   string `gorm:"uniqueIndex"`
+		Public bool   `gorm:"default:false"`
+	}
+	type Membership struct {
+		OrganizationID string `gorm:"type:char(36);primaryKey"`
+		UserID         string `gorm:"type:char(36);primaryKey;index"`
+		CreatedAt      time.Time
+	}
+	return []any{
+		&User{},
+		&Cat{},
+		&Tag{},
+		&CatLocation{},
+		&Image{},
+		&Record{},
+		&BotNotification{},
+		&AuditLog{},
+		&BotLink{},
+		&Setting{},
+		&Like{},
+		&Organization{},
+		&Membership{},
+	}
+}
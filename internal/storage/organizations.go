@@ -0,0 +1,215 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DefaultOrganizationSlug identifies the organization that owns cats created before
+// organizations existed and that users without any membership belong to.
+const DefaultOrganizationSlug = "default"
+
+// Organization is an independent volunteer group with its own cat registry.
+type Organization struct {
+	ID        string    `gorm:"type:char(36);primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Name string `json:"name"`
+	Slug string `gorm:"uniqueIndex" json:"slug"`
+	// Public organizations opt in to exposing their cats to anonymous visitors.
+	Public bool `gorm:"default:false" json:"public"`
+	// TimeZone is the IANA zone new plans of its cats repeat in.
+	TimeZone string `json:"time_zone"`
+}
+
+// Membership links a user to an organization.
+type Membership struct {
+	OrganizationID string    `gorm:"type:char(36);primaryKey" json:"organization_id"`
+	UserID         string    `gorm:"type:char(36);primaryKey;index" json:"user_id"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// OrgScope restricts queries to a set of organizations.
+type OrgScope struct {
+	All bool     // no restriction (admins, trusted bot requests)
+	IDs []string // allowed organization IDs when All is false
+}
+
+// Cats restricts a query on the cats table to the scope.
+func (sc OrgScope) Cats(db *gorm.DB) *gorm.DB {
+	if sc.All {
+		return db
+	}
+	if len(sc.IDs) == 0 {
+		return db.Where("1 = 0")
+	}
+	return db.Where("cats.organization_id IN ?", sc.IDs)
+}
+
+// ByCat restricts a query on a table with a cat_id column (records, images, locations) to the scope.
+func (sc OrgScope) ByCat(db *gorm.DB) *gorm.DB {
+	if sc.All {
+		return db
+	}
+	if len(sc.IDs) == 0 {
+		return db.Where("1 = 0")
+	}
+	sub := db.Session(&gorm.Session{NewDB: true}).Unscoped().Model(&Cat{}).Select("id").Where("organization_id IN ?", sc.IDs)
+	return db.Where("cat_id IN (?)", sub)
+}
+
+// Allows reports whether the organization is within the scope.
+func (sc OrgScope) Allows(orgID string) bool {
+	if sc.All {
+		return true
+	}
+	for _, id := range sc.IDs {
+		if id == orgID {
+			return true
+		}
+	}
+	return false
+}
+
+// Union returns a scope allowing organizations from both scopes.
+func (sc OrgScope) Union(other OrgScope) OrgScope {
+	if sc.All || other.All {
+		return OrgScope{All: true}
+	}
+	out := OrgScope{IDs: append([]string{}, sc.IDs...)}
+	for _, id := range other.IDs {
+		if !out.Allows(id) {
+			out.IDs = append(out.IDs, id)
+		}
+	}
+	return out
+}
+
+// EnsureDefaultOrganization creates the default organization if needed and assigns
+// all cats without an organization to it.
+func (s *Store) EnsureDefaultOrganization() (*Organization, error) {
+	var org Organization
+	res := s.DB.Where("slug = ?", DefaultOrganizationSlug).Limit(1).Find(&org)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		// The default organization stays public to keep the pre-organization behavior.
+		org = Organization{ID: NewUUID(), Name: "Default", Slug: DefaultOrganizationSlug, Public: true}
+		// Only the columns of the default_organization migration, which calls this
+		if err := s.DB.Select("ID", "CreatedAt", "UpdatedAt", "Name", "Slug", "Public").Create(&org).Error; err != nil {
+			return nil, err
+		}
+	}
+	if err := s.DB.Unscoped().Model(&Cat{}).
+		Where("organization_id IS NULL OR organization_id = ''").
+		Update("organization_id", org.ID).Error; err != nil {
+		return nil, fmt.Errorf("backfill cat organizations: %w", err)
+	}
+	return &org, nil
+}
+
+// DefaultOrganizationID returns the ID of the default organization.
+func (s *Store) DefaultOrganizationID() string {
+	return defaultOrganizationID(s.DB)
+}
+
+func defaultOrganizationID(db *gorm.DB) string {
+	var ids []string
+	db.Model(&Organization{}).Where("slug = ?", DefaultOrganizationSlug).Limit(1).Pluck("id", &ids)
+	if len(ids) == 0 {
+		return ""
+	}
+	return ids[0]
+}
+
+// BeforeCreate assigns cats created without an organization to the default one.
+func (c *Cat) BeforeCreate(tx *gorm.DB) error {
+	if c.OrganizationID == "" {
+		c.OrganizationID = defaultOrganizationID(tx.Session(&gorm.Session{NewDB: true}))
+	}
+	return nil
+}
+
+// UserOrganizationIDs returns the organizations the user is a member of.
+// Users without memberships belong to the default organization.
+func (s *Store) UserOrganizationIDs(userID string) []string {
+	var ids []string
+	s.DB.Model(&Membership{}).Where("user_id = ?", userID).Order("created_at ASC").Pluck("organization_id", &ids)
+	if len(ids) == 0 {
+		if def := s.DefaultOrganizationID(); def != "" {
+			ids = []string{def}
+		}
+	}
+	return ids
+}
+
+// PublicOrganizationIDs returns organizations that opted in to public visibility.
+func (s *Store) PublicOrganizationIDs() []string {
+	var ids []string
+	s.DB.Model(&Organization{}).Where("public = ?", true).Pluck("id", &ids)
+	return ids
+}
+
+// MemberScope is the scope a user may modify: their organizations, or everything for admins.
+func (s *Store) MemberScope(userID, role string) OrgScope {
+	if userID == "" {
+		return OrgScope{}
+	}
+	if role == RoleAdmin {
+		return OrgScope{All: true}
+	}
+	return OrgScope{IDs: s.UserOrganizationIDs(userID)}
+}
+
+// PublicScope is the scope visible to anonymous visitors.
+func (s *Store) PublicScope() OrgScope {
+	return OrgScope{IDs: s.PublicOrganizationIDs()}
+}
+
+// ReadScope is the scope a user may read: their organizations plus public ones.
+func (s *Store) ReadScope(userID, role string) OrgScope {
+	return s.MemberScope(userID, role).Union(s.PublicScope())
+}
+
+// ListOrganizations returns organizations within the scope ordered by name.
+func (s *Store) ListOrganizations(scope OrgScope) ([]Organization, error) {
+	var orgs []Organization
+	db := s.DB.Order("name ASC")
+	if !scope.All {
+		if len(scope.IDs) == 0 {
+			return orgs, nil
+		}
+		db = db.Where("id IN ?", scope.IDs)
+	}
+	err := db.Find(&orgs).Error
+	return orgs, err
+}
+
+// ListMembers returns users that are explicit members of the organization.
+func (s *Store) ListMembers(orgID string) ([]User, error) {
+	var users []User
+	err := s.DB.Joins("JOIN memberships ON memberships.user_id = users.id").
+		Where("memberships.organization_id = ?", orgID).
+		Order("users.name ASC").
+		Find(&users).Error
+	return users, err
+}
+
+// AddMember adds the user to the organization (idempotent).
+func (s *Store) AddMember(orgID, userID string) error {
+	var n int64
+	s.DB.Model(&Membership{}).Where("organization_id = ? AND user_id = ?", orgID, userID).Count(&n)
+	if n > 0 {
+		return nil
+	}
+	return s.DB.Create(&Membership{OrganizationID: orgID, UserID: userID}).Error
+}
+
+// RemoveMember removes the user from the organization.
+func (s *Store) RemoveMember(orgID, userID string) error {
+	return s.DB.Where("organization_id = ? AND user_id = ?", orgID, userID).Delete(&Membership{}).Error
+}
@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"sort"
+	"time"
+)
+
+// DefaultOverdueWindow is how far back occurrences of recurring records count as overdue.
+const DefaultOverdueWindow = 7 * 24 * time.Hour
+
+// OverdueRecords returns the planned records of the scope, of the cat if catID is set, whose time
+// passed before now without being done, oldest first, with Overdue set. One-off plans stay overdue until they are done;
+// occurrences of recurring records only within window before now, so that a daily feeding
+// nobody marked does not pile up forever.
+func (s *Store) OverdueRecords(scope OrgScope, catID string, now time.Time, window time.Duration) ([]Record, error) {
+	if window <= 0 {
+		window = DefaultOverdueWindow
+	}
+	db := scope.ByCat(s.DB.Model(&Record{})).Where("planned_at IS NOT NULL AND done_at IS NULL AND planned_at < ?", now)
+	if catID != "" {
+		db = db.Where("cat_id = ?", catID)
+	}
+	var recs []Record
+	if err := db.Preload("User").Find(&recs).Error; err != nil {
+		return nil, err
+	}
+	var out, recurring []Record
+	for _, r := range recs {
+		if _, ok := r.RecurrenceSet(); ok {
+			recurring = append(recurring, r)
+		} else {
+			out = append(out, r)
+		}
+	}
+	occurrences, err := s.ExpandPlannedRecords(recurring, now.Add(-window), now)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range occurrences {
+		if r.EffectiveTime().Before(now) {
+			out = append(out, r)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].EffectiveTime().Before(out[j].EffectiveTime()) })
+	MarkOverdue(out, now)
+	return out, nil
+}
+
+// MarkOverdue sets Overdue on the planned records whose time is before now.
+func MarkOverdue(recs []Record, now time.Time) {
+	for i := range recs {
+		recs[i].Overdue = recs[i].DoneAt == nil && recs[i].PlannedAt != nil && recs[i].PlannedAt.Before(now)
+	}
+}
@@ -0,0 +1,227 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Actions of occurrence overrides.
+const (
+	OverrideSkipped     = "skipped"
+	OverrideRescheduled = "rescheduled"
+	OverrideSnoozed     = "snoozed"
+	// OverrideDone is set when the occurrence is marked done and stored as its own record.
+	OverrideDone = "done"
+)
+
+// RecordOverride changes a single occurrence of a recurring record, identified by the record
+// and the day of the occurrence. There is at most one per occurrence; every change gets a new ID.
+type RecordOverride struct {
+	ID        string    `gorm:"type:char(36);primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	RecordID string `gorm:"type:char(36);uniqueIndex:idx_record_override_day" json:"record_id"`
+	Day      string `gorm:"size:8;uniqueIndex:idx_record_override_day" json:"day"` // YYYYMMDD as in virtual IDs
+	UserID   string `gorm:"type:char(36);index" json:"user_id"`
+
+	Action    string     `json:"action"`               // skipped, rescheduled, snoozed, done
+	PlannedAt *time.Time `json:"planned_at,omitempty"` // new time of rescheduled and snoozed occurrences
+}
+
+// LoadOccurrence finds the occurrence with the given ID in db, which may be scoped: a virtual ID,
+// or the ID of a recurring record for its first occurrence. It returns the record, the day of the
+// occurrence and its time as per the rule, or gorm.ErrRecordNotFound.
+func LoadOccurrence(db *gorm.DB, id string) (Record, string, time.Time, error) {
+	recordID, day, virtual := ParseVirtualRecordID(id)
+	if !virtual {
+		recordID = id
+	}
+	var rec Record
+	if err := db.Where("id = ? AND done_at IS NULL", recordID).First(&rec).Error; err != nil {
+		return rec, "", time.Time{}, err
+	}
+	set, ok := rec.RecurrenceSet()
+	if !ok {
+		return rec, "", time.Time{}, gorm.ErrRecordNotFound
+	}
+	if !virtual {
+		day = set.DayKey(*rec.PlannedAt)
+	}
+	planned, ok := set.On(day)
+	if !ok {
+		return rec, "", time.Time{}, gorm.ErrRecordNotFound
+	}
+	return rec, day, planned, nil
+}
+
+// OccurrenceChange asks to skip, reschedule or snooze one occurrence. Rescheduled occurrences
+// move to PlannedAt, or by Minutes from their current time; snoozed ones by Minutes (an hour by
+// default) from their current time or from now, whichever is later.
+type OccurrenceChange struct {
+	Action    string     `json:"action"`
+	PlannedAt *time.Time `json:"planned_at,omitempty"`
+	Minutes   int        `json:"minutes,omitempty"`
+}
+
+var (
+	// ErrInvalidOverride is returned for changes that cannot be applied to an occurrence.
+	ErrInvalidOverride = errors.New("invalid occurrence change")
+	// ErrOccurrenceDone is returned when the occurrence was already marked done.
+	ErrOccurrenceDone = errors.New("occurrence is already done")
+)
+
+// maxOverrideShift bounds how far Minutes can move an occurrence.
+const maxOverrideShift = 366 * 24 * 60
+
+// OverrideOccurrence applies the change to the occurrence with the given ID, looked up in db
+// (see LoadOccurrence). It returns the new override and the one it replaces, if any.
+func (s *Store) OverrideOccurrence(db *gorm.DB, id, userID string, ch OccurrenceChange, now time.Time) (*RecordOverride, *RecordOverride, error) {
+	rec, day, planned, err := LoadOccurrence(db, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	prev, err := s.GetRecordOverride(rec.ID, day)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, err
+	}
+	if prev != nil && prev.Action == OverrideDone {
+		return nil, nil, ErrOccurrenceDone
+	}
+	current := planned
+	if prev != nil && prev.PlannedAt != nil {
+		current = *prev.PlannedAt
+	}
+	if ch.Minutes > maxOverrideShift || ch.Minutes < -maxOverrideShift {
+		return nil, nil, fmt.Errorf("%w: minutes out of range", ErrInvalidOverride)
+	}
+	o := &RecordOverride{RecordID: rec.ID, Day: day, UserID: userID, Action: ch.Action}
+	switch ch.Action {
+	case OverrideSkipped:
+	case OverrideRescheduled:
+		switch {
+		case ch.PlannedAt != nil:
+			t := *ch.PlannedAt
+			o.PlannedAt = &t
+		case ch.Minutes != 0:
+			t := current.Add(time.Duration(ch.Minutes) * time.Minute)
+			o.PlannedAt = &t
+		default:
+			return nil, nil, fmt.Errorf("%w: planned_at or minutes is required", ErrInvalidOverride)
+		}
+	case OverrideSnoozed:
+		if ch.Minutes < 0 {
+			return nil, nil, fmt.Errorf("%w: minutes must be positive", ErrInvalidOverride)
+		}
+		d := time.Hour
+		if ch.Minutes > 0 {
+			d = time.Duration(ch.Minutes) * time.Minute
+		}
+		t := current
+		if t.Before(now) {
+			t = now
+		}
+		t = t.Add(d)
+		o.PlannedAt = &t
+	default:
+		return nil, nil, fmt.Errorf("%w: unknown action %q", ErrInvalidOverride, ch.Action)
+	}
+	if err := s.SetRecordOverride(o); err != nil {
+		return nil, nil, err
+	}
+	return o, prev, nil
+}
+
+// CompleteOccurrence stores the occurrence with the given ID, looked up in db, as a new record
+// done at doneAt, and marks the occurrence done so that it is no longer listed as planned.
+func (s *Store) CompleteOccurrence(db *gorm.DB, id, userID string, doneAt time.Time) (*Record, error) {
+	rec, day, planned, err := LoadOccurrence(db, id)
+	if err != nil {
+		return nil, err
+	}
+	prev, err := s.GetRecordOverride(rec.ID, day)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if prev != nil && prev.Action == OverrideDone {
+		return nil, ErrOccurrenceDone
+	}
+	if prev != nil && prev.PlannedAt != nil {
+		planned = *prev.PlannedAt
+	}
+	done := rec
+	done.ID = NewUUID()
+	done.CreatedAt = doneAt
+	done.PlannedAt = &planned
+	done.DoneAt = &doneAt
+	// This instance is done and does not repeat
+	done.Recurrence, done.Interval, done.EndDate = "", 0, nil
+	done.RRule, done.ExDates, done.RDates = "", nil, nil
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&done).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("record_id = ? AND day = ?", rec.ID, day).Delete(&RecordOverride{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&RecordOverride{ID: NewUUID(), CreatedAt: doneAt, RecordID: rec.ID, Day: day, UserID: userID, Action: OverrideDone}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &done, nil
+}
+
+// ClearOccurrenceOverride restores the occurrence with the given ID, looked up in db, to its time
+// as per the rule. It returns the removed override, or gorm.ErrRecordNotFound if there is none.
+func (s *Store) ClearOccurrenceOverride(db *gorm.DB, id string) (*RecordOverride, error) {
+	rec, day, _, err := LoadOccurrence(db, id)
+	if err != nil {
+		return nil, err
+	}
+	prev, err := s.GetRecordOverride(rec.ID, day)
+	if err != nil {
+		return nil, err
+	}
+	if prev.Action == OverrideDone {
+		return nil, ErrOccurrenceDone
+	}
+	return s.DeleteRecordOverride(rec.ID, day)
+}
+
+// GetRecordOverride returns the override of an occurrence or gorm.ErrRecordNotFound.
+func (s *Store) GetRecordOverride(recordID, day string) (*RecordOverride, error) {
+	var o RecordOverride
+	if err := s.DB.Where("record_id = ? AND day = ?", recordID, day).First(&o).Error; err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+// SetRecordOverride replaces the override of the occurrence with o under a new ID, so that
+// reminders keyed by it are sent again.
+func (s *Store) SetRecordOverride(o *RecordOverride) error {
+	o.ID = NewUUID()
+	o.CreatedAt = time.Now()
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("record_id = ? AND day = ?", o.RecordID, o.Day).Delete(&RecordOverride{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(o).Error
+	})
+}
+
+// DeleteRecordOverride restores an occurrence to its time as per the rule and returns the removed
+// override, or gorm.ErrRecordNotFound.
+func (s *Store) DeleteRecordOverride(recordID, day string) (*RecordOverride, error) {
+	o, err := s.GetRecordOverride(recordID, day)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.DB.Delete(o).Error; err != nil {
+		return nil, err
+	}
+	return o, nil
+}
@@ -0,0 +1,204 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/maniack/catwatch/internal/recurrence"
+)
+
+// virtualRecordPrefix marks the IDs of occurrences of recurring records that are not stored:
+// virtual-<record id>-<YYYYMMDD>.
+const virtualRecordPrefix = "virtual-"
+
+// VirtualRecordID returns the ID of the occurrence of a recurring record on the given day
+// (see recurrence.DayLayout).
+func VirtualRecordID(recordID, day string) string {
+	return virtualRecordPrefix + recordID + "-" + day
+}
+
+// ParseVirtualRecordID splits an occurrence ID into the record ID and the day.
+func ParseVirtualRecordID(id string) (recordID, day string, ok bool) {
+	rest, found := strings.CutPrefix(id, virtualRecordPrefix)
+	if !found {
+		return "", "", false
+	}
+	i := strings.LastIndex(rest, "-")
+	if i <= 0 || len(rest)-i-1 != len(recurrence.DayLayout) {
+		return "", "", false
+	}
+	return rest[:i], rest[i+1:], true
+}
+
+// NormalizeRecurrence validates the recurrence and time zone of a record and stores its rule in canonical form.
+// Records sent with the legacy Recurrence/Interval/EndDate only get the matching rule; records
+// with a rule get the legacy fields that approximate it, for clients that only show those.
+func (r *Record) NormalizeRecurrence() error {
+	if r.TimeZone != "" {
+		if _, err := LoadTimeZone(r.TimeZone); err != nil {
+			return err
+		}
+	}
+	switch r.RecurrenceAnchor {
+	case "", RecurrenceAnchorSchedule:
+	case RecurrenceAnchorDone:
+		if r.RRule == "" && r.Recurrence == "" {
+			return fmt.Errorf("%w: a rule is required to repeat after done", recurrence.ErrInvalidRule)
+		}
+		if len(r.ExDates) > 0 || len(r.RDates) > 0 {
+			return fmt.Errorf("%w: exdates and rdates need a calendar rule", recurrence.ErrInvalidRule)
+		}
+	default:
+		return fmt.Errorf("%w: unknown recurrence anchor %q", recurrence.ErrInvalidRule, r.RecurrenceAnchor)
+	}
+	switch {
+	case r.RRule != "":
+		rule, err := recurrence.Parse(r.RRule)
+		if err != nil {
+			return err
+		}
+		r.RRule = rule.String()
+		r.Recurrence = strings.ToLower(rule.Freq.String())
+		r.Interval = rule.Interval
+		if !rule.Until.IsZero() {
+			until := rule.Until
+			r.EndDate = &until
+		}
+	case r.Recurrence != "":
+		rule := recurrence.FromLegacy(r.Recurrence, r.Interval, r.EndDate)
+		if rule == nil {
+			return fmt.Errorf("%w: unknown recurrence %q", recurrence.ErrInvalidRule, r.Recurrence)
+		}
+		r.RRule = rule.String()
+	}
+	return nil
+}
+
+// RecurrenceSet returns the occurrences of a planned record, or false if it does not repeat on
+// the calendar; completion-anchored records only have their next occurrence (see NextAfterDone).
+// Records stored before RRULE support fall back to Recurrence/Interval/EndDate. Occurrences
+// repeat at the wall-clock time of planned_at in the record's zone (see Location).
+func (r Record) RecurrenceSet() (recurrence.Set, bool) {
+	if r.PlannedAt == nil || r.CompletionAnchored() {
+		return recurrence.Set{}, false
+	}
+	var rule *recurrence.Rule
+	switch {
+	case r.RRule != "":
+		var err error
+		if rule, err = recurrence.Parse(r.RRule); err != nil {
+			return recurrence.Set{}, false
+		}
+	case r.Recurrence != "":
+		rule = recurrence.FromLegacy(r.Recurrence, r.Interval, r.EndDate)
+	}
+	if rule == nil {
+		if len(r.RDates) == 0 {
+			return recurrence.Set{}, false
+		}
+		// Only the listed dates on top of planned_at
+		rule = &recurrence.Rule{Freq: recurrence.Daily, Interval: 1, Count: 1}
+	}
+	return recurrence.Set{Start: r.PlannedAt.In(r.Location()), Rule: rule, ExDates: r.ExDates, RDates: r.RDates}, true
+}
+
+// ExpandRecords replaces recurring planned records with their occurrences within [start, end],
+// sorted by time. The occurrence on the day of planned_at is the record itself; the others get
+// virtual IDs (see VirtualRecordID). Overrides drop skipped and done occurrences and move
+// rescheduled and snoozed ones, which keep their IDs. Other records are kept if their time is
+// within the range.
+func ExpandRecords(recs []Record, overrides []RecordOverride, start, end time.Time) []Record {
+	byRecord := map[string]map[string]RecordOverride{}
+	for _, o := range overrides {
+		if byRecord[o.RecordID] == nil {
+			byRecord[o.RecordID] = map[string]RecordOverride{}
+		}
+		byRecord[o.RecordID][o.Day] = o
+	}
+	inRange := func(t time.Time) bool { return !t.Before(start) && !t.After(end) }
+
+	var out []Record
+	for _, r := range recs {
+		set, ok := r.RecurrenceSet()
+		if !ok || r.DoneAt != nil {
+			if inRange(r.EffectiveTime()) {
+				out = append(out, r)
+			}
+			continue
+		}
+		first := set.DayKey(*r.PlannedAt)
+		ovs := byRecord[r.ID]
+		instance := func(day string, planned time.Time) Record {
+			inst := r
+			if day != first {
+				inst.ID = VirtualRecordID(r.ID, day)
+			}
+			if o, ok := ovs[day]; ok && o.PlannedAt != nil {
+				planned = *o.PlannedAt
+				inst.Override = &o
+			}
+			inst.PlannedAt = &planned
+			return inst
+		}
+		seen := map[string]bool{}
+		for _, t := range set.Between(start, end) {
+			day := set.DayKey(t)
+			seen[day] = true
+			if o, ok := ovs[day]; ok && (o.Action == OverrideSkipped || o.Action == OverrideDone) {
+				continue
+			}
+			if inst := instance(day, t); inRange(*inst.PlannedAt) {
+				out = append(out, inst)
+			}
+		}
+		// Occurrences moved into the range from a day outside of it
+		for day, o := range ovs {
+			if seen[day] || o.PlannedAt == nil || !inRange(*o.PlannedAt) {
+				continue
+			}
+			if t, ok := set.On(day); ok {
+				out = append(out, instance(day, t))
+			}
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].EffectiveTime().Before(out[j].EffectiveTime()) })
+	return out
+}
+
+// ExpandPlannedRecords is ExpandRecords with the overrides of the records loaded from the database.
+func (s *Store) ExpandPlannedRecords(recs []Record, start, end time.Time) ([]Record, error) {
+	var ids []string
+	for _, r := range recs {
+		if _, ok := r.RecurrenceSet(); ok && r.DoneAt == nil {
+			ids = append(ids, r.ID)
+		}
+	}
+	overrides, err := s.RecordOverrides(ids)
+	if err != nil {
+		return nil, err
+	}
+	return ExpandRecords(recs, overrides, start, end), nil
+}
+
+// RecordOverrides returns the overrides of the occurrences of the given records.
+func (s *Store) RecordOverrides(recordIDs []string) ([]RecordOverride, error) {
+	var overrides []RecordOverride
+	if len(recordIDs) == 0 {
+		return overrides, nil
+	}
+	err := s.DB.Where("record_id IN ?", recordIDs).Order("day").Find(&overrides).Error
+	return overrides, err
+}
+
+// EffectiveTime is when the record happened or is due: done_at, else planned_at, else timestamp.
+func (r Record) EffectiveTime() time.Time {
+	switch {
+	case r.DoneAt != nil:
+		return *r.DoneAt
+	case r.PlannedAt != nil:
+		return *r.PlannedAt
+	}
+	return r.Timestamp
+}
@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// User roles, from the most to the least privileged.
+//   - admin: manages users and roles, everything a coordinator can do.
+//   - coordinator: may delete cats and photos in addition to volunteer rights.
+//   - volunteer: creates and edits cats, records, photos and locations.
+//   - viewer: read-only access (plus likes).
+const (
+	RoleAdmin       = "admin"
+	RoleCoordinator = "coordinator"
+	RoleVolunteer   = "volunteer"
+	RoleViewer      = "viewer"
+)
+
+// DefaultRole is assigned to new users and to tokens issued before roles existed.
+const DefaultRole = RoleVolunteer
+
+// ErrLastAdmin is returned when a role change would leave the deployment without an admin.
+var ErrLastAdmin = errors.New("cannot demote the last admin")
+
+var roleRank = map[string]int{
+	RoleViewer:      1,
+	RoleVolunteer:   2,
+	RoleCoordinator: 3,
+	RoleAdmin:       4,
+}
+
+// Roles returns all known roles ordered from the least to the most privileged.
+func Roles() []string {
+	return []string{RoleViewer, RoleVolunteer, RoleCoordinator, RoleAdmin}
+}
+
+// ValidRole reports whether role is one of the known roles.
+func ValidRole(role string) bool {
+	_, ok := roleRank[role]
+	return ok
+}
+
+// HasRole reports whether role grants at least the permissions of required.
+// Unknown roles grant nothing.
+func HasRole(role, required string) bool {
+	have, ok := roleRank[role]
+	if !ok {
+		return false
+	}
+	return have >= roleRank[required]
+}
+
+// GetUserRole returns the role of the user; unknown users are gorm.ErrRecordNotFound.
+// An invalid stored role grants read-only access.
+func (s *Store) GetUserRole(userID string) (string, error) {
+	var u User
+	if err := s.DB.Select("role").First(&u, "id = ?", userID).Error; err != nil {
+		return "", err
+	}
+	if !ValidRole(u.Role) {
+		return RoleViewer, nil
+	}
+	return u.Role, nil
+}
+
+// ListUsers returns all users ordered by creation time.
+func (s *Store) ListUsers() ([]User, error) {
+	var users []User
+	err := s.DB.Order("created_at ASC").Find(&users).Error
+	return users, err
+}
+
+// SetUserRole changes the role of a user. Demoting the last admin is refused with ErrLastAdmin.
+func (s *Store) SetUserRole(userID, role string) (*User, error) {
+	if !ValidRole(role) {
+		return nil, fmt.Errorf("unknown role: %s", role)
+	}
+	var u User
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&u, "id = ?", userID).Error; err != nil {
+			return err
+		}
+		if u.Role == RoleAdmin && role != RoleAdmin {
+			var admins int64
+			if err := tx.Model(&User{}).Where("role = ?", RoleAdmin).Count(&admins).Error; err != nil {
+				return err
+			}
+			if admins <= 1 {
+				return ErrLastAdmin
+			}
+		}
+		u.Role = role
+		return tx.Model(&u).Update("role", role).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// PromoteAdminByEmail grants the admin role to the user if their email is in the bootstrap list.
+// It is used to seed the first admin(s) of a deployment from configuration.
+func (s *Store) PromoteAdminByEmail(u *User, emails []string) error {
+	if u == nil || u.Email == "" || u.Role == RoleAdmin {
+		return nil
+	}
+	for _, e := range emails {
+		if strings.EqualFold(strings.TrimSpace(e), u.Email) {
+			if err := s.DB.Model(u).Update("role", RoleAdmin).Error; err != nil {
+				return err
+			}
+			u.Role = RoleAdmin
+			return nil
+		}
+	}
+	return nil
+}
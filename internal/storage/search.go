@@ -0,0 +1,309 @@
+package storage
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// Full-text search over cats. The cat_search table holds one document per cat with its
+// name, tag names, color, description, location names and the notes of done records
+// (planned records are visible to members only and are not indexed). Database triggers
+// keep it in sync with cats, tags, locations and records, so every write path is covered.
+//
+// SQLite uses an FTS5 virtual table ranked with bm25, Postgres a weighted tsvector column
+// ranked with ts_rank. Both use language-neutral tokenization since cats are described in
+// several languages.
+
+// searchMaxTerms bounds the number of words taken from a query.
+const searchMaxTerms = 8
+
+// Highlight markers used inside the database. These control characters do not occur in
+// normal text and are replaced after the excerpt has been HTML-escaped.
+const (
+	markStart = "\x02"
+	markEnd   = "\x03"
+)
+
+// SearchHit is a cat matching a search query.
+type SearchHit struct {
+	CatID string  `json:"cat_id"`
+	Rank  float64 `json:"rank"` // higher is better
+	// Highlight is an HTML-escaped excerpt of the best matching text with matches wrapped in <mark>.
+	Highlight string `json:"highlight"`
+}
+
+// searchIndex is implemented per database dialect.
+type searchIndex interface {
+	// install creates the index and the triggers that keep it in sync.
+	install(tx *gorm.DB) error
+	// rebuild re-indexes all cats.
+	rebuild(tx *gorm.DB) error
+	// uninstall drops the index and its triggers.
+	uninstall(tx *gorm.DB) error
+	// query selects cat_id, rank and highlight of the cats matching all terms, joined with cats.
+	query(db *gorm.DB, terms []string) *gorm.DB
+}
+
+func newSearchIndex(postgres bool) searchIndex {
+	if postgres {
+		return postgresSearch{}
+	}
+	return sqliteSearch{}
+}
+
+// RebuildSearchIndex re-indexes all cats.
+func (s *Store) RebuildSearchIndex() error {
+	return s.DB.Transaction(func(tx *gorm.DB) error { return s.search.rebuild(tx) })
+}
+
+// Search returns the cats within the scope matching every word of q, best matches first.
+// Words match by prefix; punctuation and operators in q are ignored.
+func (s *Store) Search(scope OrgScope, q string, limit int) ([]SearchHit, error) {
+	terms := searchTerms(q)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	db := scope.Cats(s.search.query(s.DB, terms)).Where("cats.deleted_at IS NULL")
+	if limit > 0 {
+		db = db.Limit(limit)
+	}
+	var hits []SearchHit
+	if err := db.Scan(&hits).Error; err != nil {
+		return nil, err
+	}
+	for i := range hits {
+		hits[i].Highlight = highlightHTML(hits[i].Highlight)
+	}
+	return hits, nil
+}
+
+// searchTerms splits q into lower-case words of letters and digits.
+func searchTerms(q string) []string {
+	words := strings.FieldsFunc(strings.ToLower(q), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	if len(words) > searchMaxTerms {
+		words = words[:searchMaxTerms]
+	}
+	return words
+}
+
+func highlightHTML(s string) string {
+	s = html.EscapeString(s)
+	return strings.NewReplacer(markStart, "<mark>", markEnd, "</mark>").Replace(s)
+}
+
+// searchDocumentSelect selects the indexed columns of the cats matching cond (SQL on alias c).
+// agg is the string aggregate function of the dialect.
+func searchDocumentSelect(agg, cond string) string {
+	return fmt.Sprintf(`SELECT c.id, COALESCE(c.name, ''),
+	COALESCE((SELECT %[1]s(t.name, ' ') FROM cat_tags ct JOIN tags t ON t.id = ct.tag_id WHERE ct.cat_id = c.id), ''),
+	COALESCE(c.color, ''),
+	COALESCE(c.description, ''),
+	COALESCE((SELECT %[1]s(l.name, ' ') FROM cat_locations l WHERE l.cat_id = c.id AND l.name <> ''), ''),
+	COALESCE((SELECT %[1]s(r.note, ' ') FROM records r WHERE r.cat_id = c.id AND r.done_at IS NOT NULL AND r.note <> ''), '')
+FROM cats c WHERE %[2]s`, agg, cond)
+}
+
+const searchColumns = "cat_id, name, tags, color, description, locations, notes"
+
+// sqliteSearch indexes cats in an FTS5 table.
+type sqliteSearch struct{}
+
+// sqliteRefresh re-indexes the cats whose ID matches cond (e.g. "= NEW.cat_id").
+func sqliteRefresh(cond string) string {
+	return fmt.Sprintf("DELETE FROM cat_search WHERE cat_id %s;\nINSERT INTO cat_search (%s) %s;",
+		cond, searchColumns, searchDocumentSelect("group_concat", "c.id "+cond))
+}
+
+func (sqliteSearch) install(tx *gorm.DB) error {
+	stmts := []string{
+		`CREATE VIRTUAL TABLE IF NOT EXISTS cat_search USING fts5(
+	cat_id UNINDEXED, name, tags, color, description, locations, notes,
+	tokenize = 'unicode61 remove_diacritics 2'
+)`,
+	}
+	triggers := map[string]string{
+		"cat_search_cats_ai": "AFTER INSERT ON cats BEGIN " + sqliteRefresh("= NEW.id") + " END",
+		"cat_search_cats_au": "AFTER UPDATE OF name, color, description ON cats BEGIN " + sqliteRefresh("= NEW.id") + " END",
+		"cat_search_cats_ad": "AFTER DELETE ON cats BEGIN DELETE FROM cat_search WHERE cat_id = OLD.id; END",
+		"cat_search_tags_au": "AFTER UPDATE OF name ON tags BEGIN " + sqliteRefresh("IN (SELECT cat_id FROM cat_tags WHERE tag_id = NEW.id)") + " END",
+	}
+	// Child tables: an update may move a row to another cat, so both sides are refreshed
+	for _, table := range []string{"records", "cat_locations", "cat_tags"} {
+		triggers["cat_search_"+table+"_ai"] = "AFTER INSERT ON " + table + " BEGIN " + sqliteRefresh("= NEW.cat_id") + " END"
+		triggers["cat_search_"+table+"_au"] = "AFTER UPDATE ON " + table + " BEGIN " + sqliteRefresh("= OLD.cat_id") + " " + sqliteRefresh("= NEW.cat_id") + " END"
+		triggers["cat_search_"+table+"_ad"] = "AFTER DELETE ON " + table + " BEGIN " + sqliteRefresh("= OLD.cat_id") + " END"
+	}
+	for name, body := range triggers {
+		stmts = append(stmts, "DROP TRIGGER IF EXISTS "+name, "CREATE TRIGGER "+name+" "+body)
+	}
+	for _, stmt := range stmts {
+		if err := tx.Exec(stmt).Error; err != nil {
+			return fmt.Errorf("search index: %w", err)
+		}
+	}
+	return nil
+}
+
+func (sqliteSearch) rebuild(tx *gorm.DB) error {
+	if err := tx.Exec("DELETE FROM cat_search").Error; err != nil {
+		return err
+	}
+	return tx.Exec(fmt.Sprintf("INSERT INTO cat_search (%s) %s", searchColumns, searchDocumentSelect("group_concat", "1 = 1"))).Error
+}
+
+func (sqliteSearch) uninstall(tx *gorm.DB) error {
+	var names []string
+	if err := tx.Raw("SELECT name FROM sqlite_master WHERE type = 'trigger' AND name LIKE 'cat_search_%'").Scan(&names).Error; err != nil {
+		return err
+	}
+	for _, name := range names {
+		if err := tx.Exec("DROP TRIGGER IF EXISTS " + name).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Exec("DROP TABLE IF EXISTS cat_search").Error
+}
+
+func (sqliteSearch) query(db *gorm.DB, terms []string) *gorm.DB {
+	match := make([]string, len(terms))
+	for i, t := range terms {
+		match[i] = `"` + t + `"*`
+	}
+	// bm25 weights follow the column order: cat_id, name, tags, color, description, locations, notes
+	return db.Table("cat_search").
+		Select("cat_search.cat_id AS cat_id, -bm25(cat_search, 0, 10, 5, 3, 2, 2, 1) AS rank, snippet(cat_search, -1, ?, ?, '…', 16) AS highlight", markStart, markEnd).
+		Joins("JOIN cats ON cats.id = cat_search.cat_id").
+		Where("cat_search MATCH ?", strings.Join(match, " ")).
+		Order("rank DESC").Order("cats.name")
+}
+
+// postgresSearch indexes cats in a table with a weighted tsvector column.
+type postgresSearch struct{}
+
+func (postgresSearch) install(tx *gorm.DB) error {
+	stmts := []string{
+		`CREATE TABLE IF NOT EXISTS cat_search (
+	cat_id text PRIMARY KEY,
+	name text NOT NULL DEFAULT '',
+	tags text NOT NULL DEFAULT '',
+	color text NOT NULL DEFAULT '',
+	description text NOT NULL DEFAULT '',
+	locations text NOT NULL DEFAULT '',
+	notes text NOT NULL DEFAULT '',
+	document tsvector GENERATED ALWAYS AS (
+		setweight(to_tsvector('simple', name), 'A') ||
+		setweight(to_tsvector('simple', tags), 'B') ||
+		setweight(to_tsvector('simple', color), 'B') ||
+		setweight(to_tsvector('simple', description), 'C') ||
+		setweight(to_tsvector('simple', locations), 'C') ||
+		setweight(to_tsvector('simple', notes), 'D')
+	) STORED
+)`,
+		`CREATE INDEX IF NOT EXISTS idx_cat_search_document ON cat_search USING GIN (document)`,
+		`CREATE OR REPLACE FUNCTION cat_search_refresh(target text) RETURNS void AS $$
+BEGIN
+	DELETE FROM cat_search WHERE cat_id = target;
+	INSERT INTO cat_search (` + searchColumns + `) ` + searchDocumentSelect("string_agg", "c.id = target") + `;
+END
+$$ LANGUAGE plpgsql`,
+		`CREATE OR REPLACE FUNCTION cat_search_cats_trigger() RETURNS trigger AS $$
+BEGIN
+	IF TG_OP = 'DELETE' THEN
+		DELETE FROM cat_search WHERE cat_id = OLD.id;
+	ELSE
+		PERFORM cat_search_refresh(NEW.id);
+	END IF;
+	RETURN NULL;
+END
+$$ LANGUAGE plpgsql`,
+		`CREATE OR REPLACE FUNCTION cat_search_child_trigger() RETURNS trigger AS $$
+BEGIN
+	IF TG_OP IN ('UPDATE', 'DELETE') THEN
+		PERFORM cat_search_refresh(OLD.cat_id);
+	END IF;
+	IF TG_OP IN ('INSERT', 'UPDATE') THEN
+		PERFORM cat_search_refresh(NEW.cat_id);
+	END IF;
+	RETURN NULL;
+END
+$$ LANGUAGE plpgsql`,
+		`CREATE OR REPLACE FUNCTION cat_search_tags_trigger() RETURNS trigger AS $$
+BEGIN
+	PERFORM cat_search_refresh(ct.cat_id) FROM cat_tags ct WHERE ct.tag_id = NEW.id;
+	RETURN NULL;
+END
+$$ LANGUAGE plpgsql`,
+		`DROP TRIGGER IF EXISTS cat_search_cats ON cats`,
+		`CREATE TRIGGER cat_search_cats AFTER INSERT OR DELETE OR UPDATE OF name, color, description ON cats
+	FOR EACH ROW EXECUTE FUNCTION cat_search_cats_trigger()`,
+		`DROP TRIGGER IF EXISTS cat_search_tags ON tags`,
+		`CREATE TRIGGER cat_search_tags AFTER UPDATE OF name ON tags
+	FOR EACH ROW EXECUTE FUNCTION cat_search_tags_trigger()`,
+	}
+	for _, table := range []string{"records", "cat_locations", "cat_tags"} {
+		stmts = append(stmts,
+			"DROP TRIGGER IF EXISTS cat_search_"+table+" ON "+table,
+			"CREATE TRIGGER cat_search_"+table+" AFTER INSERT OR UPDATE OR DELETE ON "+table+
+				" FOR EACH ROW EXECUTE FUNCTION cat_search_child_trigger()",
+		)
+	}
+	for _, stmt := range stmts {
+		if err := tx.Exec(stmt).Error; err != nil {
+			return fmt.Errorf("search index: %w", err)
+		}
+	}
+	return nil
+}
+
+func (postgresSearch) rebuild(tx *gorm.DB) error {
+	if err := tx.Exec("DELETE FROM cat_search").Error; err != nil {
+		return err
+	}
+	return tx.Exec(fmt.Sprintf("INSERT INTO cat_search (%s) %s", searchColumns, searchDocumentSelect("string_agg", "TRUE"))).Error
+}
+
+func (postgresSearch) uninstall(tx *gorm.DB) error {
+	stmts := []string{
+		"DROP TRIGGER IF EXISTS cat_search_cats ON cats",
+		"DROP TRIGGER IF EXISTS cat_search_tags ON tags",
+	}
+	for _, table := range []string{"records", "cat_locations", "cat_tags"} {
+		stmts = append(stmts, "DROP TRIGGER IF EXISTS cat_search_"+table+" ON "+table)
+	}
+	stmts = append(stmts,
+		"DROP FUNCTION IF EXISTS cat_search_cats_trigger()",
+		"DROP FUNCTION IF EXISTS cat_search_child_trigger()",
+		"DROP FUNCTION IF EXISTS cat_search_tags_trigger()",
+		"DROP FUNCTION IF EXISTS cat_search_refresh(text)",
+		"DROP TABLE IF EXISTS cat_search",
+	)
+	for _, stmt := range stmts {
+		if err := tx.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (postgresSearch) query(db *gorm.DB, terms []string) *gorm.DB {
+	prefix := make([]string, len(terms))
+	for i, t := range terms {
+		prefix[i] = t + ":*"
+	}
+	tsq := strings.Join(prefix, " & ")
+	opts := fmt.Sprintf(`StartSel="%s", StopSel="%s", MaxWords=24, MinWords=8, MaxFragments=2, FragmentDelimiter=" … "`, markStart, markEnd)
+	return db.Table("cat_search").
+		Select(`cat_search.cat_id AS cat_id, ts_rank(cat_search.document, to_tsquery('simple', ?)) AS rank,
+	ts_headline('simple', concat_ws(' · ', NULLIF(cat_search.name, ''), NULLIF(cat_search.tags, ''), NULLIF(cat_search.color, ''),
+		NULLIF(cat_search.description, ''), NULLIF(cat_search.locations, ''), NULLIF(cat_search.notes, '')), to_tsquery('simple', ?), ?) AS highlight`,
+			tsq, tsq, opts).
+		Joins("JOIN cats ON cats.id = cat_search.cat_id").
+		Where("cat_search.document @@ to_tsquery('simple', ?)", tsq).
+		Order("rank DESC").Order("cats.name")
+}
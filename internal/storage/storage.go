@@ -8,6 +8,7 @@ import (
 
 	"github.com/glebarez/sqlite"
 	"github.com/google/uuid"
+	"github.com/maniack/catwatch/internal/blobstore"
 	"github.com/maniack/catwatch/internal/logging"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -24,6 +25,15 @@ type User struct {
 	Email      string `gorm:"index" json:"email"`
 	Name       string `json:"name"`
 	AvatarURL  string `json:"avatar_url"`
+	Role       string `gorm:"type:varchar(16);default:volunteer;index" json:"role"`
+
+	// PhotoLocationSuggestions opts in to proposing sightings from the GPS position of uploaded photos
+	PhotoLocationSuggestions bool `gorm:"default:false" json:"photo_location_suggestions"`
+	// TimeZone is the IANA zone times are shown to the user in, e.g. Europe/Moscow.
+	TimeZone string `json:"time_zone"`
+
+	// Virtual field for the bot (populated in handlers)
+	OrganizationIDs []string `gorm:"-" json:"organization_ids,omitempty"`
 }
 
 // Cat represents a homeless cat.
@@ -33,6 +43,8 @@ type Cat struct {
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 
+	OrganizationID string `gorm:"type:char(36);index" json:"organization_id"`
+
 	Name          string     `json:"name"`
 	Description   string     `json:"description"`
 	Color         string     `json:"color"`
@@ -44,6 +56,10 @@ type Cat struct {
 
 	LastSeen *time.Time `json:"last_seen,omitempty"`
 
+	// ImageRetention is how many photos of the cat the cleanup keeps; nil uses the global
+	// setting and 0 keeps all of them.
+	ImageRetention *int `json:"image_retention,omitempty"`
+
 	Locations []CatLocation `gorm:"constraint:OnDelete:CASCADE;" json:"locations"`
 
 	Images  []Image  `gorm:"constraint:OnDelete:CASCADE;" json:"images"`
@@ -80,14 +96,32 @@ type Image struct {
 	ID        string    `gorm:"type:char(36);primaryKey" json:"id"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+	// DeletedAt is set when the cleanup moves the photo to the trash
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 
 	CatID string `gorm:"type:char(36);index" json:"cat_id"`
 	URL   string `json:"url"`
-	Data  []byte `json:"-"` // Optional: embedded image data (BLOB for SQLite, BYTEA for Postgres)
-	MIME  string `json:"mime"`
-	Title string `json:"title"`
-	// Optimized marks that the optimizer has processed this image (resized/recompressed)
+	Data  []byte `json:"-"` // Optional: embedded original image data (BLOB for SQLite, BYTEA for Postgres)
+	// BlobKey locates the original in the blob store instead of Data
+	BlobKey string `json:"-"`
+	MIME    string `json:"mime"`
+	Title   string `json:"title"` // caption
+	// Position orders the photos of a cat, lowest first. The primary photo is the cover of the cat;
+	// it and pinned photos are never pruned.
+	Position  int  `gorm:"default:0" json:"position"`
+	IsPrimary bool `gorm:"default:false" json:"is_primary"`
+	Pinned    bool `gorm:"default:false" json:"pinned"`
+	// Optimized marks that the optimizer has generated the renditions of this image
 	Optimized bool `gorm:"index;default:false" json:"-"`
+	// PHash is the perceptual hash (dHash) of the photo, used to recognize cats; set by the optimizer
+	PHash *int64 `gorm:"column:phash" json:"-"`
+
+	// External URLs are downloaded by the backend. Until then URL holds the address and
+	// FetchStatus is ImageFetchPending; once stored, the address moves to SourceURL.
+	SourceURL     string `json:"source_url,omitempty"`
+	FetchStatus   string `gorm:"size:16;index" json:"fetch_status,omitempty"`
+	FetchError    string `json:"fetch_error,omitempty"`
+	FetchAttempts int    `json:"-"`
 }
 
 // Record represents a service event for a cat (feeding, medical, etc.)
@@ -109,6 +143,21 @@ type Record struct {
 	Recurrence string     `json:"recurrence,omitempty"` // daily, weekly, monthly
 	Interval   int        `json:"interval,omitempty"`   // e.g. every 2 days
 	EndDate    *time.Time `json:"end_date,omitempty"`
+	// RRule is an RFC 5545 rule such as FREQ=WEEKLY;BYDAY=MO,WE,FR, starting at PlannedAt.
+	// It takes precedence over Recurrence/Interval/EndDate, which are kept for older clients.
+	RRule string `gorm:"column:rrule" json:"rrule,omitempty"`
+	// ExDates are days skipped by the rule, RDates extra occurrences.
+	ExDates []time.Time `gorm:"column:exdates;type:text;serializer:json" json:"exdates,omitempty"`
+	RDates  []time.Time `gorm:"column:rdates;type:text;serializer:json" json:"rdates,omitempty"`
+	// TimeZone is the IANA zone the plan repeats in, so feedings keep their wall-clock time across DST.
+	TimeZone string `json:"time_zone,omitempty"`
+	// RecurrenceAnchor "done" repeats the rule from the completion of the plan instead of the
+	// calendar: the record stays a single planned one and moves on when it is marked done.
+	RecurrenceAnchor string `json:"recurrence_anchor,omitempty"`
+	// Override is set on expanded occurrences that were rescheduled or snoozed.
+	Override *RecordOverride `gorm:"-" json:"override,omitempty"`
+	// Overdue is set in listings on plans whose time has passed without being done.
+	Overdue bool `gorm:"-" json:"overdue,omitempty"`
 }
 
 // AuditLog tracks all mutating actions.
@@ -121,9 +170,17 @@ type AuditLog struct {
 	Route      string    `json:"route"`
 	TargetType string    `gorm:"index" json:"target_type"`
 	TargetID   string    `gorm:"index" json:"target_id"`
-	Status     string    `json:"status"` // success, error
+	Status     string    `json:"status"`      // success, error
+	StatusCode int       `json:"status_code"` // HTTP response status, 0 for MCP tool calls
 	RequestID  string    `json:"request_id"`
-	Delta      string    `json:"delta"`
+	Delta      string    `json:"delta"` // JSON diff, see AuditDelta
+
+	// Hash chain, see audit_chain.go. Seq is NULL only for rows written before the chain existed.
+	Seq       *int64 `gorm:"uniqueIndex" json:"seq,omitempty"`
+	Kind      string `gorm:"type:varchar(16)" json:"kind,omitempty"` // empty for regular entries, "checkpoint"
+	PrevHash  string `gorm:"type:char(64)" json:"prev_hash,omitempty"`
+	Hash      string `gorm:"type:char(64)" json:"hash,omitempty"`
+	Signature string `json:"signature,omitempty"` // HMAC of Hash, checkpoints only
 }
 
 // BotLink associates a Telegram chat with a User.
@@ -156,15 +213,39 @@ type Like struct {
 	UserID string `gorm:"type:char(36);index:idx_cat_user,unique" json:"user_id"`
 }
 
+// CatRedirect points the ID of a cat that was merged away to the cat it was merged into,
+// so links to the old ID keep working.
+type CatRedirect struct {
+	FromID    string    `gorm:"type:char(36);primaryKey" json:"from_id"`
+	ToID      string    `gorm:"type:char(36);index" json:"to_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
 type Store struct {
 	DB *gorm.DB
+
+	likes  *likeCache // nil unless EnableLikeCache was called
+	search searchIndex
+	blobs  blobstore.Store // nil keeps image bytes in the database
 }
 
-// Open initializes the database (SQLite or PostgreSQL based on DSN) and runs auto-migrations.
+// Open connects to the database and applies all pending migrations.
+func Open(dsn string) (*Store, error) {
+	st, err := Connect(dsn)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := st.MigrateUp(0); err != nil {
+		return nil, fmt.Errorf("migrate: %w", err)
+	}
+	return st, nil
+}
+
+// Connect opens the database (SQLite or PostgreSQL based on DSN) without touching the schema.
 // If the provided string looks like a PostgreSQL DSN (starts with postgres:// or postgresql://,
 // or contains key=val pairs like host=/user=/dbname=), Postgres driver will be used.
 // Otherwise, it's treated as a SQLite path/DSN.
-func Open(dsn string) (*Store, error) {
+func Connect(dsn string) (*Store, error) {
 	log := logging.L()
 
 	isPg := isPostgresDSN(dsn)
@@ -191,24 +272,7 @@ func Open(dsn string) (*Store, error) {
 		sqlDB.SetMaxOpenConns(1)
 	}
 
-	if err = db.AutoMigrate(
-		&User{},
-		&Cat{},
-		&Tag{},
-		&CatLocation{},
-		&Image{},
-		&Record{},
-		&BotNotification{},
-		&AuditLog{},
-		&BotLink{},
-		&Setting{},
-		&Like{},
-	); err != nil {
-		return nil, fmt.Errorf("auto-migrate: %w", err)
-	}
-	log.Infof("Database auto-migration completed successfully")
-
-	return &Store{DB: db}, nil
+	return &Store{DB: db, search: newSearchIndex(isPg)}, nil
 }
 
 func isPostgresDSN(s string) bool {
@@ -255,6 +319,7 @@ func (s *Store) FindOrCreateUser(provider, providerID, email, name, avatar strin
 		u.Email = email
 		u.Name = name
 		u.AvatarURL = avatar
+		u.Role = DefaultRole
 		if err := s.DB.Create(u).Error; err != nil {
 			return nil, err
 		}
@@ -286,93 +351,11 @@ func (s *Store) UnlinkBotChat(chatID int64) error {
 	return s.DB.Delete(&BotLink{}, "chat_id = ?", chatID).Error
 }
 
-// Optimizer helpers and image maintenance
-// ListImagesToOptimize returns up to 'limit' images that haven't been optimized yet.
-func (s *Store) ListImagesToOptimize(limit int) ([]Image, error) {
-	var imgs []Image
-	err := s.DB.Where("optimized = ? OR optimized IS NULL", false).Order("created_at").Limit(limit).Find(&imgs).Error
-	return imgs, err
-}
-
-// MarkImageOptimizedEmpty marks an image as optimized when there is no image data to process.
-func (s *Store) MarkImageOptimizedEmpty(id string) error {
-	return s.DB.Model(&Image{}).Where("id = ?", id).Update("optimized", true).Error
-}
-
-// MarkImageOptimizedNoChange marks an image as optimized without changing its data.
-func (s *Store) MarkImageOptimizedNoChange(id string) error {
-	return s.DB.Model(&Image{}).Where("id = ?", id).Updates(map[string]any{"optimized": true, "updated_at": time.Now()}).Error
-}
-
-// UpdateImageOptimizedData stores optimized image bytes and MIME, marking it optimized.
-func (s *Store) UpdateImageOptimizedData(id string, data []byte, mime string) error {
-	return s.DB.Model(&Image{}).Where("id = ?", id).Updates(map[string]any{"data": data, "mime": mime, "optimized": true, "updated_at": time.Now()}).Error
-}
-
-// PruneOldCatImages keeps only the newest 'keepN' images for the given cat and deletes the rest.
-// Returns the number of deleted images and an error if occurred.
-func (s *Store) PruneOldCatImages(catID string, keepN int) (int64, error) {
-	if keepN <= 0 {
-		keepN = 5
-	}
-	// Select IDs ordered by CreatedAt desc, keep first keepN
-	var ids []string
-	if err := s.DB.Model(&Image{}).Where("cat_id = ?", catID).Order("created_at DESC, id DESC").Pluck("id", &ids).Error; err != nil {
-		return 0, err
-	}
-	if len(ids) <= keepN {
-		return 0, nil
-	}
-	toDelete := ids[keepN:]
-	res := s.DB.Where("id IN ?", toDelete).Delete(&Image{})
-	return res.RowsAffected, res.Error
-}
-
-// PruneAllCatsImages iterates over all cats and prunes images to keep 'keepN' each.
-// Returns total deleted count.
-func (s *Store) PruneAllCatsImages(keepN int) (int64, error) {
-	var catIDs []string
-	if err := s.DB.Model(&Cat{}).Order("id").Pluck("id", &catIDs).Error; err != nil {
-		return 0, err
-	}
-	var total int64
-	for _, id := range catIDs {
-		n, err := s.PruneOldCatImages(id, keepN)
-		if err != nil {
-			return total, err
-		}
-		total += n
-	}
-	return total, nil
-}
-
-// Like helpers
-
-func (s *Store) IsLikedByUser(catID, userID string) (bool, error) {
-	var n int64
-	err := s.DB.Model(&Like{}).Where("cat_id = ? AND user_id = ?", catID, userID).Count(&n).Error
-	return n > 0, err
-}
-
-func (s *Store) SetLike(catID, userID string, like bool) error {
-	if like {
-		l := &Like{ID: NewUUID(), CatID: catID, UserID: userID}
-		return s.DB.Create(l).Error
-	}
-	return s.DB.Where("cat_id = ? AND user_id = ?", catID, userID).Delete(&Like{}).Error
-}
-
-func (s *Store) LikesCount(catID string) (int64, error) {
-	var n int64
-	err := s.DB.Model(&Like{}).Where("cat_id = ?", catID).Count(&n).Error
-	return n, err
-}
-
 func (s *Store) GetUserLikedCats(userID string) ([]Cat, error) {
 	var cats []Cat
 	err := s.DB.Joins("JOIN likes ON likes.cat_id = cats.id").
 		Where("likes.user_id = ?", userID).
-		Preload("Images").Preload("Tags").
+		Preload("Images", OrderedImages).Preload("Tags").
 		Find(&cats).Error
 	return cats, err
 }
@@ -386,12 +369,9 @@ func (s *Store) GetUserAuditLogs(userID string, limit int) ([]AuditLog, error) {
 	return logs, err
 }
 
-func (s *Store) PruneAuditLogs(before time.Time) (int64, error) {
-	res := s.DB.Where("timestamp < ?", before).Delete(&AuditLog{})
-	return res.RowsAffected, res.Error
-}
-
 func (s *Store) DeleteUser(userID string) error {
+	// The user's likes change the counts of many cats
+	defer s.likes.reset()
 	return s.DB.Transaction(func(tx *gorm.DB) error {
 		// Delete related data first
 		if err := tx.Where("user_id = ?", userID).Delete(&Like{}).Error; err != nil {
@@ -400,10 +380,19 @@ func (s *Store) DeleteUser(userID string) error {
 		if err := tx.Where("user_id = ?", userID).Delete(&BotLink{}).Error; err != nil {
 			return err
 		}
+		if err := tx.Where("user_id = ?", userID).Delete(&Membership{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&CalendarToken{}).Error; err != nil {
+			return err
+		}
 		// Records and AuditLogs are kept but de-identified
 		if err := tx.Model(&Record{}).Where("user_id = ?", userID).Update("user_id", "").Error; err != nil {
 			return err
 		}
+		if err := tx.Model(&RecordOverride{}).Where("user_id = ?", userID).Update("user_id", "").Error; err != nil {
+			return err
+		}
 		// Finally delete the user
 		if err := tx.Delete(&User{}, "id = ?", userID).Error; err != nil {
 			return err
@@ -427,14 +416,26 @@ func (s *Store) GetUserExport(userID string) (map[string]any, error) {
 	var records []Record
 	s.DB.Where("user_id = ?", userID).Find(&records)
 
+	var overrides []RecordOverride
+	s.DB.Where("user_id = ?", userID).Find(&overrides)
+
+	var memberships []Membership
+	s.DB.Where("user_id = ?", userID).Find(&memberships)
+
+	var calendarTokens []CalendarToken
+	s.DB.Where("user_id = ?", userID).Find(&calendarTokens)
+
 	var auditLogs []AuditLog
 	s.DB.Where("user_id = ?", userID).Order("timestamp DESC").Find(&auditLogs)
 
 	return map[string]any{
-		"user":       user,
-		"likes":      likes,
-		"bot_links":  botLinks,
-		"records":    records,
-		"audit_logs": auditLogs,
+		"user":             user,
+		"likes":            likes,
+		"bot_links":        botLinks,
+		"records":          records,
+		"record_overrides": overrides,
+		"memberships":      memberships,
+		"calendar_tokens":  calendarTokens,
+		"audit_logs":       auditLogs,
 	}, nil
 }
@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	_ "time/tzdata" // zones must resolve on hosts without a zoneinfo database
+)
+
+// ErrInvalidTimeZone is returned for names that are not IANA time zones.
+var ErrInvalidTimeZone = errors.New("unknown time zone")
+
+// LoadTimeZone returns the location of an IANA time zone such as "Europe/Moscow". "" is UTC.
+func LoadTimeZone(name string) (*time.Location, error) {
+	if name == "Local" {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidTimeZone, name)
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidTimeZone, name)
+	}
+	return loc, nil
+}
+
+// Location returns the zone the plan is expanded in: its time zone, or else the location its
+// planned_at was read in, as for records stored before time zones.
+func (r Record) Location() *time.Location {
+	if r.TimeZone != "" {
+		if loc, err := LoadTimeZone(r.TimeZone); err == nil {
+			return loc
+		}
+	}
+	if r.PlannedAt != nil {
+		return r.PlannedAt.Location()
+	}
+	return time.UTC
+}
+
+// RecordsIn returns the records with their times converted to loc for display.
+func RecordsIn(recs []Record, loc *time.Location) []Record {
+	in := func(t *time.Time) *time.Time {
+		if t == nil {
+			return nil
+		}
+		v := t.In(loc)
+		return &v
+	}
+	out := make([]Record, len(recs))
+	for i, r := range recs {
+		r.Timestamp = r.Timestamp.In(loc)
+		r.PlannedAt, r.DoneAt, r.EndDate = in(r.PlannedAt), in(r.DoneAt), in(r.EndDate)
+		if r.Override != nil {
+			o := *r.Override
+			o.PlannedAt = in(o.PlannedAt)
+			r.Override = &o
+		}
+		out[i] = r
+	}
+	return out
+}
+
+// CatTimeZone returns the time zone of the organization of a cat, "" if it has none.
+func (s *Store) CatTimeZone(catID string) string {
+	var org Organization
+	err := s.DB.Select("organizations.time_zone").
+		Joins("JOIN cats ON cats.organization_id = organizations.id").
+		Where("cats.id = ?", catID).First(&org).Error
+	if err != nil {
+		return ""
+	}
+	return org.TimeZone
+}
@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ListDeletedCats returns soft-deleted cats within the scope, most recently deleted first.
+// Cats that were merged into another one cannot be restored and are left out.
+func (s *Store) ListDeletedCats(scope OrgScope) ([]Cat, error) {
+	var cats []Cat
+	err := scope.Cats(s.DB.Unscoped()).
+		Where("cats.deleted_at IS NOT NULL").
+		Where("cats.id NOT IN (?)", s.DB.Model(&CatRedirect{}).Select("from_id")).
+		Preload("Tags").
+		Order("cats.deleted_at DESC").
+		Find(&cats).Error
+	return cats, err
+}
+
+// GetDeletedCat returns a soft-deleted cat or gorm.ErrRecordNotFound.
+func (s *Store) GetDeletedCat(id string) (*Cat, error) {
+	var cat Cat
+	if err := s.DB.Unscoped().Where("deleted_at IS NOT NULL").First(&cat, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &cat, nil
+}
+
+// RestoreCat takes a cat out of the trash. Returns gorm.ErrRecordNotFound if it is not deleted
+// and ErrCatMerged if it was merged into another cat.
+func (s *Store) RestoreCat(id string) (*Cat, error) {
+	if _, merged := s.CatRedirect(id); merged {
+		return nil, ErrCatMerged
+	}
+	res := s.DB.Unscoped().Model(&Cat{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Update("deleted_at", nil)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	var cat Cat
+	if err := s.DB.Preload("Tags").First(&cat, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &cat, nil
+}
+
+// PurgeDeletedCats permanently removes cats that were deleted before the cutoff together with
+// their images, records (with sent reminders and occurrence overrides), locations, likes, tag
+// links and the redirects of cats merged into them.
+// It returns the purged cats.
+func (s *Store) PurgeDeletedCats(before time.Time) ([]Cat, error) {
+	var cats []Cat
+	if err := s.DB.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", before).Find(&cats).Error; err != nil {
+		return nil, err
+	}
+	var purged []Cat
+	for _, c := range cats {
+		keys, err := imageBlobKeys(s.DB, s.DB.Unscoped().Model(&Image{}).Select("id").Where("cat_id = ?", c.ID))
+		if err != nil {
+			return purged, err
+		}
+		if err := s.DB.Transaction(func(tx *gorm.DB) error { return purgeCat(tx, c.ID) }); err != nil {
+			return purged, err
+		}
+		s.likes.forgetCat(c.ID)
+		s.deleteBlobs(keys)
+		purged = append(purged, c)
+	}
+	return purged, nil
+}
+
+func purgeCat(tx *gorm.DB, catID string) error {
+	records := tx.Model(&Record{}).Select("id").Where("cat_id = ?", catID)
+	for _, model := range []any{&BotNotification{}, &RecordOverride{}} {
+		if err := tx.Where("record_id IN (?)", records).Delete(model).Error; err != nil {
+			return err
+		}
+	}
+	images := tx.Unscoped().Model(&Image{}).Select("id").Where("cat_id = ?", catID)
+	if err := tx.Where("image_id IN (?)", images).Delete(&ImageRendition{}).Error; err != nil {
+		return err
+	}
+	for _, model := range []any{&Record{}, &Image{}, &CatLocation{}, &Like{}} {
+		if err := tx.Unscoped().Where("cat_id = ?", catID).Delete(model).Error; err != nil {
+			return err
+		}
+	}
+	if err := tx.Exec("DELETE FROM cat_tags WHERE cat_id = ?", catID).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("to_id = ?", catID).Delete(&CatRedirect{}).Error; err != nil {
+		return err
+	}
+	return tx.Unscoped().Delete(&Cat{}, "id = ?", catID).Error
+}